@@ -14,6 +14,7 @@ import (
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/server"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/router"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
 )
 
 func main() {
@@ -45,13 +46,45 @@ func main() {
 		&model.RolePermission{},
 		&model.Space{},
 		&model.SpaceMember{},
+		&model.SpaceRolePermission{},
+		&model.OrgUnit{},
 		&model.SubSpace{},
 		&model.Class{},
+		&model.UserSession{},
+		&model.LoginThrottle{},
+		&model.PasswordHistory{},
+		&model.UserIdentity{},
+		&model.OIDCLoginState{},
+		&model.APIKey{},
+		&model.OperationLog{},
+		&model.SpaceMembershipRequest{},
+		&model.SpaceCreationRequest{},
+		&model.ProcessedEvent{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// 把权限常量生成的权限目录写入权限表，并为未配置的空间角色写入默认权限
+	roleService := service.NewRoleService(db)
+	if err := roleService.SyncPermissions(); err != nil {
+		log.Printf("Failed to sync permission catalog: %v", err)
+	} else if err := roleService.SyncSpaceRolePermissions(); err != nil {
+		log.Printf("Failed to seed space role permissions: %v", err)
+	}
+
+	// 启动操作日志过期清理
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	go service.NewOperationLogService(db).StartRetention(retentionCtx, cfg.Audit.RetentionDays)
+
+	// 启动目录用户定时同步
+	directoryCtx, stopDirectorySync := context.WithCancel(context.Background())
+	if cfg.LDAP.Enabled {
+		authService := service.NewAuthService(db, &cfg.JWT, &cfg.Lockout, &cfg.Password)
+		authService.UseDirectory(service.NewLDAPDirectory(&cfg.LDAP))
+		go authService.StartDirectorySync(directoryCtx, time.Duration(cfg.LDAP.SyncIntervalMinutes)*time.Minute)
+	}
+
 	// 初始化路由
 	r := router.Setup(cfg, db)
 
@@ -72,6 +105,9 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	stopRetention()
+	stopDirectorySync()
+
 	// 设置 5 秒的超时时间用于优雅关闭
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
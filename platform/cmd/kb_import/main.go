@@ -0,0 +1,108 @@
+// kb_import 从数据导出包导入空间、二级空间、分类和文档
+//
+// 用法：
+//
+//	go run cmd/kb_import/main.go -file export_1.zip -operator admin -dry-run
+//	go run cmd/kb_import/main.go -file export_1.zip -operator admin
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/client"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/config"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/service"
+)
+
+func main() {
+	archivePath := flag.String("file", "", "导出包路径(ZIP)")
+	operatorName := flag.String("operator", "", "执行导入的管理员用户名，新建对象与无法匹配的文档归属到该用户")
+	dryRun := flag.Bool("dry-run", false, "只校验并输出冲突报告，不写入数据")
+	skipProcess := flag.Bool("skip-process", false, "导入后不处理文档，之后可通过 KB 服务的 retry-process 接口(force_retry)处理")
+	flag.Parse()
+
+	if *archivePath == "" || *operatorName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 加载配置
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	logger.SetLevel(cfg.Log.Level)
+
+	// 初始化数据库，表结构由 KB 服务迁移
+	db, err := database.Init(database.Config{
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		User:     cfg.Database.User,
+		Password: cfg.Database.Password,
+		DBName:   cfg.Database.DBName,
+		SSLMode:  cfg.Database.SSLMode,
+	}, database.LogConfig{
+		DBLogLevel: cfg.Log.DBLogLevel,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	var operator model.User
+	if err := db.Preload("Roles").Where("username = ?", *operatorName).First(&operator).Error; err != nil {
+		log.Fatalf("Failed to load operator %q: %v", *operatorName, err)
+	}
+
+	file, err := os.Open(*archivePath)
+	if err != nil {
+		log.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		log.Fatalf("Failed to stat archive: %v", err)
+	}
+
+	minioClient := client.NewS3Client(&cfg.Minio)
+	vectorClient := client.NewQdrantClient(&cfg.Vector)
+	if vectorClient != nil && !*dryRun && !*skipProcess {
+		if err := vectorClient.EnsureCollection(context.Background()); err != nil {
+			log.Fatalf("Failed to ensure Qdrant collection: %v", err)
+		}
+	}
+	documentService := service.NewDocumentService(db, minioClient,
+		commonClient.NewWorkflowClient(&cfg.Workflow),
+		client.NewOpenAIClient(&cfg.OpenAI),
+		client.NewPaddleOCRClient(&cfg.OCR),
+		vectorClient,
+		nil,
+	)
+	importService := service.NewImportService(db, minioClient, documentService)
+
+	ctx := context.Background()
+	report, importErr := importService.Import(ctx, file, info.Size(), *dryRun, &operator)
+
+	if report != nil {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		os.Stdout.Write(append(output, '\n'))
+
+		if !*dryRun && !*skipProcess && len(report.DocumentIDs) > 0 {
+			log.Printf("Processing %d imported documents...", len(report.DocumentIDs))
+			importService.ProcessDocuments(ctx, report.DocumentIDs)
+		}
+	}
+
+	if importErr != nil {
+		log.Fatalf("Import failed: %v", importErr)
+	}
+	log.Println("Import finished")
+}
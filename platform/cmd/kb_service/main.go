@@ -8,10 +8,12 @@ import (
 	"syscall"
 	"time"
 
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/server"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/config"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/router"
@@ -45,6 +47,11 @@ func main() {
 	db, err := database.Init(dbCfg, logCfg,
 		&model.Document{},
 		&model.DocumentChunk{},
+		&model.WebhookSubscription{},
+		&model.WebhookDelivery{},
+		&model.ProcessedEvent{},
+		&model.OperationLog{},
+		&model.ExportJob{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -54,7 +61,9 @@ func main() {
 	minioClient := client.NewS3Client(&cfg.Minio)
 
 	// 初始化workflowClient
-	workflowClient := client.NewWorkflowClient(&cfg.Workflow)
+	workflowClient := commonClient.NewWorkflowClient(&cfg.Workflow)
+	// 初始化iamClient，用于校验空间权限
+	iamClient := client.NewIamClient(&cfg.Iam)
 	// 初始化OpenAI客户端
 	openAIClient := client.NewOpenAIClient(&cfg.OpenAI)
 	// 初始化 PaddleOCR
@@ -67,8 +76,13 @@ func main() {
 		}
 	}
 
+	// 初始化 Webhook 投递器，并在后台重试失败的投递（包括 workflow 服务写入的记录）
+	dispatcher := webhook.NewDispatcher(db, &cfg.Webhook)
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	go dispatcher.Start(dispatcherCtx)
+
 	// 初始化路由
-	r := router.Setup(cfg, db, minioClient, workflowClient, openAIClient, ocrClient, vectorClient)
+	r := router.Setup(cfg, db, minioClient, workflowClient, iamClient, openAIClient, ocrClient, vectorClient, dispatcher)
 
 	// 启动服务器
 	srv := server.New(&cfg.Server, r)
@@ -86,6 +100,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopDispatcher()
 
 	// 设置 5 秒的超时时间用于优雅关闭
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
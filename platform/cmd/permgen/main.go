@@ -0,0 +1,135 @@
+// permgen 根据 PermissionName 常量生成权限目录，由 internal/common/models 的 go:generate 调用。
+//
+// 每个权限常量需要带行尾注释「显示名 (resource:action)」，常量前的分组注释作为权限分组：
+//
+//	// 内容权限
+//	PermissionCreateDocument PermissionName = "create_document" // 创建文档 (document:create)
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var commentPattern = regexp.MustCompile(`^(.+?)\s*\(([a-z_]+):([a-z_]+)\)$`)
+
+type permission struct {
+	Const       string
+	Name        string
+	DisplayName string
+	Resource    string
+	Action      string
+	Group       string
+	Pos         token.Pos
+}
+
+func main() {
+	output := flag.String("output", "permission_catalog_gen.go", "生成的文件名")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != *output
+	}, parser.ParseComments)
+	if err != nil {
+		log.Fatalf("permgen: %v", err)
+	}
+
+	var pkgName string
+	var permissions []permission
+	for name, pkg := range pkgs {
+		pkgName = name
+		for _, file := range pkg.Files {
+			found, err := collect(fset, file)
+			if err != nil {
+				log.Fatalf("permgen: %v", err)
+			}
+			permissions = append(permissions, found...)
+		}
+	}
+	if len(permissions) == 0 {
+		log.Fatalf("permgen: no PermissionName constants found")
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].Pos < permissions[j].Pos })
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by cmd/permgen; DO NOT EDIT.\n\npackage %s\n\n", pkgName)
+	buf.WriteString("// PermissionCatalog 全部权限定义，按常量声明顺序排列\n")
+	buf.WriteString("var PermissionCatalog = []PermissionDefinition{\n")
+	for _, p := range permissions {
+		fmt.Fprintf(&buf, "\t{Name: %s, DisplayName: %s, Resource: %s, Action: %s, Group: %s},\n",
+			p.Const, strconv.Quote(p.DisplayName), strconv.Quote(p.Resource), strconv.Quote(p.Action), strconv.Quote(p.Group))
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("permgen: format: %v", err)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatalf("permgen: %v", err)
+	}
+}
+
+// collect 收集文件中类型为 PermissionName 的常量
+func collect(fset *token.FileSet, file *ast.File) ([]permission, error) {
+	var permissions []permission
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+
+		group := ""
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if value.Doc != nil {
+				group = strings.TrimSpace(value.Doc.Text())
+			}
+			ident, ok := value.Type.(*ast.Ident)
+			if !ok || ident.Name != "PermissionName" {
+				continue
+			}
+
+			for i, name := range value.Names {
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					return nil, fmt.Errorf("%s: %s must be a string literal", fset.Position(name.Pos()), name.Name)
+				}
+				permissionName, _ := strconv.Unquote(lit.Value)
+
+				comment := ""
+				if value.Comment != nil {
+					comment = strings.TrimSpace(value.Comment.Text())
+				}
+				match := commentPattern.FindStringSubmatch(comment)
+				if match == nil {
+					return nil, fmt.Errorf("%s: %s needs a trailing comment like `// 显示名 (resource:action)`",
+						fset.Position(name.Pos()), name.Name)
+				}
+
+				permissions = append(permissions, permission{
+					Const:       name.Name,
+					Name:        permissionName,
+					DisplayName: match[1],
+					Resource:    match[2],
+					Action:      match[3],
+					Group:       group,
+					Pos:         name.Pos(),
+				})
+			}
+		}
+	}
+	return permissions, nil
+}
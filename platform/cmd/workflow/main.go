@@ -12,9 +12,12 @@ import (
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database"
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/outbox"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/server"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/config"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/router"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/service"
 )
 
 func main() {
@@ -42,6 +45,13 @@ func main() {
 		&model.Workflow{},
 		&model.Step{},
 		&model.Task{},
+		&model.WorkflowHistory{},
+		&model.DelegationRule{},
+		&model.WorkflowTemplate{},
+		&model.WebhookSubscription{},
+		&model.WebhookDelivery{},
+		&model.OutboxEvent{},
+		&model.OperationLog{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -50,8 +60,26 @@ func main() {
 	// 初始化IAM客户端
 	iamClient := client.NewIamClient(&cfg.Iam)
 
+	// 初始化 Webhook 投递器（重试由 kb_service 负责）
+	dispatcher := webhook.NewDispatcher(db, &cfg.Webhook)
+
+	// 路由和审批超时调度共用同一个流程服务
+	workflowService := service.NewWorkflowService(db, iamClient, dispatcher, cfg.Approval.DefaultApproverIDs)
+
 	// 初始化路由
-	r := router.Setup(cfg, db, iamClient)
+	r := router.Setup(cfg, db, iamClient, workflowService)
+
+	// 启动审批超时调度
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	go workflowService.StartTimeoutScheduler(schedulerCtx,
+		time.Duration(cfg.Scheduler.IntervalSeconds)*time.Second,
+		service.TimeoutPolicy{
+			RemindInterval: time.Duration(cfg.Scheduler.RemindIntervalHours) * time.Hour,
+			Grace:          time.Duration(cfg.Scheduler.GraceHours) * time.Hour,
+		})
+
+	// 启动事件发件箱投递，把流程结束事件发送给资源所属服务
+	go outbox.NewRelay(db, &cfg.Outbox).Start(schedulerCtx)
 
 	// 启动服务器
 	srv := server.New(&cfg.Server, r)
@@ -70,6 +98,8 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	stopScheduler()
+
 	// 设置 5 秒的超时时间用于优雅关闭
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
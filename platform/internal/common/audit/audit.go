@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+// 服务名称
+const (
+	ServiceIam      = "iam"
+	ServiceKb       = "kb"
+	ServiceWorkflow = "workflow"
+)
+
+const maxUserAgentLength = 255
+
+// Entry 一条待记录的操作
+type Entry struct {
+	Action       model.OperationAction
+	ResourceType string
+	ResourceID   any // 支持 uint、string 等，统一转成字符串保存
+	SpaceID      uint
+	Before       any // 变更前快照，nil 表示无
+	After        any // 变更后快照，nil 表示无
+	Detail       string
+	Err          error       // 操作失败时传入，记录为 failure
+	User         *model.User // 为空时从上下文或 X-User-ID 中获取
+	Username     string      // 无法确定用户时（如登录失败）记录的登录名
+}
+
+// Recorder 写入操作审计日志，审计失败只记录错误日志，不影响业务请求
+type Recorder struct {
+	db      *gorm.DB
+	service string
+}
+
+// NewRecorder 创建审计记录器
+func NewRecorder(db *gorm.DB, service string) *Recorder {
+	return &Recorder{db: db, service: service}
+}
+
+// Record 记录一条操作日志
+func (r *Recorder) Record(c *gin.Context, entry Entry) {
+	if r == nil {
+		return
+	}
+
+	log := model.OperationLog{
+		Service:      r.service,
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   formatResourceID(entry.ResourceID),
+		SpaceID:      entry.SpaceID,
+		Before:       snapshot(entry.Before),
+		After:        snapshot(entry.After),
+		Detail:       entry.Detail,
+		Result:       model.OperationResultSuccess,
+		Username:     entry.Username,
+	}
+	if entry.Err != nil {
+		log.Result = model.OperationResultFailure
+		log.ErrorMessage = entry.Err.Error()
+	}
+
+	if c != nil {
+		log.IP = c.ClientIP()
+		log.UserAgent = c.Request.UserAgent()
+		if len(log.UserAgent) > maxUserAgentLength {
+			log.UserAgent = log.UserAgent[:maxUserAgentLength]
+		}
+	}
+
+	if user := r.resolveUser(c, entry.User); user != nil {
+		log.UserID = user.ID
+		log.Username = user.Username
+		log.Nickname = user.Nickname
+	}
+
+	if err := r.db.Create(&log).Error; err != nil {
+		logger.Errorf("audit: failed to record %s: %v", entry.Action, err)
+	}
+}
+
+// resolveUser 依次从参数、上下文、X-User-ID 请求头获取操作人
+func (r *Recorder) resolveUser(c *gin.Context, user *model.User) *model.User {
+	if user != nil {
+		return user
+	}
+	if c == nil {
+		return nil
+	}
+
+	if value, exists := c.Get("user"); exists {
+		if u, ok := value.(*model.User); ok {
+			return u
+		}
+	}
+
+	userID, err := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 32)
+	if err != nil {
+		return nil
+	}
+
+	var u model.User
+	if err := r.db.Select("id", "username", "nickname").First(&u, uint(userID)).Error; err != nil {
+		return &model.User{ID: uint(userID)}
+	}
+	return &u
+}
+
+func formatResourceID(id any) string {
+	switch v := id.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case int:
+		return strconv.Itoa(v)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func snapshot(v any) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.Warnf("audit: failed to marshal snapshot: %v", err)
+		return ""
+	}
+	return string(data)
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -59,3 +60,108 @@ func (c *IamClient) GetSpaceMemebersByRole(user *model.User, spaceID uint, role
 	}
 	return users, nil
 }
+
+// GetUsersByRole 获取拥有指定全局角色（如 corp_admin）的正常用户
+func (c *IamClient) GetUsersByRole(user *model.User, role string) ([]model.User, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/users/by-global-role/%s", c.config.Url, role)
+
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, errors.New("创建请求失败: " + err.Error())
+	}
+	req.Header.Set("X-User-ID", fmt.Sprintf("%d", user.ID))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("获取用户失败: " + resp.Status)
+	}
+
+	var response struct {
+		Data []model.User `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, errors.New("获取用户失败: " + err.Error())
+	}
+	return response.Data, nil
+}
+
+// GetDepartmentHead 获取用户的部门负责人，用户未加入组织或各级都没有可用负责人时返回 nil
+func (c *IamClient) GetDepartmentHead(user *model.User, userID uint) (*model.User, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/users/%d/department-head", c.config.Url, userID)
+
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, errors.New("创建请求失败: " + err.Error())
+	}
+	req.Header.Set("X-User-ID", fmt.Sprintf("%d", user.ID))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("获取部门负责人失败: " + resp.Status)
+	}
+
+	var response struct {
+		Data *model.User `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, errors.New("获取部门负责人失败: " + err.Error())
+	}
+	return response.Data, nil
+}
+
+// CheckPermission 通过 IAM 检查用户在空间内是否拥有资源的操作权限
+func (c *IamClient) CheckPermission(user *model.User, spaceID uint, resource, action string) (bool, error) {
+	return c.CheckOwnedPermission(user, spaceID, resource, action, 0)
+}
+
+// CheckOwnedPermission 同 CheckPermission，ownerID 为资源创建者，用于判断仅限本人的空间权限
+func (c *IamClient) CheckOwnedPermission(user *model.User, spaceID uint, resource, action string, ownerID uint) (bool, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/permissions/check", c.config.Url)
+
+	body, err := json.Marshal(map[string]any{
+		"space_id": spaceID,
+		"resource": resource,
+		"action":   action,
+		"owner_id": ownerID,
+	})
+	if err != nil {
+		return false, errors.New("创建请求失败: " + err.Error())
+	}
+
+	req, err := http.NewRequest("POST", targetURL, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.New("创建请求失败: " + err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", fmt.Sprintf("%d", user.ID))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New("权限检查失败: " + resp.Status)
+	}
+
+	var response struct {
+		Data struct {
+			HasPermission bool `json:"has_permission"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return false, errors.New("权限检查失败: " + err.Error())
+	}
+	return response.Data.HasPermission, nil
+}
@@ -4,23 +4,25 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 
+	commonConfig "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/config"
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
-	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/config"
 )
 
-// TODO 存取流程打通后接入
+// WorkflowClient 资源所属服务调用工作流服务创建、启动和查询审批流程
 type WorkflowClient struct {
-	config *config.WorkflowConfig
+	config *commonConfig.WorkflowConfig
 	client *http.Client
 }
 
-func NewWorkflowClient(config *config.WorkflowConfig) *WorkflowClient {
+func NewWorkflowClient(config *commonConfig.WorkflowConfig) *WorkflowClient {
 	return &WorkflowClient{
 		config: config,
 		client: &http.Client{
@@ -110,9 +112,114 @@ func (c *WorkflowClient) CreateWorkflow(ctx context.Context, workflow *model.Wor
 	return workflowData.ID, nil
 }
 
-func (c *WorkflowClient) CheckWorkflowStatus(ctx context.Context, workflowID uint) (string, error) {
+// ErrWorkflowTemplateNotFound 资源所在位置没有绑定启用的审批模板
+var ErrWorkflowTemplateNotFound = errors.New("workflow template not found")
+
+// CreateWorkflowFromTemplate 按资源所在位置匹配的审批模板创建流程
+func (c *WorkflowClient) CreateWorkflowFromTemplate(ctx context.Context, req *model.CreateWorkflowFromTemplateRequest, userID uint) (uint, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/workflow/workflows/from-template", c.config.Url)
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", targetURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-User-ID", fmt.Sprintf("%d", userID))
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, ErrWorkflowTemplateNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("Workflow service returned status %d, body: %s", resp.StatusCode, string(bodyBytes))
+		return 0, fmt.Errorf("workflow service returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Code    int            `json:"code"`
+		Message string         `json:"message"`
+		Data    model.Workflow `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Code != 200 {
+		return 0, fmt.Errorf("workflow service error: %s", response.Message)
+	}
+	if response.Data.ID == 0 {
+		return 0, fmt.Errorf("invalid ID value in response")
+	}
+	return response.Data.ID, nil
+}
+
+var (
+	// ErrWorkflowNotFound 资源没有审批流程
+	ErrWorkflowNotFound = errors.New("workflow not found")
+	// ErrWorkflowForbidden 用户无权查看资源的审批流程
+	ErrWorkflowForbidden = errors.New("no permission to view workflow")
+)
+
+// GetResourceTimeline 获取资源所有轮次的审批时间线
+func (c *WorkflowClient) GetResourceTimeline(ctx context.Context, resourceType string, resourceID uint, userID uint) (*model.ResourceTimeline, error) {
+	query := url.Values{}
+	query.Set("resource_type", resourceType)
+	query.Set("resource_id", fmt.Sprintf("%d", resourceID))
+	targetURL := fmt.Sprintf("%s/api/v1/workflow/workflows/resource?%s", c.config.Url, query.Encode())
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("X-User-ID", fmt.Sprintf("%d", userID))
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrWorkflowNotFound
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, ErrWorkflowForbidden
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Printf("Workflow service returned status %d, body: %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("workflow service returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Code    int                    `json:"code"`
+		Message string                 `json:"message"`
+		Data    model.ResourceTimeline `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Code != 200 {
+		return nil, fmt.Errorf("workflow service error: %s", response.Message)
+	}
+	return &response.Data, nil
+}
+
+func (c *WorkflowClient) CheckWorkflowStatus(ctx context.Context, workflowID uint, userID uint) (string, error) {
 	targetURL := fmt.Sprintf("%s/api/v1/workflow/workflows/%d/status", c.config.Url, workflowID)
-	resp, err := c.client.Get(targetURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("X-User-ID", fmt.Sprintf("%d", userID))
+	resp, err := c.client.Do(httpReq)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
 	}
@@ -138,12 +245,13 @@ func (c *WorkflowClient) CheckWorkflowStatus(ctx context.Context, workflowID uin
 	return response.Data.(string), nil
 }
 
-func (c *WorkflowClient) StartWorkflow(ctx context.Context, workflowID uint, userID uint) (*model.Workflow, error) {
+func (c *WorkflowClient) StartWorkflow(ctx context.Context, workflowID uint, userID uint, attributes map[string]any) (*model.Workflow, error) {
 	targetURL := fmt.Sprintf("%s/api/v1/workflow/workflows/%d/start", c.config.Url, workflowID)
 
 	// 构造请求体
 	reqBody := model.StartWorkflowRequest{
 		WorkflowID: workflowID,
+		Attributes: attributes,
 	}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
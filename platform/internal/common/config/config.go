@@ -55,6 +55,29 @@ type IamConfig struct {
 	Url string `mapstructure:"url"`
 }
 
+// WorkflowConfig Workflow 服务配置（通用）
+type WorkflowConfig struct {
+	Url string `mapstructure:"url"`
+}
+
+// OutboxConfig 服务间领域事件投递配置（通用），发送方和接收方使用同一个 Secret 签名和验签
+type OutboxConfig struct {
+	Targets             map[string]string `mapstructure:"targets"`               // 接收方服务名到回调地址，仅发送方使用
+	Secret              string            `mapstructure:"secret"`                // 共享签名密钥
+	TimeoutSeconds      int               `mapstructure:"timeout_seconds"`       // 单次投递超时时间
+	MaxAttempts         int               `mapstructure:"max_attempts"`          // 最大投递次数（含首次）
+	RetryBaseSeconds    int               `mapstructure:"retry_base_seconds"`    // 重试退避基准时间，按 2^n 递增
+	PollIntervalSeconds int               `mapstructure:"poll_interval_seconds"` // 扫描待投递事件的间隔
+}
+
+// WebhookConfig Webhook 投递配置（通用）
+type WebhookConfig struct {
+	TimeoutSeconds      int `mapstructure:"timeout_seconds"`       // 单次投递超时时间
+	MaxAttempts         int `mapstructure:"max_attempts"`          // 最大投递次数（含首次）
+	RetryBaseSeconds    int `mapstructure:"retry_base_seconds"`    // 重试退避基准时间，按 2^n 递增
+	PollIntervalSeconds int `mapstructure:"poll_interval_seconds"` // 重试扫描间隔
+}
+
 // Load 通用配置加载函数
 // configPaths: 配置文件搜索路径，例如 ["./internal/iam/config", "./config", "."]
 // cfg: 配置结构体指针
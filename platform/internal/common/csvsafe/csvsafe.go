@@ -0,0 +1,64 @@
+// Package csvsafe 写出可以直接用电子表格打开的 CSV，防止单元格内容被当作公式执行
+package csvsafe
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// Escape 以 = + - @ 或制表符、回车开头的内容会被电子表格当作公式，前面加单引号按文本显示
+func Escape(value string) string {
+	if value == "" {
+		return value
+	}
+	if isFormulaPrefix(value[0]) {
+		return "'" + value
+	}
+	return value
+}
+
+// Unescape 去掉 Escape 添加的单引号，用于重新导入本包写出的文件
+func Unescape(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && isFormulaPrefix(value[1]) {
+		return value[1:]
+	}
+	return value
+}
+
+func isFormulaPrefix(c byte) bool {
+	switch c {
+	case '=', '+', '-', '@', '\t', '\r':
+		return true
+	}
+	return false
+}
+
+// Writer 写入前转义每个单元格的 CSV 写入器
+type Writer struct {
+	*csv.Writer
+}
+
+// NewWriter 创建写入 w 的 CSV 写入器
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{Writer: csv.NewWriter(w)}
+}
+
+// Write 转义后写入一行
+func (w *Writer) Write(record []string) error {
+	escaped := make([]string, len(record))
+	for i, value := range record {
+		escaped[i] = Escape(value)
+	}
+	return w.Writer.Write(escaped)
+}
+
+// WriteAll 转义后写入多行并刷新缓冲
+func (w *Writer) WriteAll(records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
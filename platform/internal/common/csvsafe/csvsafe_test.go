@@ -0,0 +1,55 @@
+package csvsafe
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "plain", want: "plain"},
+		{value: "a=1", want: "a=1"},
+		{value: "=HYPERLINK(\"http://x\")", want: "'=HYPERLINK(\"http://x\")"},
+		{value: "+1+1", want: "'+1+1"},
+		{value: "-2+3", want: "'-2+3"},
+		{value: "@SUM(A1)", want: "'@SUM(A1)"},
+		{value: "\t=1", want: "'\t=1"},
+		{value: "\r=1", want: "'\r=1"},
+		{value: "中文", want: "中文"},
+	}
+	for _, tt := range tests {
+		if got := Escape(tt.value); got != tt.want {
+			t.Errorf("Escape(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	for _, value := range []string{"", "plain", "'quoted", "'", "=1", "+86 138", "-2+3", "@SUM(A1)", "\t=1", "中文"} {
+		if got := Unescape(Escape(value)); got != value {
+			t.Errorf("Unescape(Escape(%q)) = %q", value, got)
+		}
+	}
+}
+
+func TestWriterEscapesEveryCell(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.Write([]string{"1", "=cmd|' /C calc'!A0", "ok"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	w.Flush()
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if got := records[0][1]; got != "'=cmd|' /C calc'!A0" {
+		t.Fatalf("cell = %q, want escaped formula", got)
+	}
+}
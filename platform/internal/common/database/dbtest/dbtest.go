@@ -0,0 +1,35 @@
+// Package dbtest 为服务层测试提供基于 SQLite 的临时数据库
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open 在测试临时目录中创建 SQLite 数据库并迁移 models，测试结束时关闭连接。
+// 使用 WAL 和忙等待，允许事务进行期间其他连接读取，与生产环境的 PostgreSQL 行为接近
+func Open(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=off"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get test database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
@@ -0,0 +1,178 @@
+// Package delivery 提供基于数据库表的投递重试队列，供 Webhook 投递和发件箱事件投递共用
+package delivery
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+)
+
+const (
+	maxRetryBackoff   = time.Hour        // 单次退避的上限
+	deliveringTimeout = 10 * time.Minute // 超过该时间仍处于投递中的记录视为中断，重新投递
+	// PollBatchSize 每轮扫描处理的到期记录数上限
+	PollBatchSize = 50
+)
+
+// Statuses 投递记录各状态在表中的取值
+type Statuses struct {
+	Pending    string // 待投递（含等待重试）
+	Delivering string // 投递中
+	Succeeded  string // 投递成功
+	Failed     string // 重试耗尽，不再投递
+}
+
+// Options 重试参数
+type Options struct {
+	MaxAttempts  int
+	RetryBase    time.Duration
+	PollInterval time.Duration
+}
+
+// Queue 把一张投递记录表当作重试队列：抢占待投递记录、记录投递结果、失败时按指数退避安排重试，
+// 并把中断的投递放回待投递。记录表需要有 id、status、attempts、last_attempt_at、next_attempt_at、last_error 列
+type Queue struct {
+	db       *gorm.DB
+	model    any
+	name     string
+	statuses Statuses
+	opts     Options
+}
+
+// NewQueue 创建投递队列，model 为记录表对应的模型指针，name 用作日志前缀
+func NewQueue(db *gorm.DB, model any, name string, statuses Statuses, opts Options) *Queue {
+	return &Queue{
+		db:       db,
+		model:    model,
+		name:     name,
+		statuses: statuses,
+		opts:     opts,
+	}
+}
+
+// Run 按轮询间隔调用 Poll，直到 ctx 结束
+func (q *Queue) Run(ctx context.Context, due func(ctx context.Context) ([]uint, error), deliver func(ctx context.Context, id uint)) {
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.Poll(ctx, due, deliver)
+		}
+	}
+}
+
+// Poll 回收中断的投递，再逐条投递 due 返回的到期记录
+func (q *Queue) Poll(ctx context.Context, due func(ctx context.Context) ([]uint, error), deliver func(ctx context.Context, id uint)) {
+	// 投递过程中进程退出会留下投递中状态的记录，超时后放回待投递
+	if err := q.records(ctx).
+		Where("status = ? AND last_attempt_at < ?", q.statuses.Delivering, time.Now().Add(-deliveringTimeout)).
+		Update("status", q.statuses.Pending).Error; err != nil {
+		logger.Errorf("%s: failed to reset stale deliveries: %v", q.name, err)
+	}
+
+	ids, err := due(ctx)
+	if err != nil {
+		logger.Errorf("%s: failed to load due deliveries: %v", q.name, err)
+		return
+	}
+	for _, id := range ids {
+		deliver(ctx, id)
+	}
+}
+
+// Due 到期待投递记录的查询，调用方可以追加条件和排序
+func (q *Queue) Due(ctx context.Context) *gorm.DB {
+	return q.records(ctx).
+		Where("status = ? AND next_attempt_at <= ?", q.statuses.Pending, time.Now()).
+		Limit(PollBatchSize)
+}
+
+// Claim 通过条件更新抢占待投递记录并累加尝试次数，避免多个进程重复投递。
+// 返回 false 表示记录已被其他进程抢占或不再待投递
+func (q *Queue) Claim(ctx context.Context, id uint) bool {
+	now := time.Now()
+	result := q.records(ctx).
+		Where("id = ? AND status = ?", id, q.statuses.Pending).
+		Updates(map[string]any{
+			"status":          q.statuses.Delivering,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_attempt_at": &now,
+		})
+	if result.Error != nil {
+		logger.Errorf("%s: failed to claim delivery %d: %v", q.name, id, result.Error)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
+// Finish 记录一次投递的结果：成功时标记成功；失败时次数用尽则标记失败，否则按退避时间安排重试。
+// attempts 为包含本次在内的尝试次数，fields 为调用方需要一并更新的列。返回 true 表示重试已用尽
+func (q *Queue) Finish(ctx context.Context, id uint, attempts int, deliverErr error, fields map[string]any) bool {
+	updates := make(map[string]any, len(fields)+3)
+	for column, value := range fields {
+		updates[column] = value
+	}
+
+	exhausted := false
+	switch {
+	case deliverErr == nil:
+		updates["status"] = q.statuses.Succeeded
+		updates["next_attempt_at"] = nil
+		updates["last_error"] = ""
+	case attempts >= q.opts.MaxAttempts:
+		exhausted = true
+		updates["status"] = q.statuses.Failed
+		updates["next_attempt_at"] = nil
+		updates["last_error"] = deliverErr.Error()
+	default:
+		nextAttemptAt := time.Now().Add(q.Backoff(attempts))
+		updates["status"] = q.statuses.Pending
+		updates["next_attempt_at"] = &nextAttemptAt
+		updates["last_error"] = deliverErr.Error()
+	}
+	q.update(ctx, id, updates)
+	return exhausted
+}
+
+// Abandon 直接标记为失败，不再重试
+func (q *Queue) Abandon(ctx context.Context, id uint, reason string, fields map[string]any) {
+	updates := make(map[string]any, len(fields)+3)
+	for column, value := range fields {
+		updates[column] = value
+	}
+	updates["status"] = q.statuses.Failed
+	updates["next_attempt_at"] = nil
+	updates["last_error"] = reason
+	q.update(ctx, id, updates)
+}
+
+// records 记录表的查询。每次使用新的模型值，并发更新时 GORM 回写字段不会互相影响
+func (q *Queue) records(ctx context.Context) *gorm.DB {
+	return q.db.WithContext(ctx).Model(reflect.New(reflect.TypeOf(q.model).Elem()).Interface())
+}
+
+func (q *Queue) update(ctx context.Context, id uint, updates map[string]any) {
+	if err := q.records(ctx).Where("id = ?", id).Updates(updates).Error; err != nil {
+		logger.Errorf("%s: failed to update delivery %d: %v", q.name, id, err)
+	}
+}
+
+// Backoff 第 n 次失败后的等待时间：base * 2^(n-1)，不超过一小时
+func (q *Queue) Backoff(attempts int) time.Duration {
+	wait := q.opts.RetryBase
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxRetryBackoff {
+			return maxRetryBackoff
+		}
+	}
+	return wait
+}
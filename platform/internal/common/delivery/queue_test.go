@@ -0,0 +1,140 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+func newTestQueue(t *testing.T) (*Queue, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t, &model.OutboxEvent{})
+	q := NewQueue(db, &model.OutboxEvent{}, "test", Statuses{
+		Pending:    string(model.OutboxEventStatusPending),
+		Delivering: string(model.OutboxEventStatusDelivering),
+		Succeeded:  string(model.OutboxEventStatusDelivered),
+		Failed:     string(model.OutboxEventStatusFailed),
+	}, Options{MaxAttempts: 3, RetryBase: time.Minute, PollInterval: time.Second})
+	return q, db
+}
+
+func createEvent(t *testing.T, db *gorm.DB, event model.OutboxEvent) model.OutboxEvent {
+	t.Helper()
+	event.EventID = time.Now().Format(time.RFC3339Nano)
+	event.EventType = model.WorkflowEventCompleted
+	event.Target = "kb"
+	event.Payload = "{}"
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+	return event
+}
+
+func TestBackoff(t *testing.T) {
+	q, _ := newTestQueue(t)
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Minute},
+		{attempts: 2, want: 2 * time.Minute},
+		{attempts: 4, want: 8 * time.Minute},
+		{attempts: 7, want: time.Hour},
+		{attempts: 50, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := q.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestClaim(t *testing.T) {
+	q, db := newTestQueue(t)
+	event := createEvent(t, db, model.OutboxEvent{Status: model.OutboxEventStatusPending})
+
+	if !q.Claim(context.Background(), event.ID) {
+		t.Fatal("first Claim() = false, want true")
+	}
+	if q.Claim(context.Background(), event.ID) {
+		t.Fatal("second Claim() = true, want false")
+	}
+
+	db.First(&event, event.ID)
+	if event.Status != model.OutboxEventStatusDelivering || event.Attempts != 1 || event.LastAttemptAt == nil {
+		t.Fatalf("claimed event = %s/%d/%v, want delivering after 1 attempt", event.Status, event.Attempts, event.LastAttemptAt)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	tests := []struct {
+		name          string
+		attempts      int
+		err           error
+		wantStatus    model.OutboxEventStatus
+		wantExhausted bool
+		wantRetry     bool
+	}{
+		{name: "success", attempts: 1, wantStatus: model.OutboxEventStatusDelivered},
+		{name: "failure is retried", attempts: 2, err: errors.New("boom"), wantStatus: model.OutboxEventStatusPending, wantRetry: true},
+		{name: "last failure gives up", attempts: 3, err: errors.New("boom"), wantStatus: model.OutboxEventStatusFailed, wantExhausted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, db := newTestQueue(t)
+			event := createEvent(t, db, model.OutboxEvent{Status: model.OutboxEventStatusDelivering, Attempts: tt.attempts})
+
+			before := time.Now()
+			if got := q.Finish(context.Background(), event.ID, tt.attempts, tt.err, nil); got != tt.wantExhausted {
+				t.Fatalf("Finish() = %v, want %v", got, tt.wantExhausted)
+			}
+
+			db.First(&event, event.ID)
+			if event.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", event.Status, tt.wantStatus)
+			}
+			if !tt.wantRetry {
+				if event.NextAttemptAt != nil {
+					t.Fatalf("next attempt = %v, want nil", event.NextAttemptAt)
+				}
+				return
+			}
+			if event.NextAttemptAt == nil || event.NextAttemptAt.Before(before.Add(q.Backoff(tt.attempts))) {
+				t.Fatalf("next attempt = %v, want after %v", event.NextAttemptAt, q.Backoff(tt.attempts))
+			}
+			if event.LastError != "boom" {
+				t.Fatalf("last error = %q, want boom", event.LastError)
+			}
+		})
+	}
+}
+
+func TestPollResetsStaleDeliveries(t *testing.T) {
+	q, db := newTestQueue(t)
+	stale := time.Now().Add(-deliveringTimeout - time.Minute)
+	recent := time.Now()
+	staleEvent := createEvent(t, db, model.OutboxEvent{Status: model.OutboxEventStatusDelivering, LastAttemptAt: &stale, NextAttemptAt: &stale})
+	recentEvent := createEvent(t, db, model.OutboxEvent{Status: model.OutboxEventStatusDelivering, LastAttemptAt: &recent, NextAttemptAt: &stale})
+
+	var delivered []uint
+	q.Poll(context.Background(),
+		func(ctx context.Context) ([]uint, error) {
+			var ids []uint
+			err := q.Due(ctx).Pluck("id", &ids).Error
+			return ids, err
+		},
+		func(ctx context.Context, id uint) { delivered = append(delivered, id) })
+
+	if len(delivered) != 1 || delivered[0] != staleEvent.ID {
+		t.Fatalf("delivered = %v, want only stale event %d", delivered, staleEvent.ID)
+	}
+	db.First(&recentEvent, recentEvent.ID)
+	if recentEvent.Status != model.OutboxEventStatusDelivering {
+		t.Fatalf("recent event status = %s, want delivering", recentEvent.Status)
+	}
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix API Key 的固定前缀，网关据此区分 API Key 与 JWT
+const APIKeyPrefix = "kbk_"
+
+// 网关与下游服务之间传递 API Key 信息的请求头
+const (
+	// HeaderAPIKey 调用方携带 API Key 的请求头，也可以使用 Authorization: Bearer kbk_...
+	HeaderAPIKey = "X-API-Key"
+	// HeaderAPIKeySpaces 网关校验 API Key 后写入，值为以逗号分隔的空间ID，表示本次调用的权限范围仅限这些空间；
+	// 没有该请求头表示不限空间。下游服务只信任网关写入的值
+	HeaderAPIKeySpaces = "X-API-Key-Spaces"
+)
+
+// APIKeyScope API Key 可以调用的接口范围
+type APIKeyScope string
+
+const (
+	APIKeyScopeKbRead    APIKeyScope = "kb:read"    // 查看文档详情、预览
+	APIKeyScopeKbUpload  APIKeyScope = "kb:upload"  // 上传文档、重新提交审批
+	APIKeyScopeKbSearch  APIKeyScope = "kb:search"  // 知识检索、文档对话
+	APIKeyScopeKbPublish APIKeyScope = "kb:publish" // 发布与下架文档
+	APIKeyScopeKbDelete  APIKeyScope = "kb:delete"  // 删除文档
+)
+
+// APIKeyScopes 支持的全部范围
+var APIKeyScopes = []APIKeyScope{
+	APIKeyScopeKbRead,
+	APIKeyScopeKbUpload,
+	APIKeyScopeKbSearch,
+	APIKeyScopeKbPublish,
+	APIKeyScopeKbDelete,
+}
+
+// IsValidAPIKeyScope 判断范围是否受支持
+func IsValidAPIKeyScope(scope APIKeyScope) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyGrant API Key 的一项授权，SpaceID 为 0 表示不限空间。
+// 授权只收窄服务账号本身的权限，服务账号仍需是空间成员并拥有相应的空间角色
+type APIKeyGrant struct {
+	Scope   APIKeyScope `json:"scope" binding:"required"`
+	SpaceID uint        `json:"space_id"`
+}
+
+// APIKey 服务账号的 API Key，只保存 SHA-256 摘要，明文只在创建时返回一次
+type APIKey struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	UserID     uint           `json:"user_id" gorm:"index;not null;comment:所属服务账号"`
+	Name       string         `json:"name" gorm:"size:100;not null"`
+	Prefix     string         `json:"prefix" gorm:"size:16;not null;comment:明文前几位，用于辨认"`
+	KeyHash    string         `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	Grants     []APIKeyGrant  `json:"grants" gorm:"serializer:json;not null"`
+	ExpiresAt  time.Time      `json:"expires_at" gorm:"index"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	LastUsedIP string         `json:"last_used_ip" gorm:"size:64"`
+	RevokedAt  *time.Time     `json:"revoked_at"`
+	CreatedBy  uint           `json:"created_by"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// CreateServiceAccountRequest 创建服务账号请求
+type CreateServiceAccountRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Nickname string `json:"nickname" binding:"max=50"` // 如「OA 系统」
+}
+
+// CreateAPIKeyRequest 为服务账号创建 API Key，有效期不能超过配置的上限
+type CreateAPIKeyRequest struct {
+	Name          string        `json:"name" binding:"required,max=100"`
+	Grants        []APIKeyGrant `json:"grants" binding:"required,min=1,dive"`
+	ExpiresInDays int           `json:"expires_in_days" binding:"omitempty,min=1"` // 为空时使用配置的上限
+}
+
+// CreateAPIKeyResponse 创建 API Key 的结果，Key 为明文，之后无法再次获取
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// ValidateAPIKeyResponse 网关校验 API Key 的结果
+type ValidateAPIKeyResponse struct {
+	User   *User         `json:"user"`
+	KeyID  uint          `json:"key_id"`
+	Grants []APIKeyGrant `json:"grants"`
+}
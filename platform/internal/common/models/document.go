@@ -20,6 +20,7 @@ const (
 	DocumentStatusPublished       DocumentStatus = "published"        // 已发布
 	DocumentStatusFailed          DocumentStatus = "failed"           // 失败
 	DocumentStatusProcessFailed   DocumentStatus = "process_failed"   // 处理失败（可重试）
+	DocumentStatusWithdrawn       DocumentStatus = "withdrawn"        // 审批已撤回（可重新提交）
 )
 
 // Document 文档模型
@@ -38,13 +39,15 @@ type Document struct {
 	UseType      UseType        `json:"use_type" gorm:"size:50;not null;default:'viewable'"` // 使用类型仅查看的文档不可用于对话
 
 	// 关联字段
-	SpaceID         uint   `json:"space_id" gorm:"foreignKey:SpaceID"`        // 所属空间ID
-	SubSpaceID      uint   `json:"sub_space_id" gorm:"foreignKey:SubSpaceID"` // 所属空间ID
-	ClassID         uint   `json:"class_id" gorm:"foreignKey:ClassID"`        // 所属分类ID
-	CreatedBy       uint   `json:"created_by" gorm:"foreignKey:UserID"`       // 创建人ID (关联IAM用户)
-	CreatorNickName string `json:"creator_nick_name" gorm:"size:100"`         // 创建人昵称
-	Department      string `json:"department" gorm:"size:100"`                // 所属部门
-	WorkflowID      uint   `json:"workflow_id"`                               // 工作流ID （关联workflow表，上传后为0表示没有，无需审批也为0，需要审批提交后为对应的workflow_id）
+	SpaceID            uint   `json:"space_id" gorm:"foreignKey:SpaceID"`        // 所属空间ID
+	SubSpaceID         uint   `json:"sub_space_id" gorm:"foreignKey:SubSpaceID"` // 所属空间ID
+	ClassID            uint   `json:"class_id" gorm:"foreignKey:ClassID"`        // 所属分类ID
+	CreatedBy          uint   `json:"created_by" gorm:"foreignKey:UserID"`       // 创建人ID (关联IAM用户)
+	CreatorNickName    string `json:"creator_nick_name" gorm:"size:100"`         // 创建人昵称
+	Department         string `json:"department" gorm:"size:100"`                // 所属部门
+	OrgUnitID          *uint  `json:"org_unit_id" gorm:"index"`                  // 所属组织单元，取上传人所在的组织单元
+	WorkflowID         uint   `json:"workflow_id"`                               // 工作流ID （关联workflow表，上传后为0表示没有，无需审批也为0，需要审批提交后为对应的workflow_id）
+	DeletionWorkflowID uint   `json:"deletion_workflow_id"`                      // 删除审批流程ID，删除申请审批中时非0
 
 	// 标签和摘要
 	Tags    string `json:"tags" gorm:"size:500"`     // 标签，JSON格式存储
@@ -76,6 +79,10 @@ var (
 	ErrEmptyChatQuestion = errors.New("question is required")
 	// ErrVectorClientNotConfigured indicates vector search is unavailable.
 	ErrVectorClientNotConfigured = errors.New("vector client is not configured")
+	// ErrDocumentNotResubmittable indicates the document is not in a rejected or withdrawn review.
+	ErrDocumentNotResubmittable = errors.New("document is not rejected or withdrawn")
+	// ErrDocumentResubmitForbidden indicates someone other than the uploader tried to resubmit.
+	ErrDocumentResubmitForbidden = errors.New("only the uploader can resubmit the document")
 )
 
 // ChatDocumentRequest 请求结构
@@ -112,11 +119,29 @@ type UploadDocumentRequest struct {
 	CreatedBy       uint
 	CreatorNickName string
 	Department      string
+	OrgUnitID       *uint
 	NeedApproval    bool
 	Version         string
 	UseType         UseType
 }
 
+// ResubmitDocumentRequest 重新提交审批，File 为 nil 时沿用原文件，指针字段为 nil 时不修改
+type ResubmitDocumentRequest struct {
+	DocumentID  uint
+	UserID      uint
+	File        io.Reader
+	FileName    string
+	FileSize    int64
+	ContentType string
+	SubSpaceID  uint
+	ClassID     uint
+	Tags        *string
+	Summary     *string
+	Department  *string
+	Version     *string
+	UseType     *UseType
+}
+
 // UploadDocumentResponse 上传文档响应
 type UploadDocumentResponse struct {
 	DocumentID uint           `json:"document_id"`
@@ -183,10 +208,12 @@ type KnowledgeSearchRequest struct {
 	SpaceID    uint   `json:"space_id"`
 	SubSpaceID uint   `json:"sub_space_id"`
 	ClassID    uint   `json:"class_id"`
+	OrgUnitID  uint   `json:"org_unit_id"` // 只返回该组织单元及其下级单元的文档
 }
 
 type KnowledgeSearchResult struct {
 	DocumentID uint    `json:"document_id"`
+	SpaceID    uint    `json:"space_id"`
 	ChunkID    uint    `json:"chunk_id"`
 	Title      string  `json:"title"`
 	Content    string  `json:"content"`
@@ -0,0 +1,93 @@
+package model
+
+import "time"
+
+// ExportScope 导出范围
+type ExportScope string
+
+const (
+	ExportScopeSpace    ExportScope = "space"     // 导出整个空间
+	ExportScopeSubSpace ExportScope = "sub_space" // 导出二级空间
+	ExportScopeClass    ExportScope = "class"     // 导出分类
+	ExportScopeAll      ExportScope = "all"       // 导出全部数据，需要 export_all_data 权限
+)
+
+// ExportJobStatus 导出任务状态
+type ExportJobStatus string
+
+const (
+	ExportJobStatusPendingApproval ExportJobStatus = "pending_approval" // 空间绑定了导出审批模板，等待审批
+	ExportJobStatusRejected        ExportJobStatus = "rejected"         // 审批被拒绝、撤回或取消，不再执行
+	ExportJobStatusPending         ExportJobStatus = "pending"
+	ExportJobStatusRunning         ExportJobStatus = "running"
+	ExportJobStatusSucceeded       ExportJobStatus = "succeeded"
+	ExportJobStatusFailed          ExportJobStatus = "failed"
+)
+
+// ExportJob 数据导出任务，导出结果为存放在 MinIO 中的 ZIP 包
+type ExportJob struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	Scope         ExportScope     `json:"scope" gorm:"size:20;not null"`
+	SpaceID       uint            `json:"space_id" gorm:"index"`
+	SubSpaceID    uint            `json:"sub_space_id"`
+	ClassID       uint            `json:"class_id"`
+	IncludeText   bool            `json:"include_text" gorm:"default:false;comment:是否包含解析后的文本"`
+	Status        ExportJobStatus `json:"status" gorm:"size:20;not null;default:'pending'"`
+	Progress      int             `json:"progress" gorm:"default:0"` // 导出进度(0-100)
+	DocumentCount int             `json:"document_count" gorm:"default:0"`
+	FilePath      string          `json:"-" gorm:"size:500"` // ZIP 在 MinIO 中的路径
+	FileSize      int64           `json:"file_size"`
+	Error         string          `json:"error" gorm:"type:text"`
+	WorkflowID    uint            `json:"workflow_id"` // 导出审批流程ID，无需审批时为0
+	CreatedBy     uint            `json:"created_by" gorm:"index;not null"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	FinishedAt    *time.Time      `json:"finished_at"`
+}
+
+// CreateExportJobRequest 创建导出任务请求
+type CreateExportJobRequest struct {
+	Scope       ExportScope `json:"scope" binding:"required,oneof=space sub_space class all"`
+	SpaceID     uint        `json:"space_id"`
+	SubSpaceID  uint        `json:"sub_space_id"`
+	ClassID     uint        `json:"class_id"`
+	IncludeText bool        `json:"include_text"`
+}
+
+// ExportSearchRequest 检索结果导出请求
+type ExportSearchRequest struct {
+	KnowledgeSearchRequest
+	Format string `json:"format" binding:"omitempty,oneof=csv xlsx"` // 默认 csv
+}
+
+// ExportManifest ZIP 包中的 manifest.json
+type ExportManifest struct {
+	ExportedAt time.Time                `json:"exported_at"`
+	ExportedBy uint                     `json:"exported_by"`
+	Scope      ExportScope              `json:"scope"`
+	Spaces     []Space                  `json:"spaces"`
+	SubSpaces  []SubSpace               `json:"sub_spaces"`
+	Classes    []Class                  `json:"classes"`
+	Documents  []ExportManifestDocument `json:"documents"`
+}
+
+// ExportManifestDocument manifest 中的文档元数据
+type ExportManifestDocument struct {
+	Document
+	CreatorUsername string                 `json:"creator_username"`
+	CreatorPhone    string                 `json:"creator_phone"`
+	ArchivePath     string                 `json:"archive_path"`        // 原始文件在 ZIP 中的路径
+	TextPath        string                 `json:"text_path,omitempty"` // 解析文本在 ZIP 中的路径
+	ApprovalHistory []ExportApprovalRecord `json:"approval_history"`
+}
+
+// ExportApprovalRecord 文档的审批记录
+type ExportApprovalRecord struct {
+	WorkflowID       uint       `json:"workflow_id"`
+	StepName         string     `json:"step_name"`
+	StepOrder        int        `json:"step_order"`
+	ApproverID       uint       `json:"approver_id"`
+	ApproverNickName string     `json:"approver_nick_name"`
+	Status           TaskStatus `json:"status"`
+	Comment          string     `json:"comment"`
+}
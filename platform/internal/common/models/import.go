@@ -0,0 +1,47 @@
+package model
+
+// ImportRequest 导入导出包的参数（multipart 表单字段）
+type ImportRequest struct {
+	DryRun bool `form:"dry_run"` // 只校验并返回冲突报告，不写入任何数据
+}
+
+// ImportAction 导入时对单个对象的处理方式
+type ImportAction string
+
+const (
+	ImportActionCreate ImportAction = "create" // 新建
+	ImportActionReuse  ImportAction = "reuse"  // 目标环境已存在同名对象，直接复用
+	ImportActionSkip   ImportAction = "skip"   // 存在冲突，跳过
+)
+
+// ImportItem 空间、二级空间、分类或文档的导入计划
+type ImportItem struct {
+	Type     string       `json:"type"` // space, sub_space, class, document
+	SourceID uint         `json:"source_id"`
+	TargetID uint         `json:"target_id,omitempty"` // dry-run 时新建对象没有目标ID
+	Name     string       `json:"name"`
+	Action   ImportAction `json:"action"`
+	Reason   string       `json:"reason,omitempty"`
+}
+
+// ImportUserMapping 导出包中的用户与目标环境用户的对应关系
+type ImportUserMapping struct {
+	SourceID     uint   `json:"source_id"`
+	Username     string `json:"username"`
+	Phone        string `json:"phone"`
+	TargetID     uint   `json:"target_id"`
+	MatchedBy    string `json:"matched_by"` // phone, username, fallback
+	FallbackUsed bool   `json:"fallback_used"`
+}
+
+// ImportReport 导入结果；dry-run 时为导入计划与冲突列表
+type ImportReport struct {
+	DryRun      bool                `json:"dry_run"`
+	Items       []ImportItem        `json:"items"`
+	Users       []ImportUserMapping `json:"users"`
+	Conflicts   []string            `json:"conflicts"`
+	Created     int                 `json:"created"`
+	Reused      int                 `json:"reused"`
+	Skipped     int                 `json:"skipped"`
+	DocumentIDs []uint              `json:"document_ids"` // 新建的文档ID，已加入处理队列
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// LoginThrottleScope 登录失败计数的维度
+type LoginThrottleScope string
+
+const (
+	LoginThrottleAccount LoginThrottleScope = "account" // 按账号计数，已知用户为用户ID，未知登录名为 login:<登录名>
+	LoginThrottleIP      LoginThrottleScope = "ip"      // 按来源IP计数
+)
+
+// LoginThrottle 登录失败计数与锁定状态，每个维度的每个标识一行
+type LoginThrottle struct {
+	ID            uint               `json:"id" gorm:"primaryKey"`
+	Scope         LoginThrottleScope `json:"scope" gorm:"size:20;not null;uniqueIndex:idx_login_throttle_identifier"`
+	Identifier    string             `json:"identifier" gorm:"size:128;not null;uniqueIndex:idx_login_throttle_identifier"`
+	Failures      int                `json:"failures" gorm:"default:0"`   // 当前计数窗口内的失败次数
+	WindowStart   time.Time          `json:"window_start"`                // 当前计数窗口的开始时间
+	LockCount     int                `json:"lock_count" gorm:"default:0"` // 连续锁定次数，决定下一次锁定时长
+	LockedUntil   *time.Time         `json:"locked_until" gorm:"index"`
+	LastFailureAt *time.Time         `json:"last_failure_at"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// PasswordHistory 用户设置过的密码哈希，用于禁止重复使用近期密码
+type PasswordHistory struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"index;not null"`
+	PasswordHash string    `json:"-" gorm:"size:255;not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ExpiredPasswordChangeRequest 密码过期后修改密码，用户此时无法登录，需要再次提供账号和原密码
+type ExpiredPasswordChangeRequest struct {
+	Login       string `json:"login" binding:"required"`
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// UserIdentity 外部身份与本地用户的绑定，同一用户可以绑定多个身份提供方
+type UserIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"index;not null"`
+	Provider    string     `json:"provider" gorm:"size:255;not null;uniqueIndex:idx_user_identity_subject"` // 身份提供方的 issuer
+	Subject     string     `json:"subject" gorm:"size:255;not null;uniqueIndex:idx_user_identity_subject"`  // 身份提供方中的 sub
+	Email       string     `json:"email" gorm:"size:100"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// OIDCLoginState 发起 OIDC 登录时保存的一次性状态，回调时校验并删除
+type OIDCLoginState struct {
+	State        string    `json:"state" gorm:"primaryKey;size:64"`
+	Nonce        string    `json:"-" gorm:"size:64;not null"`
+	CodeVerifier string    `json:"-" gorm:"size:128;not null"` // PKCE
+	RedirectURI  string    `json:"redirect_uri" gorm:"size:1024"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
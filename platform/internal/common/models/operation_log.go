@@ -0,0 +1,132 @@
+package model
+
+import "time"
+
+// OperationAction 操作类型
+type OperationAction string
+
+const (
+	// 认证
+	OperationLogin          OperationAction = "auth.login"
+	OperationLoginFailed    OperationAction = "auth.login_failed"
+	OperationLogout         OperationAction = "auth.logout"
+	OperationTokenReused    OperationAction = "auth.token_reused"
+	OperationPasswordChange OperationAction = "auth.password_change"
+
+	// 会话
+	OperationSessionRevoke   OperationAction = "session.revoke"
+	OperationUserForceLogout OperationAction = "user.force_logout"
+	OperationUserUnlock      OperationAction = "user.unlock"
+	OperationDirectorySync   OperationAction = "user.directory_sync"
+
+	// 用户与角色
+	OperationUserCreate     OperationAction = "user.create"
+	OperationUserUpdate     OperationAction = "user.update"
+	OperationUserDelete     OperationAction = "user.delete"
+	OperationUserRoleAssign OperationAction = "user.role_assign"
+	OperationUserRoleRemove OperationAction = "user.role_remove"
+	OperationUserOrgAssign  OperationAction = "user.org_assign"
+	OperationUserImport     OperationAction = "user.import"
+	OperationUserExport     OperationAction = "user.export"
+
+	// 服务账号与 API Key
+	OperationServiceAccountCreate OperationAction = "service_account.create"
+	OperationAPIKeyCreate         OperationAction = "api_key.create"
+	OperationAPIKeyRevoke         OperationAction = "api_key.revoke"
+
+	// 角色管理
+	OperationRoleCreate           OperationAction = "role.create"
+	OperationRoleUpdate           OperationAction = "role.update"
+	OperationRoleDelete           OperationAction = "role.delete"
+	OperationRolePermissionUpdate OperationAction = "role.permission_update"
+	OperationSpaceRoleUpdate      OperationAction = "role.space_role_update"
+
+	// 组织架构
+	OperationOrgUnitCreate     OperationAction = "org_unit.create"
+	OperationOrgUnitUpdate     OperationAction = "org_unit.update"
+	OperationOrgUnitDelete     OperationAction = "org_unit.delete"
+	OperationOrgUnitHeadUpdate OperationAction = "org_unit.head_update"
+
+	// 空间与成员
+	OperationSpaceCreate       OperationAction = "space.create"
+	OperationSpaceUpdate       OperationAction = "space.update"
+	OperationSpaceDelete       OperationAction = "space.delete"
+	OperationSpaceMemberAdd    OperationAction = "space.member_add"
+	OperationSpaceMemberUpdate OperationAction = "space.member_update"
+	OperationSpaceMemberRemove OperationAction = "space.member_remove"
+
+	// 文档与知识检索
+	OperationDocumentUpload    OperationAction = "document.upload"
+	OperationDocumentPublish   OperationAction = "document.publish"
+	OperationDocumentUnpublish OperationAction = "document.unpublish"
+	OperationDocumentDelete    OperationAction = "document.delete"
+	OperationDocumentResubmit  OperationAction = "document.resubmit"
+	OperationDocumentPreview   OperationAction = "document.preview"
+	OperationKnowledgeSearch   OperationAction = "knowledge.search"
+	OperationKnowledgeChat     OperationAction = "knowledge.chat"
+	OperationDataExport        OperationAction = "data.export"
+	OperationDataImport        OperationAction = "data.import"
+
+	// 审批
+	OperationTaskApprove OperationAction = "workflow.task_approve"
+	OperationTaskReject  OperationAction = "workflow.task_reject"
+
+	// 转交、加签与外出委托
+	OperationTaskTransfer     OperationAction = "workflow.task_transfer"
+	OperationTaskAddApprover  OperationAction = "workflow.task_add_approver"
+	OperationDelegationCreate OperationAction = "workflow.delegation_create"
+	OperationDelegationCancel OperationAction = "workflow.delegation_cancel"
+
+	// 撤回与取消
+	OperationWorkflowWithdraw OperationAction = "workflow.withdraw"
+	OperationWorkflowCancel   OperationAction = "workflow.cancel"
+
+	// 审批模板
+	OperationWorkflowTemplateCreate OperationAction = "workflow.template_create"
+	OperationWorkflowTemplateUpdate OperationAction = "workflow.template_update"
+	OperationWorkflowTemplateDelete OperationAction = "workflow.template_delete"
+)
+
+// OperationResult 操作结果
+type OperationResult string
+
+const (
+	OperationResultSuccess OperationResult = "success"
+	OperationResultFailure OperationResult = "failure"
+)
+
+// OperationLog 操作审计日志：谁在什么时候对哪个资源做了什么，以及变更前后的快照
+type OperationLog struct {
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	Service      string          `json:"service" gorm:"size:20;index;comment:产生日志的服务:iam,kb,workflow"`
+	Action       OperationAction `json:"action" gorm:"size:50;index;not null"`
+	UserID       uint            `json:"user_id" gorm:"index"`
+	Username     string          `json:"username" gorm:"size:100"`
+	Nickname     string          `json:"nickname" gorm:"size:100"`
+	ResourceType string          `json:"resource_type" gorm:"size:50;index"`
+	ResourceID   string          `json:"resource_id" gorm:"size:100;index"`
+	SpaceID      uint            `json:"space_id" gorm:"index"`
+	Before       string          `json:"before" gorm:"type:text;comment:变更前快照(JSON)"`
+	After        string          `json:"after" gorm:"type:text;comment:变更后快照(JSON)"`
+	Detail       string          `json:"detail" gorm:"type:text;comment:补充信息，如检索关键词"`
+	Result       OperationResult `json:"result" gorm:"size:20"`
+	ErrorMessage string          `json:"error_message" gorm:"type:text"`
+	IP           string          `json:"ip" gorm:"size:64"`
+	UserAgent    string          `json:"user_agent" gorm:"size:255"`
+	CreatedAt    time.Time       `json:"created_at" gorm:"index"`
+}
+
+// OperationLogQuery 操作日志查询条件
+type OperationLogQuery struct {
+	UserID       uint       `form:"user_id"`
+	Service      string     `form:"service"`
+	Action       string     `form:"action"`
+	ResourceType string     `form:"resource_type"`
+	ResourceID   string     `form:"resource_id"`
+	SpaceID      uint       `form:"space_id"`
+	Result       string     `form:"result"`
+	StartTime    *time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime      *time.Time `form:"end_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page         int        `form:"page"`
+	PageSize     int        `form:"page_size"`
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrgUnitType 组织单元类型
+type OrgUnitType string
+
+const (
+	OrgUnitTypeCompany    OrgUnitType = "company"    // 企业
+	OrgUnitTypeDepartment OrgUnitType = "department" // 部门
+)
+
+// OrgUnit 组织单元，企业和部门组成一棵树。
+// Path 为从根到自身的ID路径（如 /1/5/12/），按前缀匹配查询子树
+type OrgUnit struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Name       string         `json:"name" gorm:"not null;size:100"`
+	Type       OrgUnitType    `json:"type" gorm:"size:20;not null;default:department;comment:类型:company,department"`
+	ParentID   *uint          `json:"parent_id" gorm:"index"`
+	Path       string         `json:"path" gorm:"size:500;index;comment:ID路径"`
+	HeadUserID *uint          `json:"head_user_id" gorm:"index;comment:负责人"`
+	Sort       int            `json:"sort" gorm:"default:0"`
+	Status     int            `json:"status" gorm:"default:1;comment:1-正常 0-禁用"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 关联关系
+	Head *User `json:"head,omitempty" gorm:"foreignKey:HeadUserID"`
+}
+
+// OrgUnitNode 组织树节点
+type OrgUnitNode struct {
+	OrgUnit
+	Children []*OrgUnitNode `json:"children"`
+}
+
+// CreateOrgUnitRequest 创建组织单元请求
+type CreateOrgUnitRequest struct {
+	Name       string      `json:"name" binding:"required,max=100"`
+	Type       OrgUnitType `json:"type" binding:"required,oneof=company department"`
+	ParentID   *uint       `json:"parent_id"`
+	HeadUserID *uint       `json:"head_user_id"`
+	Sort       int         `json:"sort"`
+}
+
+// UpdateOrgUnitRequest 更新组织单元请求，修改 ParentID 即移动到新的上级，ParentID 为 0 表示移到根级
+type UpdateOrgUnitRequest struct {
+	Name     *string      `json:"name" binding:"omitempty,max=100"`
+	Type     *OrgUnitType `json:"type" binding:"omitempty,oneof=company department"`
+	ParentID *uint        `json:"parent_id"`
+	Sort     *int         `json:"sort"`
+	Status   *int         `json:"status"`
+}
+
+// SetOrgUnitHeadRequest 设置部门负责人请求，UserID 为空表示取消负责人
+type SetOrgUnitHeadRequest struct {
+	UserID *uint `json:"user_id"`
+}
+
+// AssignUserOrgUnitRequest 调整用户所属组织单元请求，OrgUnitID 为空表示移出组织
+type AssignUserOrgUnitRequest struct {
+	OrgUnitID *uint `json:"org_unit_id"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// OutboxEventStatus 事件投递状态
+type OutboxEventStatus string
+
+const (
+	OutboxEventStatusPending    OutboxEventStatus = "pending"    // 待投递
+	OutboxEventStatusDelivering OutboxEventStatus = "delivering" // 投递中
+	OutboxEventStatusDelivered  OutboxEventStatus = "delivered"  // 已投递
+	OutboxEventStatusFailed     OutboxEventStatus = "failed"     // 超过最大次数，不再重试
+)
+
+// OutboxEvent 事务发件箱：领域事件与业务数据在同一事务内写入，由投递循环异步发送给接收方
+type OutboxEvent struct {
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	EventID       string            `json:"event_id" gorm:"size:36;uniqueIndex;not null"`
+	EventType     WorkflowEventType `json:"event_type" gorm:"size:50;index;not null"`
+	Target        string            `json:"target" gorm:"size:50;index;not null"` // 接收方服务，对应 outbox.targets 中的配置
+	AggregateID   uint              `json:"aggregate_id" gorm:"index"`            // 事件所属的流程ID
+	Payload       string            `json:"payload" gorm:"type:text;not null"`
+	Status        OutboxEventStatus `json:"status" gorm:"size:20;index;not null;default:'pending'"`
+	Attempts      int               `json:"attempts" gorm:"default:0"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at" gorm:"index"`
+	LastAttemptAt *time.Time        `json:"last_attempt_at"`
+	LastError     string            `json:"last_error" gorm:"type:text"`
+	DeliveredAt   *time.Time        `json:"delivered_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// WorkflowEventType 审批流程领域事件类型
+type WorkflowEventType string
+
+const (
+	WorkflowEventCompleted WorkflowEventType = "workflow.completed" // 审批通过
+	WorkflowEventRejected  WorkflowEventType = "workflow.rejected"  // 审批被拒绝
+	WorkflowEventWithdrawn WorkflowEventType = "workflow.withdrawn" // 发起人撤回
+	WorkflowEventCancelled WorkflowEventType = "workflow.cancelled" // 管理员取消
+)
+
+// WorkflowStatusEvent 流程结束时发送给资源所属服务的事件内容，资源状态由接收方自行决定
+type WorkflowStatusEvent struct {
+	EventID      string            `json:"event_id"`
+	EventType    WorkflowEventType `json:"event_type"`
+	WorkflowID   uint              `json:"workflow_id"`
+	ResourceType string            `json:"resource_type"`
+	ResourceID   uint              `json:"resource_id"`
+	SpaceID      uint              `json:"space_id"`
+	Status       WorkflowStatus    `json:"status"`
+	Round        int               `json:"round"`
+	OccurredAt   time.Time         `json:"occurred_at"`
+}
+
+// ProcessedEvent 接收方已处理的事件，用于重复投递时的幂等判断
+type ProcessedEvent struct {
+	EventID     string            `json:"event_id" gorm:"primaryKey;size:36"`
+	EventType   WorkflowEventType `json:"event_type" gorm:"size:50"`
+	ProcessedAt time.Time         `json:"processed_at"`
+}
@@ -0,0 +1,20 @@
+// Code generated by cmd/permgen; DO NOT EDIT.
+
+package model
+
+// PermissionCatalog 全部权限定义，按常量声明顺序排列
+var PermissionCatalog = []PermissionDefinition{
+	{Name: PermissionViewAllContent, DisplayName: "查看所有内容", Resource: "content", Action: "view", Group: "内容权限"},
+	{Name: PermissionCreateDocument, DisplayName: "创建文档", Resource: "document", Action: "create", Group: "内容权限"},
+	{Name: PermissionDeleteDocument, DisplayName: "删除文档", Resource: "document", Action: "delete", Group: "内容权限"},
+	{Name: PermissionMoveDocument, DisplayName: "移动文档", Resource: "document", Action: "move", Group: "内容权限"},
+	{Name: PermissionSetDocumentPermission, DisplayName: "设置文档权限", Resource: "document", Action: "set_permission", Group: "内容权限"},
+	{Name: PermissionPublishDocument, DisplayName: "发布与下架文档", Resource: "document", Action: "publish", Group: "内容权限"},
+	{Name: PermissionCreateSpace, DisplayName: "创建知识空间", Resource: "space", Action: "create", Group: "空间权限"},
+	{Name: PermissionManageSpaceMember, DisplayName: "管理空间成员", Resource: "space", Action: "manage_members", Group: "空间权限"},
+	{Name: PermissionConfigureWorkflow, DisplayName: "配置审批流", Resource: "workflow", Action: "configure", Group: "审批流权限"},
+	{Name: PermissionExportData, DisplayName: "导出数据", Resource: "data", Action: "export", Group: "数据权限"},
+	{Name: PermissionExportAllData, DisplayName: "导出全部数据", Resource: "data", Action: "export_all", Group: "数据权限"},
+	{Name: PermissionViewOperationLog, DisplayName: "查看操作日志", Resource: "log", Action: "view", Group: "数据权限"},
+	{Name: PermissionAddDeleteUser, DisplayName: "管理用户", Resource: "user", Action: "manage", Group: "数据权限"},
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// SessionRevokeReason 会话失效原因
+type SessionRevokeReason string
+
+const (
+	SessionRevokeLogout       SessionRevokeReason = "logout"        // 用户登出
+	SessionRevokeLogoutAll    SessionRevokeReason = "logout_all"    // 用户登出全部会话
+	SessionRevokeForceLogout  SessionRevokeReason = "force_logout"  // 管理员强制下线
+	SessionRevokeUserDisabled SessionRevokeReason = "user_disabled" // 用户被禁用或删除
+	SessionRevokeTokenReused  SessionRevokeReason = "token_reused"  // 已轮换的刷新令牌被再次使用，疑似泄露
+)
+
+// UserSession 登录会话：一次登录对应一个会话，access token 和 refresh token 都携带会话ID，
+// 会话失效后该会话签发的全部令牌立即失效
+type UserSession struct {
+	ID             string              `json:"id" gorm:"primaryKey;size:36"`
+	UserID         uint                `json:"user_id" gorm:"index;not null"`
+	RefreshTokenID string              `json:"-" gorm:"size:36;not null"` // 当前有效的刷新令牌ID，每次刷新轮换
+	IP             string              `json:"ip" gorm:"size:64"`
+	UserAgent      string              `json:"user_agent" gorm:"size:255"`
+	ExpiresAt      time.Time           `json:"expires_at" gorm:"index"` // 刷新令牌过期时间
+	LastUsedAt     time.Time           `json:"last_used_at"`
+	RevokedAt      *time.Time          `json:"revoked_at" gorm:"index"`
+	RevokeReason   SessionRevokeReason `json:"revoke_reason" gorm:"size:20"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+
+	Current bool `json:"current" gorm:"-"` // 是否为发起请求的会话，仅用于列表展示
+}
+
+// Active 会话未失效且未过期
+func (s *UserSession) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// LogoutRequest 登出请求
+type LogoutRequest struct {
+	All bool `json:"all"` // 为 true 时登出该用户的全部会话
+}
@@ -82,6 +82,63 @@ var SpaceMemberRoleMap = map[SpaceMemberRole]int{
 	SpaceMemberRoleReader:   4,
 }
 
+// SpaceRolePermission 空间角色拥有的权限，在用户所属空间内生效。
+// OwnOnly 表示只对本人创建的资源生效，对应需求文档权限表中的「是（部分）」
+type SpaceRolePermission struct {
+	SpaceRole    SpaceMemberRole `json:"space_role" gorm:"primaryKey;size:20"`
+	PermissionID uint            `json:"permission_id" gorm:"primaryKey"`
+	OwnOnly      bool            `json:"own_only" gorm:"default:false"`
+
+	// 关联关系
+	Permission Permission `json:"permission,omitempty" gorm:"foreignKey:PermissionID"`
+}
+
+// SpaceRolePermissionGrant 空间角色的一项授权
+type SpaceRolePermissionGrant struct {
+	Name    PermissionName `json:"name" binding:"required"`
+	OwnOnly bool           `json:"own_only"`
+}
+
+// SetSpaceRolePermissionsRequest 替换空间角色权限请求
+type SetSpaceRolePermissionsRequest struct {
+	Permissions []SpaceRolePermissionGrant `json:"permissions"`
+}
+
+// DefaultSpaceRolePermissions 需求文档中的空间角色权限表，IAM 启动时写入尚未配置的空间角色
+var DefaultSpaceRolePermissions = map[SpaceMemberRole][]SpaceRolePermissionGrant{
+	SpaceMemberRoleAdmin: {
+		{Name: PermissionViewAllContent},
+		{Name: PermissionCreateDocument},
+		{Name: PermissionDeleteDocument},
+		{Name: PermissionMoveDocument},
+		{Name: PermissionSetDocumentPermission},
+		{Name: PermissionPublishDocument},
+		{Name: PermissionCreateSpace},
+		{Name: PermissionManageSpaceMember},
+		{Name: PermissionConfigureWorkflow},
+		{Name: PermissionExportData},
+	},
+	SpaceMemberRoleApprover: {
+		{Name: PermissionViewAllContent},
+		{Name: PermissionSetDocumentPermission},
+		{Name: PermissionPublishDocument},
+		{Name: PermissionExportData},
+	},
+	SpaceMemberRoleEditor: {
+		{Name: PermissionViewAllContent},
+		{Name: PermissionCreateDocument},
+		{Name: PermissionDeleteDocument, OwnOnly: true},
+		{Name: PermissionMoveDocument, OwnOnly: true},
+		{Name: PermissionSetDocumentPermission, OwnOnly: true},
+		{Name: PermissionPublishDocument, OwnOnly: true},
+		{Name: PermissionExportData},
+	},
+	SpaceMemberRoleReader: {
+		{Name: PermissionViewAllContent},
+		{Name: PermissionExportData},
+	},
+}
+
 // SpaceType 空间类型
 type SpaceType string
 
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// SpaceRequestStatus 加入空间、创建空间申请的状态
+type SpaceRequestStatus string
+
+const (
+	SpaceRequestStatusPending   SpaceRequestStatus = "pending"   // 审批中
+	SpaceRequestStatusApproved  SpaceRequestStatus = "approved"  // 审批通过，已加入或已创建空间
+	SpaceRequestStatusRejected  SpaceRequestStatus = "rejected"  // 审批被拒绝
+	SpaceRequestStatusWithdrawn SpaceRequestStatus = "withdrawn" // 申请人撤回或流程被取消
+)
+
+// SpaceMembershipRequest 加入空间申请，审批通过后申请人以申请的角色加入空间
+type SpaceMembershipRequest struct {
+	ID         uint               `json:"id" gorm:"primaryKey"`
+	SpaceID    uint               `json:"space_id" gorm:"index;not null"`
+	UserID     uint               `json:"user_id" gorm:"index;not null"`
+	Role       SpaceMemberRole    `json:"role" gorm:"size:20;not null"`
+	Reason     string             `json:"reason" gorm:"size:500"`
+	Status     SpaceRequestStatus `json:"status" gorm:"size:20;not null;default:'pending'"`
+	WorkflowID uint               `json:"workflow_id"` // 审批流程ID，提交审批前为0
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// SpaceCreationRequest 创建空间申请，审批通过后创建空间，申请人成为空间管理员
+type SpaceCreationRequest struct {
+	ID          uint               `json:"id" gorm:"primaryKey"`
+	Name        string             `json:"name" gorm:"size:100;not null"`
+	Description string             `json:"description" gorm:"size:255"`
+	Type        SpaceType          `json:"type" gorm:"size:50"`
+	Reason      string             `json:"reason" gorm:"size:500"`
+	Status      SpaceRequestStatus `json:"status" gorm:"size:20;not null;default:'pending'"`
+	WorkflowID  uint               `json:"workflow_id"` // 审批流程ID，提交审批前为0
+	SpaceID     uint               `json:"space_id"`    // 审批通过后创建的空间ID
+	CreatedBy   uint               `json:"created_by" gorm:"index;not null"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// CreateSpaceMembershipRequest 申请加入空间请求
+type CreateSpaceMembershipRequest struct {
+	Role   SpaceMemberRole `json:"role" binding:"required,oneof=admin approver editor reader"`
+	Reason string          `json:"reason" binding:"max=500"`
+}
+
+// CreateSpaceCreationRequest 申请创建空间请求
+type CreateSpaceCreationRequest struct {
+	Name        string    `json:"name" binding:"required,max=100"`
+	Description string    `json:"description" binding:"max=255"`
+	Type        SpaceType `json:"type"`
+	Reason      string    `json:"reason" binding:"max=500"`
+}
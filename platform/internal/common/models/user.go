@@ -8,25 +8,50 @@ import (
 
 // User 用户模型
 type User struct {
-	ID         uint           `json:"id" gorm:"primaryKey"`
-	Username   string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
-	Phone      string         `json:"phone" gorm:"uniqueIndex;not null;size:20;comment:手机号"`
-	Email      string         `json:"email" gorm:"size:100;comment:邮箱"`
-	Password   string         `json:"-" gorm:"not null;size:255"`
-	Nickname   string         `json:"nickname" gorm:"size:50"`
-	Avatar     string         `json:"avatar" gorm:"size:255"`
-	Department string         `json:"department" gorm:"size:100;comment:所属部门"`
-	Company    string         `json:"company" gorm:"size:100;comment:所属企业"`
-	Status     int            `json:"status" gorm:"default:1;comment:1-正常 0-禁用"`
-	LastLogin  *time.Time     `json:"last_login"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	Username          string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
+	Phone             string         `json:"phone" gorm:"uniqueIndex;not null;size:20;comment:手机号"`
+	Email             string         `json:"email" gorm:"size:100;comment:邮箱"`
+	Password          string         `json:"-" gorm:"not null;size:255"`
+	Nickname          string         `json:"nickname" gorm:"size:50"`
+	Avatar            string         `json:"avatar" gorm:"size:255"`
+	Department        string         `json:"department" gorm:"size:100;comment:所属部门"` // 设置组织单元后同步为部门名称
+	Company           string         `json:"company" gorm:"size:100;comment:所属企业"`    // 设置组织单元后同步为所属企业名称
+	OrgUnitID         *uint          `json:"org_unit_id" gorm:"index;comment:所属组织单元"`
+	Status            int            `json:"status" gorm:"default:1;comment:1-正常 0-禁用"`
+	Source            UserSource     `json:"source" gorm:"size:20;default:local;comment:用户来源"` // 目录用户使用目录密码登录，资料由目录同步
+	ExternalID        string         `json:"external_id" gorm:"size:255;index;comment:目录中的DN"` // 目录用户在目录中的 DN
+	LastLogin         *time.Time     `json:"last_login"`
+	PasswordChangedAt *time.Time     `json:"password_changed_at"` // 为空时按创建时间计算密码有效期
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// 关联关系
 	Roles []Role `json:"roles" gorm:"many2many:user_roles;"`
 }
 
+// UserSource 用户来源
+type UserSource string
+
+const (
+	UserSourceLocal   UserSource = "local"   // 本地创建，使用本地密码
+	UserSourceLDAP    UserSource = "ldap"    // 来自 LDAP / AD 目录
+	UserSourceOIDC    UserSource = "oidc"    // 通过 OIDC 单点登录自动创建，没有本地密码
+	UserSourceService UserSource = "service" // 服务账号，供其他系统集成，没有密码，只能通过 API Key 调用
+)
+
+// DirectorySyncResult 一次目录同步的结果
+type DirectorySyncResult struct {
+	Created    int       `json:"created"`
+	Updated    int       `json:"updated"`
+	Disabled   int       `json:"disabled"`
+	Skipped    int       `json:"skipped"`
+	Errors     []string  `json:"errors"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
 // Role 角色模型
 type Role struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
@@ -58,6 +83,45 @@ type Permission struct {
 	Roles []Role `json:"roles" gorm:"many2many:role_permissions;"`
 }
 
+// CreateRoleRequest 创建角色请求
+type CreateRoleRequest struct {
+	Name        string           `json:"name" binding:"required"`
+	DisplayName string           `json:"display_name" binding:"required"`
+	Description string           `json:"description"`
+	Permissions []PermissionName `json:"permissions"`
+}
+
+// UpdateRoleRequest 更新角色请求，角色名创建后不可修改
+type UpdateRoleRequest struct {
+	DisplayName *string `json:"display_name"`
+	Description *string `json:"description"`
+	Status      *int    `json:"status"`
+}
+
+// SetRolePermissionsRequest 替换角色权限请求
+type SetRolePermissionsRequest struct {
+	Permissions []PermissionName `json:"permissions"`
+}
+
+// PermissionCheck 一项权限检查，SpaceID 为 0 时按全局角色判断
+type PermissionCheck struct {
+	SpaceID  uint   `json:"space_id"`
+	Resource string `json:"resource" binding:"required"`
+	Action   string `json:"action" binding:"required"`
+	OwnerID  uint   `json:"owner_id"` // 资源创建者，用于判断仅限本人的空间权限
+}
+
+// PermissionCheckResult 权限检查结果
+type PermissionCheckResult struct {
+	PermissionCheck
+	HasPermission bool `json:"has_permission"`
+}
+
+// BatchPermissionCheckRequest 批量权限检查请求，结果按请求顺序返回
+type BatchPermissionCheckRequest struct {
+	Checks []PermissionCheck `json:"checks" binding:"required,min=1,max=500,dive"`
+}
+
 // UserRole 用户角色关联表
 type UserRole struct {
 	UserID uint `gorm:"primaryKey"`
@@ -78,27 +142,64 @@ const (
 	RoleEnterpriseAdmin RoleName = "corp_admin"  // 企业管理员 - 企业级管理权限
 )
 
+//go:generate go run ../../../cmd/permgen
+
+// PermissionName 权限名称。新增常量时按「显示名 (resource:action)」写行尾注释，
+// 执行 go generate 后会出现在权限目录中，IAM 启动时自动写入权限表
 type PermissionName string
 
 // 权限常量定义
 const (
 	// 内容权限
-	PermissionViewAllContent        PermissionName = "view_all_content"
-	PermissionCreateDocument        PermissionName = "create_document"
-	PermissionDeleteDocument        PermissionName = "delete_document"
-	PermissionMoveDocument          PermissionName = "move_document"
-	PermissionSetDocumentPermission PermissionName = "set_document_permission"
+	PermissionViewAllContent        PermissionName = "view_all_content"        // 查看所有内容 (content:view)
+	PermissionCreateDocument        PermissionName = "create_document"         // 创建文档 (document:create)
+	PermissionDeleteDocument        PermissionName = "delete_document"         // 删除文档 (document:delete)
+	PermissionMoveDocument          PermissionName = "move_document"           // 移动文档 (document:move)
+	PermissionSetDocumentPermission PermissionName = "set_document_permission" // 设置文档权限 (document:set_permission)
+	PermissionPublishDocument       PermissionName = "publish_document"        // 发布与下架文档 (document:publish)
 
 	// 空间权限
-	PermissionCreateSpace       PermissionName = "create_space"
-	PermissionManageSpaceMember PermissionName = "manage_space_member"
+	PermissionCreateSpace       PermissionName = "create_space"        // 创建知识空间 (space:create)
+	PermissionManageSpaceMember PermissionName = "manage_space_member" // 管理空间成员 (space:manage_members)
 
 	// 审批流权限
-	PermissionConfigureWorkflow PermissionName = "configure_workflow"
+	PermissionConfigureWorkflow PermissionName = "configure_workflow" // 配置审批流 (workflow:configure)
 
 	// 数据权限
-	PermissionExportData       PermissionName = "export_data"
-	PermissionExportAllData    PermissionName = "export_all_data"
-	PermissionViewOperationLog PermissionName = "view_operation_log"
-	PermissionAddDeleteUser    PermissionName = "add_delete_user"
+	PermissionExportData       PermissionName = "export_data"        // 导出数据 (data:export)
+	PermissionExportAllData    PermissionName = "export_all_data"    // 导出全部数据 (data:export_all)
+	PermissionViewOperationLog PermissionName = "view_operation_log" // 查看操作日志 (log:view)
+	PermissionAddDeleteUser    PermissionName = "add_delete_user"    // 管理用户 (user:manage)
 )
+
+// PermissionDefinition 权限目录中的一项，由 cmd/permgen 根据权限常量生成
+type PermissionDefinition struct {
+	Name        PermissionName `json:"name"`
+	DisplayName string         `json:"display_name"`
+	Resource    string         `json:"resource"`
+	Action      string         `json:"action"`
+	Group       string         `json:"group"`
+}
+
+// LookupPermission 在权限目录中查找权限定义
+func LookupPermission(name PermissionName) (PermissionDefinition, bool) {
+	for _, definition := range PermissionCatalog {
+		if definition.Name == name {
+			return definition, true
+		}
+	}
+	return PermissionDefinition{}, false
+}
+
+// BuiltinRoles 内置角色，不能删除、改名或停用
+var BuiltinRoles = []RoleName{RoleSuperAdmin, RoleEnterpriseAdmin}
+
+// IsBuiltin 是否为内置角色
+func (n RoleName) IsBuiltin() bool {
+	for _, name := range BuiltinRoles {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package model
+
+// UserImportMode 批量导入用户时对已存在用户的处理方式
+type UserImportMode string
+
+const (
+	UserImportModeCreate UserImportMode = "create" // 只新建，用户名已存在的行报错
+	UserImportModeUpsert UserImportMode = "upsert" // 用户名已存在时更新资料、角色和空间成员
+)
+
+// 导入导出文件的列，首行为列名，顺序不限。roles 为以 ; 分隔的角色名，
+// spaces 为以 ; 分隔的「空间名:空间角色|空间角色」，password 只用于导入，为空时随机生成初始密码
+const (
+	UserColumnUsername   = "username"
+	UserColumnPhone      = "phone"
+	UserColumnEmail      = "email"
+	UserColumnNickname   = "nickname"
+	UserColumnCompany    = "company"
+	UserColumnDepartment = "department"
+	UserColumnOrgUnitID  = "org_unit_id" // 填写后部门和企业按组织单元同步，忽略 department、company 列
+	UserColumnStatus     = "status"
+	UserColumnRoles      = "roles"
+	UserColumnSpaces     = "spaces"
+	UserColumnPassword   = "password"
+)
+
+// UserExportColumns 导出文件的列，也是导入模板的列
+var UserExportColumns = []string{
+	UserColumnUsername, UserColumnPhone, UserColumnEmail, UserColumnNickname, UserColumnCompany,
+	UserColumnDepartment, UserColumnOrgUnitID, UserColumnStatus, UserColumnRoles, UserColumnSpaces,
+}
+
+// UserImportRequest 批量导入用户的参数（multipart 表单字段，文件字段为 file，支持 .csv 和 .xlsx）
+type UserImportRequest struct {
+	Mode   UserImportMode `form:"mode" binding:"omitempty,oneof=create upsert"` // 默认 create
+	DryRun bool           `form:"dry_run"`                                      // 只校验并返回每行的处理结果，不写入任何数据
+}
+
+// UserImportAction 单行的处理结果
+type UserImportAction string
+
+const (
+	UserImportActionCreate UserImportAction = "create"
+	UserImportActionUpdate UserImportAction = "update"
+	UserImportActionError  UserImportAction = "error"
+)
+
+// UserImportRowResult 单行的校验与导入结果，Row 为文件中的行号（首行列名为第 1 行）
+type UserImportRowResult struct {
+	Row      int              `json:"row"`
+	Username string           `json:"username"`
+	Action   UserImportAction `json:"action"`
+	UserID   uint             `json:"user_id,omitempty"`
+	Errors   []string         `json:"errors,omitempty"`
+	// 未配置初始密码通知或通知发送失败时返回初始密码，由管理员自行转告
+	InitialPassword string `json:"initial_password,omitempty"`
+	NoticeSent      bool   `json:"notice_sent"`
+	NoticeError     string `json:"notice_error,omitempty"`
+}
+
+// UserImportReport 批量导入结果。任一行校验失败时不写入任何数据
+type UserImportReport struct {
+	Mode    UserImportMode        `json:"mode"`
+	DryRun  bool                  `json:"dry_run"`
+	Applied bool                  `json:"applied"` // 是否已写入
+	Total   int                   `json:"total"`
+	Created int                   `json:"created"`
+	Updated int                   `json:"updated"`
+	Failed  int                   `json:"failed"`
+	Rows    []UserImportRowResult `json:"rows"`
+}
+
+// UserExportQuery 导出用户列表的参数
+type UserExportQuery struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv xlsx"` // 默认 csv
+	Status *int   `form:"status" binding:"omitempty,oneof=0 1"`
+}
+
+// InitialPasswordNotice 新建用户的初始密码通知，发送到配置的通知地址
+type InitialPasswordNotice struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+	Phone    string `json:"phone"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEvent Webhook 事件类型
+type WebhookEvent string
+
+const (
+	WebhookEventDocumentUploaded  WebhookEvent = "document.uploaded"  // 文档上传完成
+	WebhookEventDocumentProcessed WebhookEvent = "document.processed" // 文档解析/向量化完成
+	WebhookEventDocumentPublished WebhookEvent = "document.published" // 文档发布
+	WebhookEventWorkflowCompleted WebhookEvent = "workflow.completed" // 审批流程完成
+	WebhookEventWorkflowRejected  WebhookEvent = "workflow.rejected"  // 审批流程被驳回
+	WebhookEventWorkflowWithdrawn WebhookEvent = "workflow.withdrawn" // 审批流程被发起人撤回
+	WebhookEventWorkflowCancelled WebhookEvent = "workflow.cancelled" // 审批流程被管理员取消
+	WebhookEventTaskAssigned      WebhookEvent = "task.assigned"      // 审批任务分配
+	WebhookEventTaskReminded      WebhookEvent = "task.reminded"      // 审批任务超时提醒
+	WebhookEventTaskEscalated     WebhookEvent = "task.escalated"     // 审批任务超时升级
+)
+
+// WebhookEvents 支持订阅的全部事件
+var WebhookEvents = []WebhookEvent{
+	WebhookEventDocumentUploaded,
+	WebhookEventDocumentProcessed,
+	WebhookEventDocumentPublished,
+	WebhookEventWorkflowCompleted,
+	WebhookEventWorkflowRejected,
+	WebhookEventWorkflowWithdrawn,
+	WebhookEventWorkflowCancelled,
+	WebhookEventTaskAssigned,
+	WebhookEventTaskReminded,
+	WebhookEventTaskEscalated,
+}
+
+// IsValidWebhookEvent 判断事件类型是否受支持
+func IsValidWebhookEvent(event WebhookEvent) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscription 空间级别的 Webhook 订阅
+type WebhookSubscription struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	SpaceID   uint           `json:"space_id" gorm:"not null;index"`
+	Name      string         `json:"name" gorm:"size:100"`
+	URL       string         `json:"url" gorm:"not null;size:500"`
+	Secret    string         `json:"-" gorm:"not null;size:128;comment:HMAC签名密钥"`
+	Events    []WebhookEvent `json:"events" gorm:"serializer:json;not null;comment:订阅的事件列表"`
+	Status    int            `json:"status" gorm:"default:1;comment:1-启用 0-停用"`
+	CreatedBy uint           `json:"created_by" gorm:"not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// Subscribes 判断订阅是否关注指定事件
+func (s *WebhookSubscription) Subscribes(event WebhookEvent) bool {
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus 投递状态
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending    WebhookDeliveryStatus = "pending"    // 等待投递（含等待重试）
+	WebhookDeliveryStatusDelivering WebhookDeliveryStatus = "delivering" // 投递中
+	WebhookDeliveryStatusSucceeded  WebhookDeliveryStatus = "succeeded"  // 投递成功
+	WebhookDeliveryStatusFailed     WebhookDeliveryStatus = "failed"     // 重试耗尽，投递失败
+)
+
+// WebhookDelivery Webhook 投递记录
+type WebhookDelivery struct {
+	ID             uint                  `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint                  `json:"subscription_id" gorm:"not null;index"`
+	EventID        string                `json:"event_id" gorm:"size:64;index;comment:同一事件的多次投递共享该ID"`
+	Event          WebhookEvent          `json:"event" gorm:"size:50;not null"`
+	SpaceID        uint                  `json:"space_id" gorm:"index"`
+	Payload        string                `json:"payload" gorm:"type:text"`
+	Status         WebhookDeliveryStatus `json:"status" gorm:"size:20;index"`
+	Attempts       int                   `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at" gorm:"index"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at"`
+	ResponseStatus int                   `json:"response_status"`
+	ResponseBody   string                `json:"response_body" gorm:"type:text"`
+	LastError      string                `json:"last_error" gorm:"type:text"`
+	RedeliveryOf   uint                  `json:"redelivery_of" gorm:"comment:手动重新投递时指向原投递记录"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// WebhookPayload 投递给订阅方的消息体
+type WebhookPayload struct {
+	ID         string       `json:"id"`
+	Event      WebhookEvent `json:"event"`
+	SpaceID    uint         `json:"space_id"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Data       any          `json:"data"`
+}
+
+type CreateWebhookRequest struct {
+	SpaceID uint           `json:"space_id" binding:"required"`
+	Name    string         `json:"name"`
+	URL     string         `json:"url" binding:"required,url"`
+	Secret  string         `json:"secret"`
+	Events  []WebhookEvent `json:"events" binding:"required,min=1"`
+}
+
+type UpdateWebhookRequest struct {
+	Name   *string        `json:"name"`
+	URL    *string        `json:"url" binding:"omitempty,url"`
+	Secret *string        `json:"secret"`
+	Events []WebhookEvent `json:"events"`
+	Status *int           `json:"status" binding:"omitempty,oneof=0 1"`
+}
+
+// CreateWebhookResponse 创建订阅的响应，签名密钥仅在此时返回
+type CreateWebhookResponse struct {
+	*WebhookSubscription
+	Secret string `json:"secret"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Workflow struct {
 	ID            uint           `json:"id" gorm:"primaryKey"`
 	Name          string         `json:"name" binding:"required"`
@@ -9,13 +11,26 @@ type Workflow struct {
 	CurrentStepID uint           `json:"current_step_id"` // 当前步骤ID
 	ResourceType  string         `json:"resource_type" binding:"required"`
 	ResourceID    uint           `json:"resource_id" binding:"required"`
+	Attributes    map[string]any `json:"attributes" gorm:"serializer:json"` // 资源属性，启动时由资源所属服务提供，用于分支条件求值
+
+	// 重新提交：被拒绝、撤回或取消后，资源以新一轮流程重新进入审批
+	PreviousWorkflowID uint `json:"previous_workflow_id" gorm:"index"` // 上一轮流程ID，0 表示首次提交
+	Round              int  `json:"round" gorm:"default:1"`            // 提交轮次
+
+	FinishedAt *time.Time `json:"finished_at"` // 完成、拒绝、撤回或取消的时间
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 
 	// 关联字段
-	CreatedBy       uint   `json:"created_by"`
-	CreatorNickName string `json:"creator_nick_name"`
-	Steps           []Step `json:"steps" gorm:"foreignKey:WorkflowID"` // 一对多关联
+	CreatedBy       uint              `json:"created_by"`
+	CreatorNickName string            `json:"creator_nick_name"`
+	Steps           []Step            `json:"steps" gorm:"foreignKey:WorkflowID"`               // 一对多关联
+	Histories       []WorkflowHistory `json:"histories,omitempty" gorm:"foreignKey:WorkflowID"` // 流程历史
 }
 
+// StepRoleDepartmentHead 步骤角色取该值时由流程发起人的部门负责人审批，其余取值为空间角色
+const StepRoleDepartmentHead = "dept_head"
+
 type Step struct {
 	ID           uint       `json:"id" gorm:"primaryKey"`
 	StepName     string     `json:"step_name" binding:"required"`
@@ -26,6 +41,31 @@ type Step struct {
 	Tasks        []Task     `json:"tasks" gorm:"foreignKey:StepID"` // 一对多关联
 	Status       StepStatus `json:"status" binding:"required"`
 
+	// 审批规则：any-任一人审批即决定（或签），all-全部同意（会签），
+	// percentage-同意人数达到 ApprovalThreshold 百分比，n_of_m-同意人数达到 ApprovalThreshold
+	ApprovalMode      ApprovalMode `json:"approval_mode" gorm:"size:20;default:'any'"`
+	ApprovalThreshold int          `json:"approval_threshold" gorm:"default:0"`
+
+	// 投票统计，在生成任务和每次审批时更新
+	TotalCount    int `json:"total_count" gorm:"default:0"`    // 审批人总数
+	RequiredCount int `json:"required_count" gorm:"default:0"` // 通过所需的同意人数
+	ApprovedCount int `json:"approved_count" gorm:"default:0"` // 已同意人数
+	RejectedCount int `json:"rejected_count" gorm:"default:0"` // 已拒绝人数
+
+	// 超时策略：任务超过 TimeoutHours 未处理时的动作，超时后都会发送提醒
+	TimeoutAction TimeoutAction `json:"timeout_action" gorm:"size:20;default:'remind'"`
+	EscalateRole  string        `json:"escalate_role" gorm:"size:50;default:'admin'"` // 审批人没有部门负责人时升级处理的空间角色
+
+	// 分支：Condition 为空或成立时才进入该步骤，否则跳过；
+	// 步骤通过后按顺序匹配 Transitions，第一个成立的规则决定下一步，都不成立时进入下一个满足条件的步骤
+	Condition   string           `json:"condition" gorm:"type:text"`
+	Transitions []StepTransition `json:"transitions" gorm:"serializer:json"`
+
+	StartedAt  *time.Time `json:"started_at"`  // 进入步骤、生成任务的时间
+	FinishedAt *time.Time `json:"finished_at"` // 通过、拒绝、跳过或取消的时间
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
 	// 关联字段
 	WorkflowID uint `json:"workflow_id"`
 }
@@ -39,6 +79,21 @@ type Task struct {
 	ApproverID       uint       `json:"approver_id"`
 	ApproverNickName string     `json:"approver_nick_name"`
 	Comment          string     `json:"comment"`
+	DecidedAt        *time.Time `json:"decided_at"` // 同意或拒绝的时间
+
+	// 超时处理
+	DueAt           *time.Time `json:"due_at" gorm:"index"` // 截止时间，TimeoutHours 为 0 时不限时
+	RemindedAt      *time.Time `json:"reminded_at"`         // 最近一次超时提醒时间
+	EscalatedAt     *time.Time `json:"escalated_at"`        // 升级时间
+	EscalatedFromID uint       `json:"escalated_from_id"`   // 由哪个超时任务升级而来
+
+	// 转交、委托与加签
+	TransferredFromID  uint                `json:"transferred_from_id"`                   // 由哪个任务转交而来
+	OriginalApproverID uint                `json:"original_approver_id"`                  // 委托时的原审批人，0 表示本人审批
+	ParentTaskID       uint                `json:"parent_task_id" gorm:"index"`           // 加签时被加签的任务
+	AddPosition        AddApproverPosition `json:"add_position,omitempty" gorm:"size:10"` // 加签位置
+	CreatedAt          time.Time           `json:"created_at"`
+	UpdatedAt          time.Time           `json:"updated_at"`
 
 	// 关联字段
 	WorkflowID uint     `json:"workflow_id"`
@@ -52,7 +107,33 @@ type WorkflowStatus string
 const (
 	WorkflowStatusProcessing WorkflowStatus = "processing"
 	WorkflowStatusCompleted  WorkflowStatus = "completed"
-	WorkflowStatusCancelled  WorkflowStatus = "cancelled"
+	WorkflowStatusCancelled  WorkflowStatus = "cancelled" // 管理员取消
+	WorkflowStatusRejected   WorkflowStatus = "rejected"  // 审批被拒绝
+	WorkflowStatusWithdrawn  WorkflowStatus = "withdrawn" // 发起人撤回
+)
+
+type ApprovalMode string
+
+const (
+	ApprovalModeAny        ApprovalMode = "any"        // 或签：任一审批人同意或拒绝即决定步骤结果
+	ApprovalModeAll        ApprovalMode = "all"        // 会签：全部同意才通过，任一拒绝即驳回
+	ApprovalModePercentage ApprovalMode = "percentage" // 按比例：同意人数达到百分比阈值即通过
+	ApprovalModeNOfM       ApprovalMode = "n_of_m"     // N/M：同意人数达到 N 即通过
+)
+
+// StepTransition 步骤流转规则
+type StepTransition struct {
+	Condition     string `json:"condition"`       // 条件表达式，为空表示无条件
+	NextStepOrder int    `json:"next_step_order"` // 下一步骤序号，0 表示结束流程
+}
+
+type TimeoutAction string
+
+const (
+	TimeoutActionRemind      TimeoutAction = "remind"       // 只提醒审批人
+	TimeoutActionEscalate    TimeoutAction = "escalate"     // 提醒并升级给审批人的部门负责人，没有时升级给 EscalateRole 角色的空间成员
+	TimeoutActionAutoApprove TimeoutAction = "auto_approve" // 自动同意
+	TimeoutActionAutoReject  TimeoutAction = "auto_reject"  // 自动拒绝
 )
 
 type StepStatus string
@@ -61,6 +142,8 @@ const (
 	StepStatusProcessing StepStatus = "processing"
 	StepStatusApproved   StepStatus = "approved"
 	StepStatusRejected   StepStatus = "rejected"
+	StepStatusSkipped    StepStatus = "skipped"   // 条件不满足或被流转规则跳过
+	StepStatusCancelled  StepStatus = "cancelled" // 流程撤回或取消时未结束的步骤
 )
 
 type TaskStatus string
@@ -71,6 +154,18 @@ const (
 	TaskStatusApprovedByOther TaskStatus = "approved_by_others"
 	TaskStatusRejected        TaskStatus = "rejected"
 	TaskStatusRejectedByOther TaskStatus = "rejected_by_others"
+	TaskStatusEscalated       TaskStatus = "escalated"   // 超时后已升级给其他人处理
+	TaskStatusTransferred     TaskStatus = "transferred" // 已转交给其他人处理
+	TaskStatusWaiting         TaskStatus = "waiting"     // 等待加签人处理，暂不能审批
+	TaskStatusCancelled       TaskStatus = "cancelled"   // 加签链中被取消，或流程撤回、取消时关闭
+)
+
+// AddApproverPosition 加签位置
+type AddApproverPosition string
+
+const (
+	AddApproverBefore AddApproverPosition = "before" // 前加签：加签人先审批，同意后交回当前审批人
+	AddApproverAfter  AddApproverPosition = "after"  // 后加签：当前审批人同意后还需加签人同意
 )
 
 type CreateWorkflowRequest struct {
@@ -78,13 +173,16 @@ type CreateWorkflowRequest struct {
 	Description  string `json:"description"`
 	ResourceType string `json:"resource_type" binding:"required"`
 	ResourceID   uint   `json:"resource_id" binding:"required"`
-	SpaceID      uint   `json:"space_id" binding:"required"`
+	SpaceID      uint   `json:"space_id"` // 平台级申请（如创建空间）为 0，由企业管理员审批
 	Priority     int    `json:"priority"`
 	Steps        []Step `json:"steps" binding:"required"`
+
+	PreviousWorkflowID uint `json:"previous_workflow_id"` // 重新提交时的上一轮流程
 }
 
 type StartWorkflowRequest struct {
-	WorkflowID uint `json:"workflow_id" binding:"required"`
+	WorkflowID uint           `json:"workflow_id" binding:"required"`
+	Attributes map[string]any `json:"attributes"` // 资源属性，用于分支条件求值
 }
 
 type ApproveTaskRequest struct {
@@ -92,3 +190,203 @@ type ApproveTaskRequest struct {
 	Comment string     `json:"comment"`
 	Status  TaskStatus `json:"status" binding:"required"`
 }
+
+// TaskQuery 审批人任务列表查询条件
+type TaskQuery struct {
+	Status       string    `form:"status"` // 任务状态，多个用逗号分隔，为空时返回全部
+	SpaceID      uint      `form:"space_id"`
+	ResourceType string    `form:"resource_type"`
+	InitiatorID  uint      `form:"initiator_id"` // 流程发起人
+	StartDate    time.Time `form:"start_date" time_format:"2006-01-02"`
+	EndDate      time.Time `form:"end_date" time_format:"2006-01-02"` // 包含当天
+	SortBy       string    `form:"sort_by"`                           // created_at、due_at、updated_at，默认 id
+	SortOrder    string    `form:"sort_order"`                        // asc 或 desc，默认 desc
+	Page         int       `form:"page"`
+	PageSize     int       `form:"page_size"`
+}
+
+// StuckWorkflowReason 流程卡住的原因
+type StuckWorkflowReason string
+
+const (
+	StuckWorkflowNotStarted  StuckWorkflowReason = "not_started"   // 已创建但启动失败
+	StuckWorkflowNoOpenTasks StuckWorkflowReason = "no_open_tasks" // 当前步骤没有待处理任务
+)
+
+// StuckWorkflow 审批中但无人可处理的流程
+type StuckWorkflow struct {
+	WorkflowID      uint                `json:"workflow_id"`
+	Name            string              `json:"name"`
+	SpaceID         uint                `json:"space_id"`
+	ResourceType    string              `json:"resource_type"`
+	ResourceID      uint                `json:"resource_id"`
+	CreatedBy       uint                `json:"created_by"`
+	CreatorNickName string              `json:"creator_nick_name"`
+	CurrentStepID   uint                `json:"current_step_id"`
+	StepName        string              `json:"step_name"`
+	StepRole        string              `json:"step_role"`
+	StepStartedAt   *time.Time          `json:"step_started_at"`
+	CreatedAt       time.Time           `json:"created_at"`
+	Reason          StuckWorkflowReason `json:"reason"`
+}
+
+// StuckWorkflowQuery 卡住流程查询条件
+type StuckWorkflowQuery struct {
+	SpaceID  uint `form:"space_id"`
+	Page     int  `form:"page"`
+	PageSize int  `form:"page_size"`
+}
+
+// PendingTaskCount 待审批任务数量
+type PendingTaskCount struct {
+	Total   int64                   `json:"total"`
+	Overdue int64                   `json:"overdue"` // 已超过截止时间
+	Spaces  []PendingTaskSpaceCount `json:"spaces"`
+}
+
+// PendingTaskSpaceCount 单个空间的待审批任务数量
+type PendingTaskSpaceCount struct {
+	SpaceID uint  `json:"space_id"`
+	Count   int64 `json:"count"`
+}
+
+// BulkApproveTaskRequest 批量审批，每个任务在各自的事务中处理
+type BulkApproveTaskRequest struct {
+	TaskIDs []uint     `json:"task_ids" binding:"required,min=1,max=100"`
+	Comment string     `json:"comment"`
+	Status  TaskStatus `json:"status" binding:"required"`
+}
+
+// BulkApproveTaskResult 单个任务的批量审批结果
+type BulkApproveTaskResult struct {
+	TaskID  uint   `json:"task_id"`
+	Success bool   `json:"success"`
+	Task    *Task  `json:"task,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// WorkflowHistory 工作流历史，记录审批及超时处理等动作
+type WorkflowHistory struct {
+	ID           uint                  `json:"id" gorm:"primaryKey"`
+	WorkflowID   uint                  `json:"workflow_id" gorm:"not null;index"`
+	StepID       uint                  `json:"step_id"`
+	TaskID       uint                  `json:"task_id"`
+	Action       WorkflowHistoryAction `json:"action" gorm:"size:30;not null"`
+	OperatorID   uint                  `json:"operator_id"` // 0 表示系统自动处理
+	OperatorName string                `json:"operator_name" gorm:"size:50"`
+	Comment      string                `json:"comment" gorm:"type:text"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+type WorkflowHistoryAction string
+
+const (
+	WorkflowHistoryStarted      WorkflowHistoryAction = "started"       // 启动流程
+	WorkflowHistoryApproved     WorkflowHistoryAction = "approved"      // 审批同意
+	WorkflowHistoryRejected     WorkflowHistoryAction = "rejected"      // 审批拒绝
+	WorkflowHistoryReminded     WorkflowHistoryAction = "reminded"      // 超时提醒
+	WorkflowHistoryEscalated    WorkflowHistoryAction = "escalated"     // 超时升级
+	WorkflowHistoryAutoApproved WorkflowHistoryAction = "auto_approved" // 超时自动同意
+	WorkflowHistoryAutoRejected WorkflowHistoryAction = "auto_rejected" // 超时自动拒绝
+	WorkflowHistoryTransferred  WorkflowHistoryAction = "transferred"   // 转交
+	WorkflowHistoryDelegated    WorkflowHistoryAction = "delegated"     // 按委托规则转给代理人
+	WorkflowHistoryAddApprover  WorkflowHistoryAction = "add_approver"  // 加签
+	WorkflowHistoryWithdrawn    WorkflowHistoryAction = "withdrawn"     // 发起人撤回
+	WorkflowHistoryCancelled    WorkflowHistoryAction = "cancelled"     // 管理员取消
+	WorkflowHistoryStepStarted  WorkflowHistoryAction = "step_started"  // 进入步骤
+	WorkflowHistoryStepSkipped  WorkflowHistoryAction = "step_skipped"  // 跳过步骤
+	WorkflowHistoryCompleted    WorkflowHistoryAction = "completed"     // 流程通过
+
+	WorkflowHistoryApproverFallback WorkflowHistoryAction = "approver_fallback" // 步骤角色没有成员，由兜底审批人审批
+)
+
+// TimelineEvent 时间线中的一条记录，由流程历史和步骤名称组成
+type TimelineEvent struct {
+	Time         time.Time             `json:"time"`
+	Action       WorkflowHistoryAction `json:"action"`
+	StepID       uint                  `json:"step_id,omitempty"`
+	StepName     string                `json:"step_name,omitempty"`
+	TaskID       uint                  `json:"task_id,omitempty"`
+	OperatorID   uint                  `json:"operator_id"`
+	OperatorName string                `json:"operator_name"`
+	Comment      string                `json:"comment,omitempty"`
+}
+
+// WorkflowTimeline 一轮审批的详情及按时间排序的事件
+type WorkflowTimeline struct {
+	Workflow *Workflow       `json:"workflow"`
+	Events   []TimelineEvent `json:"events"`
+}
+
+// ResourceTimeline 资源的全部审批轮次，按提交先后排序
+type ResourceTimeline struct {
+	ResourceType string             `json:"resource_type"`
+	ResourceID   uint               `json:"resource_id"`
+	Rounds       []WorkflowTimeline `json:"rounds"`
+}
+
+// ResourceTimelineQuery 按资源查询审批时间线
+type ResourceTimelineQuery struct {
+	ResourceType string `form:"resource_type" binding:"required"`
+	ResourceID   uint   `form:"resource_id" binding:"required"`
+}
+
+// WithdrawWorkflowRequest 撤回或取消流程
+type WithdrawWorkflowRequest struct {
+	Reason string `json:"reason"`
+}
+
+// TransferTaskRequest 转交任务
+type TransferTaskRequest struct {
+	ToUserID uint   `json:"to_user_id" binding:"required"`
+	Comment  string `json:"comment"`
+}
+
+// AddApproverRequest 加签
+type AddApproverRequest struct {
+	UserID   uint                `json:"user_id" binding:"required"`
+	Position AddApproverPosition `json:"position" binding:"required,oneof=before after"`
+	Comment  string              `json:"comment"`
+}
+
+// DelegationRule 外出委托规则，生效期间分配给委托人的任务改由代理人审批
+type DelegationRule struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"` // 委托人
+	DelegateID   uint       `json:"delegate_id" gorm:"not null"`   // 代理人
+	DelegateName string     `json:"delegate_name" gorm:"size:50"`
+	SpaceID      uint       `json:"space_id" gorm:"default:0;comment:0 表示所有空间"`
+	StartAt      time.Time  `json:"start_at" gorm:"not null"`
+	EndAt        time.Time  `json:"end_at" gorm:"not null"`
+	Reason       string     `json:"reason" gorm:"size:255"`
+	Status       int        `json:"status" gorm:"default:1;comment:1-启用 0-停用"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	CancelledAt  *time.Time `json:"cancelled_at"`
+}
+
+// CreateDelegationRequest 创建委托规则
+type CreateDelegationRequest struct {
+	DelegateID uint      `json:"delegate_id" binding:"required"`
+	SpaceID    uint      `json:"space_id"`
+	StartAt    time.Time `json:"start_at" binding:"required"`
+	EndAt      time.Time `json:"end_at" binding:"required"`
+	Reason     string    `json:"reason"`
+}
+
+// 审批资源类型，新增类型需在工作流服务注册对应的资源处理器
+const (
+	ResourceTypeDocument         = "document"          // 文档发布
+	ResourceTypeDocumentDeletion = "document_deletion" // 文档删除
+	ResourceTypeDataExport       = "data_export"       // 数据导出
+	ResourceTypeSpaceMembership  = "space_membership"  // 加入空间申请
+	ResourceTypeSpaceCreation    = "space_creation"    // 创建空间申请
+)
+
+// ResourceTypeInfo 已注册的审批资源类型
+type ResourceTypeInfo struct {
+	Type       string   `json:"type"`
+	Name       string   `json:"name"`
+	Owner      string   `json:"owner"`      // 资源所属服务，流程结束事件投递给该服务
+	Attributes []string `json:"attributes"` // 分支条件可使用的资源属性
+}
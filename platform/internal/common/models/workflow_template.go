@@ -0,0 +1,97 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowTemplate 审批流程模板，绑定到空间、二级空间或分类
+type WorkflowTemplate struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Name         string         `json:"name" gorm:"not null;size:100"`
+	Description  string         `json:"description" gorm:"size:255"`
+	ResourceType string         `json:"resource_type" gorm:"not null;size:50;default:'document';index:idx_workflow_template_binding"`
+	SpaceID      uint           `json:"space_id" gorm:"not null;index:idx_workflow_template_binding"`
+	SubSpaceID   uint           `json:"sub_space_id" gorm:"default:0;index:idx_workflow_template_binding;comment:0 表示绑定到整个空间"`
+	ClassID      uint           `json:"class_id" gorm:"default:0;index:idx_workflow_template_binding;comment:0 表示绑定到整个二级空间"`
+	Steps        []TemplateStep `json:"steps" gorm:"serializer:json;not null"`
+	Status       int            `json:"status" gorm:"default:1;comment:1-启用 0-停用"`
+	CreatedBy    uint           `json:"created_by" gorm:"not null"`
+	UpdatedBy    uint           `json:"updated_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TemplateStep 模板中的步骤定义，字段含义与 Step 相同
+type TemplateStep struct {
+	StepName          string           `json:"step_name" binding:"required"`
+	StepOrder         int              `json:"step_order" binding:"required"`
+	StepRole          string           `json:"step_role" binding:"required"`
+	IsRequired        bool             `json:"is_required"`
+	TimeoutHours      int              `json:"timeout_hours"`
+	ApprovalMode      ApprovalMode     `json:"approval_mode"`
+	ApprovalThreshold int              `json:"approval_threshold"`
+	TimeoutAction     TimeoutAction    `json:"timeout_action"`
+	EscalateRole      string           `json:"escalate_role"`
+	Condition         string           `json:"condition"`
+	Transitions       []StepTransition `json:"transitions"`
+}
+
+// ToStep 将模板步骤转换为流程步骤
+func (t TemplateStep) ToStep() Step {
+	return Step{
+		StepName:          t.StepName,
+		StepOrder:         t.StepOrder,
+		StepRole:          t.StepRole,
+		IsRequired:        t.IsRequired,
+		TimeoutHours:      t.TimeoutHours,
+		Status:            StepStatusProcessing,
+		ApprovalMode:      t.ApprovalMode,
+		ApprovalThreshold: t.ApprovalThreshold,
+		TimeoutAction:     t.TimeoutAction,
+		EscalateRole:      t.EscalateRole,
+		Condition:         t.Condition,
+		Transitions:       t.Transitions,
+	}
+}
+
+// WorkflowTemplateRequest 创建或更新模板
+type WorkflowTemplateRequest struct {
+	Name         string         `json:"name" binding:"required"`
+	Description  string         `json:"description"`
+	ResourceType string         `json:"resource_type"` // 默认 document
+	SpaceID      uint           `json:"space_id" binding:"required"`
+	SubSpaceID   uint           `json:"sub_space_id"`
+	ClassID      uint           `json:"class_id"`
+	Steps        []TemplateStep `json:"steps" binding:"required,min=1,dive"`
+	Status       *int           `json:"status"` // 默认启用
+}
+
+// WorkflowTemplateQuery 模板列表查询条件
+type WorkflowTemplateQuery struct {
+	SpaceID      uint   `form:"space_id"`
+	ResourceType string `form:"resource_type"`
+	Page         int    `form:"page"`
+	PageSize     int    `form:"page_size"`
+}
+
+// MatchWorkflowTemplateRequest 按资源所在位置查找模板，分类优先于二级空间，二级空间优先于空间
+type MatchWorkflowTemplateRequest struct {
+	ResourceType string `json:"resource_type" form:"resource_type"`
+	SpaceID      uint   `json:"space_id" form:"space_id" binding:"required"`
+	SubSpaceID   uint   `json:"sub_space_id" form:"sub_space_id"`
+	ClassID      uint   `json:"class_id" form:"class_id"`
+}
+
+// CreateWorkflowFromTemplateRequest 根据模板创建流程；未指定 TemplateID 时按资源位置匹配模板
+type CreateWorkflowFromTemplateRequest struct {
+	MatchWorkflowTemplateRequest
+	TemplateID  uint   `json:"template_id"`
+	ResourceID  uint   `json:"resource_id" binding:"required"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	PreviousWorkflowID uint `json:"previous_workflow_id"` // 重新提交时的上一轮流程
+}
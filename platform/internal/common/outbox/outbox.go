@@ -0,0 +1,223 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	commonConfig "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/config"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/delivery"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
+)
+
+const (
+	maxResponseBodyLog = 512
+	// SignatureTolerance 接收方允许的签名时间偏差，超出视为重放
+	SignatureTolerance = 5 * time.Minute
+)
+
+// 事件接收方，与 outbox.targets 配置的键对应
+const (
+	TargetKb  = "kb"
+	TargetIam = "iam"
+)
+
+// ErrInvalidSignature 签名缺失、过期或不匹配
+var ErrInvalidSignature = errors.New("invalid event signature")
+
+// Enqueue 在业务事务内写入发给 target 的待投递事件，payload 中的 EventID 由这里填写
+func Enqueue(tx *gorm.DB, target string, aggregateID uint, payload *model.WorkflowStatusEvent) error {
+	payload.EventID = uuid.NewString()
+	if payload.OccurredAt.IsZero() {
+		payload.OccurredAt = time.Now()
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+
+	now := time.Now()
+	return tx.Create(&model.OutboxEvent{
+		EventID:       payload.EventID,
+		EventType:     payload.EventType,
+		Target:        target,
+		AggregateID:   aggregateID,
+		Payload:       string(body),
+		Status:        model.OutboxEventStatusPending,
+		NextAttemptAt: &now,
+	}).Error
+}
+
+// Verify 校验接收到的事件签名，签名方式与 Webhook 相同
+func Verify(secret string, timestampHeader string, signatureHeader string, body []byte) error {
+	if secret == "" {
+		return fmt.Errorf("%w: secret is not configured", ErrInvalidSignature)
+	}
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if skew := time.Since(time.Unix(timestamp, 0)); skew > SignatureTolerance || skew < -SignatureTolerance {
+		return fmt.Errorf("%w: timestamp expired", ErrInvalidSignature)
+	}
+
+	expected := webhook.Sign(secret, timestamp, body)
+	actual := strings.TrimPrefix(signatureHeader, "sha256=")
+	if !hmac.Equal([]byte(expected), []byte(actual)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Relay 扫描发件箱并把事件投递到接收方回调地址，失败时按指数退避重试
+type Relay struct {
+	db      *gorm.DB
+	client  *http.Client
+	targets map[string]string
+	secret  string
+	queue   *delivery.Queue
+}
+
+// NewRelay 创建发件箱投递器
+func NewRelay(db *gorm.DB, cfg *commonConfig.OutboxConfig) *Relay {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+
+	retryBase := time.Duration(cfg.RetryBaseSeconds) * time.Second
+	if retryBase <= 0 {
+		retryBase = 10 * time.Second
+	}
+
+	pollInterval := time.Duration(cfg.PollIntervalSeconds) * time.Second
+	if pollInterval <= 0 {
+		pollInterval = 5 * time.Second
+	}
+
+	return &Relay{
+		db:      db,
+		client:  &http.Client{Timeout: timeout},
+		targets: cfg.Targets,
+		secret:  cfg.Secret,
+		queue: delivery.NewQueue(db, &model.OutboxEvent{}, "outbox", delivery.Statuses{
+			Pending:    string(model.OutboxEventStatusPending),
+			Delivering: string(model.OutboxEventStatusDelivering),
+			Succeeded:  string(model.OutboxEventStatusDelivered),
+			Failed:     string(model.OutboxEventStatusFailed),
+		}, delivery.Options{
+			MaxAttempts:  maxAttempts,
+			RetryBase:    retryBase,
+			PollInterval: pollInterval,
+		}),
+	}
+}
+
+// Start 启动投递循环，直到 ctx 结束；未配置回调地址的接收方，其事件只保留在发件箱中
+func (r *Relay) Start(ctx context.Context) {
+	if len(r.configuredTargets()) == 0 {
+		logger.Warnf("outbox: no target is configured, events will stay in the outbox")
+		return
+	}
+
+	r.queue.Run(ctx, r.dueEvents, r.deliver)
+}
+
+func (r *Relay) deliverDue(ctx context.Context) {
+	r.queue.Poll(ctx, r.dueEvents, r.deliver)
+}
+
+// dueEvents 到期的待投递事件。同一接收方、同一流程的事件按写入顺序投递：前面的事件尚未投递成功
+// （待重试或投递中）时，后面的事件不投递。前面的事件超过最大次数被标记为 failed 后不再阻塞，之后的事件照常投递
+func (r *Relay) dueEvents(ctx context.Context) ([]uint, error) {
+	earlier := r.db.Model(&model.OutboxEvent{}).Select("1").
+		Where("earlier.target = outbox_events.target AND earlier.aggregate_id = outbox_events.aggregate_id").
+		Where("earlier.id < outbox_events.id AND earlier.status IN ?",
+			[]model.OutboxEventStatus{model.OutboxEventStatusPending, model.OutboxEventStatusDelivering})
+	var ids []uint
+	err := r.queue.Due(ctx).
+		Where("target IN ?", r.configuredTargets()).
+		Where("NOT EXISTS (?)", earlier.Table("outbox_events AS earlier")).
+		Order("id ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// deliver 投递单个事件，抢占失败说明事件已由其他进程处理
+func (r *Relay) deliver(ctx context.Context, id uint) {
+	if !r.queue.Claim(ctx, id) {
+		return
+	}
+
+	var event model.OutboxEvent
+	if err := r.db.WithContext(ctx).First(&event, id).Error; err != nil {
+		logger.Errorf("outbox: failed to load event %d: %v", id, err)
+		return
+	}
+
+	err := r.send(ctx, &event)
+	fields := map[string]any{}
+	if err == nil {
+		fields["delivered_at"] = time.Now()
+	}
+	if r.queue.Finish(ctx, event.ID, event.Attempts, err, fields) {
+		logger.Warnf("outbox: event %s failed after %d attempts: %v", event.EventID, event.Attempts, err)
+	}
+}
+
+func (r *Relay) send(ctx context.Context, event *model.OutboxEvent) error {
+	body := []byte(event.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.targets[event.Target], bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, string(event.EventType))
+	req.Header.Set(webhook.HeaderEventID, event.EventID)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.HeaderSignature, "sha256="+webhook.Sign(r.secret, timestamp, body))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBytes, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyLog))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, string(respBytes))
+	}
+	return nil
+}
+
+// configuredTargets 已配置回调地址的接收方
+func (r *Relay) configuredTargets() []string {
+	targets := make([]string, 0, len(r.targets))
+	for target, url := range r.targets {
+		if url != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
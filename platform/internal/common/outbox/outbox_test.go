@@ -0,0 +1,210 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	commonConfig "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/config"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
+	"gorm.io/gorm"
+)
+
+const testSecret = "outbox-secret"
+
+// receiver 校验签名后记录收到的事件，statuses 按顺序指定每次响应的状态码，用完后响应 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	events   []model.WorkflowStatusEvent
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if err := Verify(testSecret, req.Header.Get(webhook.HeaderTimestamp), req.Header.Get(webhook.HeaderSignature), body); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var event model.WorkflowStatusEvent
+	json.Unmarshal(body, &event)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() []model.WorkflowStatusEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.WorkflowStatusEvent(nil), r.events...)
+}
+
+func newTestRelay(t *testing.T, statuses ...int) (*Relay, *gorm.DB, *receiver) {
+	t.Helper()
+	db := dbtest.Open(t, &model.OutboxEvent{})
+	recv := &receiver{statuses: statuses}
+	server := httptest.NewServer(recv)
+	t.Cleanup(server.Close)
+
+	relay := NewRelay(db, &commonConfig.OutboxConfig{
+		Targets:          map[string]string{TargetKb: server.URL, "unconfigured": ""},
+		Secret:           testSecret,
+		MaxAttempts:      2,
+		RetryBaseSeconds: 60,
+	})
+	return relay, db, recv
+}
+
+func enqueue(t *testing.T, db *gorm.DB, target string, workflowID uint, eventType model.WorkflowEventType) *model.WorkflowStatusEvent {
+	t.Helper()
+	payload := &model.WorkflowStatusEvent{EventType: eventType, WorkflowID: workflowID, ResourceType: model.ResourceTypeDocument, ResourceID: 1}
+	if err := Enqueue(db, target, workflowID, payload); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	return payload
+}
+
+func loadEvent(t *testing.T, db *gorm.DB, eventID string) model.OutboxEvent {
+	t.Helper()
+	var event model.OutboxEvent
+	if err := db.Where("event_id = ?", eventID).First(&event).Error; err != nil {
+		t.Fatalf("load event %s: %v", eventID, err)
+	}
+	return event
+}
+
+func TestEnqueue(t *testing.T) {
+	db := dbtest.Open(t, &model.OutboxEvent{})
+	payload := enqueue(t, db, TargetKb, 7, model.WorkflowEventCompleted)
+	if payload.EventID == "" || payload.OccurredAt.IsZero() {
+		t.Fatalf("payload = %+v, want event id and occurred time filled in", payload)
+	}
+
+	event := loadEvent(t, db, payload.EventID)
+	if event.Status != model.OutboxEventStatusPending || event.Target != TargetKb || event.AggregateID != 7 || event.NextAttemptAt == nil {
+		t.Fatalf("outbox event = %+v, want pending kb event of workflow 7", event)
+	}
+	var stored model.WorkflowStatusEvent
+	if err := json.Unmarshal([]byte(event.Payload), &stored); err != nil || stored.EventID != payload.EventID {
+		t.Fatalf("stored payload = %s, want event %s", event.Payload, payload.EventID)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event_id":"e"}`)
+	now := time.Now().Unix()
+	sign := func(timestamp int64) string { return "sha256=" + webhook.Sign(testSecret, timestamp, body) }
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		wantErr   bool
+	}{
+		{name: "valid", secret: testSecret, timestamp: strconv.FormatInt(now, 10), signature: sign(now)},
+		{name: "missing secret", secret: "", timestamp: strconv.FormatInt(now, 10), signature: sign(now), wantErr: true},
+		{name: "invalid timestamp", secret: testSecret, timestamp: "x", signature: sign(now), wantErr: true},
+		{name: "expired timestamp", secret: testSecret, timestamp: strconv.FormatInt(now-600, 10), signature: sign(now - 600), wantErr: true},
+		{name: "wrong signature", secret: testSecret, timestamp: strconv.FormatInt(now, 10), signature: "sha256=deadbeef", wantErr: true},
+		{name: "signed with another secret", secret: "other", timestamp: strconv.FormatInt(now, 10), signature: sign(now), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, body)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("Verify() error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestRelayDelivers(t *testing.T) {
+	relay, db, recv := newTestRelay(t)
+	first := enqueue(t, db, TargetKb, 1, model.WorkflowEventCompleted)
+	second := enqueue(t, db, TargetKb, 2, model.WorkflowEventRejected)
+	skipped := enqueue(t, db, "unconfigured", 3, model.WorkflowEventCompleted)
+
+	relay.deliverDue(context.Background())
+
+	if got := recv.received(); len(got) != 2 || got[0].EventID != first.EventID || got[1].EventID != second.EventID {
+		t.Fatalf("received = %+v, want events %s and %s", got, first.EventID, second.EventID)
+	}
+	for _, payload := range []*model.WorkflowStatusEvent{first, second} {
+		event := loadEvent(t, db, payload.EventID)
+		if event.Status != model.OutboxEventStatusDelivered || event.DeliveredAt == nil || event.Attempts != 1 {
+			t.Fatalf("event %s = %s/%d, want delivered after 1 attempt", event.EventID, event.Status, event.Attempts)
+		}
+	}
+	if event := loadEvent(t, db, skipped.EventID); event.Status != model.OutboxEventStatusPending || event.Attempts != 0 {
+		t.Fatalf("event without target = %s/%d, want untouched", event.Status, event.Attempts)
+	}
+}
+
+func TestRelayKeepsOrderWithinWorkflow(t *testing.T) {
+	relay, db, recv := newTestRelay(t, http.StatusConflict)
+	first := enqueue(t, db, TargetKb, 1, model.WorkflowEventWithdrawn)
+	second := enqueue(t, db, TargetKb, 1, model.WorkflowEventCompleted)
+	other := enqueue(t, db, TargetKb, 2, model.WorkflowEventCompleted)
+
+	// 第一个事件投递失败，同一流程的后续事件等待，其他流程不受影响
+	relay.deliverDue(context.Background())
+	if got := recv.received(); len(got) != 2 || got[0].EventID != first.EventID || got[1].EventID != other.EventID {
+		t.Fatalf("received = %+v, want %s then %s", got, first.EventID, other.EventID)
+	}
+	event := loadEvent(t, db, first.EventID)
+	if event.Status != model.OutboxEventStatusPending || event.LastError == "" {
+		t.Fatalf("failed event = %s/%q, want pending with error", event.Status, event.LastError)
+	}
+	if event := loadEvent(t, db, second.EventID); event.Attempts != 0 {
+		t.Fatalf("later event attempts = %d, want 0", event.Attempts)
+	}
+
+	// 重试时间到达后先重投第一个事件，下一轮再投递后续事件
+	db.Model(&model.OutboxEvent{}).Where("event_id = ?", first.EventID).Update("next_attempt_at", time.Now())
+	relay.deliverDue(context.Background())
+	relay.deliverDue(context.Background())
+	got := recv.received()
+	if len(got) != 4 || got[2].EventID != first.EventID || got[3].EventID != second.EventID {
+		t.Fatalf("received = %+v, want retry of %s then %s", got, first.EventID, second.EventID)
+	}
+}
+
+func TestRelayFailedEventStopsBlocking(t *testing.T) {
+	relay, db, recv := newTestRelay(t, http.StatusInternalServerError, http.StatusInternalServerError)
+	first := enqueue(t, db, TargetKb, 1, model.WorkflowEventWithdrawn)
+	second := enqueue(t, db, TargetKb, 1, model.WorkflowEventCompleted)
+
+	relay.deliverDue(context.Background())
+	db.Model(&model.OutboxEvent{}).Where("event_id = ?", first.EventID).Update("next_attempt_at", time.Now())
+	relay.deliverDue(context.Background())
+
+	if event := loadEvent(t, db, first.EventID); event.Status != model.OutboxEventStatusFailed || event.NextAttemptAt != nil {
+		t.Fatalf("exhausted event = %s/%v, want failed without retry", event.Status, event.NextAttemptAt)
+	}
+	// 前一个事件标记为失败后，后续事件不再被阻塞
+	relay.deliverDue(context.Background())
+	if event := loadEvent(t, db, second.EventID); event.Status != model.OutboxEventStatusDelivered {
+		t.Fatalf("later event status = %s, want delivered", event.Status)
+	}
+	if got := recv.received(); len(got) != 3 {
+		t.Fatalf("received %d requests, want 3", len(got))
+	}
+}
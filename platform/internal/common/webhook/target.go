@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrTargetNotAllowed 投递地址指向本机、内网或其他保留地址
+var ErrTargetNotAllowed = errors.New("webhook target address is not allowed")
+
+// blockedNetworks 除 net.IP 自带判断外需要拦截的保留网段
+var blockedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // 本网络
+	mustParseCIDR("100.64.0.0/10"), // 运营商级 NAT
+	mustParseCIDR("192.0.0.0/24"),  // IETF 协议分配
+	mustParseCIDR("198.18.0.0/15"), // 网络基准测试
+	mustParseCIDR("240.0.0.0/4"),   // 保留地址
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// isBlockedIP 判断地址是否为回环、私有、链路本地、组播或保留地址
+func isBlockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateURL 保存订阅时校验投递地址：只允许 http/https，主机名解析出的地址都必须是公网地址。
+// 投递时还会在建立连接前再次校验实际连接的地址，防止域名解析结果被替换
+func ValidateURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url scheme must be http or https")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("url host is required")
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return ErrTargetNotAllowed
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if isBlockedIP(ip) {
+			return ErrTargetNotAllowed
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if isBlockedIP(addr.IP) {
+			return ErrTargetNotAllowed
+		}
+	}
+	return nil
+}
+
+// newTargetClient 创建投递用的 HTTP 客户端，连接建立前拒绝内网和保留地址。
+// 不使用环境变量中的代理，否则校验的是代理地址而不是订阅方地址
+func newTargetClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || isBlockedIP(ip) {
+				return fmt.Errorf("%w: %s", ErrTargetNotAllowed, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
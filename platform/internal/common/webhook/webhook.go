@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	commonConfig "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/config"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/delivery"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+// 投递时附带的请求头
+const (
+	HeaderEvent     = "X-KBase-Event"
+	HeaderEventID   = "X-KBase-Event-ID"
+	HeaderDelivery  = "X-KBase-Delivery"
+	HeaderTimestamp = "X-KBase-Timestamp"
+	HeaderSignature = "X-KBase-Signature"
+)
+
+// maxResponseBodySize 投递日志中保存的响应体长度上限
+const maxResponseBodySize = 2048
+
+// Dispatcher 负责写入投递记录、签名并投递 Webhook，失败时按指数退避重试
+type Dispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+	queue  *delivery.Queue
+}
+
+// NewDispatcher 创建 Webhook 投递器
+func NewDispatcher(db *gorm.DB, cfg *commonConfig.WebhookConfig) *Dispatcher {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 6
+	}
+
+	retryBase := time.Duration(cfg.RetryBaseSeconds) * time.Second
+	if retryBase <= 0 {
+		retryBase = 30 * time.Second
+	}
+
+	pollInterval := time.Duration(cfg.PollIntervalSeconds) * time.Second
+	if pollInterval <= 0 {
+		pollInterval = 15 * time.Second
+	}
+
+	return &Dispatcher{
+		db:     db,
+		client: newTargetClient(timeout),
+		queue: delivery.NewQueue(db, &model.WebhookDelivery{}, "webhook", delivery.Statuses{
+			Pending:    string(model.WebhookDeliveryStatusPending),
+			Delivering: string(model.WebhookDeliveryStatusDelivering),
+			Succeeded:  string(model.WebhookDeliveryStatusSucceeded),
+			Failed:     string(model.WebhookDeliveryStatusFailed),
+		}, delivery.Options{
+			MaxAttempts:  maxAttempts,
+			RetryBase:    retryBase,
+			PollInterval: pollInterval,
+		}),
+	}
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, "<timestamp>.<body>")，结果为十六进制字符串
+// 订阅方使用同样的方式计算并与 X-KBase-Signature 中 sha256= 之后的部分比较
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret 生成随机签名密钥
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Publish 为订阅了该事件的空间订阅写入投递记录并立即尝试投递
+// 事件发布失败不影响业务流程，调用方无需处理错误，这里只记录日志
+func (d *Dispatcher) Publish(ctx context.Context, event model.WebhookEvent, spaceID uint, data any) {
+	if d == nil {
+		return
+	}
+
+	var subscriptions []model.WebhookSubscription
+	if err := d.db.WithContext(ctx).
+		Where("space_id = ? AND status = ?", spaceID, 1).
+		Find(&subscriptions).Error; err != nil {
+		logger.Errorf("webhook: failed to load subscriptions for space %d: %v", spaceID, err)
+		return
+	}
+
+	payload := model.WebhookPayload{
+		ID:         uuid.NewString(),
+		Event:      event,
+		SpaceID:    spaceID,
+		OccurredAt: time.Now(),
+		Data:       data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.Errorf("webhook: failed to marshal %s payload: %v", event, err)
+		return
+	}
+
+	now := time.Now()
+	for _, sub := range subscriptions {
+		if !sub.Subscribes(event) {
+			continue
+		}
+
+		delivery := model.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        payload.ID,
+			Event:          event,
+			SpaceID:        spaceID,
+			Payload:        string(body),
+			Status:         model.WebhookDeliveryStatusPending,
+			NextAttemptAt:  &now,
+		}
+		if err := d.db.WithContext(ctx).Create(&delivery).Error; err != nil {
+			logger.Errorf("webhook: failed to create delivery for subscription %d: %v", sub.ID, err)
+			continue
+		}
+
+		go d.deliver(context.Background(), delivery.ID)
+	}
+}
+
+// Redeliver 手动重新投递，复制原投递记录的负载生成新的投递记录
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID uint) (*model.WebhookDelivery, error) {
+	var original model.WebhookDelivery
+	if err := d.db.WithContext(ctx).First(&original, deliveryID).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := model.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		Event:          original.Event,
+		SpaceID:        original.SpaceID,
+		Payload:        original.Payload,
+		Status:         model.WebhookDeliveryStatusPending,
+		NextAttemptAt:  &now,
+		RedeliveryOf:   original.ID,
+	}
+	if err := d.db.WithContext(ctx).Create(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to create redelivery: %w", err)
+	}
+
+	d.deliver(ctx, delivery.ID)
+
+	if err := d.db.WithContext(ctx).First(&delivery, delivery.ID).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Start 启动重试循环，定期扫描到期的投递记录，直到 ctx 结束
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.queue.Run(ctx, d.dueDeliveries, d.deliver)
+}
+
+func (d *Dispatcher) retryDue(ctx context.Context) {
+	d.queue.Poll(ctx, d.dueDeliveries, d.deliver)
+}
+
+func (d *Dispatcher) dueDeliveries(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := d.queue.Due(ctx).Order("next_attempt_at ASC").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// deliver 投递单条记录，抢占失败说明记录已由其他进程处理
+func (d *Dispatcher) deliver(ctx context.Context, deliveryID uint) {
+	if !d.queue.Claim(ctx, deliveryID) {
+		return
+	}
+
+	var delivery model.WebhookDelivery
+	if err := d.db.WithContext(ctx).First(&delivery, deliveryID).Error; err != nil {
+		logger.Errorf("webhook: failed to load delivery %d: %v", deliveryID, err)
+		return
+	}
+
+	var subscription model.WebhookSubscription
+	if err := d.db.WithContext(ctx).First(&subscription, delivery.SubscriptionID).Error; err != nil {
+		// 订阅已删除，不再重试
+		d.queue.Abandon(ctx, delivery.ID, "subscription not found", map[string]any{
+			"response_status": 0,
+			"response_body":   "",
+		})
+		return
+	}
+
+	statusCode, respBody, err := d.send(ctx, &subscription, &delivery)
+	if d.queue.Finish(ctx, delivery.ID, delivery.Attempts, err, map[string]any{
+		"response_status": statusCode,
+		"response_body":   respBody,
+	}) {
+		logger.Warnf("webhook: delivery %d to %s failed after %d attempts: %v", delivery.ID, subscription.URL, delivery.Attempts, err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KBase-Webhook/1.0")
+	req.Header.Set(HeaderEvent, string(delivery.Event))
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBytes), errors.New("unexpected status " + resp.Status)
+	}
+	return resp.StatusCode, string(respBytes), nil
+}
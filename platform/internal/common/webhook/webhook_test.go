@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	commonConfig "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/config"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+// recordingReceiver 记录收到的投递并按预设状态码响应
+type recordingReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *recordingReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := r.status
+	r.mu.Unlock()
+	w.WriteHeader(status)
+	io.WriteString(w, "ok")
+}
+
+func (r *recordingReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// newTestDispatcher 创建投递到本地接收方的投递器，测试中放开内网地址限制
+func newTestDispatcher(t *testing.T, status int) (*Dispatcher, *gorm.DB, *recordingReceiver, string) {
+	t.Helper()
+	db := dbtest.Open(t, &model.WebhookSubscription{}, &model.WebhookDelivery{})
+	receiver := &recordingReceiver{status: status}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	d := NewDispatcher(db, &commonConfig.WebhookConfig{MaxAttempts: 3, RetryBaseSeconds: 60})
+	d.client = server.Client()
+	return d, db, receiver, server.URL
+}
+
+func createSubscription(t *testing.T, db *gorm.DB, sub model.WebhookSubscription) model.WebhookSubscription {
+	t.Helper()
+	if sub.Secret == "" {
+		sub.Secret = "test-secret"
+	}
+	disabled := sub.Status == 0
+	if err := db.Create(&sub).Error; err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if disabled {
+		// Status 的数据库默认值为 1，停用需要单独更新
+		db.Model(&sub).Update("status", 0)
+	}
+	return sub
+}
+
+func TestPublishFiltersSubscriptions(t *testing.T) {
+	d, db, receiver, url := newTestDispatcher(t, http.StatusOK)
+	events := []model.WebhookEvent{model.WebhookEventWorkflowCompleted}
+	wanted := createSubscription(t, db, model.WebhookSubscription{SpaceID: 1, URL: url, Events: events, Status: 1})
+	createSubscription(t, db, model.WebhookSubscription{SpaceID: 1, URL: url, Events: []model.WebhookEvent{model.WebhookEventTaskAssigned}, Status: 1})
+	createSubscription(t, db, model.WebhookSubscription{SpaceID: 2, URL: url, Events: events, Status: 1})
+	createSubscription(t, db, model.WebhookSubscription{SpaceID: 1, URL: url, Events: events, Status: 0})
+
+	d.Publish(context.Background(), model.WebhookEventWorkflowCompleted, 1, map[string]any{"id": 7})
+
+	var deliveries []model.WebhookDelivery
+	if err := db.Find(&deliveries).Error; err != nil {
+		t.Fatalf("load deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].SubscriptionID != wanted.ID {
+		t.Fatalf("deliveries = %+v, want one for subscription %d", deliveries, wanted.ID)
+	}
+
+	// Publish 在后台投递，等待投递完成
+	deadline := time.Now().Add(5 * time.Second)
+	for deliveries[0].Status != model.WebhookDeliveryStatusSucceeded && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		db.First(&deliveries[0], deliveries[0].ID)
+	}
+	if receiver.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", receiver.count())
+	}
+}
+
+func TestRedeliverSignsRequest(t *testing.T) {
+	d, db, receiver, url := newTestDispatcher(t, http.StatusOK)
+	sub := createSubscription(t, db, model.WebhookSubscription{SpaceID: 1, URL: url, Events: []model.WebhookEvent{model.WebhookEventTaskAssigned}, Status: 1})
+	original := model.WebhookDelivery{SubscriptionID: sub.ID, EventID: "event-1", Event: model.WebhookEventTaskAssigned,
+		SpaceID: 1, Payload: `{"id":"event-1"}`, Status: model.WebhookDeliveryStatusFailed}
+	db.Create(&original)
+
+	delivery, err := d.Redeliver(context.Background(), original.ID)
+	if err != nil {
+		t.Fatalf("Redeliver() error = %v", err)
+	}
+	if delivery.Status != model.WebhookDeliveryStatusSucceeded || delivery.RedeliveryOf != original.ID || delivery.Attempts != 1 {
+		t.Fatalf("redelivery = %+v, want succeeded copy of %d after 1 attempt", delivery, original.ID)
+	}
+
+	req := receiver.requests[0]
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("timestamp header = %q", req.Header.Get(HeaderTimestamp))
+	}
+	if got, want := req.Header.Get(HeaderSignature), "sha256="+Sign(sub.Secret, timestamp, receiver.bodies[0]); got != want {
+		t.Fatalf("signature = %s, want %s", got, want)
+	}
+	if req.Header.Get(HeaderEvent) != string(model.WebhookEventTaskAssigned) || req.Header.Get(HeaderEventID) != "event-1" {
+		t.Fatalf("event headers = %s/%s", req.Header.Get(HeaderEvent), req.Header.Get(HeaderEventID))
+	}
+}
+
+func TestDeliveryRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		attempts     int // 本次投递之前已尝试的次数
+		wantStatus   model.WebhookDeliveryStatus
+		wantBackoff  time.Duration
+		wantNextNull bool
+	}{
+		{name: "success", status: http.StatusNoContent, wantStatus: model.WebhookDeliveryStatusSucceeded, wantNextNull: true},
+		{name: "first failure waits base", status: http.StatusInternalServerError, wantStatus: model.WebhookDeliveryStatusPending, wantBackoff: time.Minute},
+		{name: "second failure doubles", status: http.StatusBadGateway, attempts: 1, wantStatus: model.WebhookDeliveryStatusPending, wantBackoff: 2 * time.Minute},
+		{name: "last attempt fails", status: http.StatusNotFound, attempts: 2, wantStatus: model.WebhookDeliveryStatusFailed, wantNextNull: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, db, _, url := newTestDispatcher(t, tt.status)
+			sub := createSubscription(t, db, model.WebhookSubscription{SpaceID: 1, URL: url, Events: []model.WebhookEvent{model.WebhookEventTaskAssigned}, Status: 1})
+			past := time.Now().Add(-time.Second)
+			delivery := model.WebhookDelivery{SubscriptionID: sub.ID, EventID: "e", Event: model.WebhookEventTaskAssigned,
+				Payload: "{}", Status: model.WebhookDeliveryStatusPending, Attempts: tt.attempts, NextAttemptAt: &past}
+			db.Create(&delivery)
+
+			before := time.Now()
+			d.retryDue(context.Background())
+
+			delivery = model.WebhookDelivery{}
+			db.First(&delivery, 1)
+			if delivery.Status != tt.wantStatus || delivery.Attempts != tt.attempts+1 {
+				t.Fatalf("delivery status/attempts = %s/%d, want %s/%d", delivery.Status, delivery.Attempts, tt.wantStatus, tt.attempts+1)
+			}
+			if delivery.ResponseStatus != tt.status {
+				t.Fatalf("response status = %d, want %d", delivery.ResponseStatus, tt.status)
+			}
+			if tt.wantNextNull {
+				if delivery.NextAttemptAt != nil {
+					t.Fatalf("next attempt = %v, want nil", delivery.NextAttemptAt)
+				}
+				return
+			}
+			if delivery.NextAttemptAt == nil {
+				t.Fatal("next attempt = nil, want retry")
+			}
+			if wait := delivery.NextAttemptAt.Sub(before); wait < tt.wantBackoff || wait > tt.wantBackoff+5*time.Second {
+				t.Fatalf("retry after %v, want about %v", wait, tt.wantBackoff)
+			}
+			if !strings.Contains(delivery.LastError, strconv.Itoa(tt.status)) {
+				t.Fatalf("last error = %q", delivery.LastError)
+			}
+		})
+	}
+}
+
+func TestDeliveryToDeletedSubscriptionFails(t *testing.T) {
+	d, db, receiver, url := newTestDispatcher(t, http.StatusOK)
+	sub := createSubscription(t, db, model.WebhookSubscription{SpaceID: 1, URL: url, Events: []model.WebhookEvent{model.WebhookEventTaskAssigned}, Status: 1})
+	now := time.Now()
+	delivery := model.WebhookDelivery{SubscriptionID: sub.ID, EventID: "e", Event: model.WebhookEventTaskAssigned,
+		Payload: "{}", Status: model.WebhookDeliveryStatusPending, NextAttemptAt: &now}
+	db.Create(&delivery)
+	db.Delete(&sub)
+
+	d.retryDue(context.Background())
+
+	db.First(&delivery, delivery.ID)
+	if delivery.Status != model.WebhookDeliveryStatusFailed || receiver.count() != 0 {
+		t.Fatalf("delivery status = %s, requests = %d, want failed without request", delivery.Status, receiver.count())
+	}
+}
@@ -49,3 +49,31 @@ func (c *IamClient) ValidateToken(token string) (*model.User, error) {
 	}
 	return &response.Data, nil
 }
+
+// ValidateAPIKey 校验服务账号的 API Key，返回服务账号及其授权范围
+func (c *IamClient) ValidateAPIKey(key string, clientIP string) (*model.ValidateAPIKeyResponse, error) {
+	req, err := http.NewRequest("POST", c.config.Url+"/api/v1/auth/validate-api-key", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(model.HeaderAPIKey, key)
+	req.Header.Set("X-Real-IP", clientIP)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("无效的 API Key: " + resp.Status)
+	}
+
+	var response struct {
+		Message string                       `json:"message"`
+		Data    model.ValidateAPIKeyResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
@@ -17,6 +17,8 @@ type Config struct {
 	Kb       KbConfig                  `mapstructure:"kb"`
 	Gin      commonConfig.GinConfig    `mapstructure:"gin"`
 	Log      commonConfig.LogConfig    `mapstructure:"log"`
+	// TrustedProxies 网关前面的反向代理地址或网段，只有来自这些地址的 X-Forwarded-For 才被采信；为空时直接使用连接来源IP
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // IamConfig IAM 服务地址配置（Gateway 特有）
@@ -91,6 +93,7 @@ func bindEnvVars(v *viper.Viper) {
 	v.BindEnv("workflow.url", "KBASE_WORKFLOW_URL", "WORKFLOW_URL")
 	v.BindEnv("kb.url", "KBASE_KB_URL", "KB_URL")
 	v.BindEnv("gin.mode", "KBASE_GIN_MODE", "GIN_MODE")
+	v.BindEnv("trusted_proxies", "KBASE_TRUSTED_PROXIES", "TRUSTED_PROXIES")
 	v.BindEnv("log.level", "KBASE_LOG_LEVEL", "LOG_LEVEL")
 	v.BindEnv("log.db_log_level", "KBASE_LOG_DB_LOG_LEVEL", "LOG_DB_LOG_LEVEL")
 }
@@ -102,6 +105,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("workflow.url", "http://workflow-service:8082")
 	v.SetDefault("kb.url", "http://kb-service:8083")
 	v.SetDefault("gin.mode", "debug")
+	v.SetDefault("trusted_proxies", []string{})
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.db_log_level", "warn")
 }
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"github.com/gin-gonic/gin"
+)
+
+// forwardedIdentityHeaders 由网关写入、下游服务据此识别调用方的请求头，客户端传入的值一律丢弃
+var forwardedIdentityHeaders = []string{"X-User-ID", "X-Real-IP"}
+
+// setForwardedIdentity 清除客户端自带的身份头，再写入网关认证得到的用户ID和客户端IP
+func setForwardedIdentity(c *gin.Context, header http.Header) {
+	for _, key := range forwardedIdentityHeaders {
+		header.Del(key)
+	}
+
+	if user, ok := c.Get("user"); ok && user != nil {
+		header.Set("X-User-ID", fmt.Sprintf("%d", user.(*model.User).ID))
+	}
+
+	// 透传客户端IP，供下游服务记录操作日志
+	header.Set("X-Real-IP", c.ClientIP())
+}
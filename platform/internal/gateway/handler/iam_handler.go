@@ -52,6 +52,10 @@ func (h *IamHandler) ProxyToIamClient(c *gin.Context) {
 
 	client := &http.Client{
 		Timeout: 30 * time.Second, // 减少超时时间到30秒
+		// 重定向（如单点登录跳转）原样返回给浏览器，不由网关跟随
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	}
 
 	fullURL := targetURL
@@ -80,11 +84,8 @@ func (h *IamHandler) ProxyToIamClient(c *gin.Context) {
 		}
 	}
 
-	user, _ := c.Get("user")
-
-	if user != nil {
-		req.Header.Add("X-User-ID", fmt.Sprintf("%d", user.(*model.User).ID))
-	}
+	// 身份头只能由网关写入
+	setForwardedIdentity(c, req.Header)
 
 	// 发送请求
 	resp, err := client.Do(req)
@@ -164,3 +165,12 @@ func (h *IamHandler) ValidateToken(c *gin.Context) (*model.User, error) {
 
 	return user, nil
 }
+
+// ValidateAPIKey 通过 IAM 校验服务账号的 API Key
+func (h *IamHandler) ValidateAPIKey(c *gin.Context, key string) (*model.ValidateAPIKeyResponse, error) {
+	result, err := h.iamClient.ValidateAPIKey(key, c.ClientIP())
+	if err != nil {
+		return nil, errors.New("无效的 API Key")
+	}
+	return result, nil
+}
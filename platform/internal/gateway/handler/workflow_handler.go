@@ -8,7 +8,6 @@ import (
 	"strings"
 	"time"
 
-	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/gateway/configs"
 	"github.com/gin-gonic/gin"
 )
@@ -56,11 +55,8 @@ func (h *WorkflowHandler) ProxyToWorkflowClient(c *gin.Context) {
 		}
 	}
 
-	user, _ := c.Get("user")
-
-	if user != nil {
-		req.Header.Add("X-User-ID", fmt.Sprintf("%d", user.(*model.User).ID))
-	}
+	// 身份头只能由网关写入
+	setForwardedIdentity(c, req.Header)
 
 	// 发送请求
 	resp, err := client.Do(req)
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/gateway/handler"
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyRoutes API Key 可以调用的接口及所需的授权范围，未列出的接口只接受用户登录令牌
+var apiKeyRoutes = map[string]model.APIKeyScope{
+	"GET /api/v1/kb/:id/preview":      model.APIKeyScopeKbRead,
+	"GET /api/v1/kb/:id/info":         model.APIKeyScopeKbRead,
+	"POST /api/v1/kb/upload":          model.APIKeyScopeKbUpload,
+	"POST /api/v1/kb/:id/resubmit":    model.APIKeyScopeKbUpload,
+	"POST /api/v1/kb/search":          model.APIKeyScopeKbSearch,
+	"POST /api/v1/kb/:id/chat":        model.APIKeyScopeKbSearch,
+	"POST /api/v1/kb/:id/chat/stream": model.APIKeyScopeKbSearch,
+	"POST /api/v1/kb/:id/publish":     model.APIKeyScopeKbPublish,
+	"POST /api/v1/kb/:id/unpublish":   model.APIKeyScopeKbPublish,
+	"DELETE /api/v1/kb/:id":           model.APIKeyScopeKbDelete,
+}
+
+// apiKeyFromRequest 从 X-API-Key 头或以 API Key 前缀开头的 Bearer 令牌中取出 API Key
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(model.HeaderAPIKey); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && strings.HasPrefix(token, model.APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey 校验 API Key 及其对当前接口的授权范围。授权限定了空间时，
+// 将允许的空间写入 X-API-Key-Spaces 头，由下游服务按资源所属空间校验
+func authenticateAPIKey(c *gin.Context, iamHandler *handler.IamHandler, key string) {
+	// 服务账号只能以自身身份调用，不接受客户端指定的用户
+	if c.GetHeader("X-User-ID") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API Key 调用不能携带 X-User-ID 头"})
+		c.Abort()
+		return
+	}
+
+	scope, ok := apiKeyRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该接口不支持 API Key 调用"})
+		c.Abort()
+		return
+	}
+
+	result, err := iamHandler.ValidateAPIKey(c, key)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
+
+	var spaces []string
+	granted, unrestricted := false, false
+	for _, grant := range result.Grants {
+		if grant.Scope != scope {
+			continue
+		}
+		granted = true
+		if grant.SpaceID == 0 {
+			unrestricted = true
+			break
+		}
+		spaces = append(spaces, strconv.FormatUint(uint64(grant.SpaceID), 10))
+	}
+	if !granted {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API Key 未授权 %s", scope)})
+		c.Abort()
+		return
+	}
+	if !unrestricted {
+		c.Request.Header.Set(model.HeaderAPIKeySpaces, strings.Join(spaces, ","))
+	}
+
+	// API Key 不再转发给下游服务
+	c.Request.Header.Del(model.HeaderAPIKey)
+	c.Request.Header.Del("Authorization")
+
+	c.Set("user", result.User)
+	c.Next()
+}
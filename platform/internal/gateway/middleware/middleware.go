@@ -3,14 +3,23 @@ package middleware
 import (
 	"net/http"
 
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/gateway/handler"
 	"github.com/gin-gonic/gin"
 )
 
 // AuthRequired Gateway 专用的认证中间件
-// 通过 IAM 服务验证 token 并将用户 ID 添加到请求头中
+// 通过 IAM 服务验证 token 或服务账号的 API Key，并将用户 ID 添加到请求头中
 func AuthRequired(iamHandler *handler.IamHandler) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 空间范围只能由网关写入
+		c.Request.Header.Del(model.HeaderAPIKeySpaces)
+
+		if key := apiKeyFromRequest(c); key != "" {
+			authenticateAPIKey(c, iamHandler, key)
+			return
+		}
+
 		token := c.GetHeader("Authorization")
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少Authorization头"})
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"log"
+
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/middleware"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/gateway/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/gateway/configs"
@@ -14,6 +16,10 @@ import (
 func Setup(cfg *configs.Config) *gin.Engine {
 	gin.SetMode(cfg.Gin.Mode)
 	r := gin.New()
+	// 客户端IP用于登录限流和操作日志，只采信可信代理传入的 X-Forwarded-For
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted_proxies: %v", err)
+	}
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
 	r.Use(middleware.CORS())
@@ -38,9 +44,14 @@ func Setup(cfg *configs.Config) *gin.Engine {
 				auth.POST("/login", iamHandler.ProxyToIamClient)
 				auth.POST("/logout", iamHandler.ProxyToIamClient)
 				auth.POST("/refresh", iamHandler.ProxyToIamClient)
+				auth.POST("/expired-password", iamHandler.ProxyToIamClient) // 密码过期后修改密码
+				auth.GET("/oidc/login", iamHandler.ProxyToIamClient)        // 跳转到身份提供方登录
+				auth.GET("/oidc/callback", iamHandler.ProxyToIamClient)     // 身份提供方回调
 				auth.PATCH("/change-password",
 					gw_middleware.AuthRequired(iamHandler),
 					iamHandler.ProxyToIamClient)
+				auth.GET("/sessions", iamHandler.ProxyToIamClient)        // 当前用户的登录会话
+				auth.DELETE("/sessions/:id", iamHandler.ProxyToIamClient) // 下线指定会话
 			}
 
 			// 用户管理路由
@@ -54,6 +65,26 @@ func Setup(cfg *configs.Config) *gin.Engine {
 				users.POST("/:id/roles", iamHandler.ProxyToIamClient)
 				users.PUT("/:id", iamHandler.ProxyToIamClient)
 				users.DELETE("/:id", iamHandler.ProxyToIamClient)
+				users.POST("/:id/logout", iamHandler.ProxyToIamClient)         // 强制下线
+				users.POST("/:id/unlock", iamHandler.ProxyToIamClient)         // 解除登录锁定
+				users.POST("/directory-sync", iamHandler.ProxyToIamClient)     // 同步目录用户
+				users.PUT("/:id/org-unit", iamHandler.ProxyToIamClient)        // 调整所属组织
+				users.GET("/:id/department-head", iamHandler.ProxyToIamClient) // 部门负责人
+				users.POST("/import", iamHandler.ProxyToIamClient)             // 批量导入用户
+				users.GET("/export", iamHandler.ProxyToIamClient)              // 导出用户列表
+			}
+
+			// 组织架构路由
+			orgUnits := iam.Group("/org-units")
+			orgUnits.Use(gw_middleware.AuthRequired(iamHandler))
+			{
+				orgUnits.GET("", iamHandler.ProxyToIamClient) // ?tree=true&root_id=
+				orgUnits.GET("/:id", iamHandler.ProxyToIamClient)
+				orgUnits.GET("/:id/members", iamHandler.ProxyToIamClient) // ?include_sub=true
+				orgUnits.POST("", iamHandler.ProxyToIamClient)
+				orgUnits.PUT("/:id", iamHandler.ProxyToIamClient)
+				orgUnits.DELETE("/:id", iamHandler.ProxyToIamClient)
+				orgUnits.PUT("/:id/head", iamHandler.ProxyToIamClient) // 设置部门负责人
 			}
 
 			// 角色管理路由
@@ -66,6 +97,19 @@ func Setup(cfg *configs.Config) *gin.Engine {
 				roles.POST("", iamHandler.ProxyToIamClient)
 				roles.PUT("/:id", iamHandler.ProxyToIamClient)
 				roles.DELETE("/:id", iamHandler.ProxyToIamClient)
+				roles.PUT("/:id/permissions", iamHandler.ProxyToIamClient) // 替换角色权限
+			}
+
+			// 服务账号与 API Key 路由
+			serviceAccounts := iam.Group("/service-accounts")
+			serviceAccounts.Use(gw_middleware.AuthRequired(iamHandler))
+			{
+				serviceAccounts.GET("/scopes", iamHandler.ProxyToIamClient) // API Key 授权范围
+				serviceAccounts.GET("", iamHandler.ProxyToIamClient)
+				serviceAccounts.POST("", iamHandler.ProxyToIamClient)
+				serviceAccounts.GET("/:id/api-keys", iamHandler.ProxyToIamClient)
+				serviceAccounts.POST("/:id/api-keys", iamHandler.ProxyToIamClient)
+				serviceAccounts.DELETE("/:id/api-keys/:key_id", iamHandler.ProxyToIamClient) // 吊销
 			}
 
 			// 权限管理路由
@@ -73,8 +117,12 @@ func Setup(cfg *configs.Config) *gin.Engine {
 			permissions.Use(gw_middleware.AuthRequired(iamHandler))
 			{
 				permissions.GET("", iamHandler.ProxyToIamClient)
+				permissions.GET("/catalog", iamHandler.ProxyToIamClient)           // 权限目录
+				permissions.GET("/space-roles", iamHandler.ProxyToIamClient)       // 空间角色权限表
+				permissions.PUT("/space-roles/:role", iamHandler.ProxyToIamClient) // 替换空间角色权限
 				permissions.GET("/:id", iamHandler.ProxyToIamClient)
 				permissions.POST("/check", iamHandler.ProxyToIamClient)
+				permissions.POST("/check-batch", iamHandler.ProxyToIamClient) // 批量权限检查
 			}
 
 			// 空间管理路由
@@ -99,6 +147,14 @@ func Setup(cfg *configs.Config) *gin.Engine {
 
 			}
 
+			// 操作日志路由（仅超级管理员，由 IAM 校验）
+			operationLogs := iam.Group("/operation-logs")
+			operationLogs.Use(gw_middleware.AuthRequired(iamHandler))
+			{
+				operationLogs.GET("", iamHandler.ProxyToIamClient)
+				operationLogs.GET("/export", iamHandler.ProxyToIamClient)
+			}
+
 		}
 
 		workflow := api.Group("/workflow")
@@ -107,15 +163,47 @@ func Setup(cfg *configs.Config) *gin.Engine {
 			// 工作流管理
 			workflows := workflow.Group("/workflows")
 			{
-				workflows.POST("", workflowHandler.ProxyToWorkflowClient)           // 创建工作流
-				workflows.POST("/:id/start", workflowHandler.ProxyToWorkflowClient) // 启动工作流
+				workflows.POST("", workflowHandler.ProxyToWorkflowClient)               // 创建工作流
+				workflows.POST("/from-template", workflowHandler.ProxyToWorkflowClient) // 按模板创建工作流
+				workflows.POST("/:id/start", workflowHandler.ProxyToWorkflowClient)     // 启动工作流
+				workflows.GET("/:id", workflowHandler.ProxyToWorkflowClient)            // 获取工作流详情
+				workflows.GET("/resource", workflowHandler.ProxyToWorkflowClient)       // 按资源获取审批时间线
+				workflows.GET("/resource-types", workflowHandler.ProxyToWorkflowClient) // 支持审批的资源类型
+				workflows.GET("/stuck", workflowHandler.ProxyToWorkflowClient)          // 管理员查看无人可处理的流程
+				workflows.GET("/:id/timeline", workflowHandler.ProxyToWorkflowClient)   // 获取审批时间线
+				workflows.GET("/:id/status", workflowHandler.ProxyToWorkflowClient)     // 获取工作流状态
+				workflows.POST("/:id/withdraw", workflowHandler.ProxyToWorkflowClient)  // 发起人撤回
+				workflows.POST("/:id/cancel", workflowHandler.ProxyToWorkflowClient)    // 管理员取消
+			}
+
+			// 审批模板管理
+			templates := workflow.Group("/templates")
+			{
+				templates.GET("", workflowHandler.ProxyToWorkflowClient)
+				templates.GET("/match", workflowHandler.ProxyToWorkflowClient)
+				templates.GET("/:id", workflowHandler.ProxyToWorkflowClient)
+				templates.POST("", workflowHandler.ProxyToWorkflowClient)
+				templates.PUT("/:id", workflowHandler.ProxyToWorkflowClient)
+				templates.DELETE("/:id", workflowHandler.ProxyToWorkflowClient)
 			}
 
 			// 任务管理
 			tasks := workflow.Group("/tasks")
 			{
-				tasks.GET("", workflowHandler.ProxyToWorkflowClient)          // 获取任务列表
-				tasks.POST("/approve", workflowHandler.ProxyToWorkflowClient) // 审批任务
+				tasks.GET("", workflowHandler.ProxyToWorkflowClient)                   // 获取任务列表
+				tasks.GET("/pending-count", workflowHandler.ProxyToWorkflowClient)     // 待审批数量
+				tasks.POST("/approve", workflowHandler.ProxyToWorkflowClient)          // 审批任务
+				tasks.POST("/bulk-approve", workflowHandler.ProxyToWorkflowClient)     // 批量审批
+				tasks.POST("/:id/transfer", workflowHandler.ProxyToWorkflowClient)     // 转交任务
+				tasks.POST("/:id/add-approver", workflowHandler.ProxyToWorkflowClient) // 加签
+			}
+
+			// 外出委托
+			delegations := workflow.Group("/delegations")
+			{
+				delegations.GET("", workflowHandler.ProxyToWorkflowClient)
+				delegations.POST("", workflowHandler.ProxyToWorkflowClient)
+				delegations.DELETE("/:id", workflowHandler.ProxyToWorkflowClient)
 			}
 		}
 
@@ -129,14 +217,35 @@ func Setup(cfg *configs.Config) *gin.Engine {
 			kb.GET("/homepage", kbHandler.ProxyToKbClient)
 			kb.DELETE("/:id", kbHandler.ProxyToKbClient)
 			kb.POST("/search", kbHandler.ProxyToKbClient)
+			kb.POST("/search/export", kbHandler.ProxyToKbClient)
 			kb.GET("/tag-cloud", kbHandler.ProxyToKbClient)
 
 			kb.POST("/:id/publish", kbHandler.ProxyToKbClient)
 			kb.POST("/:id/unpublish", kbHandler.ProxyToKbClient)
+			kb.POST("/:id/resubmit", kbHandler.ProxyToKbClient)
+			kb.GET("/:id/review-history", kbHandler.ProxyToKbClient)
 
 			// 文档对话
 			kb.POST("/:id/chat", kbHandler.ProxyToKbClient)
 			kb.POST("/:id/chat/stream", kbHandler.ProxyToKbClient)
+
+			// Webhook 订阅
+			kb.POST("/webhooks", kbHandler.ProxyToKbClient)
+			kb.GET("/webhooks", kbHandler.ProxyToKbClient)
+			kb.GET("/webhooks/:id", kbHandler.ProxyToKbClient)
+			kb.PUT("/webhooks/:id", kbHandler.ProxyToKbClient)
+			kb.DELETE("/webhooks/:id", kbHandler.ProxyToKbClient)
+			kb.GET("/webhooks/:id/deliveries", kbHandler.ProxyToKbClient)
+			kb.POST("/webhooks/deliveries/:delivery_id/redeliver", kbHandler.ProxyToKbClient)
+
+			// 数据导出
+			kb.POST("/exports", kbHandler.ProxyToKbClient)
+			kb.GET("/exports", kbHandler.ProxyToKbClient)
+			kb.GET("/exports/:id", kbHandler.ProxyToKbClient)
+			kb.GET("/exports/:id/download", kbHandler.ProxyToKbClient)
+
+			// 从导出包导入
+			kb.POST("/import", kbHandler.ProxyToKbClient)
 		}
 	}
 
@@ -11,11 +11,125 @@ import (
 
 // Config IAM 服务配置
 type Config struct {
-	Server   commonConfig.ServerConfig   `mapstructure:"server"`
-	Database commonConfig.DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig                   `mapstructure:"jwt"`
-	Log      commonConfig.LogConfig      `mapstructure:"log"`
-	Gin      commonConfig.GinConfig      `mapstructure:"gin"`
+	Server          commonConfig.ServerConfig   `mapstructure:"server"`
+	Database        commonConfig.DatabaseConfig `mapstructure:"database"`
+	JWT             JWTConfig                   `mapstructure:"jwt"`
+	Log             commonConfig.LogConfig      `mapstructure:"log"`
+	Gin             commonConfig.GinConfig      `mapstructure:"gin"`
+	Audit           AuditConfig                 `mapstructure:"audit"`
+	Lockout         LockoutConfig               `mapstructure:"lockout"`
+	Password        PasswordPolicyConfig        `mapstructure:"password"`
+	PermissionCache PermissionCacheConfig       `mapstructure:"permission_cache"`
+	LDAP            LDAPConfig                  `mapstructure:"ldap"`
+	OIDC            OIDCConfig                  `mapstructure:"oidc"`
+	UserImport      UserImportConfig            `mapstructure:"user_import"`
+	APIKey          APIKeyConfig                `mapstructure:"api_key"`
+	Workflow        commonConfig.WorkflowConfig `mapstructure:"workflow"`
+	Outbox          commonConfig.OutboxConfig   `mapstructure:"outbox"` // 接收工作流服务投递的流程结束事件
+
+	// TrustedProxies 网关的地址或网段，只有来自这些地址的 X-Real-IP 才被采信；为空时直接使用连接来源IP
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// APIKeyConfig 服务账号 API Key 配置
+type APIKeyConfig struct {
+	MaxExpireDays           int `mapstructure:"max_expire_days"`            // 有效期上限，也是未指定有效期时的默认值
+	LastUsedIntervalSeconds int `mapstructure:"last_used_interval_seconds"` // 最近使用时间的更新间隔，避免每次调用都写库
+}
+
+// UserImportConfig 批量导入用户配置。配置 notice_url 后，新建用户的初始密码以签名的 POST 请求
+// 发送给通知服务（由其转发短信或邮件），签名方式与 Webhook 相同；未配置时初始密码在导入结果中返回
+type UserImportConfig struct {
+	MaxRows              int    `mapstructure:"max_rows"` // 单个文件的最大数据行数
+	MaxFileSizeMB        int    `mapstructure:"max_file_size_mb"`
+	NoticeURL            string `mapstructure:"notice_url"`
+	NoticeSecret         string `mapstructure:"notice_secret"`
+	NoticeTimeoutSeconds int    `mapstructure:"notice_timeout_seconds"`
+}
+
+// PermissionCacheConfig 权限判定缓存配置。角色、权限、空间成员变更时缓存整体失效，
+// 有效期用于兜底多实例部署时其他实例的变更
+type PermissionCacheConfig struct {
+	TTLSeconds int `mapstructure:"ttl_seconds"` // 判定结果有效期，0 表示不缓存
+	MaxEntries int `mapstructure:"max_entries"` // 缓存条目上限，超出后清空重建
+}
+
+// OIDCConfig OIDC 单点登录配置（授权码模式）
+type OIDCConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	IssuerURL    string   `mapstructure:"issuer_url"` // 通过 {issuer_url}/.well-known/openid-configuration 发现端点
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"` // 在身份提供方登记的回调地址，指向网关的 /api/v1/iam/auth/oidc/callback
+	Scopes       []string `mapstructure:"scopes"`
+	// 登录完成后携带令牌跳转的前端地址，请求中的 redirect 参数必须以其中之一为前缀
+	AllowedRedirects []string `mapstructure:"allowed_redirects"`
+	// 声明名称
+	UsernameClaim string `mapstructure:"username_claim"`
+	EmailClaim    string `mapstructure:"email_claim"`
+	PhoneClaim    string `mapstructure:"phone_claim"`
+	NameClaim     string `mapstructure:"name_claim"`
+	GroupsClaim   string `mapstructure:"groups_claim"`
+	// 首次登录时按该字段匹配本地用户：email、username 或 phone
+	MatchField string `mapstructure:"match_field"`
+	// 匹配不到本地用户时自动创建
+	AutoProvision bool `mapstructure:"auto_provision"`
+	// 组到角色的映射，登录时按用户当前所属组增删映射中的角色，未出现在映射中的角色不受影响
+	RoleMappings []OIDCRoleMapping `mapstructure:"role_mappings"`
+}
+
+// OIDCRoleMapping 身份提供方的组与平台角色的对应关系
+type OIDCRoleMapping struct {
+	Group string `mapstructure:"group"`
+	Role  string `mapstructure:"role"` // 角色名，如 super_admin、corp_admin
+}
+
+// LDAPConfig LDAP / Active Directory 认证与用户同步配置
+type LDAPConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	URL                string `mapstructure:"url"`                  // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool   `mapstructure:"start_tls"`            // ldap:// 连接升级为 TLS
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"` // 跳过证书校验，仅用于测试环境
+	TimeoutSeconds     int    `mapstructure:"timeout_seconds"`
+	BindDN             string `mapstructure:"bind_dn"` // 用于搜索用户的服务账号
+	BindPassword       string `mapstructure:"bind_password"`
+	BaseDN             string `mapstructure:"base_dn"`
+	UserFilter         string `mapstructure:"user_filter"`     // 参与认证和同步的用户过滤条件
+	LoginAttribute     string `mapstructure:"login_attribute"` // 与本地用户名对应的属性，OpenLDAP 为 uid，AD 为 sAMAccountName
+	// 属性映射，值为目录中的属性名
+	Attributes          LDAPAttributeMapping `mapstructure:"attributes"`
+	SyncIntervalMinutes int                  `mapstructure:"sync_interval_minutes"` // 同步间隔，0 表示只能手动同步
+}
+
+// LDAPAttributeMapping 目录属性到用户字段的映射
+type LDAPAttributeMapping struct {
+	Email      string `mapstructure:"email"`
+	Phone      string `mapstructure:"phone"`
+	Nickname   string `mapstructure:"nickname"`
+	Department string `mapstructure:"department"`
+	Company    string `mapstructure:"company"`
+}
+
+// LockoutConfig 登录失败锁定配置，账号和来源IP分别计数，次数为 0 表示不限制该维度
+type LockoutConfig struct {
+	AccountMaxFailures int `mapstructure:"account_max_failures"` // 同一账号在窗口内允许的失败次数
+	IPMaxFailures      int `mapstructure:"ip_max_failures"`      // 同一IP在窗口内允许的失败次数
+	WindowMinutes      int `mapstructure:"window_minutes"`       // 失败计数窗口
+	LockMinutes        int `mapstructure:"lock_minutes"`         // 首次锁定时长，之后每次锁定翻倍
+	MaxLockMinutes     int `mapstructure:"max_lock_minutes"`     // 单次锁定时长上限
+}
+
+// PasswordPolicyConfig 密码策略
+type PasswordPolicyConfig struct {
+	MinLength      int `mapstructure:"min_length"`       // 最小长度
+	MinCharClasses int `mapstructure:"min_char_classes"` // 大写字母、小写字母、数字、特殊字符中至少包含的类别数
+	HistoryCount   int `mapstructure:"history_count"`    // 不能与最近几次使用过的密码相同
+	ExpireDays     int `mapstructure:"expire_days"`      // 密码有效天数，0 表示永不过期
+}
+
+// AuditConfig 操作日志配置
+type AuditConfig struct {
+	RetentionDays int `mapstructure:"retention_days"` // 日志保留天数，0 表示永久保留
 }
 
 // JWTConfig JWT 配置（IAM 特有）
@@ -98,6 +212,68 @@ func bindEnvVars(v *viper.Viper) {
 
 	// Gin配置
 	v.BindEnv("gin.mode", "KBASE_GIN_MODE", "GIN_MODE")
+
+	// 操作日志配置
+	v.BindEnv("audit.retention_days", "KBASE_AUDIT_RETENTION_DAYS", "AUDIT_RETENTION_DAYS")
+
+	// 登录锁定配置
+	v.BindEnv("lockout.account_max_failures", "KBASE_LOCKOUT_ACCOUNT_MAX_FAILURES", "LOCKOUT_ACCOUNT_MAX_FAILURES")
+	v.BindEnv("lockout.ip_max_failures", "KBASE_LOCKOUT_IP_MAX_FAILURES", "LOCKOUT_IP_MAX_FAILURES")
+	v.BindEnv("lockout.window_minutes", "KBASE_LOCKOUT_WINDOW_MINUTES", "LOCKOUT_WINDOW_MINUTES")
+	v.BindEnv("lockout.lock_minutes", "KBASE_LOCKOUT_LOCK_MINUTES", "LOCKOUT_LOCK_MINUTES")
+	v.BindEnv("lockout.max_lock_minutes", "KBASE_LOCKOUT_MAX_LOCK_MINUTES", "LOCKOUT_MAX_LOCK_MINUTES")
+
+	// 密码策略配置
+	v.BindEnv("password.min_length", "KBASE_PASSWORD_MIN_LENGTH", "PASSWORD_MIN_LENGTH")
+	v.BindEnv("password.min_char_classes", "KBASE_PASSWORD_MIN_CHAR_CLASSES", "PASSWORD_MIN_CHAR_CLASSES")
+	v.BindEnv("password.history_count", "KBASE_PASSWORD_HISTORY_COUNT", "PASSWORD_HISTORY_COUNT")
+	v.BindEnv("password.expire_days", "KBASE_PASSWORD_EXPIRE_DAYS", "PASSWORD_EXPIRE_DAYS")
+
+	// 权限判定缓存配置
+	v.BindEnv("permission_cache.ttl_seconds", "KBASE_PERMISSION_CACHE_TTL_SECONDS", "PERMISSION_CACHE_TTL_SECONDS")
+	v.BindEnv("permission_cache.max_entries", "KBASE_PERMISSION_CACHE_MAX_ENTRIES", "PERMISSION_CACHE_MAX_ENTRIES")
+
+	// LDAP配置
+	v.BindEnv("ldap.enabled", "KBASE_LDAP_ENABLED", "LDAP_ENABLED")
+	v.BindEnv("ldap.url", "KBASE_LDAP_URL", "LDAP_URL")
+	v.BindEnv("ldap.start_tls", "KBASE_LDAP_START_TLS", "LDAP_START_TLS")
+	v.BindEnv("ldap.insecure_skip_verify", "KBASE_LDAP_INSECURE_SKIP_VERIFY", "LDAP_INSECURE_SKIP_VERIFY")
+	v.BindEnv("ldap.timeout_seconds", "KBASE_LDAP_TIMEOUT_SECONDS", "LDAP_TIMEOUT_SECONDS")
+	v.BindEnv("ldap.bind_dn", "KBASE_LDAP_BIND_DN", "LDAP_BIND_DN")
+	v.BindEnv("ldap.bind_password", "KBASE_LDAP_BIND_PASSWORD", "LDAP_BIND_PASSWORD")
+	v.BindEnv("ldap.base_dn", "KBASE_LDAP_BASE_DN", "LDAP_BASE_DN")
+	v.BindEnv("ldap.user_filter", "KBASE_LDAP_USER_FILTER", "LDAP_USER_FILTER")
+	v.BindEnv("ldap.login_attribute", "KBASE_LDAP_LOGIN_ATTRIBUTE", "LDAP_LOGIN_ATTRIBUTE")
+	v.BindEnv("ldap.sync_interval_minutes", "KBASE_LDAP_SYNC_INTERVAL_MINUTES", "LDAP_SYNC_INTERVAL_MINUTES")
+
+	// OIDC配置
+	v.BindEnv("oidc.enabled", "KBASE_OIDC_ENABLED", "OIDC_ENABLED")
+	v.BindEnv("oidc.issuer_url", "KBASE_OIDC_ISSUER_URL", "OIDC_ISSUER_URL")
+	v.BindEnv("oidc.client_id", "KBASE_OIDC_CLIENT_ID", "OIDC_CLIENT_ID")
+	v.BindEnv("oidc.client_secret", "KBASE_OIDC_CLIENT_SECRET", "OIDC_CLIENT_SECRET")
+	v.BindEnv("oidc.redirect_url", "KBASE_OIDC_REDIRECT_URL", "OIDC_REDIRECT_URL")
+	v.BindEnv("oidc.match_field", "KBASE_OIDC_MATCH_FIELD", "OIDC_MATCH_FIELD")
+	v.BindEnv("oidc.auto_provision", "KBASE_OIDC_AUTO_PROVISION", "OIDC_AUTO_PROVISION")
+
+	// 批量导入用户配置
+	v.BindEnv("user_import.max_rows", "KBASE_USER_IMPORT_MAX_ROWS", "USER_IMPORT_MAX_ROWS")
+	v.BindEnv("user_import.max_file_size_mb", "KBASE_USER_IMPORT_MAX_FILE_SIZE_MB", "USER_IMPORT_MAX_FILE_SIZE_MB")
+	v.BindEnv("user_import.notice_url", "KBASE_USER_IMPORT_NOTICE_URL", "USER_IMPORT_NOTICE_URL")
+	v.BindEnv("user_import.notice_secret", "KBASE_USER_IMPORT_NOTICE_SECRET", "USER_IMPORT_NOTICE_SECRET")
+	v.BindEnv("user_import.notice_timeout_seconds", "KBASE_USER_IMPORT_NOTICE_TIMEOUT_SECONDS", "USER_IMPORT_NOTICE_TIMEOUT_SECONDS")
+
+	// API Key配置
+	v.BindEnv("api_key.max_expire_days", "KBASE_API_KEY_MAX_EXPIRE_DAYS", "API_KEY_MAX_EXPIRE_DAYS")
+	v.BindEnv("api_key.last_used_interval_seconds", "KBASE_API_KEY_LAST_USED_INTERVAL_SECONDS", "API_KEY_LAST_USED_INTERVAL_SECONDS")
+
+	// Workflow配置
+	v.BindEnv("workflow.url", "KBASE_WORKFLOW_URL", "WORKFLOW_URL")
+
+	// 事件接收配置
+	v.BindEnv("outbox.secret", "KBASE_OUTBOX_SECRET", "OUTBOX_SECRET")
+
+	// 可信代理配置
+	v.BindEnv("trusted_proxies", "KBASE_TRUSTED_PROXIES", "TRUSTED_PROXIES")
 }
 
 func setDefaults(v *viper.Viper) {
@@ -105,6 +281,9 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.host", "localhost")
 	v.SetDefault("server.port", "8081")
 
+	// Workflow默认配置
+	v.SetDefault("workflow.url", "http://localhost:8082")
+
 	// 数据库默认配置
 	v.SetDefault("database.host", "localhost")
 	v.SetDefault("database.port", "5432")
@@ -124,4 +303,59 @@ func setDefaults(v *viper.Viper) {
 
 	// Gin默认配置
 	v.SetDefault("gin.mode", "debug")
+
+	// 操作日志默认配置
+	v.SetDefault("audit.retention_days", 180)
+
+	// 登录锁定默认配置
+	v.SetDefault("lockout.account_max_failures", 5)
+	v.SetDefault("lockout.ip_max_failures", 20)
+	v.SetDefault("lockout.window_minutes", 15)
+	v.SetDefault("lockout.lock_minutes", 15)
+	v.SetDefault("lockout.max_lock_minutes", 1440)
+
+	// 密码策略默认配置
+	v.SetDefault("password.min_length", 8)
+	v.SetDefault("password.min_char_classes", 3)
+	v.SetDefault("password.history_count", 5)
+	v.SetDefault("password.expire_days", 90)
+
+	// 权限判定缓存默认配置
+	v.SetDefault("permission_cache.ttl_seconds", 60)
+	v.SetDefault("permission_cache.max_entries", 100000)
+
+	// LDAP默认配置
+	v.SetDefault("ldap.enabled", false)
+	v.SetDefault("ldap.timeout_seconds", 10)
+	v.SetDefault("ldap.user_filter", "(objectClass=inetOrgPerson)")
+	v.SetDefault("ldap.login_attribute", "uid")
+	v.SetDefault("ldap.attributes.email", "mail")
+	v.SetDefault("ldap.attributes.phone", "mobile")
+	v.SetDefault("ldap.attributes.nickname", "displayName")
+	v.SetDefault("ldap.attributes.department", "ou")
+	v.SetDefault("ldap.attributes.company", "o")
+	v.SetDefault("ldap.sync_interval_minutes", 60)
+
+	// OIDC默认配置
+	v.SetDefault("oidc.enabled", false)
+	v.SetDefault("oidc.scopes", []string{"openid", "profile", "email"})
+	v.SetDefault("oidc.username_claim", "preferred_username")
+	v.SetDefault("oidc.email_claim", "email")
+	v.SetDefault("oidc.phone_claim", "phone_number")
+	v.SetDefault("oidc.name_claim", "name")
+	v.SetDefault("oidc.groups_claim", "groups")
+	v.SetDefault("oidc.match_field", "email")
+	v.SetDefault("oidc.auto_provision", false)
+
+	// 批量导入用户默认配置
+	v.SetDefault("user_import.max_rows", 5000)
+	v.SetDefault("user_import.max_file_size_mb", 10)
+	v.SetDefault("user_import.notice_timeout_seconds", 10)
+
+	// API Key默认配置
+	v.SetDefault("api_key.max_expire_days", 365)
+	v.SetDefault("api_key.last_used_interval_seconds", 60)
+
+	// 可信代理默认配置，本地部署时网关与服务在同一主机
+	v.SetDefault("trusted_proxies", []string{"127.0.0.1", "::1"})
 }
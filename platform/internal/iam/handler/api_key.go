@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAPIKeyScopes 获取 API Key 支持的授权范围
+func (h *Handler) GetAPIKeyScopes(c *gin.Context) {
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取授权范围成功",
+		Data:    model.APIKeyScopes,
+	})
+}
+
+// GetServiceAccounts 超级管理员获取全部服务账号
+func (h *Handler) GetServiceAccounts(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	users, err := h.apiKeyService.ListServiceAccounts()
+	if err != nil {
+		respondAPIKeyError(c, "获取服务账号失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取服务账号成功",
+		Data:    users,
+	})
+}
+
+// CreateServiceAccount 超级管理员创建服务账号，空间权限通过添加空间成员授予
+func (h *Handler) CreateServiceAccount(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	var req model.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, err := h.apiKeyService.CreateServiceAccount(&req)
+	entry := audit.Entry{
+		Action:       model.OperationServiceAccountCreate,
+		ResourceType: "user",
+		After:        user,
+		Err:          err,
+	}
+	if user != nil {
+		entry.ResourceID = user.ID
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		respondAPIKeyError(c, "创建服务账号失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "创建服务账号成功",
+		Data:    user,
+	})
+}
+
+// GetAPIKeys 超级管理员获取服务账号的 API Key，不含明文
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	accountID, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.ListKeys(accountID)
+	if err != nil {
+		respondAPIKeyError(c, "获取 API Key 失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取 API Key 成功",
+		Data:    keys,
+	})
+}
+
+// CreateAPIKey 超级管理员为服务账号创建 API Key，明文只在本次响应中返回
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	accountID, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, _ := c.Get("user")
+	created, err := h.apiKeyService.CreateKey(accountID, &req, user.(*model.User).ID)
+	entry := audit.Entry{
+		Action:       model.OperationAPIKeyCreate,
+		ResourceType: "api_key",
+		Err:          err,
+	}
+	if created != nil {
+		// 审计快照不含明文
+		entry.ResourceID = created.ID
+		entry.After = created.APIKey
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		respondAPIKeyError(c, "创建 API Key 失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "创建 API Key 成功，请妥善保存，之后无法再次查看",
+		Data:    created,
+	})
+}
+
+// RevokeAPIKey 超级管理员吊销 API Key
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	accountID, ok := serviceAccountID(c)
+	if !ok {
+		return
+	}
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的 API Key ID",
+		})
+		return
+	}
+
+	key, err := h.apiKeyService.RevokeKey(accountID, uint(keyID))
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationAPIKeyRevoke,
+		ResourceType: "api_key",
+		ResourceID:   keyID,
+		After:        key,
+		Err:          err,
+	})
+
+	if err != nil {
+		respondAPIKeyError(c, "吊销 API Key 失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "吊销 API Key 成功",
+		Data:    key,
+	})
+}
+
+// ValidateAPIKey 校验 X-API-Key 头中的 API Key，供网关调用
+func (h *Handler) ValidateAPIKey(c *gin.Context) {
+	key := c.GetHeader(model.HeaderAPIKey)
+	if key == "" {
+		c.JSON(http.StatusUnauthorized, model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "缺少 X-API-Key 头",
+		})
+		return
+	}
+
+	result, err := h.apiKeyService.Validate(key, c.ClientIP())
+	if err != nil {
+		respondAPIKeyError(c, "API Key 校验失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "API Key 有效",
+		Data:    result,
+	})
+}
+
+func serviceAccountID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的服务账号ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondAPIKeyError(c *gin.Context, message string, err error) {
+	var status int
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKey):
+		status = http.StatusUnauthorized
+	case errors.Is(err, service.ErrServiceAccountNotFound), errors.Is(err, service.ErrAPIKeyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrServiceAccountExists):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrAPIKeyInvalidGrant), errors.Is(err, service.ErrAPIKeyInvalidExpiry):
+		status = http.StatusBadRequest
+	default:
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SyncDirectory 超级管理员手动触发目录用户同步
+func (h *Handler) SyncDirectory(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	result, err := h.authService.SyncDirectory()
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDirectorySync,
+		ResourceType: "user",
+		After:        result,
+		Err:          err,
+	})
+
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, service.ErrDirectoryNotConfigured) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, model.APIResponse{
+			Code:    status,
+			Message: "同步目录用户失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "同步目录用户成功",
+		Data:    result,
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
 	"github.com/gin-gonic/gin"
@@ -13,15 +14,31 @@ import (
 
 // Handler 处理器结构体
 type Handler struct {
-	db          *gorm.DB
-	authService *service.AuthService
+	db                  *gorm.DB
+	authService         *service.AuthService
+	oidcService         *service.OIDCService
+	roleService         *service.RoleService
+	orgUnitService      *service.OrgUnitService
+	userImportService   *service.UserImportService
+	apiKeyService       *service.APIKeyService
+	operationLogService *service.OperationLogService
+	spaceRequestService *service.SpaceRequestService
+	recorder            *audit.Recorder
 }
 
 // NewHandler 创建新的处理器
-func NewHandler(db *gorm.DB, authService *service.AuthService) *Handler {
+func NewHandler(db *gorm.DB, authService *service.AuthService, oidcService *service.OIDCService, roleService *service.RoleService, orgUnitService *service.OrgUnitService, userImportService *service.UserImportService, apiKeyService *service.APIKeyService, operationLogService *service.OperationLogService, spaceRequestService *service.SpaceRequestService, recorder *audit.Recorder) *Handler {
 	return &Handler{
-		db:          db,
-		authService: authService,
+		db:                  db,
+		authService:         authService,
+		oidcService:         oidcService,
+		roleService:         roleService,
+		orgUnitService:      orgUnitService,
+		userImportService:   userImportService,
+		apiKeyService:       apiKeyService,
+		operationLogService: operationLogService,
+		spaceRequestService: spaceRequestService,
+		recorder:            recorder,
 	}
 }
 
@@ -45,15 +62,28 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Login(&req)
+	response, err := h.authService.Login(&req, service.SessionClient{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, model.APIResponse{
-			Code:    http.StatusUnauthorized,
-			Message: "登录失败: " + err.Error(),
+		h.recorder.Record(c, audit.Entry{
+			Action:       model.OperationLoginFailed,
+			ResourceType: "user",
+			Username:     req.Login,
+			Err:          err,
 		})
+		respondLoginError(c, err)
 		return
 	}
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationLogin,
+		ResourceType: "user",
+		ResourceID:   response.User.ID,
+		User:         response.User,
+	})
+
 	c.JSON(http.StatusOK, model.APIResponse{
 		Code:    http.StatusOK,
 		Message: "登录成功",
@@ -61,8 +91,43 @@ func (h *Handler) Login(c *gin.Context) {
 	})
 }
 
+// Logout 使当前会话失效，请求体 {"all": true} 时使该用户的全部会话失效
 func (h *Handler) Logout(c *gin.Context) {
-	// JWT是无状态的，登出只需要客户端删除token
+	var req model.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 令牌已失效时视为已登出
+	user, session, err := h.authService.ValidateTokenSession(c.GetHeader("Authorization"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "登出成功"})
+		return
+	}
+
+	if req.All {
+		_, err = h.authService.RevokeUserSessions(user.ID, model.SessionRevokeLogoutAll)
+	} else {
+		err = h.authService.RevokeSession(user.ID, session.ID, model.SessionRevokeLogout)
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationLogout,
+		ResourceType: "user",
+		ResourceID:   user.ID,
+		After:        gin.H{"session_id": session.ID, "all": req.All},
+		User:         user,
+		Err:          err,
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "登出成功"})
 }
 
@@ -85,7 +150,16 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.RefreshToken(req.RefreshToken)
+	response, session, err := h.authService.RefreshToken(req.RefreshToken)
+	if errors.Is(err, service.ErrRefreshTokenReused) {
+		h.recorder.Record(c, audit.Entry{
+			Action:       model.OperationTokenReused,
+			ResourceType: "user",
+			ResourceID:   session.UserID,
+			After:        gin.H{"session_id": session.ID},
+			Err:          err,
+		})
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -128,7 +202,15 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.authService.ChangePassword(userModel.ID, &req); err != nil {
+	err := h.authService.ChangePassword(userModel.ID, &req)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationPasswordChange,
+		ResourceType: "user",
+		ResourceID:   userModel.ID,
+		User:         userModel,
+		Err:          err,
+	})
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -212,6 +294,38 @@ func (h *Handler) GetUserByRoleIdAndSpaceId(c *gin.Context) {
 	}
 }
 
+// GetUsersByGlobalRole 获取拥有指定全局角色的正常用户，供审批流兜底审批人使用
+func (h *Handler) GetUsersByGlobalRole(c *gin.Context) {
+	role := c.Param("role")
+	if role == "" {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "角色不能为空",
+		})
+		return
+	}
+
+	var users []model.User
+	if err := h.db.
+		Joins("JOIN user_roles ON user_roles.user_id = users.id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.name = ? AND roles.status = 1 AND roles.deleted_at IS NULL AND users.status = 1", role).
+		Order("users.id").
+		Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取用户失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取用户成功",
+		Data:    users,
+	})
+}
+
 func (h *Handler) CreateUser(c *gin.Context) {
 	var req service.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -225,6 +339,13 @@ func (h *Handler) CreateUser(c *gin.Context) {
 		return
 	}
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationUserCreate,
+		ResourceType: "user",
+		ResourceID:   user.ID,
+		After:        user,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "创建用户成功",
 		"data":    user,
@@ -260,6 +381,8 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	before := user
+
 	// 更新用户信息
 	user.Nickname = updateData.Nickname
 	user.Department = updateData.Department
@@ -271,6 +394,22 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// 禁用用户时结束其全部会话
+	if before.Status == 1 && user.Status != 1 {
+		if _, err := h.authService.RevokeUserSessions(user.ID, model.SessionRevokeUserDisabled); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationUserUpdate,
+		ResourceType: "user",
+		ResourceID:   user.ID,
+		Before:       before,
+		After:        user,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "更新用户成功",
 		"data":    user,
@@ -284,10 +423,32 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	var before model.User
+	if err := h.db.First(&before, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 删除是幂等的，用户不存在时同样返回成功，不记录审计
+			c.JSON(http.StatusOK, gin.H{"message": "删除用户成功"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err := h.db.Delete(&model.User{}, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if _, err := h.authService.RevokeUserSessions(before.ID, model.SessionRevokeUserDisabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationUserDelete,
+		ResourceType: "user",
+		ResourceID:   before.ID,
+		Before:       before,
+	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "删除用户成功"})
 }
@@ -425,6 +586,7 @@ func (h *Handler) CheckPermission(c *gin.Context) {
 		SpaceID  uint   `json:"space_id"`
 		Resource string `json:"resource" binding:"required"`
 		Action   string `json:"action" binding:"required"`
+		OwnerID  uint   `json:"owner_id"` // 资源创建者，用于判断仅限本人的空间权限
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -433,7 +595,7 @@ func (h *Handler) CheckPermission(c *gin.Context) {
 	}
 
 	// 检查用户权限
-	hasPermission, err := h.authService.CheckPermission(userModel.ID, req.SpaceID, req.Resource, req.Action)
+	hasPermission, err := h.authService.CheckPermission(userModel.ID, req.SpaceID, req.Resource, req.Action, req.OwnerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "权限检查失败"})
 		return
@@ -451,6 +613,43 @@ func (h *Handler) CheckPermission(c *gin.Context) {
 	})
 }
 
+// CheckPermissionsBatch 批量检查当前用户的权限，供服务端按空间过滤检索结果等场景使用
+func (h *Handler) CheckPermissionsBatch(c *gin.Context) {
+	user, ok := c.Get("user")
+	userModel, isUser := user.(*model.User)
+	if !ok || !isUser {
+		c.JSON(http.StatusUnauthorized, model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户未认证",
+		})
+		return
+	}
+
+	var req model.BatchPermissionCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	results, err := h.authService.CheckPermissions(userModel.ID, req.Checks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "权限检查失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "权限检查完成",
+		Data:    results,
+	})
+}
+
 // 空间管理处理器
 
 // @Summary 获取空间列表
@@ -572,6 +771,14 @@ func (h *Handler) CreateSpace(c *gin.Context) {
 		return
 	}
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationSpaceCreate,
+		ResourceType: "space",
+		ResourceID:   space.ID,
+		SpaceID:      space.ID,
+		After:        space,
+	})
+
 	c.JSON(http.StatusOK, model.APIResponse{
 		Code:    http.StatusOK,
 		Message: "创建空间成功",
@@ -698,6 +905,8 @@ func (h *Handler) UpdateSpace(c *gin.Context) {
 		return
 	}
 
+	before := space
+
 	if err := c.ShouldBindJSON(&space); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -708,6 +917,15 @@ func (h *Handler) UpdateSpace(c *gin.Context) {
 		return
 	}
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationSpaceUpdate,
+		ResourceType: "space",
+		ResourceID:   space.ID,
+		SpaceID:      space.ID,
+		Before:       before,
+		After:        space,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "更新空间成功",
 		"data":    space,
@@ -732,11 +950,30 @@ func (h *Handler) DeleteSpace(c *gin.Context) {
 		return
 	}
 
+	var before model.Space
+	if err := h.db.First(&before, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 删除是幂等的，空间不存在时同样返回成功，不记录审计
+			c.JSON(http.StatusOK, gin.H{"message": "删除空间成功"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err := h.db.Delete(&model.Space{}, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationSpaceDelete,
+		ResourceType: "space",
+		ResourceID:   before.ID,
+		SpaceID:      before.ID,
+		Before:       before,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "删除空间成功"})
 }
 
@@ -839,6 +1076,9 @@ func (h *Handler) AddSpaceMember(c *gin.Context) {
 	var member model.SpaceMember
 	var message string
 
+	var before *model.SpaceMember
+	action := model.OperationSpaceMemberAdd
+
 	if err := h.db.Where("space_id = ? AND user_id = ?", id, req.UserID).First(&existingMember).Error; err == nil {
 		// 用户已存在
 		if len(req.Roles) == 0 {
@@ -847,12 +1087,23 @@ func (h *Handler) AddSpaceMember(c *gin.Context) {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
+			h.authService.InvalidatePermissions()
+			h.recorder.Record(c, audit.Entry{
+				Action:       model.OperationSpaceMemberRemove,
+				ResourceType: "space_member",
+				ResourceID:   req.UserID,
+				SpaceID:      uint(id),
+				Before:       existingMember,
+			})
 			c.JSON(http.StatusOK, gin.H{
 				"message": "移除空间成员成功",
 			})
 			return
 		}
 		// 更新权限
+		snapshot := existingMember
+		before = &snapshot
+		action = model.OperationSpaceMemberUpdate
 		existingMember.Roles = req.Roles
 		if err := h.db.Save(&existingMember).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -882,10 +1133,20 @@ func (h *Handler) AddSpaceMember(c *gin.Context) {
 		}
 		message = "添加空间成员成功"
 	}
+	h.authService.InvalidatePermissions()
 
 	// 预加载用户信息
 	h.db.Preload("User").First(&member, "space_id = ? AND user_id = ?", id, req.UserID)
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       action,
+		ResourceType: "space_member",
+		ResourceID:   req.UserID,
+		SpaceID:      uint(id),
+		Before:       before,
+		After:        member,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": message,
 		"data":    member,
@@ -933,6 +1194,15 @@ func (h *Handler) RemoveSpaceMember(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.authService.InvalidatePermissions()
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationSpaceMemberRemove,
+		ResourceType: "space_member",
+		ResourceID:   userID,
+		SpaceID:      uint(id),
+		Before:       member,
+	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "移除空间成员成功"})
 }
@@ -997,16 +1267,28 @@ func (h *Handler) UpdateSpaceMemberRole(c *gin.Context) {
 		return
 	}
 
+	before := member
+
 	// 更新角色
 	member.Roles = req.Roles
 	if err := h.db.Save(&member).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.authService.InvalidatePermissions()
 
 	// 预加载用户信息
 	h.db.Preload("User").First(&member, "space_id = ? AND user_id = ?", id, userID)
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationSpaceMemberUpdate,
+		ResourceType: "space_member",
+		ResourceID:   userID,
+		SpaceID:      uint(id),
+		Before:       before,
+		After:        member,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "更新空间成员角色成功",
 		"data":    member,
@@ -1118,6 +1400,14 @@ func (h *Handler) AssignUserRole(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.authService.InvalidatePermissions()
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationUserRoleAssign,
+		ResourceType: "user",
+		ResourceID:   user.ID,
+		After:        gin.H{"role_id": role.ID, "role_name": role.Name},
+	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "分配用户角色成功"})
 }
@@ -1148,11 +1438,22 @@ func (h *Handler) RemoveUserRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.Where("user_id = ? AND role_id = ?", id, roleID).Delete(&model.UserRole{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	result := h.db.Where("user_id = ? AND role_id = ?", id, roleID).Delete(&model.UserRole{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
 		return
 	}
 
+	if result.RowsAffected > 0 {
+		h.authService.InvalidatePermissions()
+		h.recorder.Record(c, audit.Entry{
+			Action:       model.OperationUserRoleRemove,
+			ResourceType: "user",
+			ResourceID:   id,
+			Before:       gin.H{"role_id": roleID},
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "移除用户角色成功"})
 }
 
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLogin 跳转到身份提供方登录，redirect 参数为登录完成后返回的前端地址
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	authURL, err := h.oidcService.AuthCodeURL(c.Request.Context(), c.Query("redirect"))
+	if err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, service.ErrOIDCNotConfigured):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrOIDCRedirectNotAllowed):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, model.APIResponse{
+			Code:    status,
+			Message: "发起单点登录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 身份提供方回调，签发平台令牌。发起登录时指定了前端地址的，
+// 令牌以 URL 片段的形式跳转回前端，否则直接返回 JSON
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	var (
+		response *service.LoginResponse
+		redirect string
+		err      error
+	)
+	if providerError := c.Query("error"); providerError != "" {
+		err = errors.New("身份提供方拒绝登录: " + providerError + " " + c.Query("error_description"))
+	} else {
+		response, redirect, err = h.oidcService.Callback(c.Request.Context(), c.Query("state"), c.Query("code"), service.SessionClient{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+	}
+
+	if err != nil {
+		h.recorder.Record(c, audit.Entry{
+			Action:       model.OperationLoginFailed,
+			ResourceType: "user",
+			After:        gin.H{"method": "oidc"},
+			Err:          err,
+		})
+		if redirect != "" {
+			c.Redirect(http.StatusFound, redirect+"#"+url.Values{"error": {err.Error()}}.Encode())
+			return
+		}
+		status := http.StatusUnauthorized
+		if errors.Is(err, service.ErrOIDCNotConfigured) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, model.APIResponse{
+			Code:    status,
+			Message: "单点登录失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationLogin,
+		ResourceType: "user",
+		ResourceID:   response.User.ID,
+		After:        gin.H{"method": "oidc"},
+		User:         response.User,
+	})
+
+	if redirect != "" {
+		fragment := url.Values{
+			"access_token":             {response.AccessToken},
+			"refresh_token":            {response.RefreshToken},
+			"token_type":               {"Bearer"},
+			"access_token_expires_at":  {strconv.FormatInt(response.AccessTokenExpiresAt.Unix(), 10)},
+			"refresh_token_expires_at": {strconv.FormatInt(response.RefreshTokenExpiresAt.Unix(), 10)},
+		}
+		c.Redirect(http.StatusFound, redirect+"#"+fragment.Encode())
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "登录成功",
+		Data:    response,
+	})
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"github.com/gin-gonic/gin"
+)
+
+// 操作日志处理器，按 PRD 要求仅超级管理员可查看
+
+// @Summary 查询操作日志
+// @Description 按用户、服务、操作类型、资源、时间范围等条件分页查询操作日志（仅超级管理员）
+// @Tags OperationLogs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "操作人ID"
+// @Param service query string false "服务:iam,kb,workflow"
+// @Param action query string false "操作类型"
+// @Param resource_type query string false "资源类型"
+// @Param resource_id query string false "资源ID"
+// @Param space_id query int false "空间ID"
+// @Param result query string false "结果:success,failure"
+// @Param start_time query string false "开始时间(RFC3339)"
+// @Param end_time query string false "结束时间(RFC3339)"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Success 200 {object} model.APIResponse
+// @Failure 403 {object} model.APIResponse
+// @Router /api/v1/operation-logs [get]
+func (h *Handler) GetOperationLogs(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	var query model.OperationLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "查询参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := h.operationLogService.QueryLogs(&query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "查询操作日志失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取操作日志成功",
+		Data:    result,
+	})
+}
+
+// @Summary 导出操作日志
+// @Description 按查询条件导出操作日志为 CSV 文件（仅超级管理员）
+// @Tags OperationLogs
+// @Produce text/csv
+// @Security BearerAuth
+// @Param user_id query int false "操作人ID"
+// @Param service query string false "服务:iam,kb,workflow"
+// @Param action query string false "操作类型"
+// @Param start_time query string false "开始时间(RFC3339)"
+// @Param end_time query string false "结束时间(RFC3339)"
+// @Success 200 {file} file
+// @Failure 403 {object} model.APIResponse
+// @Router /api/v1/operation-logs/export [get]
+func (h *Handler) ExportOperationLogs(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	var query model.OperationLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "查询参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("operation_logs_%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+
+	// 写入 UTF-8 BOM，避免 Excel 打开中文乱码
+	if _, err := c.Writer.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return
+	}
+
+	// 响应头已发送，导出中途失败只能记录错误
+	if err := h.operationLogService.ExportCSV(c.Writer, &query); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// requireSuperAdmin 校验当前用户为超级管理员，失败时直接写入响应
+func (h *Handler) requireSuperAdmin(c *gin.Context) bool {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户未认证",
+		})
+		return false
+	}
+
+	userModel, ok := user.(*model.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "用户信息格式错误",
+		})
+		return false
+	}
+
+	for _, role := range userModel.Roles {
+		if role.Name == model.RoleSuperAdmin {
+			return true
+		}
+	}
+
+	c.JSON(http.StatusForbidden, model.APIResponse{
+		Code:    http.StatusForbidden,
+		Message: "仅超级管理员可以执行该操作",
+	})
+	return false
+}
@@ -0,0 +1,377 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrgUnits 获取组织单元，tree=true 时返回树形结构，root_id 指定子树的根
+func (h *Handler) GetOrgUnits(c *gin.Context) {
+	if c.Query("tree") != "true" {
+		units, err := h.orgUnitService.List()
+		if err != nil {
+			respondOrgUnitError(c, "获取组织单元失败", err)
+			return
+		}
+		c.JSON(http.StatusOK, model.APIResponse{
+			Code:    http.StatusOK,
+			Message: "获取组织单元成功",
+			Data:    units,
+		})
+		return
+	}
+
+	var rootID uint64
+	if rootIDStr := c.Query("root_id"); rootIDStr != "" {
+		var err error
+		if rootID, err = strconv.ParseUint(rootIDStr, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Code:    http.StatusBadRequest,
+				Message: "无效的 root_id",
+			})
+			return
+		}
+	}
+
+	tree, err := h.orgUnitService.Tree(uint(rootID))
+	if err != nil {
+		respondOrgUnitError(c, "获取组织树失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取组织树成功",
+		Data:    tree,
+	})
+}
+
+// GetOrgUnit 获取组织单元详情
+func (h *Handler) GetOrgUnit(c *gin.Context) {
+	id, ok := orgUnitID(c)
+	if !ok {
+		return
+	}
+
+	unit, err := h.orgUnitService.Get(id)
+	if err != nil {
+		respondOrgUnitError(c, "获取组织单元失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取组织单元成功",
+		Data:    unit,
+	})
+}
+
+// GetOrgUnitMembers 获取组织单元成员，include_sub=true 时包含下级单元的成员
+func (h *Handler) GetOrgUnitMembers(c *gin.Context) {
+	id, ok := orgUnitID(c)
+	if !ok {
+		return
+	}
+
+	users, err := h.orgUnitService.Members(id, c.Query("include_sub") == "true")
+	if err != nil {
+		respondOrgUnitError(c, "获取组织成员失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取组织成员成功",
+		Data:    users,
+	})
+}
+
+// CreateOrgUnit 超级管理员或企业管理员创建组织单元
+func (h *Handler) CreateOrgUnit(c *gin.Context) {
+	if !h.requireGlobalAdmin(c) {
+		return
+	}
+
+	var req model.CreateOrgUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	unit, err := h.orgUnitService.Create(&req)
+	entry := audit.Entry{
+		Action:       model.OperationOrgUnitCreate,
+		ResourceType: "org_unit",
+		After:        unit,
+		Err:          err,
+	}
+	if unit != nil {
+		entry.ResourceID = unit.ID
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		respondOrgUnitError(c, "创建组织单元失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "创建组织单元成功",
+		Data:    unit,
+	})
+}
+
+// UpdateOrgUnit 超级管理员或企业管理员更新组织单元，修改 parent_id 即移动
+func (h *Handler) UpdateOrgUnit(c *gin.Context) {
+	if !h.requireGlobalAdmin(c) {
+		return
+	}
+
+	id, ok := orgUnitID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateOrgUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before, _ := h.orgUnitService.Get(id)
+	unit, err := h.orgUnitService.Update(id, &req)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationOrgUnitUpdate,
+		ResourceType: "org_unit",
+		ResourceID:   id,
+		Before:       before,
+		After:        unit,
+		Err:          err,
+	})
+
+	if err != nil {
+		respondOrgUnitError(c, "更新组织单元失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "更新组织单元成功",
+		Data:    unit,
+	})
+}
+
+// DeleteOrgUnit 超级管理员或企业管理员删除组织单元
+func (h *Handler) DeleteOrgUnit(c *gin.Context) {
+	if !h.requireGlobalAdmin(c) {
+		return
+	}
+
+	id, ok := orgUnitID(c)
+	if !ok {
+		return
+	}
+
+	unit, err := h.orgUnitService.Delete(id)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationOrgUnitDelete,
+		ResourceType: "org_unit",
+		ResourceID:   id,
+		Before:       unit,
+		Err:          err,
+	})
+
+	if err != nil {
+		respondOrgUnitError(c, "删除组织单元失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "删除组织单元成功",
+	})
+}
+
+// SetOrgUnitHead 超级管理员或企业管理员设置部门负责人
+func (h *Handler) SetOrgUnitHead(c *gin.Context) {
+	if !h.requireGlobalAdmin(c) {
+		return
+	}
+
+	id, ok := orgUnitID(c)
+	if !ok {
+		return
+	}
+
+	var req model.SetOrgUnitHeadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before, _ := h.orgUnitService.Get(id)
+	unit, err := h.orgUnitService.SetHead(id, req.UserID)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationOrgUnitHeadUpdate,
+		ResourceType: "org_unit",
+		ResourceID:   id,
+		Before:       before,
+		After:        unit,
+		Err:          err,
+	})
+
+	if err != nil {
+		respondOrgUnitError(c, "设置部门负责人失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "设置部门负责人成功",
+		Data:    unit,
+	})
+}
+
+// AssignUserOrgUnit 超级管理员或企业管理员调整用户所属组织单元
+func (h *Handler) AssignUserOrgUnit(c *gin.Context) {
+	if !h.requireGlobalAdmin(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	var req model.AssignUserOrgUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var before model.User
+	h.db.First(&before, id)
+	user, err := h.orgUnitService.AssignUser(uint(id), req.OrgUnitID)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationUserOrgAssign,
+		ResourceType: "user",
+		ResourceID:   id,
+		Before:       before,
+		After:        user,
+		Err:          err,
+	})
+
+	if err != nil {
+		respondOrgUnitError(c, "调整用户组织失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "调整用户组织成功",
+		Data:    user,
+	})
+}
+
+// GetDepartmentHead 查找用户的部门负责人，供审批流按部门负责人分配审批人
+func (h *Handler) GetDepartmentHead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	head, err := h.orgUnitService.DepartmentHead(uint(id))
+	if err != nil {
+		respondOrgUnitError(c, "获取部门负责人失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取部门负责人成功",
+		Data:    head,
+	})
+}
+
+// requireGlobalAdmin 校验当前用户为超级管理员或企业管理员，失败时直接写入响应
+func (h *Handler) requireGlobalAdmin(c *gin.Context) bool {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户未认证",
+		})
+		return false
+	}
+
+	userModel, ok := user.(*model.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "用户信息格式错误",
+		})
+		return false
+	}
+
+	for _, role := range userModel.Roles {
+		if role.Name == model.RoleSuperAdmin || role.Name == model.RoleEnterpriseAdmin {
+			return true
+		}
+	}
+
+	c.JSON(http.StatusForbidden, model.APIResponse{
+		Code:    http.StatusForbidden,
+		Message: "仅超级管理员和企业管理员可以执行该操作",
+	})
+	return false
+}
+
+func orgUnitID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的组织单元ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondOrgUnitError(c *gin.Context, message string, err error) {
+	var status int
+	switch {
+	case errors.Is(err, service.ErrOrgUnitNotFound), errors.Is(err, service.ErrUserNotFound),
+		errors.Is(err, service.ErrDepartmentHeadNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrOrgUnitInUse):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrOrgUnitInvalidParent), errors.Is(err, service.ErrOrgUnitInvalidStatus),
+		errors.Is(err, service.ErrOrgUnitUserNotFound):
+		status = http.StatusBadRequest
+	default:
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
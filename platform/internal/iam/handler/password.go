@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChangeExpiredPassword 密码过期的用户凭账号和原密码修改密码，修改成功后需重新登录
+func (h *Handler) ChangeExpiredPassword(c *gin.Context) {
+	var req model.ExpiredPasswordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, err := h.authService.ChangeExpiredPassword(&req, service.SessionClient{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+
+	entry := audit.Entry{
+		Action:       model.OperationPasswordChange,
+		ResourceType: "user",
+		Username:     req.Login,
+		Err:          err,
+	}
+	if user != nil {
+		entry.ResourceID = user.ID
+		entry.User = user
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		if errors.Is(err, service.ErrPasswordPolicy) || errors.Is(err, service.ErrDirectoryManagedPassword) {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Code:    http.StatusBadRequest,
+				Message: "修改密码失败: " + err.Error(),
+			})
+			return
+		}
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "密码修改成功，请重新登录",
+	})
+}
+
+// UnlockUser 超级管理员解除用户的登录锁定
+func (h *Handler) UnlockUser(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	var target model.User
+	if err := h.db.First(&target, id).Error; err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, model.APIResponse{
+			Code:    status,
+			Message: "获取用户失败: " + err.Error(),
+		})
+		return
+	}
+
+	unlocked, err := h.authService.UnlockUser(target.ID)
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationUserUnlock,
+		ResourceType: "user",
+		ResourceID:   target.ID,
+		After:        gin.H{"unlocked": unlocked},
+		Err:          err,
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "解除锁定失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "已解除登录锁定",
+		Data:    gin.H{"unlocked": unlocked},
+	})
+}
+
+// respondLoginError 账号锁定返回 429 并带 Retry-After，密码过期返回 403，其余返回 401
+func respondLoginError(c *gin.Context, err error) {
+	var locked *service.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter().Seconds()))))
+		c.JSON(http.StatusTooManyRequests, model.APIResponse{
+			Code:    http.StatusTooManyRequests,
+			Message: "登录失败: " + err.Error(),
+			Data:    gin.H{"locked_until": locked.Until},
+		})
+	case errors.Is(err, service.ErrPasswordExpired):
+		c.JSON(http.StatusForbidden, model.APIResponse{
+			Code:    http.StatusForbidden,
+			Message: "登录失败: " + err.Error(),
+			Data:    gin.H{"password_expired": true},
+		})
+	default:
+		c.JSON(http.StatusUnauthorized, model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "登录失败: " + err.Error(),
+		})
+	}
+}
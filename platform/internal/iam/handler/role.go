@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetPermissionCatalog 获取权限目录，按权限常量的声明顺序返回
+func (h *Handler) GetPermissionCatalog(c *gin.Context) {
+	permissions, err := h.roleService.Catalog()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取权限目录失败: " + err.Error(),
+		})
+		return
+	}
+
+	groups := make(map[model.PermissionName]string, len(model.PermissionCatalog))
+	for _, definition := range model.PermissionCatalog {
+		groups[definition.Name] = definition.Group
+	}
+	items := make([]gin.H, 0, len(permissions))
+	for _, permission := range permissions {
+		items = append(items, gin.H{
+			"id":           permission.ID,
+			"name":         permission.Name,
+			"display_name": permission.DisplayName,
+			"resource":     permission.Resource,
+			"action":       permission.Action,
+			"group":        groups[permission.Name],
+		})
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取权限目录成功",
+		Data:    items,
+	})
+}
+
+// CreateRole 超级管理员创建自定义角色
+func (h *Handler) CreateRole(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	var req model.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	role, err := h.roleService.CreateRole(&req)
+	entry := audit.Entry{
+		Action:       model.OperationRoleCreate,
+		ResourceType: "role",
+		After:        role,
+		Err:          err,
+	}
+	if role != nil {
+		entry.ResourceID = role.ID
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		respondRoleError(c, "创建角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "创建角色成功",
+		Data:    role,
+	})
+}
+
+// UpdateRole 超级管理员更新角色的显示名、描述和状态
+func (h *Handler) UpdateRole(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before, _ := h.roleService.GetRole(id)
+	role, err := h.roleService.UpdateRole(id, &req)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationRoleUpdate,
+		ResourceType: "role",
+		ResourceID:   id,
+		Before:       before,
+		After:        role,
+		Err:          err,
+	})
+
+	if err != nil {
+		respondRoleError(c, "更新角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "更新角色成功",
+		Data:    role,
+	})
+}
+
+// DeleteRole 超级管理员删除自定义角色
+func (h *Handler) DeleteRole(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	role, err := h.roleService.DeleteRole(id)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationRoleDelete,
+		ResourceType: "role",
+		ResourceID:   id,
+		Before:       role,
+		Err:          err,
+	})
+
+	if err != nil {
+		respondRoleError(c, "删除角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "删除角色成功",
+	})
+}
+
+// SetRolePermissions 超级管理员替换角色的权限
+func (h *Handler) SetRolePermissions(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	var req model.SetRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	before, _ := h.roleService.GetRole(id)
+	role, err := h.roleService.SetRolePermissions(id, req.Permissions)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationRolePermissionUpdate,
+		ResourceType: "role",
+		ResourceID:   id,
+		Before:       before,
+		After:        role,
+		Err:          err,
+	})
+
+	if err != nil {
+		respondRoleError(c, "更新角色权限失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "更新角色权限成功",
+		Data:    role,
+	})
+}
+
+func roleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的角色ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetSpaceRolePermissions 获取空间角色权限表
+func (h *Handler) GetSpaceRolePermissions(c *gin.Context) {
+	matrix, err := h.roleService.SpaceRolePermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取空间角色权限失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取空间角色权限成功",
+		Data:    matrix,
+	})
+}
+
+// SetSpaceRolePermissions 超级管理员替换空间角色的权限
+func (h *Handler) SetSpaceRolePermissions(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	var req model.SetSpaceRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	role := model.SpaceMemberRole(c.Param("role"))
+	matrix, _ := h.roleService.SpaceRolePermissions()
+	permissions, err := h.roleService.SetSpaceRolePermissions(role, req.Permissions)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationSpaceRoleUpdate,
+		ResourceType: "space_role",
+		ResourceID:   string(role),
+		Before:       matrix[role],
+		After:        permissions,
+		Err:          err,
+	})
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrSpaceRoleInvalid):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrPermissionNotFound):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, model.APIResponse{
+			Code:    status,
+			Message: "更新空间角色权限失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "更新空间角色权限成功",
+		Data:    permissions,
+	})
+}
+
+func respondRoleError(c *gin.Context, message string, err error) {
+	var status int
+	switch {
+	case errors.Is(err, service.ErrRoleNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrRoleExists), errors.Is(err, service.ErrRoleInUse):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrRoleBuiltin):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrRoleInvalidName), errors.Is(err, service.ErrRoleInvalidStatus),
+		errors.Is(err, service.ErrPermissionNotFound):
+		status = http.StatusBadRequest
+	default:
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListSessions 获取当前用户未失效的登录会话
+func (h *Handler) ListSessions(c *gin.Context) {
+	user, session, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(user.ID, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取会话失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "获取会话成功",
+		Data:    sessions,
+	})
+}
+
+// RevokeSession 使当前用户的指定会话失效，用于下线其他设备
+func (h *Handler) RevokeSession(c *gin.Context) {
+	user, _, ok := h.currentSession(c)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Param("id")
+	err := h.authService.RevokeSession(user.ID, sessionID, model.SessionRevokeLogout)
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationSessionRevoke,
+		ResourceType: "user",
+		ResourceID:   user.ID,
+		After:        gin.H{"session_id": sessionID},
+		User:         user,
+		Err:          err,
+	})
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, model.APIResponse{
+			Code:    status,
+			Message: "注销会话失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "会话已注销",
+	})
+}
+
+// ForceLogout 超级管理员强制用户下线，使其全部会话失效
+func (h *Handler) ForceLogout(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的用户ID",
+		})
+		return
+	}
+
+	var target model.User
+	if err := h.db.First(&target, id).Error; err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, model.APIResponse{
+			Code:    status,
+			Message: "获取用户失败: " + err.Error(),
+		})
+		return
+	}
+
+	revoked, err := h.authService.RevokeUserSessions(target.ID, model.SessionRevokeForceLogout)
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationUserForceLogout,
+		ResourceType: "user",
+		ResourceID:   target.ID,
+		After:        gin.H{"revoked_sessions": revoked},
+		Err:          err,
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "强制下线失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "用户已强制下线",
+		Data:    gin.H{"revoked_sessions": revoked},
+	})
+}
+
+// currentSession 根据 Authorization 头获取当前用户和会话
+func (h *Handler) currentSession(c *gin.Context) (*model.User, *model.UserSession, bool) {
+	user, session, err := h.authService.ValidateTokenSession(c.GetHeader("Authorization"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "无效的token: " + err.Error(),
+		})
+		return nil, nil, false
+	}
+	return user, session, true
+}
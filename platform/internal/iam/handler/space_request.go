@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/outbox"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxWorkflowEventBody 单个事件请求体的大小上限
+const maxWorkflowEventBody = 64 << 10
+
+// RequestSpaceMembership 申请加入空间，审批通过后以申请的角色加入
+func (h *Handler) RequestSpaceMembership(c *gin.Context) {
+	spaceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的空间ID",
+		})
+		return
+	}
+
+	var req model.CreateSpaceMembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, _ := c.Get("user")
+	request, err := h.spaceRequestService.RequestMembership(c.Request.Context(), uint(spaceID), &req, user.(*model.User).ID)
+	if err != nil {
+		respondSpaceRequestError(c, "申请加入空间失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "已提交加入空间申请",
+		Data:    request,
+	})
+}
+
+// RequestSpaceCreation 申请创建空间，审批通过后创建空间，申请人成为空间管理员
+func (h *Handler) RequestSpaceCreation(c *gin.Context) {
+	var req model.CreateSpaceCreationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, _ := c.Get("user")
+	request, err := h.spaceRequestService.RequestSpaceCreation(c.Request.Context(), &req, user.(*model.User).ID)
+	if err != nil {
+		respondSpaceRequestError(c, "申请创建空间失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "已提交创建空间申请",
+		Data:    request,
+	})
+}
+
+func respondSpaceRequestError(c *gin.Context, message string, err error) {
+	var status int
+	switch {
+	case errors.Is(err, service.ErrSpaceNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrAlreadySpaceMember), errors.Is(err, service.ErrSpaceRequestPending):
+		status = http.StatusConflict
+	default:
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
+
+// WorkflowEventHandler 接收工作流服务投递的流程结束事件
+type WorkflowEventHandler struct {
+	spaceRequestService *service.SpaceRequestService
+	secret              string
+}
+
+// NewWorkflowEventHandler 创建工作流事件处理器，secret 用于校验事件签名
+func NewWorkflowEventHandler(spaceRequestService *service.SpaceRequestService, secret string) *WorkflowEventHandler {
+	return &WorkflowEventHandler{
+		spaceRequestService: spaceRequestService,
+		secret:              secret,
+	}
+}
+
+// ReceiveWorkflowEvent 校验签名后更新申请状态；重复投递的事件直接返回成功
+func (h *WorkflowEventHandler) ReceiveWorkflowEvent(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWorkflowEventBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "读取请求失败",
+		})
+		return
+	}
+
+	if err := outbox.Verify(h.secret, c.GetHeader(webhook.HeaderTimestamp), c.GetHeader(webhook.HeaderSignature), body); err != nil {
+		c.JSON(http.StatusUnauthorized, model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var event model.WorkflowStatusEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	applied, err := h.spaceRequestService.ApplyWorkflowEvent(c.Request.Context(), &event)
+	if errors.Is(err, service.ErrWorkflowEventNotReady) {
+		logger.Warnf("defer workflow event %s: %v", event.EventID, err)
+		c.JSON(http.StatusConflict, model.APIResponse{
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		logger.Errorf("failed to apply workflow event %s: %v", event.EventID, err)
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "处理流程事件失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "已接收流程事件",
+		Data:    gin.H{"event_id": event.EventID, "duplicate": !applied},
+	})
+}
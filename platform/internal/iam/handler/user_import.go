@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportUsers 超级管理员或企业管理员从 CSV/XLSX 批量导入用户。
+// 任一行校验失败时不写入任何数据，返回 422 和每行的校验结果；dry_run=true 时只校验
+func (h *Handler) ImportUsers(c *gin.Context) {
+	if !h.requireGlobalAdmin(c) {
+		return
+	}
+
+	var req model.UserImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请上传导入文件",
+		})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "读取导入文件失败: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	user, _ := c.Get("user")
+	report, err := h.userImportService.Import(c.Request.Context(), fileHeader.Filename, file, fileHeader.Size, &req, user.(*model.User))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUserImportFormat) || errors.Is(err, service.ErrUserImportInvalidFile) ||
+			errors.Is(err, service.ErrUserImportTooManyRows) || errors.Is(err, service.ErrUserImportTooLarge) {
+			status = http.StatusBadRequest
+		}
+		if !req.DryRun {
+			h.recorder.Record(c, audit.Entry{
+				Action:       model.OperationUserImport,
+				ResourceType: "user",
+				Detail:       fileHeader.Filename,
+				Err:          err,
+			})
+		}
+		c.JSON(status, model.APIResponse{
+			Code:    status,
+			Message: "导入用户失败: " + err.Error(),
+		})
+		return
+	}
+
+	if report.Applied {
+		h.recorder.Record(c, audit.Entry{
+			Action:       model.OperationUserImport,
+			ResourceType: "user",
+			Detail:       fileHeader.Filename,
+			After:        gin.H{"mode": report.Mode, "total": report.Total, "created": report.Created, "updated": report.Updated},
+		})
+	}
+
+	switch {
+	case report.Failed > 0:
+		c.JSON(http.StatusUnprocessableEntity, model.APIResponse{
+			Code:    http.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("%d 行校验未通过，未导入任何数据", report.Failed),
+			Data:    report,
+		})
+	case report.DryRun:
+		c.JSON(http.StatusOK, model.APIResponse{
+			Code:    http.StatusOK,
+			Message: "校验通过",
+			Data:    report,
+		})
+	default:
+		c.JSON(http.StatusOK, model.APIResponse{
+			Code:    http.StatusOK,
+			Message: "导入用户成功",
+			Data:    report,
+		})
+	}
+}
+
+// ExportUsers 超级管理员或企业管理员导出用户列表，列与导入文件相同
+func (h *Handler) ExportUsers(c *gin.Context) {
+	if !h.requireGlobalAdmin(c) {
+		return
+	}
+
+	var query model.UserExportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "查询参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	contentType, ext := "text/csv; charset=utf-8", "csv"
+	if query.Format == "xlsx" {
+		contentType, ext = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"
+	}
+	filename := fmt.Sprintf("users_%s.%s", time.Now().Format("20060102150405"), ext)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，导出中途失败只能记录错误
+	err := h.userImportService.Export(c.Writer, &query)
+	if err != nil {
+		_ = c.Error(err)
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationUserExport,
+		ResourceType: "user",
+		Detail:       filename,
+		Err:          err,
+	})
+}
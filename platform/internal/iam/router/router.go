@@ -1,6 +1,10 @@
 package router
 
 import (
+	"log"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/middleware"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/handler"
@@ -16,6 +20,11 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 	gin.SetMode(cfg.Gin.Mode)
 
 	r := gin.New()
+	// 客户端IP只取网关写入的 X-Real-IP，且只采信来自网关的请求头
+	r.RemoteIPHeaders = []string{"X-Real-IP"}
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted_proxies: %v", err)
+	}
 
 	// 添加中间件
 	r.Use(middleware.Logger())
@@ -23,10 +32,31 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/swagger/doc.json")))
 
 	// 创建服务
-	authService := service.NewAuthService(db, &cfg.JWT)
+	authService := service.NewAuthService(db, &cfg.JWT, &cfg.Lockout, &cfg.Password)
+	if cfg.LDAP.Enabled {
+		authService.UseDirectory(service.NewLDAPDirectory(&cfg.LDAP))
+	}
+	oidcService := service.NewOIDCService(db, authService, &cfg.OIDC)
+	roleService := service.NewRoleService(db)
+	spaceRequestService := service.NewSpaceRequestService(db, commonClient.NewWorkflowClient(&cfg.Workflow))
+	// 权限判定缓存，写入角色、权限、空间成员的服务在提交后使其失效
+	if permissionCache := service.NewPermissionCache(&cfg.PermissionCache); permissionCache != nil {
+		authService.UsePermissionCache(permissionCache)
+		roleService.UsePermissionCache(permissionCache)
+		spaceRequestService.UsePermissionCache(permissionCache)
+	}
+	orgUnitService := service.NewOrgUnitService(db)
+	userImportService := service.NewUserImportService(db, authService, &cfg.UserImport)
+	if notifier := service.NewHTTPPasswordNotifier(&cfg.UserImport); notifier != nil {
+		userImportService.UseNotifier(notifier)
+	}
+	apiKeyService := service.NewAPIKeyService(db, &cfg.APIKey)
+	operationLogService := service.NewOperationLogService(db)
+	recorder := audit.NewRecorder(db, audit.ServiceIam)
 
 	// 创建处理器
-	h := handler.NewHandler(db, authService)
+	h := handler.NewHandler(db, authService, oidcService, roleService, orgUnitService, userImportService, apiKeyService, operationLogService, spaceRequestService, recorder)
+	workflowEventHandler := handler.NewWorkflowEventHandler(spaceRequestService, cfg.Outbox.Secret)
 
 	// API路由组
 	api := r.Group("/api/v1")
@@ -42,8 +72,19 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 			auth.POST("/logout", h.Logout)
 			auth.POST("/refresh", h.RefreshToken)
 			auth.PATCH("/change-password", middleware.FetchUserFromHeader(db), h.ChangePassword)
+			// 密码过期的用户无法登录，凭账号和原密码修改密码
+			auth.POST("/expired-password", h.ChangeExpiredPassword)
+
+			// OIDC 单点登录，由浏览器直接访问
+			auth.GET("/oidc/login", h.OIDCLogin)
+			auth.GET("/oidc/callback", h.OIDCCallback)
 
 			auth.POST("/validate-token", h.ValidateToken)
+			auth.POST("/validate-api-key", h.ValidateAPIKey) // 网关校验服务账号的 API Key
+
+			// 登录会话，按 Authorization 头识别当前会话
+			auth.GET("/sessions", h.ListSessions)
+			auth.DELETE("/sessions/:id", h.RevokeSession)
 		}
 
 		// 用户管理路由
@@ -53,8 +94,12 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 			// 查看所有内容 - 所有认证用户都可以
 			users.GET("", h.GetUsers)
 			users.GET("/by-role/:rid/space/:sid", h.GetUserByRoleIdAndSpaceId)
+			users.GET("/by-global-role/:role", h.GetUsersByGlobalRole)
 			users.GET("/:id", h.GetUser)
 
+			// 同步目录用户 - 只有超级管理员
+			users.POST("/directory-sync", h.SyncDirectory)
+
 			// 更新用户 - 先检查角色，再检查权限
 			users.PUT("/:id", h.UpdateUser)
 
@@ -62,9 +107,22 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 			users.POST("", h.CreateUser)
 			users.DELETE("/:id", h.DeleteUser)
 
+			// 批量导入/导出用户 - 超级管理员、企业管理员
+			users.POST("/import", h.ImportUsers) // multipart: file, mode=create|upsert, dry_run
+			users.GET("/export", h.ExportUsers)  // ?format=csv|xlsx&status=
+
 			// 用户角色管理 - 先检查角色，再检查权限
 			users.POST("/:id/roles", h.AssignUserRole)
 			users.DELETE("/:id/roles/:role_id", h.RemoveUserRole)
+
+			// 用户所属组织 - 超级管理员、企业管理员调整；部门负责人供审批流查询
+			users.PUT("/:id/org-unit", h.AssignUserOrgUnit)
+			users.GET("/:id/department-head", h.GetDepartmentHead)
+
+			// 强制下线 - 只有超级管理员
+			users.POST("/:id/logout", h.ForceLogout)
+			// 解除登录锁定 - 只有超级管理员
+			users.POST("/:id/unlock", h.UnlockUser)
 		}
 
 		// 角色管理路由
@@ -75,6 +133,42 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 			roles.GET("", h.GetRoles)
 			roles.GET("/:id", h.GetRole)
 			roles.GET("/:id/permissions", h.GetRolePermissions)
+
+			// 角色维护与权限分配 - 只有超级管理员，内置角色不能删除、改名或停用
+			roles.POST("", h.CreateRole)
+			roles.PUT("/:id", h.UpdateRole)
+			roles.DELETE("/:id", h.DeleteRole)
+			roles.PUT("/:id/permissions", h.SetRolePermissions)
+		}
+
+		// 组织架构路由
+		orgUnits := api.Group("/org-units")
+		orgUnits.Use(middleware.FetchUserFromHeader(db))
+		{
+			// 查看组织架构 - 所有认证用户都可以
+			orgUnits.GET("", h.GetOrgUnits) // ?tree=true&root_id=
+			orgUnits.GET("/:id", h.GetOrgUnit)
+			orgUnits.GET("/:id/members", h.GetOrgUnitMembers) // ?include_sub=true
+
+			// 维护组织架构 - 超级管理员、企业管理员
+			orgUnits.POST("", h.CreateOrgUnit)
+			orgUnits.PUT("/:id", h.UpdateOrgUnit)
+			orgUnits.DELETE("/:id", h.DeleteOrgUnit)
+			orgUnits.PUT("/:id/head", h.SetOrgUnitHead)
+		}
+
+		// 服务账号与 API Key 路由
+		serviceAccounts := api.Group("/service-accounts")
+		serviceAccounts.Use(middleware.FetchUserFromHeader(db))
+		{
+			serviceAccounts.GET("/scopes", h.GetAPIKeyScopes)
+
+			// 管理服务账号和 API Key - 只有超级管理员；禁用服务账号使用 PUT /users/:id
+			serviceAccounts.GET("", h.GetServiceAccounts)
+			serviceAccounts.POST("", h.CreateServiceAccount)
+			serviceAccounts.GET("/:id/api-keys", h.GetAPIKeys)
+			serviceAccounts.POST("/:id/api-keys", h.CreateAPIKey)
+			serviceAccounts.DELETE("/:id/api-keys/:key_id", h.RevokeAPIKey)
 		}
 
 		// 权限管理路由
@@ -82,8 +176,12 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 		permissions.Use(middleware.FetchUserFromHeader(db))
 		{
 			permissions.GET("", h.GetPermissions)
+			permissions.GET("/catalog", h.GetPermissionCatalog)
+			permissions.GET("/space-roles", h.GetSpaceRolePermissions)       // 空间角色权限表
+			permissions.PUT("/space-roles/:role", h.SetSpaceRolePermissions) // 只有超级管理员
 			permissions.GET("/:id", h.GetPermission)
 			permissions.POST("/check", h.CheckPermission)
+			permissions.POST("/check-batch", h.CheckPermissionsBatch)
 		}
 
 		// 空间管理路由
@@ -96,6 +194,8 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 
 			// 创建知识空间 - 超级管理员、企业管理员、空间管理员
 			spaces.POST("", h.CreateSpace)
+			// 申请创建空间 - 所有认证用户，由企业管理员审批
+			spaces.POST("/creation-requests", h.RequestSpaceCreation)
 
 			// 管理空间 - 先检查空间成员，再检查角色权限
 			spaces.PUT("/:id", h.UpdateSpace)
@@ -108,9 +208,22 @@ func Setup(cfg *config.Config, db *gorm.DB) *gin.Engine {
 			spaces.GET("/members/:user_id", h.GetMembersByUserId)
 			spaces.PUT("/:id/members/:user_id", h.UpdateSpaceMemberRole)
 			spaces.GET("/:id/members/role/:role", h.GetSpaceMembersByRole)
+			// 申请加入空间 - 所有认证用户，由空间管理员审批
+			spaces.POST("/:id/membership-requests", h.RequestSpaceMembership)
 			spaces.POST("/subspaces", h.CreateSubSpace)
 			spaces.POST("/classes", h.CreateClass)
 		}
+
+		// 工作流服务投递的流程结束事件，仅供服务间调用，通过签名校验来源
+		api.POST("/spaces/workflow-events", workflowEventHandler.ReceiveWorkflowEvent)
+
+		// 操作日志路由 - 只有超级管理员
+		operationLogs := api.Group("/operation-logs")
+		operationLogs.Use(middleware.FetchUserFromHeader(db))
+		{
+			operationLogs.GET("", h.GetOperationLogs)
+			operationLogs.GET("/export", h.ExportOperationLogs)
+		}
 	}
 
 	return r
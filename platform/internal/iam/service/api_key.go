@@ -0,0 +1,247 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrServiceAccountNotFound 服务账号不存在
+	ErrServiceAccountNotFound = errors.New("服务账号不存在")
+	// ErrServiceAccountExists 用户名已被占用
+	ErrServiceAccountExists = errors.New("用户名已存在")
+	// ErrAPIKeyNotFound API Key 不存在
+	ErrAPIKeyNotFound = errors.New("API Key 不存在")
+	// ErrAPIKeyInvalidGrant 授权范围不受支持或空间不存在，具体原因附在错误信息后
+	ErrAPIKeyInvalidGrant = errors.New("API Key 授权范围无效")
+	// ErrAPIKeyInvalidExpiry 有效期超过上限
+	ErrAPIKeyInvalidExpiry = errors.New("API Key 有效期超过上限")
+	// ErrInvalidAPIKey API Key 不存在、已吊销、已过期，或所属服务账号已禁用
+	ErrInvalidAPIKey = errors.New("无效的 API Key")
+)
+
+// apiKeyPrefixLength 保存并展示的明文前缀长度，包含固定前缀
+const apiKeyPrefixLength = 12
+
+// APIKeyService 服务账号与 API Key 管理
+type APIKeyService struct {
+	db  *gorm.DB
+	cfg *config.APIKeyConfig
+}
+
+// NewAPIKeyService 创建 API Key 服务
+func NewAPIKeyService(db *gorm.DB, cfg *config.APIKeyConfig) *APIKeyService {
+	return &APIKeyService{db: db, cfg: cfg}
+}
+
+// CreateServiceAccount 创建服务账号。服务账号没有密码和手机号，
+// 手机号使用随机占位值以满足唯一约束；空间权限与普通用户一样通过空间成员授予
+func (s *APIKeyService) CreateServiceAccount(req *model.CreateServiceAccountRequest) (*model.User, error) {
+	var count int64
+	if err := s.db.Unscoped().Model(&model.User{}).Where("username = ?", req.Username).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrServiceAccountExists
+	}
+
+	user := &model.User{
+		Username: req.Username,
+		Phone:    "sa:" + randomToken()[:16],
+		Nickname: req.Nickname,
+		Status:   1,
+		Source:   model.UserSourceService,
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListServiceAccounts 获取全部服务账号
+func (s *APIKeyService) ListServiceAccounts() ([]model.User, error) {
+	var users []model.User
+	if err := s.db.Preload("Roles").Where("source = ?", model.UserSourceService).Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *APIKeyService) serviceAccount(id uint) (*model.User, error) {
+	var user model.User
+	if err := s.db.Where("source = ?", model.UserSourceService).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateKey 为服务账号创建 API Key，明文只在返回值中出现一次
+func (s *APIKeyService) CreateKey(accountID uint, req *model.CreateAPIKeyRequest, creatorID uint) (*model.CreateAPIKeyResponse, error) {
+	if _, err := s.serviceAccount(accountID); err != nil {
+		return nil, err
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = s.cfg.MaxExpireDays
+	}
+	if s.cfg.MaxExpireDays > 0 && days > s.cfg.MaxExpireDays {
+		return nil, fmt.Errorf("%w: 最长 %d 天", ErrAPIKeyInvalidExpiry, s.cfg.MaxExpireDays)
+	}
+
+	grants, err := s.normalizeGrants(req.Grants)
+	if err != nil {
+		return nil, err
+	}
+
+	key := model.APIKeyPrefix + randomToken()
+
+	apiKey := model.APIKey{
+		UserID:    accountID,
+		Name:      req.Name,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   hashAPIKey(key),
+		Grants:    grants,
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+		CreatedBy: creatorID,
+	}
+	if err := s.db.Create(&apiKey).Error; err != nil {
+		return nil, err
+	}
+	return &model.CreateAPIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+// normalizeGrants 校验范围和空间并去重，同一范围同时授予了全部空间时只保留不限空间的授权
+func (s *APIKeyService) normalizeGrants(grants []model.APIKeyGrant) ([]model.APIKeyGrant, error) {
+	unrestricted := make(map[model.APIKeyScope]bool)
+	var spaceIDs []uint
+	for _, grant := range grants {
+		if !model.IsValidAPIKeyScope(grant.Scope) {
+			return nil, fmt.Errorf("%w: 不支持的范围 %s", ErrAPIKeyInvalidGrant, grant.Scope)
+		}
+		if grant.SpaceID == 0 {
+			unrestricted[grant.Scope] = true
+		} else {
+			spaceIDs = append(spaceIDs, grant.SpaceID)
+		}
+	}
+
+	if len(spaceIDs) > 0 {
+		var found []uint
+		if err := s.db.Model(&model.Space{}).Where("id IN ?", spaceIDs).Pluck("id", &found).Error; err != nil {
+			return nil, err
+		}
+		exists := make(map[uint]bool, len(found))
+		for _, id := range found {
+			exists[id] = true
+		}
+		for _, id := range spaceIDs {
+			if !exists[id] {
+				return nil, fmt.Errorf("%w: 空间 %d 不存在", ErrAPIKeyInvalidGrant, id)
+			}
+		}
+	}
+
+	seen := make(map[model.APIKeyGrant]bool, len(grants))
+	normalized := make([]model.APIKeyGrant, 0, len(grants))
+	for _, grant := range grants {
+		if grant.SpaceID != 0 && unrestricted[grant.Scope] {
+			continue
+		}
+		if seen[grant] {
+			continue
+		}
+		seen[grant] = true
+		normalized = append(normalized, grant)
+	}
+	return normalized, nil
+}
+
+// ListKeys 获取服务账号的全部 API Key，包括已吊销和已过期的
+func (s *APIKeyService) ListKeys(accountID uint) ([]model.APIKey, error) {
+	if _, err := s.serviceAccount(accountID); err != nil {
+		return nil, err
+	}
+	var keys []model.APIKey
+	if err := s.db.Where("user_id = ?", accountID).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeKey 吊销 API Key，立即失效，重复吊销保持首次吊销时间
+func (s *APIKeyService) RevokeKey(accountID uint, keyID uint) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := s.db.Where("user_id = ?", accountID).First(&key, keyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := s.db.Model(&key).Update("revoked_at", &now).Error; err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &now
+	}
+	return &key, nil
+}
+
+// Validate 校验 API Key 并返回所属服务账号和授权范围，按配置的间隔记录最近使用时间和来源IP
+func (s *APIKeyService) Validate(key string, clientIP string) (*model.ValidateAPIKeyResponse, error) {
+	if !strings.HasPrefix(key, model.APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var apiKey model.APIKey
+	if err := s.db.Where("key_hash = ?", hashAPIKey(key)).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if apiKey.RevokedAt != nil || now.After(apiKey.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var user model.User
+	if err := s.db.Preload("Roles").
+		Where("status = 1 AND source = ?", model.UserSourceService).
+		First(&user, apiKey.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	interval := time.Duration(s.cfg.LastUsedIntervalSeconds) * time.Second
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= interval || apiKey.LastUsedIP != clientIP {
+		if err := s.db.Model(&apiKey).UpdateColumns(map[string]any{
+			"last_used_at": now,
+			"last_used_ip": clientIP,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.ValidateAPIKeyResponse{User: &user, KeyID: apiKey.ID, Grants: apiKey.Grants}, nil
+}
+
+// hashAPIKey API Key 为高熵随机串，使用 SHA-256 摘要即可按摘要直接查找
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
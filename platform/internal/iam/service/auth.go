@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
@@ -12,17 +13,26 @@ import (
 )
 
 type AuthService struct {
-	db     *gorm.DB
-	config *config.JWTConfig
+	db             *gorm.DB
+	config         *config.JWTConfig
+	lockout        *config.LockoutConfig
+	passwordPolicy *config.PasswordPolicyConfig
+	directory      Directory        // 为空表示未启用目录认证
+	permissions    *PermissionCache // 为空表示不缓存权限判定
 }
 
-func NewAuthService(db *gorm.DB, cfg *config.JWTConfig) *AuthService {
+func NewAuthService(db *gorm.DB, cfg *config.JWTConfig, lockout *config.LockoutConfig, passwordPolicy *config.PasswordPolicyConfig) *AuthService {
 	return &AuthService{
-		db:     db,
-		config: cfg,
+		db:             db,
+		config:         cfg,
+		lockout:        lockout,
+		passwordPolicy: passwordPolicy,
 	}
 }
 
+// ErrInvalidCredentials 用户名或密码错误，不区分用户是否存在
+var ErrInvalidCredentials = errors.New("用户名或密码错误")
+
 // LoginRequest 登录请求
 type LoginRequest struct {
 	Login    string `json:"login" binding:"required"` // 支持用户名、手机号、邮箱登录
@@ -43,7 +53,7 @@ type RegisterRequest struct {
 	Username   string `json:"username" binding:"required"`
 	Phone      string `json:"phone" binding:"required,len=11"` // 手机号必填，11位
 	Email      string `json:"email"`                           // 邮箱非必填
-	Password   string `json:"password" binding:"required,min=6"`
+	Password   string `json:"password" binding:"required"`     // 长度和复杂度由密码策略校验
 	Nickname   string `json:"nickname"`
 	Department string `json:"department"`
 	Company    string `json:"company"`
@@ -52,33 +62,30 @@ type RegisterRequest struct {
 // ChangePasswordRequest 修改密码请求
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required"` // 长度和复杂度由密码策略校验
 }
 
-// Login 用户登录
-func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
-	var user model.User
-
-	// 查找用户（支持用户名、手机号、邮箱登录）
-	if err := s.db.Preload("Roles").Where("username = ? OR phone = ? OR email = ?", req.Login, req.Login, req.Login).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户名或密码错误")
-		}
+// Login 用户登录，每次登录创建一个新会话。密码已过期时返回 ErrPasswordExpired，不签发令牌
+func (s *AuthService) Login(req *LoginRequest, client SessionClient) (*LoginResponse, error) {
+	user, err := s.authenticate(req.Login, req.Password, client)
+	if err != nil {
 		return nil, err
 	}
-
-	// 检查用户状态
-	if user.Status != 1 {
-		return nil, errors.New("用户已被禁用")
+	if s.passwordExpired(user) {
+		return nil, ErrPasswordExpired
 	}
+	return s.issueLogin(user, client)
+}
 
-	// 验证密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("用户名或密码错误")
+// issueLogin 为已通过认证的用户创建会话并签发令牌，本地密码、目录和单点登录共用
+func (s *AuthService) issueLogin(user *model.User, client SessionClient) (*LoginResponse, error) {
+	session, err := s.createSession(user, client)
+	if err != nil {
+		return nil, err
 	}
 
 	// 生成JWT token
-	accessToken, refreshToken, accessTokenExpiresAt, refreshTokenExpiresAt, err := s.generateToken(&user)
+	accessToken, refreshToken, accessTokenExpiresAt, refreshTokenExpiresAt, err := s.generateToken(user, session)
 	if err != nil {
 		return nil, err
 	}
@@ -86,17 +93,63 @@ func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
 	// 更新最后登录时间
 	now := time.Now()
 	user.LastLogin = &now
-	s.db.Save(&user)
+	s.db.Save(user)
 
 	return &LoginResponse{
 		AccessToken:           accessToken,
 		RefreshToken:          refreshToken,
-		User:                  &user,
+		User:                  user,
 		AccessTokenExpiresAt:  accessTokenExpiresAt,
 		RefreshTokenExpiresAt: refreshTokenExpiresAt,
 	}, nil
 }
 
+// authenticate 校验账号和密码。账号或来源IP处于锁定期时直接拒绝，
+// 目录用户和本地不存在的用户在启用目录时由目录认证，其余用户校验本地密码；
+// 密码错误计入失败次数，校验通过后清除失败计数
+func (s *AuthService) authenticate(login string, password string, client SessionClient) (*model.User, error) {
+	var user *model.User
+
+	// 查找用户（支持用户名、手机号、邮箱登录）
+	var found model.User
+	err := s.db.Preload("Roles").Where("username = ? OR phone = ? OR email = ?", login, login, login).First(&found).Error
+	if err == nil {
+		user = &found
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	targets := s.loginThrottleTargets(login, user, client)
+	if err := s.checkLoginLocked(targets); err != nil {
+		return nil, err
+	}
+
+	directoryUser, handled, err := s.authenticateDirectory(login, password, user)
+	switch {
+	case handled && err == nil:
+		user = directoryUser
+	case handled && !errors.Is(err, ErrInvalidCredentials):
+		// 目录不可用不计入失败次数
+		return nil, err
+	case handled,
+		user == nil,
+		user.Source == model.UserSourceLDAP,
+		user.Source == model.UserSourceService,
+		bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil:
+		if err := s.recordLoginFailure(targets); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	s.clearLoginFailures(targets)
+
+	// 检查用户状态
+	if user.Status != 1 {
+		return nil, errors.New("用户已被禁用")
+	}
+	return user, nil
+}
+
 // Register 用户注册
 func (s *AuthService) Register(req *RegisterRequest) (*model.User, error) {
 	// 检查用户名或手机号是否已存在
@@ -112,6 +165,10 @@ func (s *AuthService) Register(req *RegisterRequest) (*model.User, error) {
 		}
 	}
 
+	if err := s.validatePassword(req.Password, req.Username); err != nil {
+		return nil, err
+	}
+
 	// 加密密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -119,18 +176,26 @@ func (s *AuthService) Register(req *RegisterRequest) (*model.User, error) {
 	}
 
 	// 创建用户
+	now := time.Now()
 	user := &model.User{
-		Username:   req.Username,
-		Phone:      req.Phone,
-		Email:      req.Email,
-		Password:   string(hashedPassword),
-		Nickname:   req.Nickname,
-		Department: req.Department,
-		Company:    req.Company,
-		Status:     1,
-	}
-
-	if err := s.db.Create(user).Error; err != nil {
+		Username:          req.Username,
+		Phone:             req.Phone,
+		Email:             req.Email,
+		Password:          string(hashedPassword),
+		PasswordChangedAt: &now,
+		Nickname:          req.Nickname,
+		Department:        req.Department,
+		Company:           req.Company,
+		Status:            1,
+		Source:            model.UserSourceLocal,
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return s.recordPasswordHistory(tx, user)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -144,46 +209,59 @@ func (s *AuthService) ChangePassword(userID uint, req *ChangePasswordRequest) er
 		return err
 	}
 
+	if user.Source == model.UserSourceLDAP {
+		return ErrDirectoryManagedPassword
+	}
+
 	// 验证旧密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)); err != nil {
 		return errors.New("原密码错误")
 	}
 
-	// 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.setPassword(tx, &user, req.NewPassword)
+	})
+}
+
+// ChangeExpiredPassword 密码过期的用户凭账号和原密码修改密码，校验过程与登录一样受失败锁定限制
+func (s *AuthService) ChangeExpiredPassword(req *model.ExpiredPasswordChangeRequest, client SessionClient) (*model.User, error) {
+	user, err := s.authenticate(req.Login, req.OldPassword, client)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// 更新密码
-	user.Password = string(hashedPassword)
-	if err := s.db.Save(&user).Error; err != nil {
-		return err
+	if user.Source == model.UserSourceLDAP {
+		return nil, ErrDirectoryManagedPassword
 	}
 
-	return nil
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.setPassword(tx, user, req.NewPassword)
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
-// generateToken 生成JWT token
-func (s *AuthService) generateToken(user *model.User) (string, string, time.Time, time.Time, error) {
-	accessToken, accessTokenExpiresAt, err := s.generateAccessToken(user)
+// generateToken 为会话生成JWT token，两种令牌都携带会话ID
+func (s *AuthService) generateToken(user *model.User, session *model.UserSession) (string, string, time.Time, time.Time, error) {
+	accessToken, accessTokenExpiresAt, err := s.generateAccessToken(user, session)
 	if err != nil {
 		return "", "", time.Time{}, time.Time{}, err
 	}
-	refreshToken, refreshTokenExpiresAt, err := s.generateRefreshToken(user)
+	refreshToken, err := s.generateRefreshToken(user, session)
 	if err != nil {
 		return "", "", time.Time{}, time.Time{}, err
 	}
 
-	return accessToken, refreshToken, accessTokenExpiresAt, refreshTokenExpiresAt, nil
+	return accessToken, refreshToken, accessTokenExpiresAt, session.ExpiresAt, nil
 }
 
-func (s *AuthService) generateAccessToken(user *model.User) (string, time.Time, error) {
+func (s *AuthService) generateAccessToken(user *model.User, session *model.UserSession) (string, time.Time, error) {
 	expiresAt := time.Now().Add(time.Duration(s.config.AccessTokenExpireTime) * time.Hour)
 
 	claims := jwt.MapClaims{
 		"user_id":  user.ID,
 		"type":     "access",
+		"sid":      session.ID,
 		"username": user.Username,
 		"email":    user.Email,
 		"exp":      expiresAt.Unix(),
@@ -199,114 +277,116 @@ func (s *AuthService) generateAccessToken(user *model.User) (string, time.Time,
 	return tokenString, expiresAt, nil
 }
 
-func (s *AuthService) generateRefreshToken(user *model.User) (string, time.Time, error) {
-	expiresAt := time.Now().Add(time.Duration(s.config.RefreshTokenExpireTime) * time.Hour)
-
+// generateRefreshToken 生成刷新令牌，jti 与会话当前的刷新令牌ID一致时才能使用
+func (s *AuthService) generateRefreshToken(user *model.User, session *model.UserSession) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
 		"type":    "refresh",
-		"exp":     expiresAt.Unix(),
+		"sid":     session.ID,
+		"jti":     session.RefreshTokenID,
+		"exp":     session.ExpiresAt.Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.config.Secret))
-	if err != nil {
-		return "", time.Time{}, err
-	}
-
-	return tokenString, expiresAt, nil
+	return token.SignedString([]byte(s.config.Secret))
 }
 
-// ValidateToken 验证JWT token
-func (s *AuthService) ValidateToken(tokenString string) (*model.User, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+// parseToken 校验签名、有效期和令牌类型，返回令牌声明
+func (s *AuthService) parseToken(tokenString string, tokenType string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(strings.TrimPrefix(tokenString, "Bearer "), func(token *jwt.Token) (any, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
 		return []byte(s.config.Secret), nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// 验证token类型
-		tokenType, ok := claims["type"].(string)
-		if !ok || tokenType != "access" {
-			return nil, errors.New("invalid token type")
-		}
-
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			return nil, errors.New("invalid token claims")
-		}
-
-		var user model.User
-		if err := s.db.Preload("Roles").First(&user, uint(userID)).Error; err != nil {
-			return nil, err
-		}
-
-		return &user, nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
 	}
-
-	return nil, errors.New("invalid token")
+	if claimType, ok := claims["type"].(string); !ok || claimType != tokenType {
+		return nil, errors.New("invalid token type")
+	}
+	if _, ok := claims["user_id"].(float64); !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	// 会话机制之前签发的令牌没有会话ID，需要重新登录
+	if sid, ok := claims["sid"].(string); !ok || sid == "" {
+		return nil, ErrSessionRevoked
+	}
+	return claims, nil
 }
 
-// ValidateRefreshToken 验证刷新token
-func (s *AuthService) ValidateRefreshToken(tokenString string) (*model.User, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.config.Secret), nil
-	})
-
-	if err != nil {
+// loadActiveUser 加载令牌对应的用户，已禁用的用户令牌无效
+func (s *AuthService) loadActiveUser(claims jwt.MapClaims) (*model.User, error) {
+	var user model.User
+	if err := s.db.Preload("Roles").First(&user, uint(claims["user_id"].(float64))).Error; err != nil {
 		return nil, err
 	}
+	if user.Status != 1 {
+		return nil, errors.New("用户已被禁用")
+	}
+	return &user, nil
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// 验证token类型
-		tokenType, ok := claims["type"].(string)
-		if !ok || tokenType != "refresh" {
-			return nil, errors.New("invalid refresh token type")
-		}
-
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			return nil, errors.New("invalid token claims")
-		}
+// ValidateToken 验证JWT token
+func (s *AuthService) ValidateToken(tokenString string) (*model.User, error) {
+	user, _, err := s.ValidateTokenSession(tokenString)
+	return user, err
+}
 
-		var user model.User
-		if err := s.db.Preload("Roles").First(&user, uint(userID)).Error; err != nil {
-			return nil, err
-		}
+// ValidateTokenSession 验证 access token 及其所属会话，会话失效后令牌立即失效
+func (s *AuthService) ValidateTokenSession(tokenString string) (*model.User, *model.UserSession, error) {
+	claims, err := s.parseToken(tokenString, "access")
+	if err != nil {
+		return nil, nil, err
+	}
 
-		return &user, nil
+	session, err := s.loadActiveSession(claims["sid"].(string))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return nil, errors.New("invalid refresh token")
+	user, err := s.loadActiveUser(claims)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.ID != session.UserID {
+		return nil, nil, errors.New("invalid token claims")
+	}
+	return user, session, nil
 }
 
-// RefreshToken 刷新token
-func (s *AuthService) RefreshToken(refreshToken string) (*LoginResponse, error) {
-	// 验证刷新token
-	user, err := s.ValidateRefreshToken(refreshToken)
+// RefreshToken 刷新token。每次刷新都会轮换刷新令牌，旧令牌立即作废
+func (s *AuthService) RefreshToken(refreshToken string) (*LoginResponse, *model.UserSession, error) {
+	claims, err := s.parseToken(refreshToken, "refresh")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	tokenID, _ := claims["jti"].(string)
 
 	// 检查用户状态
-	if user.Status != 1 {
-		return nil, errors.New("用户已被禁用")
+	user, err := s.loadActiveUser(claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, err := s.rotateRefreshToken(claims["sid"].(string), tokenID)
+	if err != nil {
+		return nil, session, err
+	}
+	if session.UserID != user.ID {
+		return nil, nil, errors.New("invalid token claims")
 	}
 
 	// 生成新的token对
-	accessToken, newRefreshToken, accessTokenExpiresAt, refreshTokenExpiresAt, err := s.generateToken(user)
+	accessToken, newRefreshToken, accessTokenExpiresAt, refreshTokenExpiresAt, err := s.generateToken(user, session)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 更新最后登录时间
@@ -320,57 +400,21 @@ func (s *AuthService) RefreshToken(refreshToken string) (*LoginResponse, error)
 		User:                  user,
 		AccessTokenExpiresAt:  accessTokenExpiresAt,
 		RefreshTokenExpiresAt: refreshTokenExpiresAt,
-	}, nil
+	}, session, nil
 }
 
-// CheckPermission 检查用户权限
-func (s *AuthService) CheckPermission(userID uint, spaceID uint, resource string, action string) (bool, error) {
-	var user model.User
-	if err := s.db.Preload("Roles.Permissions").First(&user, userID).Error; err != nil {
+// CheckPermission 检查用户权限，规则见 CheckPermissions
+func (s *AuthService) CheckPermission(userID uint, spaceID uint, resource string, action string, ownerID uint) (bool, error) {
+	results, err := s.CheckPermissions(userID, []model.PermissionCheck{{
+		SpaceID:  spaceID,
+		Resource: resource,
+		Action:   action,
+		OwnerID:  ownerID,
+	}})
+	if err != nil {
 		return false, err
 	}
-
-	// 检查用户状态
-	if user.Status != 1 {
-		return false, errors.New("用户已被禁用")
-	}
-
-	// 检查用户角色权限
-	for _, role := range user.Roles {
-		// 超级管理员拥有万能权限，不受空间限制
-		if role.Name == "super_admin" {
-			return true, nil
-		}
-
-		// 企业管理员拥有跨空间权限，但需要检查具体权限
-		if role.Name == "corp_admin" {
-			for _, permission := range role.Permissions {
-				if permission.Resource == resource && permission.Action == action {
-					return true, nil // 有权限，可以跨空间访问
-				}
-			}
-			// 企业管理员但没有对应权限，继续检查其他角色
-		}
-	}
-
-	// 其他角色需要检查空间权限
-	if spaceID > 0 {
-		var spaceMember model.SpaceMember
-		if err := s.db.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&spaceMember).Error; err != nil {
-			return false, nil // 用户不在该空间中
-		}
-	}
-
-	// 检查用户角色权限
-	for _, role := range user.Roles {
-		for _, permission := range role.Permissions {
-			if permission.Resource == resource && permission.Action == action {
-				return true, nil
-			}
-		}
-	}
-
-	return false, nil
+	return results[0].HasPermission, nil
 }
 
 func (s *AuthService) CreateSubSpace(req *model.CreateSubSpaceRequest, userID uint) (*model.SubSpace, error) {
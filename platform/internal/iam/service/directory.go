@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDirectoryUserNotFound 目录中没有该登录名对应的用户
+	ErrDirectoryUserNotFound = errors.New("directory user not found")
+	// ErrDirectoryUnavailable 无法连接目录服务或服务账号绑定失败
+	ErrDirectoryUnavailable = errors.New("目录服务不可用")
+	// ErrDirectoryNotConfigured 未启用目录服务
+	ErrDirectoryNotConfigured = errors.New("未启用目录服务")
+	// ErrDirectoryManagedPassword 目录用户的密码由目录管理
+	ErrDirectoryManagedPassword = errors.New("目录用户的密码需在目录服务中修改")
+)
+
+// DirectoryUser 目录中的用户条目
+type DirectoryUser struct {
+	DN         string
+	Username   string
+	Email      string
+	Phone      string
+	Nickname   string
+	Department string
+	Company    string
+}
+
+// Directory 外部用户目录。认证失败返回 ErrInvalidCredentials，
+// 找不到用户返回 ErrDirectoryUserNotFound，其余错误视为目录不可用
+type Directory interface {
+	Authenticate(login string, password string) (*DirectoryUser, error)
+	ListUsers() ([]DirectoryUser, error)
+}
+
+// UseDirectory 启用外部目录认证与同步
+func (s *AuthService) UseDirectory(directory Directory) {
+	s.directory = directory
+}
+
+// authenticateDirectory 目录用户和本地不存在的用户先到目录认证，认证通过后同步本地用户。
+// handled 为 false 表示该用户不由目录认证，继续校验本地密码
+func (s *AuthService) authenticateDirectory(login string, password string, user *model.User) (*model.User, bool, error) {
+	if s.directory == nil {
+		return nil, false, nil
+	}
+	if user != nil && user.Source != model.UserSourceLDAP {
+		return nil, false, nil
+	}
+
+	// 已同步的目录用户可能使用手机号或邮箱登录，目录中按用户名查找
+	if user != nil {
+		login = user.Username
+	}
+
+	entry, err := s.directory.Authenticate(login, password)
+	if err != nil {
+		if errors.Is(err, ErrDirectoryUserNotFound) {
+			return nil, true, ErrInvalidCredentials
+		}
+		return nil, true, err
+	}
+
+	synced, _, err := s.upsertDirectoryUser(s.db, entry)
+	if err != nil {
+		return nil, true, fmt.Errorf("同步目录用户失败: %w", err)
+	}
+
+	var loaded model.User
+	if err := s.db.Preload("Roles").First(&loaded, synced.ID).Error; err != nil {
+		return nil, true, err
+	}
+	return &loaded, true, nil
+}
+
+// upsertDirectoryUser 按 DN 匹配本地用户并更新资料，不存在时创建。
+// 用户名和手机号都一致的本地用户视为同一人，转为目录用户，此后使用目录密码登录；
+// 仅用户名相同的本地用户视为冲突，避免目录条目接管本地账号
+func (s *AuthService) upsertDirectoryUser(tx *gorm.DB, entry *DirectoryUser) (*model.User, bool, error) {
+	if entry.Username == "" {
+		return nil, false, fmt.Errorf("目录用户 %s 缺少登录名属性", entry.DN)
+	}
+	if entry.Phone == "" {
+		return nil, false, fmt.Errorf("目录用户 %s 缺少手机号属性", entry.DN)
+	}
+
+	var user model.User
+	err := tx.Where("source = ? AND external_id = ?", model.UserSourceLDAP, entry.DN).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = tx.Where("username = ?", entry.Username).First(&user).Error
+		if err == nil && user.Source != model.UserSourceLDAP && user.Phone != entry.Phone {
+			return nil, false, fmt.Errorf("本地已存在用户名为 %s 的其他用户", entry.Username)
+		}
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = model.User{
+			Username:   entry.Username,
+			Phone:      entry.Phone,
+			Email:      entry.Email,
+			Nickname:   entry.Nickname,
+			Department: entry.Department,
+			Company:    entry.Company,
+			Status:     1,
+			Source:     model.UserSourceLDAP,
+			ExternalID: entry.DN,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return nil, false, err
+		}
+		return &user, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 只同步资料，不修改启用状态：被禁用的用户需要管理员重新启用
+	if err := tx.Model(&user).Updates(map[string]any{
+		"username":    entry.Username,
+		"phone":       entry.Phone,
+		"email":       entry.Email,
+		"nickname":    entry.Nickname,
+		"department":  entry.Department,
+		"company":     entry.Company,
+		"source":      model.UserSourceLDAP,
+		"external_id": entry.DN,
+	}).Error; err != nil {
+		return nil, false, err
+	}
+	return &user, false, nil
+}
+
+// SyncDirectory 从目录同步用户：创建新用户、更新资料，禁用已不在目录（或不再满足过滤条件）的目录用户并使其会话失效
+func (s *AuthService) SyncDirectory() (*model.DirectorySyncResult, error) {
+	if s.directory == nil {
+		return nil, ErrDirectoryNotConfigured
+	}
+
+	result := &model.DirectorySyncResult{StartedAt: time.Now(), Errors: []string{}}
+	entries, err := s.directory.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make([]string, 0, len(entries))
+	for i := range entries {
+		user, created, err := s.upsertDirectoryUser(s.db, &entries[i])
+		if err != nil {
+			result.Skipped++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", entries[i].DN, err))
+			continue
+		}
+		seen = append(seen, user.ExternalID)
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	// 目录返回空结果多半是过滤条件或权限配置错误，此时不禁用任何用户
+	if len(entries) == 0 {
+		logger.Warnf("directory sync: directory returned no users, skip disabling")
+		result.FinishedAt = time.Now()
+		return result, nil
+	}
+
+	var missing []model.User
+	if err := s.db.Where("source = ? AND status = 1 AND external_id NOT IN ?", model.UserSourceLDAP, seen).
+		Find(&missing).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range missing {
+		if err := s.db.Model(&user).Update("status", 0).Error; err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", user.ExternalID, err))
+			continue
+		}
+		if _, err := s.RevokeUserSessions(user.ID, model.SessionRevokeUserDisabled); err != nil {
+			logger.Errorf("directory sync: failed to revoke sessions of user %d: %v", user.ID, err)
+		}
+		result.Disabled++
+	}
+
+	result.FinishedAt = time.Now()
+	return result, nil
+}
+
+// StartDirectorySync 按间隔定时同步目录用户，直到 ctx 结束
+func (s *AuthService) StartDirectorySync(ctx context.Context, interval time.Duration) {
+	if s.directory == nil || interval <= 0 {
+		logger.Infof("directory sync disabled")
+		return
+	}
+
+	sync := func() {
+		result, err := s.SyncDirectory()
+		if err != nil {
+			logger.Errorf("directory sync failed: %v", err)
+			return
+		}
+		logger.Infof("directory sync finished: created=%d updated=%d disabled=%d skipped=%d",
+			result.Created, result.Updated, result.Disabled, result.Skipped)
+	}
+
+	sync()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sync()
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapPageSize 同步时分页搜索的页大小，AD 默认单次最多返回 1000 条
+const ldapPageSize = 500
+
+// LDAPDirectory 基于 LDAP / AD 的目录实现。每次操作使用独立连接，
+// 先用服务账号搜索用户，再用用户 DN 和密码绑定完成认证
+type LDAPDirectory struct {
+	cfg *config.LDAPConfig
+}
+
+// NewLDAPDirectory 创建 LDAP 目录
+func NewLDAPDirectory(cfg *config.LDAPConfig) *LDAPDirectory {
+	return &LDAPDirectory{cfg: cfg}
+}
+
+// Authenticate 按登录属性查找用户并用其 DN 绑定校验密码
+func (d *LDAPDirectory) Authenticate(login string, password string) (*DirectoryUser, error) {
+	// 空密码会被服务器当作匿名绑定而成功，必须拒绝
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filter := fmt.Sprintf("(&%s(%s=%s))", d.userFilter(), d.cfg.LoginAttribute, ldap.EscapeFilter(login))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, 0, false, filter, d.attributes(), nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrDirectoryUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("ldap: login %q matches multiple entries", login)
+	}
+
+	entry := result.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap bind failed: %w", err)
+	}
+	return d.toUser(entry), nil
+}
+
+// ListUsers 列出过滤条件下的全部用户
+func (d *LDAPDirectory) ListUsers() ([]DirectoryUser, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	result, err := conn.SearchWithPaging(ldap.NewSearchRequest(
+		d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, d.userFilter(), d.attributes(), nil,
+	), ldapPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+
+	users := make([]DirectoryUser, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		users = append(users, *d.toUser(entry))
+	}
+	return users, nil
+}
+
+// connect 建立连接并以服务账号绑定
+func (d *LDAPDirectory) connect() (*ldap.Conn, error) {
+	timeout := time.Duration(d.cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: d.cfg.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(d.cfg.URL, ldap.DialWithTLSConfig(tlsConfig), ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDirectoryUnavailable, err)
+	}
+	conn.SetTimeout(timeout)
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: start tls: %v", ErrDirectoryUnavailable, err)
+		}
+	}
+
+	if d.cfg.BindDN != "" {
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: service account bind: %v", ErrDirectoryUnavailable, err)
+		}
+	}
+	return conn, nil
+}
+
+func (d *LDAPDirectory) userFilter() string {
+	filter := strings.TrimSpace(d.cfg.UserFilter)
+	if filter == "" {
+		return "(objectClass=*)"
+	}
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	return filter
+}
+
+func (d *LDAPDirectory) attributes() []string {
+	attrs := []string{d.cfg.LoginAttribute}
+	mapping := d.cfg.Attributes
+	for _, attr := range []string{mapping.Email, mapping.Phone, mapping.Nickname, mapping.Department, mapping.Company} {
+		if attr != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+func (d *LDAPDirectory) toUser(entry *ldap.Entry) *DirectoryUser {
+	mapping := d.cfg.Attributes
+	value := func(attr string) string {
+		if attr == "" {
+			return ""
+		}
+		return strings.TrimSpace(entry.GetAttributeValue(attr))
+	}
+	return &DirectoryUser{
+		DN:         entry.DN,
+		Username:   value(d.cfg.LoginAttribute),
+		Email:      value(mapping.Email),
+		Phone:      value(mapping.Phone),
+		Nickname:   value(mapping.Nickname),
+		Department: value(mapping.Department),
+		Company:    value(mapping.Company),
+	}
+}
+
+// 编译期检查
+var _ Directory = (*LDAPDirectory)(nil)
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeLDAPEntry 内存目录中的条目，password 为空表示不能绑定
+type fakeLDAPEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// fakeLDAPServer 只实现 LDAPDirectory 用到的操作：简单绑定、带分页控制的子树搜索、解绑。
+// 过滤条件支持 and、or、not、等值和存在判断，属性名和值都不区分大小写
+type fakeLDAPServer struct {
+	listener net.Listener
+	entries  []fakeLDAPEntry
+
+	mu           sync.Mutex
+	maxPageSizes []uint32 // 每次分页搜索请求的页大小
+}
+
+func newFakeLDAPServer(t *testing.T, entries ...fakeLDAPEntry) *fakeLDAPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeLDAPServer{listener: listener, entries: entries}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeLDAPServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	boundDN := ""
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			code := s.bind(dn, password)
+			if code == ldap.LDAPResultSuccess {
+				boundDN = dn
+			}
+			responses = append(responses, ldapResponse(messageID, ldap.ApplicationBindResponse, code, nil))
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationSearchRequest:
+			var controls *ber.Packet
+			if len(packet.Children) > 2 {
+				controls = packet.Children[2]
+			}
+			responses = s.search(messageID, op, controls, boundDN)
+		default:
+			return
+		}
+
+		for _, response := range responses {
+			if _, err := conn.Write(response.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind 空 DN 为匿名绑定；空密码的非匿名绑定按 RFC 4513 视为未认证绑定，这里直接拒绝
+func (s *fakeLDAPServer) bind(dn, password string) uint16 {
+	if dn == "" && password == "" {
+		return ldap.LDAPResultSuccess
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.dn, dn) {
+			if entry.password != "" && entry.password == password {
+				return ldap.LDAPResultSuccess
+			}
+			break
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+func (s *fakeLDAPServer) search(messageID int64, op, controls *ber.Packet, boundDN string) []*ber.Packet {
+	if boundDN == "" {
+		return []*ber.Packet{ldapResponse(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights, nil)}
+	}
+
+	baseDN := strings.ToLower(op.Children[0].Value.(string))
+	sizeLimit := int(op.Children[3].Value.(int64))
+	filter := op.Children[6]
+	var wanted []string
+	for _, attr := range op.Children[7].Children {
+		wanted = append(wanted, attr.Value.(string))
+	}
+
+	var paging *ldap.ControlPaging
+	if controls != nil {
+		for _, child := range controls.Children {
+			control, err := ldap.DecodeControl(child)
+			if err == nil && control.GetControlType() == ldap.ControlTypePaging {
+				paging = control.(*ldap.ControlPaging)
+			}
+		}
+	}
+
+	var matched []fakeLDAPEntry
+	for _, entry := range s.entries {
+		if strings.HasSuffix(strings.ToLower(entry.dn), baseDN) && matchFilter(filter, entry) {
+			matched = append(matched, entry)
+		}
+	}
+
+	if paging != nil {
+		s.mu.Lock()
+		s.maxPageSizes = append(s.maxPageSizes, paging.PagingSize)
+		s.mu.Unlock()
+	}
+
+	code := uint16(ldap.LDAPResultSuccess)
+	var responseControls []ldap.Control
+	if paging != nil {
+		offset := 0
+		if len(paging.Cookie) > 0 {
+			offset = int(ber.DecodePacket(paging.Cookie).Value.(int64))
+		}
+		end := min(offset+int(paging.PagingSize), len(matched))
+		next := &ldap.ControlPaging{}
+		if end < len(matched) {
+			next.Cookie = ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, int64(end), "offset").Bytes()
+		}
+		responseControls = append(responseControls, next)
+		matched = matched[offset:end]
+	} else if sizeLimit > 0 && len(matched) > sizeLimit {
+		matched = matched[:sizeLimit]
+		code = ldap.LDAPResultSizeLimitExceeded
+	}
+
+	responses := make([]*ber.Packet, 0, len(matched)+1)
+	for _, entry := range matched {
+		responses = append(responses, searchResultEntry(messageID, entry, wanted))
+	}
+	return append(responses, ldapResponse(messageID, ldap.ApplicationSearchResultDone, code, responseControls))
+}
+
+// matchFilter 按 RFC 4511 的过滤条件编码匹配条目
+func matchFilter(filter *ber.Packet, entry fakeLDAPEntry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(filter.Children[0], entry)
+	case ldap.FilterEqualityMatch:
+		attr := filter.Children[0].Value.(string)
+		value := filter.Children[1].Value.(string)
+		for _, v := range entryValues(entry, attr) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		attr := filter.Data.String()
+		return strings.EqualFold(attr, "objectClass") || len(entryValues(entry, attr)) > 0
+	default:
+		return false
+	}
+}
+
+func entryValues(entry fakeLDAPEntry, attr string) []string {
+	for name, values := range entry.attributes {
+		if strings.EqualFold(name, attr) {
+			return values
+		}
+	}
+	return nil
+}
+
+func searchResultEntry(messageID int64, entry fakeLDAPEntry, wanted []string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "DN"))
+	attributes := ber.NewSequence("Attributes")
+	for name, values := range entry.attributes {
+		if len(wanted) > 0 && !containsFold(wanted, name) {
+			continue
+		}
+		attribute := ber.NewSequence("Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+	return ldapEnvelope(messageID, op, nil)
+}
+
+func ldapResponse(messageID int64, tag ber.Tag, code uint16, controls []ldap.Control) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return ldapEnvelope(messageID, op, controls)
+}
+
+func ldapEnvelope(messageID int64, op *ber.Packet, controls []ldap.Control) *ber.Packet {
+	packet := ber.NewSequence("LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+	if len(controls) > 0 {
+		encoded := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		for _, control := range controls {
+			encoded.AppendChild(control.Encode())
+		}
+		packet.AppendChild(encoded)
+	}
+	return packet
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
+)
+
+const (
+	testBaseDN       = "ou=people,dc=example,dc=com"
+	testBindDN       = "cn=reader,dc=example,dc=com"
+	testBindPassword = "reader-secret"
+)
+
+func testLDAPConfig(url string) *config.LDAPConfig {
+	return &config.LDAPConfig{
+		Enabled:        true,
+		URL:            url,
+		TimeoutSeconds: 5,
+		BindDN:         testBindDN,
+		BindPassword:   testBindPassword,
+		BaseDN:         testBaseDN,
+		UserFilter:     "(objectClass=inetOrgPerson)",
+		LoginAttribute: "uid",
+		Attributes: config.LDAPAttributeMapping{
+			Email:      "mail",
+			Phone:      "telephoneNumber",
+			Nickname:   "displayName",
+			Department: "departmentNumber",
+			Company:    "o",
+		},
+	}
+}
+
+func testLDAPPerson(uid, password string, extra map[string][]string) fakeLDAPEntry {
+	attributes := map[string][]string{
+		"objectClass":      {"inetOrgPerson"},
+		"uid":              {uid},
+		"mail":             {uid + "@example.com"},
+		"telephoneNumber":  {"138" + fmt.Sprintf("%08d", len(uid))},
+		"displayName":      {"User " + uid},
+		"departmentNumber": {"研发部"},
+		"o":                {"示例公司"},
+		"userPassword":     {password},
+	}
+	for name, values := range extra {
+		attributes[name] = values
+	}
+	return fakeLDAPEntry{dn: "uid=" + uid + "," + testBaseDN, password: password, attributes: attributes}
+}
+
+func newTestDirectory(t *testing.T) (*LDAPDirectory, *fakeLDAPServer) {
+	t.Helper()
+	server := newFakeLDAPServer(t,
+		fakeLDAPEntry{dn: testBindDN, password: testBindPassword, attributes: map[string][]string{"cn": {"reader"}}},
+		testLDAPPerson("alice", "alice-pass", nil),
+		testLDAPPerson("bob", "bob-pass", map[string][]string{"departmentNumber": {"财务部"}, "o": {"分公司"}}),
+		// 不满足用户过滤条件
+		testLDAPPerson("printer", "printer-pass", map[string][]string{"objectClass": {"device"}}),
+		// 两个条目使用同一登录名
+		testLDAPPerson("dup", "dup-pass", nil),
+		fakeLDAPEntry{dn: "uid=dup,ou=contractors,ou=people,dc=example,dc=com", password: "dup-pass", attributes: map[string][]string{
+			"objectClass": {"inetOrgPerson"}, "uid": {"dup"},
+		}},
+		// 不在 BaseDN 下
+		fakeLDAPEntry{dn: "uid=carol,ou=other,dc=example,dc=com", password: "carol-pass", attributes: map[string][]string{
+			"objectClass": {"inetOrgPerson"}, "uid": {"carol"},
+		}},
+	)
+	return NewLDAPDirectory(testLDAPConfig(server.url())), server
+}
+
+func TestLDAPDirectoryAuthenticate(t *testing.T) {
+	directory, _ := newTestDirectory(t)
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantErr  error
+		wantUser *DirectoryUser
+	}{
+		{
+			name:     "valid credentials map attributes",
+			login:    "alice",
+			password: "alice-pass",
+			wantUser: &DirectoryUser{
+				DN:         "uid=alice," + testBaseDN,
+				Username:   "alice",
+				Email:      "alice@example.com",
+				Phone:      "13800000005",
+				Nickname:   "User alice",
+				Department: "研发部",
+				Company:    "示例公司",
+			},
+		},
+		{
+			name:     "department and company come from the entry",
+			login:    "bob",
+			password: "bob-pass",
+			wantUser: &DirectoryUser{
+				DN:         "uid=bob," + testBaseDN,
+				Username:   "bob",
+				Email:      "bob@example.com",
+				Phone:      "13800000003",
+				Nickname:   "User bob",
+				Department: "财务部",
+				Company:    "分公司",
+			},
+		},
+		{name: "wrong password", login: "alice", password: "wrong", wantErr: ErrInvalidCredentials},
+		{name: "empty password is not an anonymous bind", login: "alice", password: "", wantErr: ErrInvalidCredentials},
+		{name: "empty login", login: "", password: "alice-pass", wantErr: ErrInvalidCredentials},
+		{name: "unknown user", login: "nobody", password: "x", wantErr: ErrDirectoryUserNotFound},
+		{name: "entry excluded by user filter", login: "printer", password: "printer-pass", wantErr: ErrDirectoryUserNotFound},
+		{name: "entry outside base dn", login: "carol", password: "carol-pass", wantErr: ErrDirectoryUserNotFound},
+		{name: "filter metacharacters are escaped", login: "*", password: "alice-pass", wantErr: ErrDirectoryUserNotFound},
+		{name: "injected filter is escaped", login: "alice)(uid=*", password: "alice-pass", wantErr: ErrDirectoryUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := directory.Authenticate(tt.login, tt.password)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if *user != *tt.wantUser {
+				t.Fatalf("Authenticate() = %+v, want %+v", *user, *tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestLDAPDirectoryAuthenticateAmbiguousLogin(t *testing.T) {
+	directory, _ := newTestDirectory(t)
+
+	_, err := directory.Authenticate("dup", "dup-pass")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrDirectoryUserNotFound) {
+		t.Fatalf("Authenticate() error = %v, want ambiguous login error", err)
+	}
+}
+
+func TestLDAPDirectoryServiceAccountBind(t *testing.T) {
+	_, server := newTestDirectory(t)
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.LDAPConfig)
+	}{
+		{name: "wrong service account password", modify: func(cfg *config.LDAPConfig) { cfg.BindPassword = "wrong" }},
+		{name: "unknown service account", modify: func(cfg *config.LDAPConfig) { cfg.BindDN = "cn=ghost,dc=example,dc=com" }},
+		{name: "server unreachable", modify: func(cfg *config.LDAPConfig) { cfg.URL = "ldap://127.0.0.1:1" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testLDAPConfig(server.url())
+			tt.modify(cfg)
+			directory := NewLDAPDirectory(cfg)
+
+			if _, err := directory.Authenticate("alice", "alice-pass"); !errors.Is(err, ErrDirectoryUnavailable) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, ErrDirectoryUnavailable)
+			}
+			if _, err := directory.ListUsers(); !errors.Is(err, ErrDirectoryUnavailable) {
+				t.Fatalf("ListUsers() error = %v, want %v", err, ErrDirectoryUnavailable)
+			}
+		})
+	}
+}
+
+func TestLDAPDirectoryListUsers(t *testing.T) {
+	directory, _ := newTestDirectory(t)
+
+	users, err := directory.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+
+	got := make(map[string]DirectoryUser, len(users))
+	for _, user := range users {
+		got[user.DN] = user
+	}
+	want := []string{
+		"uid=alice," + testBaseDN,
+		"uid=bob," + testBaseDN,
+		"uid=dup," + testBaseDN,
+		"uid=dup,ou=contractors," + testBaseDN,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ListUsers() returned %d users, want %d: %+v", len(got), len(want), users)
+	}
+	for _, dn := range want {
+		if _, ok := got[dn]; !ok {
+			t.Errorf("ListUsers() missing %s", dn)
+		}
+	}
+	if bob := got["uid=bob,"+testBaseDN]; bob.Department != "财务部" || bob.Company != "分公司" {
+		t.Errorf("bob department/company = %q/%q, want 财务部/分公司", bob.Department, bob.Company)
+	}
+}
+
+func TestLDAPDirectoryListUsersPaging(t *testing.T) {
+	total := ldapPageSize*2 + 17
+	entries := []fakeLDAPEntry{{dn: testBindDN, password: testBindPassword}}
+	for i := 0; i < total; i++ {
+		entries = append(entries, testLDAPPerson(fmt.Sprintf("user%04d", i), "", nil))
+	}
+	server := newFakeLDAPServer(t, entries...)
+	directory := NewLDAPDirectory(testLDAPConfig(server.url()))
+
+	users, err := directory.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(users) != total {
+		t.Fatalf("ListUsers() returned %d users, want %d", len(users), total)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.maxPageSizes) != 3 {
+		t.Fatalf("paged searches = %d, want 3", len(server.maxPageSizes))
+	}
+	for _, size := range server.maxPageSizes {
+		if size != ldapPageSize {
+			t.Errorf("page size = %d, want %d", size, ldapPageSize)
+		}
+	}
+}
+
+func TestLDAPDirectoryDefaultUserFilter(t *testing.T) {
+	server := newFakeLDAPServer(t,
+		fakeLDAPEntry{dn: testBindDN, password: testBindPassword},
+		testLDAPPerson("alice", "alice-pass", nil),
+		testLDAPPerson("printer", "printer-pass", map[string][]string{"objectClass": {"device"}}),
+	)
+
+	tests := []struct {
+		name   string
+		filter string
+		want   int
+	}{
+		{name: "empty filter matches every entry", filter: "", want: 2},
+		{name: "filter without parentheses", filter: "objectClass=device", want: 1},
+		{name: "filter with parentheses", filter: "(objectClass=inetOrgPerson)", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testLDAPConfig(server.url())
+			cfg.UserFilter = tt.filter
+			users, err := NewLDAPDirectory(cfg).ListUsers()
+			if err != nil {
+				t.Fatalf("ListUsers() error = %v", err)
+			}
+			if len(users) != tt.want {
+				t.Fatalf("ListUsers() returned %d users, want %d", len(users), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginLockedError 登录失败次数过多，账号或来源IP被临时锁定
+type LoginLockedError struct {
+	Until time.Time
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("登录失败次数过多，请于 %s 后重试", e.Until.Format("2006-01-02 15:04:05"))
+}
+
+// RetryAfter 距离解锁的剩余时间
+func (e *LoginLockedError) RetryAfter() time.Duration {
+	if wait := time.Until(e.Until); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// throttleTarget 一次登录尝试涉及的计数维度
+type throttleTarget struct {
+	scope       model.LoginThrottleScope
+	identifier  string
+	maxFailures int
+}
+
+// loginThrottleTargets 账号维度优先使用用户ID，避免用户名、手机号、邮箱分别计数
+func (s *AuthService) loginThrottleTargets(login string, user *model.User, client SessionClient) []throttleTarget {
+	account := "login:" + strings.ToLower(strings.TrimSpace(login))
+	if user != nil {
+		account = strconv.FormatUint(uint64(user.ID), 10)
+	}
+
+	targets := []throttleTarget{{scope: model.LoginThrottleAccount, identifier: account, maxFailures: s.lockout.AccountMaxFailures}}
+	if client.IP != "" {
+		targets = append(targets, throttleTarget{scope: model.LoginThrottleIP, identifier: client.IP, maxFailures: s.lockout.IPMaxFailures})
+	}
+	return targets
+}
+
+// checkLoginLocked 任一维度处于锁定期内时拒绝登录，不再校验密码
+func (s *AuthService) checkLoginLocked(targets []throttleTarget) error {
+	now := time.Now()
+	var until time.Time
+	for _, target := range targets {
+		if target.maxFailures <= 0 {
+			continue
+		}
+		var throttle model.LoginThrottle
+		err := s.db.Where("scope = ? AND identifier = ? AND locked_until > ?", target.scope, target.identifier, now).
+			First(&throttle).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if throttle.LockedUntil.After(until) {
+			until = *throttle.LockedUntil
+		}
+	}
+
+	if !until.IsZero() {
+		return &LoginLockedError{Until: until}
+	}
+	return nil
+}
+
+// recordLoginFailure 累计失败次数，达到上限时锁定；本次失败触发锁定时返回 LoginLockedError
+func (s *AuthService) recordLoginFailure(targets []throttleTarget) error {
+	var until time.Time
+	for _, target := range targets {
+		if target.maxFailures <= 0 {
+			continue
+		}
+		lockedUntil, err := s.incrementThrottle(target)
+		if err != nil {
+			return err
+		}
+		if lockedUntil != nil && lockedUntil.After(until) {
+			until = *lockedUntil
+		}
+	}
+
+	if !until.IsZero() {
+		return &LoginLockedError{Until: until}
+	}
+	return nil
+}
+
+func (s *AuthService) incrementThrottle(target throttleTarget) (*time.Time, error) {
+	now := time.Now()
+	window := time.Duration(s.lockout.WindowMinutes) * time.Minute
+	maxLock := time.Duration(s.lockout.MaxLockMinutes) * time.Minute
+
+	var lockedUntil *time.Time
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.LoginThrottle{
+			Scope:       target.scope,
+			Identifier:  target.identifier,
+			WindowStart: now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var throttle model.LoginThrottle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("scope = ? AND identifier = ?", target.scope, target.identifier).
+			First(&throttle).Error; err != nil {
+			return err
+		}
+
+		// 上次锁定结束后长时间没有再被锁定，锁定时长从头计算
+		if throttle.LockedUntil != nil && now.Sub(*throttle.LockedUntil) > maxLock {
+			throttle.LockCount = 0
+		}
+		// 超出计数窗口后重新计数
+		if now.Sub(throttle.WindowStart) > window {
+			throttle.Failures = 0
+			throttle.WindowStart = now
+		}
+
+		throttle.Failures++
+		throttle.LastFailureAt = &now
+		if throttle.Failures >= target.maxFailures {
+			throttle.LockCount++
+			until := now.Add(s.lockDuration(throttle.LockCount))
+			throttle.LockedUntil = &until
+			throttle.Failures = 0
+			throttle.WindowStart = now
+			lockedUntil = &until
+		}
+		return tx.Save(&throttle).Error
+	})
+	return lockedUntil, err
+}
+
+// lockDuration 第 n 次锁定的时长：lock_minutes * 2^(n-1)，不超过 max_lock_minutes
+func (s *AuthService) lockDuration(lockCount int) time.Duration {
+	wait := time.Duration(s.lockout.LockMinutes) * time.Minute
+	maxLock := time.Duration(s.lockout.MaxLockMinutes) * time.Minute
+	for i := 1; i < lockCount; i++ {
+		wait *= 2
+		if maxLock > 0 && wait >= maxLock {
+			return maxLock
+		}
+	}
+	return wait
+}
+
+// clearLoginFailures 登录成功后只清零账号维度的失败计数。保留锁定次数，再次被锁定时锁定时长继续递增；
+// 来源IP维度不清除，避免攻击者登录自己的账号来重置同一IP的暴力破解计数
+func (s *AuthService) clearLoginFailures(targets []throttleTarget) {
+	for _, target := range targets {
+		if target.scope != model.LoginThrottleAccount {
+			continue
+		}
+		s.db.Model(&model.LoginThrottle{}).
+			Where("scope = ? AND identifier = ?", target.scope, target.identifier).
+			Updates(map[string]any{"failures": 0, "window_start": time.Now()})
+	}
+}
+
+// UnlockUser 解除用户账号维度的登录锁定，返回是否存在锁定
+func (s *AuthService) UnlockUser(userID uint) (bool, error) {
+	result := s.db.Where("scope = ? AND identifier = ? AND locked_until > ?",
+		model.LoginThrottleAccount, strconv.FormatUint(uint64(userID), 10), time.Now()).
+		Delete(&model.LoginThrottle{})
+	return result.RowsAffected > 0, result.Error
+}
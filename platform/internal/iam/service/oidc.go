@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// oidcStateTTL 发起登录到回调之间允许的最长时间
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	// ErrOIDCNotConfigured 未启用 OIDC 登录
+	ErrOIDCNotConfigured = errors.New("未启用 OIDC 登录")
+	// ErrOIDCInvalidState state 不存在、已使用或已过期
+	ErrOIDCInvalidState = errors.New("登录请求已失效，请重新登录")
+	// ErrOIDCRedirectNotAllowed 登录后跳转地址不在允许范围内
+	ErrOIDCRedirectNotAllowed = errors.New("不允许的跳转地址")
+	// ErrOIDCUserNotFound 找不到对应的本地用户且未开启自动创建
+	ErrOIDCUserNotFound = errors.New("未找到对应的平台用户，请联系管理员开通")
+)
+
+// OIDCClaims 从 ID Token 和 UserInfo 中取出的用户信息
+type OIDCClaims struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Phone    string
+	Name     string
+	Groups   []string
+}
+
+// OIDCService OIDC 授权码登录。身份提供方在首次使用时发现，发现失败会在下次请求时重试
+type OIDCService struct {
+	db   *gorm.DB
+	auth *AuthService
+	cfg  *config.OIDCConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+	oauth2   *oauth2.Config
+}
+
+// NewOIDCService 创建 OIDC 登录服务，登录成功后由 auth 创建会话并签发令牌
+func NewOIDCService(db *gorm.DB, auth *AuthService, cfg *config.OIDCConfig) *OIDCService {
+	return &OIDCService{db: db, auth: auth, cfg: cfg}
+}
+
+// discover 获取身份提供方端点并缓存
+func (s *OIDCService) discover(ctx context.Context) (*oidc.Provider, error) {
+	if !s.cfg.Enabled {
+		return nil, ErrOIDCNotConfigured
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, s.cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	s.provider = provider
+	s.verifier = provider.Verifier(&oidc.Config{ClientID: s.cfg.ClientID})
+	s.oauth2 = &oauth2.Config{
+		ClientID:     s.cfg.ClientID,
+		ClientSecret: s.cfg.ClientSecret,
+		RedirectURL:  s.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       s.cfg.Scopes,
+	}
+	return provider, nil
+}
+
+// AuthCodeURL 生成跳转到身份提供方的登录地址，redirect 为登录完成后的前端地址，可为空
+func (s *OIDCService) AuthCodeURL(ctx context.Context, redirect string) (string, error) {
+	if _, err := s.discover(ctx); err != nil {
+		return "", err
+	}
+	if redirect != "" && !s.redirectAllowed(redirect) {
+		return "", ErrOIDCRedirectNotAllowed
+	}
+
+	state := model.OIDCLoginState{
+		State:        randomToken(),
+		Nonce:        randomToken(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		RedirectURI:  redirect,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+	if err := s.db.Create(&state).Error; err != nil {
+		return "", err
+	}
+
+	// 顺带清理过期的登录状态
+	s.db.Where("expires_at < ?", time.Now()).Delete(&model.OIDCLoginState{})
+
+	return s.oauth2.AuthCodeURL(state.State,
+		oidc.Nonce(state.Nonce),
+		oauth2.S256ChallengeOption(state.CodeVerifier),
+	), nil
+}
+
+// Callback 校验 state，用授权码换取并校验 ID Token，匹配或创建本地用户后签发平台令牌。
+// 返回登录前指定的前端跳转地址
+func (s *OIDCService) Callback(ctx context.Context, stateValue string, code string, client SessionClient) (*LoginResponse, string, error) {
+	if _, err := s.discover(ctx); err != nil {
+		return nil, "", err
+	}
+
+	state, err := s.consumeState(stateValue)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.oauth2.Exchange(ctx, code, oauth2.VerifierOption(state.CodeVerifier))
+	if err != nil {
+		return nil, state.RedirectURI, fmt.Errorf("oidc code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, state.RedirectURI, errors.New("oidc: token response has no id_token")
+	}
+	idToken, err := s.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, state.RedirectURI, fmt.Errorf("oidc: invalid id_token: %w", err)
+	}
+	if idToken.Nonce != state.Nonce {
+		return nil, state.RedirectURI, errors.New("oidc: nonce mismatch")
+	}
+
+	claims, err := s.extractClaims(ctx, idToken, token)
+	if err != nil {
+		return nil, state.RedirectURI, err
+	}
+
+	user, err := s.resolveUser(claims)
+	if err != nil {
+		return nil, state.RedirectURI, err
+	}
+	if user.Status != 1 {
+		return nil, state.RedirectURI, errors.New("用户已被禁用")
+	}
+
+	response, err := s.auth.issueLogin(user, client)
+	if err != nil {
+		return nil, state.RedirectURI, err
+	}
+	return response, state.RedirectURI, nil
+}
+
+// consumeState 取出并删除登录状态，每个 state 只能使用一次
+func (s *OIDCService) consumeState(value string) (*model.OIDCLoginState, error) {
+	if value == "" {
+		return nil, ErrOIDCInvalidState
+	}
+
+	var state model.OIDCLoginState
+	result := s.db.Clauses(clause.Returning{}).Where("state = ?", value).Delete(&state)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || time.Now().After(state.ExpiresAt) {
+		return nil, ErrOIDCInvalidState
+	}
+	return &state, nil
+}
+
+// extractClaims 合并 UserInfo 与 ID Token 中的声明，ID Token 优先
+func (s *OIDCService) extractClaims(ctx context.Context, idToken *oidc.IDToken, token *oauth2.Token) (*OIDCClaims, error) {
+	raw := map[string]any{}
+	if s.provider.UserInfoEndpoint() != "" {
+		userInfo, err := s.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			logger.Warnf("oidc: failed to fetch userinfo: %v", err)
+		} else if userInfo.Subject == idToken.Subject {
+			if err := userInfo.Claims(&raw); err != nil {
+				logger.Warnf("oidc: failed to decode userinfo: %v", err)
+			}
+		}
+	}
+
+	tokenClaims := map[string]any{}
+	if err := idToken.Claims(&tokenClaims); err != nil {
+		return nil, fmt.Errorf("oidc: failed to decode id_token claims: %w", err)
+	}
+	for key, value := range tokenClaims {
+		raw[key] = value
+	}
+
+	return &OIDCClaims{
+		Issuer:   idToken.Issuer,
+		Subject:  idToken.Subject,
+		Username: claimString(raw, s.cfg.UsernameClaim),
+		Email:    claimString(raw, s.cfg.EmailClaim),
+		Phone:    claimString(raw, s.cfg.PhoneClaim),
+		Name:     claimString(raw, s.cfg.NameClaim),
+		Groups:   claimStrings(raw, s.cfg.GroupsClaim),
+	}, nil
+}
+
+// resolveUser 按已绑定的身份、匹配字段依次查找本地用户，找不到时按配置自动创建，并同步组映射的角色
+func (s *OIDCService) resolveUser(claims *OIDCClaims) (*model.User, error) {
+	var (
+		user         model.User
+		rolesChanged bool
+	)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var identity model.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", claims.Issuer, claims.Subject).First(&identity).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := s.matchOrProvision(tx, claims, &user); err != nil {
+				return err
+			}
+			identity = model.UserIdentity{UserID: user.ID, Provider: claims.Issuer, Subject: claims.Subject}
+		default:
+			return err
+		}
+
+		now := time.Now()
+		identity.Email = claims.Email
+		identity.LastLoginAt = &now
+		if err := tx.Save(&identity).Error; err != nil {
+			return err
+		}
+		changed, err := s.syncRoles(tx, user.ID, claims.Groups)
+		rolesChanged = changed
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rolesChanged {
+		s.auth.InvalidatePermissions()
+	}
+
+	if err := s.db.Preload("Roles").First(&user, user.ID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *OIDCService) matchOrProvision(tx *gorm.DB, claims *OIDCClaims, user *model.User) error {
+	var column, value string
+	switch s.cfg.MatchField {
+	case "username":
+		column, value = "username", claims.Username
+	case "phone":
+		column, value = "phone", claims.Phone
+	default:
+		column, value = "email", claims.Email
+	}
+
+	if value != "" {
+		// 服务账号不能通过单点登录使用
+		err := tx.Where(column+" = ? AND source <> ?", value, model.UserSourceService).First(user).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	if !s.cfg.AutoProvision {
+		return ErrOIDCUserNotFound
+	}
+	if claims.Username == "" || claims.Phone == "" {
+		return fmt.Errorf("%w: 身份信息缺少用户名或手机号，无法自动创建", ErrOIDCUserNotFound)
+	}
+
+	*user = model.User{
+		Username: claims.Username,
+		Phone:    claims.Phone,
+		Email:    claims.Email,
+		Nickname: claims.Name,
+		Status:   1,
+		Source:   model.UserSourceOIDC,
+	}
+	return tx.Create(user).Error
+}
+
+// syncRoles 按组映射增删角色：用户所在组映射的角色会被授予，不再所在组映射的角色会被移除。
+// 返回用户的角色是否发生了变化
+func (s *OIDCService) syncRoles(tx *gorm.DB, userID uint, groups []string) (bool, error) {
+	if len(s.cfg.RoleMappings) == 0 {
+		return false, nil
+	}
+
+	inGroup := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		inGroup[group] = true
+	}
+	granted := map[string]bool{}
+	managed := map[string]bool{}
+	for _, mapping := range s.cfg.RoleMappings {
+		managed[mapping.Role] = true
+		if inGroup[mapping.Group] {
+			granted[mapping.Role] = true
+		}
+	}
+
+	var roles []model.Role
+	if err := tx.Where("name IN ?", mapKeys(managed)).Find(&roles).Error; err != nil {
+		return false, err
+	}
+	changed := false
+	for _, role := range roles {
+		var result *gorm.DB
+		if granted[string(role.Name)] {
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&model.UserRole{UserID: userID, RoleID: role.ID})
+		} else {
+			result = tx.Where("user_id = ? AND role_id = ?", userID, role.ID).Delete(&model.UserRole{})
+		}
+		if result.Error != nil {
+			return false, result.Error
+		}
+		changed = changed || result.RowsAffected > 0
+	}
+	return changed, nil
+}
+
+// redirectAllowed 跳转地址必须以配置的前缀之一开头，防止开放重定向
+func (s *OIDCService) redirectAllowed(redirect string) bool {
+	parsed, err := url.Parse(redirect)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+	for _, prefix := range s.cfg.AllowedRedirects {
+		if prefix != "" && strings.HasPrefix(redirect, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func claimString(claims map[string]any, name string) string {
+	if name == "" {
+		return ""
+	}
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// claimStrings 组声明可能是字符串数组，也可能是单个字符串
+func claimStrings(claims map[string]any, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func mapKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func randomToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
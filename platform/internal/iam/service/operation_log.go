@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/csvsafe"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+const exportBatchSize = 500
+
+// OperationLogService 操作日志查询、导出与过期清理
+type OperationLogService struct {
+	db *gorm.DB
+}
+
+func NewOperationLogService(db *gorm.DB) *OperationLogService {
+	return &OperationLogService{db: db}
+}
+
+// QueryLogs 分页查询操作日志，按时间倒序
+func (s *OperationLogService) QueryLogs(query *model.OperationLogQuery) (*model.PaginationResponse, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := s.applyFilters(s.db.Model(&model.OperationLog{}), query)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var logs []model.OperationLog
+	if err := db.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	return &model.PaginationResponse{
+		Items:      logs,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+	}, nil
+}
+
+// ExportCSV 按查询条件导出全部日志为 CSV（不分页），用户输入的内容按文本写出，不会被当作公式
+func (s *OperationLogService) ExportCSV(w io.Writer, query *model.OperationLogQuery) error {
+	writer := csvsafe.NewWriter(w)
+
+	header := []string{"ID", "时间", "服务", "操作", "用户ID", "用户名", "昵称", "资源类型", "资源ID", "空间ID", "结果", "错误信息", "IP", "补充信息", "变更前", "变更后"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	var batch []model.OperationLog
+	err := s.applyFilters(s.db.Model(&model.OperationLog{}), query).
+		Order("id ASC").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, log := range batch {
+				record := []string{
+					strconv.FormatUint(uint64(log.ID), 10),
+					log.CreatedAt.Format(time.RFC3339),
+					log.Service,
+					string(log.Action),
+					strconv.FormatUint(uint64(log.UserID), 10),
+					log.Username,
+					log.Nickname,
+					log.ResourceType,
+					log.ResourceID,
+					strconv.FormatUint(uint64(log.SpaceID), 10),
+					string(log.Result),
+					log.ErrorMessage,
+					log.IP,
+					log.Detail,
+					log.Before,
+					log.After,
+				}
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+			}
+			writer.Flush()
+			return writer.Error()
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// PurgeExpired 删除超过保留天数的日志，返回删除条数
+func (s *OperationLogService) PurgeExpired(retentionDays int) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	result := s.db.Where("created_at < ?", cutoff).Delete(&model.OperationLog{})
+	return result.RowsAffected, result.Error
+}
+
+// StartRetention 按保留策略每天清理一次过期日志，直到 ctx 结束
+func (s *OperationLogService) StartRetention(ctx context.Context, retentionDays int) {
+	if retentionDays <= 0 {
+		logger.Infof("operation log retention disabled")
+		return
+	}
+
+	purge := func() {
+		deleted, err := s.PurgeExpired(retentionDays)
+		if err != nil {
+			logger.Errorf("failed to purge operation logs: %v", err)
+			return
+		}
+		if deleted > 0 {
+			logger.Infof("purged %d operation logs older than %d days", deleted, retentionDays)
+		}
+	}
+
+	purge()
+
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purge()
+		}
+	}
+}
+
+func (s *OperationLogService) applyFilters(db *gorm.DB, query *model.OperationLogQuery) *gorm.DB {
+	if query.UserID > 0 {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.Service != "" {
+		db = db.Where("service = ?", query.Service)
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.ResourceType != "" {
+		db = db.Where("resource_type = ?", query.ResourceType)
+	}
+	if query.ResourceID != "" {
+		db = db.Where("resource_id = ?", query.ResourceID)
+	}
+	if query.SpaceID > 0 {
+		db = db.Where("space_id = ?", query.SpaceID)
+	}
+	if query.Result != "" {
+		db = db.Where("result = ?", query.Result)
+	}
+	if query.StartTime != nil {
+		db = db.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("created_at <= ?", *query.EndTime)
+	}
+	return db
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestOperationLogExportCSV(t *testing.T) {
+	db := dbtest.Open(t, &model.OperationLog{})
+	logs := []model.OperationLog{
+		{Service: "kb", Action: model.OperationAction("document.search"), UserID: 1, Username: "alice", Nickname: "=1+1", Detail: "@SUM(A1:A9)", Result: model.OperationResultSuccess},
+		{Service: "iam", Action: model.OperationAction("user.update"), UserID: 2, Username: "bob", Nickname: "Bob", ErrorMessage: "-fail", Result: model.OperationResultFailure},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		query   model.OperationLogQuery
+		want    int
+		checkFn func(t *testing.T, rows [][]string)
+	}{
+		{
+			name:  "formula cells are escaped",
+			query: model.OperationLogQuery{UserID: 1},
+			want:  1,
+			checkFn: func(t *testing.T, rows [][]string) {
+				if rows[1][6] != "'=1+1" || rows[1][13] != "'@SUM(A1:A9)" {
+					t.Fatalf("nickname/detail = %q/%q, want escaped", rows[1][6], rows[1][13])
+				}
+			},
+		},
+		{
+			name:  "filter by result",
+			query: model.OperationLogQuery{Result: string(model.OperationResultFailure)},
+			want:  1,
+			checkFn: func(t *testing.T, rows [][]string) {
+				if rows[1][5] != "bob" || rows[1][11] != "'-fail" {
+					t.Fatalf("username/error = %q/%q", rows[1][5], rows[1][11])
+				}
+			},
+		},
+		{name: "all logs", want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewOperationLogService(db).ExportCSV(&buf, &tt.query); err != nil {
+				t.Fatalf("ExportCSV() error = %v", err)
+			}
+			rows, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatalf("parse csv: %v", err)
+			}
+			if len(rows)-1 != tt.want {
+				t.Fatalf("exported %d rows, want %d", len(rows)-1, tt.want)
+			}
+			if tt.checkFn != nil {
+				tt.checkFn(t, rows)
+			}
+		})
+	}
+}
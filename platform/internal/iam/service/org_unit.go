@@ -0,0 +1,455 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOrgUnitNotFound 组织单元不存在
+	ErrOrgUnitNotFound = errors.New("组织单元不存在")
+	// ErrOrgUnitInvalidParent 上级单元不存在，或移动到了自身及其下级单元之下
+	ErrOrgUnitInvalidParent = errors.New("上级组织单元无效")
+	// ErrOrgUnitInvalidStatus 组织单元状态不合法
+	ErrOrgUnitInvalidStatus = errors.New("status 只能为 0 或 1")
+	// ErrOrgUnitInUse 组织单元下仍有下级单元或成员
+	ErrOrgUnitInUse = errors.New("组织单元下仍有下级单元或成员，请先移除")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrOrgUnitUserNotFound 用户不存在或已禁用
+	ErrOrgUnitUserNotFound = errors.New("用户不存在或已禁用")
+	// ErrDepartmentHeadNotFound 用户所在组织及其上级都没有可用的负责人
+	ErrDepartmentHeadNotFound = errors.New("未找到部门负责人")
+)
+
+// OrgUnitService 组织架构管理
+type OrgUnitService struct {
+	db *gorm.DB
+}
+
+func NewOrgUnitService(db *gorm.DB) *OrgUnitService {
+	return &OrgUnitService{db: db}
+}
+
+// List 获取全部组织单元，按层级和排序号排列
+func (s *OrgUnitService) List() ([]model.OrgUnit, error) {
+	var units []model.OrgUnit
+	if err := s.db.Preload("Head").Order("path").Order("sort").Order("id").Find(&units).Error; err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+// Tree 获取组织树，rootID 为 0 时返回整棵树，否则返回以该单元为根的子树
+func (s *OrgUnitService) Tree(rootID uint) ([]*model.OrgUnitNode, error) {
+	db := s.db.Preload("Head")
+	if rootID != 0 {
+		root, err := s.Get(rootID)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("path LIKE ?", root.Path+"%")
+	}
+
+	var units []model.OrgUnit
+	if err := db.Order("sort").Order("id").Find(&units).Error; err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*model.OrgUnitNode, len(units))
+	for _, unit := range units {
+		nodes[unit.ID] = &model.OrgUnitNode{OrgUnit: unit, Children: []*model.OrgUnitNode{}}
+	}
+	roots := make([]*model.OrgUnitNode, 0)
+	for _, unit := range units {
+		node := nodes[unit.ID]
+		if parent, ok := nodes[parentID(unit)]; ok && unit.ID != rootID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// Get 获取组织单元
+func (s *OrgUnitService) Get(id uint) (*model.OrgUnit, error) {
+	var unit model.OrgUnit
+	if err := s.db.Preload("Head").First(&unit, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrgUnitNotFound
+		}
+		return nil, err
+	}
+	return &unit, nil
+}
+
+// Create 创建组织单元
+func (s *OrgUnitService) Create(req *model.CreateOrgUnitRequest) (*model.OrgUnit, error) {
+	unit := model.OrgUnit{
+		Name:     strings.TrimSpace(req.Name),
+		Type:     req.Type,
+		ParentID: req.ParentID,
+		Sort:     req.Sort,
+		Status:   1,
+	}
+	if unit.ParentID != nil && *unit.ParentID == 0 {
+		unit.ParentID = nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		parentPath := "/"
+		if unit.ParentID != nil {
+			var parent model.OrgUnit
+			if err := tx.First(&parent, *unit.ParentID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrOrgUnitInvalidParent
+				}
+				return err
+			}
+			parentPath = parent.Path
+		}
+		if req.HeadUserID != nil {
+			if err := checkActiveUser(tx, *req.HeadUserID); err != nil {
+				return err
+			}
+			unit.HeadUserID = req.HeadUserID
+		}
+
+		if err := tx.Create(&unit).Error; err != nil {
+			return err
+		}
+		unit.Path = fmt.Sprintf("%s%d/", parentPath, unit.ID)
+		return tx.Model(&unit).Update("path", unit.Path).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(unit.ID)
+}
+
+// Update 更新组织单元。修改上级时同步更新整棵子树的路径；名称变化时同步成员的部门、企业名称
+func (s *OrgUnitService) Update(id uint, req *model.UpdateOrgUnitRequest) (*model.OrgUnit, error) {
+	unit, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{}
+		if req.Name != nil {
+			updates["name"] = strings.TrimSpace(*req.Name)
+		}
+		if req.Type != nil {
+			updates["type"] = *req.Type
+		}
+		if req.Sort != nil {
+			updates["sort"] = *req.Sort
+		}
+		if req.Status != nil {
+			if *req.Status != 0 && *req.Status != 1 {
+				return ErrOrgUnitInvalidStatus
+			}
+			updates["status"] = *req.Status
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(unit).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		if req.ParentID != nil && *req.ParentID != parentID(*unit) {
+			if err := moveOrgUnit(tx, unit, *req.ParentID); err != nil {
+				return err
+			}
+		}
+
+		if req.Name != nil || req.Type != nil || req.ParentID != nil {
+			return syncSubtreeMemberNames(tx, unit.Path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// moveOrgUnit 把组织单元移动到新的上级之下，newParentID 为 0 表示移到根级
+func moveOrgUnit(tx *gorm.DB, unit *model.OrgUnit, newParentID uint) error {
+	newParentPath := "/"
+	var parent *uint
+	if newParentID != 0 {
+		var target model.OrgUnit
+		if err := tx.First(&target, newParentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrgUnitInvalidParent
+			}
+			return err
+		}
+		// 不能移动到自身或自身的下级单元之下
+		if strings.HasPrefix(target.Path, unit.Path) {
+			return ErrOrgUnitInvalidParent
+		}
+		newParentPath = target.Path
+		parent = &newParentID
+	}
+
+	oldPath := unit.Path
+	newPath := fmt.Sprintf("%s%d/", newParentPath, unit.ID)
+	if err := tx.Model(unit).Update("parent_id", parent).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.OrgUnit{}).Where("path LIKE ?", oldPath+"%").
+		Update("path", gorm.Expr("CONCAT(?::text, SUBSTRING(path FROM ?))", newPath, len(oldPath)+1)).Error; err != nil {
+		return err
+	}
+	unit.ParentID = parent
+	unit.Path = newPath
+	return nil
+}
+
+// Delete 删除组织单元，仍有下级单元或成员时不允许删除
+func (s *OrgUnitService) Delete(id uint) (*model.OrgUnit, error) {
+	unit, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var children, members int64
+		if err := tx.Model(&model.OrgUnit{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.User{}).Where("org_unit_id = ?", id).Count(&members).Error; err != nil {
+			return err
+		}
+		if children > 0 || members > 0 {
+			return ErrOrgUnitInUse
+		}
+		return tx.Delete(unit).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+// SetHead 设置或取消部门负责人，负责人不要求是本单元成员
+func (s *OrgUnitService) SetHead(id uint, userID *uint) (*model.OrgUnit, error) {
+	unit, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if userID != nil && *userID == 0 {
+		userID = nil
+	}
+	if userID != nil {
+		if err := checkActiveUser(s.db, *userID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.db.Model(unit).Update("head_user_id", userID).Error; err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// Members 获取组织单元的成员，includeSub 为真时包含全部下级单元的成员
+func (s *OrgUnitService) Members(id uint, includeSub bool) ([]model.User, error) {
+	unit, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.db.Model(&model.User{})
+	if includeSub {
+		db = db.Where("org_unit_id IN (?)", s.db.Model(&model.OrgUnit{}).Select("id").Where("path LIKE ?", unit.Path+"%"))
+	} else {
+		db = db.Where("org_unit_id = ?", id)
+	}
+
+	var users []model.User
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// AssignUser 调整用户所属组织单元，并把部门、企业名称同步到用户资料，orgUnitID 为空表示移出组织
+func (s *OrgUnitService) AssignUser(userID uint, orgUnitID *uint) (*model.User, error) {
+	if orgUnitID != nil && *orgUnitID == 0 {
+		orgUnitID = nil
+	}
+
+	var user model.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		updates := map[string]any{"org_unit_id": orgUnitID}
+		if orgUnitID != nil {
+			var unit model.OrgUnit
+			if err := tx.First(&unit, *orgUnitID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrOrgUnitNotFound
+				}
+				return err
+			}
+			department, company, err := orgUnitNames(tx, &unit)
+			if err != nil {
+				return err
+			}
+			updates["department"], updates["company"] = department, company
+		}
+		return tx.Model(&user).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Preload("Roles").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DepartmentHead 查找用户的部门负责人：从用户所在单元逐级向上，取第一个已设置、可用且不是用户本人的负责人
+func (s *OrgUnitService) DepartmentHead(userID uint) (*model.User, error) {
+	var user model.User
+	if err := s.db.Select("id", "org_unit_id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.OrgUnitID == nil {
+		return nil, ErrDepartmentHeadNotFound
+	}
+
+	var unit model.OrgUnit
+	if err := s.db.First(&unit, *user.OrgUnitID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentHeadNotFound
+		}
+		return nil, err
+	}
+
+	// 祖先单元按路径从近到远排列
+	ancestors, err := loadAncestors(s.db, &unit)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		headID := ancestors[i].HeadUserID
+		if headID == nil || *headID == userID || ancestors[i].Status != 1 {
+			continue
+		}
+		var head model.User
+		err := s.db.Where("id = ? AND status = 1", *headID).First(&head).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &head, nil
+	}
+	return nil, ErrDepartmentHeadNotFound
+}
+
+// loadAncestors 按从根到自身的顺序返回组织单元及其全部上级
+func loadAncestors(db *gorm.DB, unit *model.OrgUnit) ([]model.OrgUnit, error) {
+	ids := pathIDs(unit.Path)
+	var units []model.OrgUnit
+	if err := db.Where("id IN ?", ids).Find(&units).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]model.OrgUnit, len(units))
+	for _, u := range units {
+		byID[u.ID] = u
+	}
+	ordered := make([]model.OrgUnit, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := byID[id]; ok {
+			ordered = append(ordered, u)
+		}
+	}
+	return ordered, nil
+}
+
+// orgUnitNames 计算组织单元对应的部门名称和所属企业名称：部门取自身名称（企业单元为空），企业取最近的企业单元
+func orgUnitNames(db *gorm.DB, unit *model.OrgUnit) (string, string, error) {
+	ancestors, err := loadAncestors(db, unit)
+	if err != nil {
+		return "", "", err
+	}
+	var department, company string
+	if unit.Type == model.OrgUnitTypeDepartment {
+		department = unit.Name
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if ancestors[i].Type == model.OrgUnitTypeCompany {
+			company = ancestors[i].Name
+			break
+		}
+	}
+	return department, company, nil
+}
+
+// syncSubtreeMemberNames 组织单元改名、改类型或移动后，重新计算子树内成员的部门和企业名称
+func syncSubtreeMemberNames(tx *gorm.DB, path string) error {
+	var units []model.OrgUnit
+	if err := tx.Where("path LIKE ?", path+"%").Find(&units).Error; err != nil {
+		return err
+	}
+	for i := range units {
+		department, company, err := orgUnitNames(tx, &units[i])
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&model.User{}).Where("org_unit_id = ?", units[i].ID).
+			Updates(map[string]any{"department": department, "company": company}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkActiveUser(db *gorm.DB, userID uint) error {
+	var count int64
+	if err := db.Model(&model.User{}).Where("id = ? AND status = 1", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrOrgUnitUserNotFound
+	}
+	return nil
+}
+
+func parentID(unit model.OrgUnit) uint {
+	if unit.ParentID == nil {
+		return 0
+	}
+	return *unit.ParentID
+}
+
+// pathIDs 解析 /1/5/12/ 形式的路径
+func pathIDs(path string) []uint {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	ids := make([]uint, 0, len(parts))
+	for _, part := range parts {
+		var id uint
+		if _, err := fmt.Sscan(part, &id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPasswordPolicy 密码不符合密码策略，具体原因附在错误信息后
+	ErrPasswordPolicy = errors.New("密码不符合安全策略")
+	// ErrPasswordExpired 密码已过期，需要修改密码后才能登录
+	ErrPasswordExpired = errors.New("密码已过期，请修改密码后重新登录")
+)
+
+// validatePassword 按密码策略检查长度和字符类别，密码中不能包含用户名
+func (s *AuthService) validatePassword(password string, username string) error {
+	policy := s.passwordPolicy
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return fmt.Errorf("%w: 长度不能少于 %d 位", ErrPasswordPolicy, policy.MinLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{upper, lower, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < policy.MinCharClasses {
+		return fmt.Errorf("%w: 需包含大写字母、小写字母、数字、特殊字符中的至少 %d 类", ErrPasswordPolicy, policy.MinCharClasses)
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("%w: 不能包含用户名", ErrPasswordPolicy)
+	}
+	return nil
+}
+
+// passwordExpired 密码是否已超过有效期，从未修改过密码的用户按创建时间计算，目录用户不受本地有效期限制
+func (s *AuthService) passwordExpired(user *model.User) bool {
+	if s.passwordPolicy.ExpireDays <= 0 || user.Source == model.UserSourceLDAP {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return time.Since(changedAt) > time.Duration(s.passwordPolicy.ExpireDays)*24*time.Hour
+}
+
+// setPassword 校验新密码并更新已有用户的密码，同时记录密码历史。
+// 新密码不能与当前密码及最近 history_count 次密码相同
+func (s *AuthService) setPassword(tx *gorm.DB, user *model.User, password string) error {
+	if err := s.validatePassword(password, user.Username); err != nil {
+		return err
+	}
+
+	hashes := []string{user.Password}
+	if s.passwordPolicy.HistoryCount > 0 {
+		var history []string
+		if err := tx.Model(&model.PasswordHistory{}).
+			Where("user_id = ?", user.ID).
+			Order("id DESC").
+			Limit(s.passwordPolicy.HistoryCount).
+			Pluck("password_hash", &history).Error; err != nil {
+			return err
+		}
+		hashes = append(hashes, history...)
+	}
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return fmt.Errorf("%w: 不能与最近 %d 次使用过的密码相同", ErrPasswordPolicy, max(s.passwordPolicy.HistoryCount, 1))
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := tx.Model(user).Updates(map[string]any{
+		"password":            string(hashedPassword),
+		"password_changed_at": &now,
+	}).Error; err != nil {
+		return err
+	}
+	user.Password = string(hashedPassword)
+	user.PasswordChangedAt = &now
+
+	return s.recordPasswordHistory(tx, user)
+}
+
+// recordPasswordHistory 记录用户当前密码，只保留策略需要的历史记录
+func (s *AuthService) recordPasswordHistory(tx *gorm.DB, user *model.User) error {
+	if err := tx.Create(&model.PasswordHistory{UserID: user.ID, PasswordHash: user.Password}).Error; err != nil {
+		return err
+	}
+
+	keep := max(s.passwordPolicy.HistoryCount, 1)
+	return tx.Where("user_id = ? AND id NOT IN (?)", user.ID,
+		tx.Model(&model.PasswordHistory{}).Select("id").Where("user_id = ?", user.ID).Order("id DESC").Limit(keep)).
+		Delete(&model.PasswordHistory{}).Error
+}
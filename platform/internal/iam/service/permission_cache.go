@@ -0,0 +1,107 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
+)
+
+type permissionCacheKey struct {
+	userID   uint
+	spaceID  uint
+	resource string
+	action   string
+	owner    bool // 用户是否为资源创建者，仅限本人的权限只与这一点有关
+}
+
+type permissionCacheEntry struct {
+	allowed   bool
+	version   uint64
+	expiresAt time.Time
+}
+
+// PermissionCache 带版本号的权限判定缓存。写入角色、权限、空间成员的服务在提交后调用 Invalidate
+// 递增版本号，旧版本的条目随即失效；判定期间版本号发生变化时结果不写入缓存
+type PermissionCache struct {
+	ttl        time.Duration
+	maxEntries int
+	version    atomic.Uint64
+
+	mu      sync.RWMutex
+	entries map[permissionCacheKey]permissionCacheEntry
+}
+
+// NewPermissionCache 创建权限判定缓存，有效期为 0 时返回 nil，即不缓存
+func NewPermissionCache(cfg *config.PermissionCacheConfig) *PermissionCache {
+	if cfg.TTLSeconds <= 0 {
+		return nil
+	}
+	return &PermissionCache{
+		ttl:        time.Duration(cfg.TTLSeconds) * time.Second,
+		maxEntries: cfg.MaxEntries,
+		entries:    make(map[permissionCacheKey]permissionCacheEntry),
+	}
+}
+
+// Version 当前版本号
+func (c *PermissionCache) Version() uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.version.Load()
+}
+
+// Invalidate 递增版本号并清空缓存
+func (c *PermissionCache) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.version.Add(1)
+	c.mu.Lock()
+	c.entries = make(map[permissionCacheKey]permissionCacheEntry)
+	c.mu.Unlock()
+}
+
+// Get 读取当前版本下未过期的判定结果
+func (c *PermissionCache) Get(userID uint, check model.PermissionCheck) (bool, bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.RLock()
+	entry, ok := c.entries[newPermissionCacheKey(userID, check)]
+	c.mu.RUnlock()
+	if !ok || entry.version != c.version.Load() || time.Now().After(entry.expiresAt) {
+		return false, false
+	}
+	return entry.allowed, true
+}
+
+// Put 写入判定结果，version 为开始判定前读取的版本号，期间发生变更时放弃写入
+func (c *PermissionCache) Put(userID uint, check model.PermissionCheck, version uint64, allowed bool) {
+	if c == nil || version != c.version.Load() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.entries = make(map[permissionCacheKey]permissionCacheEntry)
+	}
+	c.entries[newPermissionCacheKey(userID, check)] = permissionCacheEntry{
+		allowed:   allowed,
+		version:   version,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+func newPermissionCacheKey(userID uint, check model.PermissionCheck) permissionCacheKey {
+	return permissionCacheKey{
+		userID:   userID,
+		spaceID:  check.SpaceID,
+		resource: check.Resource,
+		action:   check.Action,
+		owner:    check.OwnerID != 0 && check.OwnerID == userID,
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+// UsePermissionCache 启用权限判定缓存，写入角色、权限、空间成员后需调用 InvalidatePermissions
+func (s *AuthService) UsePermissionCache(cache *PermissionCache) {
+	s.permissions = cache
+}
+
+// InvalidatePermissions 使权限判定缓存失效，在角色、权限、空间成员的写入提交后调用
+func (s *AuthService) InvalidatePermissions() {
+	s.permissions.Invalidate()
+}
+
+// CheckPermissions 批量检查用户权限，结果按检查项顺序返回。
+// 超级管理员和拥有对应权限的企业管理员跨空间生效；指定空间时按用户在该空间内的角色和空间角色权限表判断，
+// OwnerID 为资源创建者，用于判断仅限本人的权限；未指定空间时按用户的全局角色判断
+func (s *AuthService) CheckPermissions(userID uint, checks []model.PermissionCheck) ([]model.PermissionCheckResult, error) {
+	// 用户状态不缓存，每次都检查
+	var user model.User
+	if err := s.db.Select("id", "status").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.Status != 1 {
+		return nil, errors.New("用户已被禁用")
+	}
+
+	version := s.permissions.Version()
+	results := make([]model.PermissionCheckResult, len(checks))
+	pending := make([]int, 0, len(checks))
+	for i, check := range checks {
+		results[i].PermissionCheck = check
+		if allowed, ok := s.permissions.Get(userID, check); ok {
+			results[i].HasPermission = allowed
+			continue
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	evaluator, err := s.newPermissionEvaluator(userID, checks, pending)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range pending {
+		allowed := evaluator.allows(checks[i])
+		results[i].HasPermission = allowed
+		s.permissions.Put(userID, checks[i], version, allowed)
+	}
+	return results, nil
+}
+
+// permissionEvaluator 一次批量检查所需的数据，只在缓存未命中时加载
+type permissionEvaluator struct {
+	userID  uint
+	roles   []model.Role
+	members map[uint][]model.SpaceMemberRole // 空间ID -> 用户在该空间的角色
+	grants  []spaceRoleGrant
+}
+
+func (s *AuthService) newPermissionEvaluator(userID uint, checks []model.PermissionCheck, pending []int) (*permissionEvaluator, error) {
+	var user model.User
+	if err := s.db.Preload("Roles.Permissions").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	evaluator := &permissionEvaluator{
+		userID:  userID,
+		roles:   user.Roles,
+		members: make(map[uint][]model.SpaceMemberRole),
+	}
+
+	spaceIDs := make([]uint, 0, len(pending))
+	for _, i := range pending {
+		if checks[i].SpaceID > 0 {
+			spaceIDs = append(spaceIDs, checks[i].SpaceID)
+		}
+	}
+	if len(spaceIDs) == 0 {
+		return evaluator, nil
+	}
+
+	var members []model.SpaceMember
+	if err := s.db.Where("user_id = ? AND space_id IN ?", userID, spaceIDs).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		evaluator.members[member.SpaceID] = member.Roles
+	}
+	if len(members) > 0 {
+		grants, err := loadSpaceRoleGrants(s.db)
+		if err != nil {
+			return nil, err
+		}
+		evaluator.grants = grants
+	}
+	return evaluator, nil
+}
+
+func (e *permissionEvaluator) allows(check model.PermissionCheck) bool {
+	for _, role := range e.roles {
+		// 超级管理员拥有万能权限，不受空间限制
+		if role.Name == model.RoleSuperAdmin {
+			return true
+		}
+
+		// 企业管理员拥有跨空间权限，但需要检查具体权限
+		if role.Name == model.RoleEnterpriseAdmin && roleHasPermission(role, check.Resource, check.Action) {
+			return true
+		}
+	}
+
+	// 其他角色按空间角色权限表判断，用户不在该空间中则无权限
+	if check.SpaceID > 0 {
+		roles, ok := e.members[check.SpaceID]
+		if !ok {
+			return false
+		}
+		owner := check.OwnerID != 0 && check.OwnerID == e.userID
+		return spaceRoleAllows(e.grants, roles, check.Resource, check.Action, owner)
+	}
+
+	for _, role := range e.roles {
+		if roleHasPermission(role, check.Resource, check.Action) {
+			return true
+		}
+	}
+	return false
+}
+
+func roleHasPermission(role model.Role, resource, action string) bool {
+	for _, permission := range role.Permissions {
+		if permission.Resource == resource && permission.Action == action {
+			return true
+		}
+	}
+	return false
+}
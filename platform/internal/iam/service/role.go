@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRoleNotFound 角色不存在
+	ErrRoleNotFound = errors.New("角色不存在")
+	// ErrRoleExists 角色名已存在
+	ErrRoleExists = errors.New("角色名已存在")
+	// ErrRoleInvalidName 角色名不合法
+	ErrRoleInvalidName = errors.New("角色名只能包含小写字母、数字和下划线，且以字母开头")
+	// ErrRoleInvalidStatus 角色状态不合法
+	ErrRoleInvalidStatus = errors.New("status 只能为 0 或 1")
+	// ErrRoleBuiltin 内置角色不能删除、改名或停用
+	ErrRoleBuiltin = errors.New("内置角色不允许此操作")
+	// ErrRoleInUse 角色仍分配给用户
+	ErrRoleInUse = errors.New("角色仍分配给用户，请先移除")
+	// ErrPermissionNotFound 权限不在权限目录中
+	ErrPermissionNotFound = errors.New("权限不存在")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// RoleService 角色与权限管理
+type RoleService struct {
+	db    *gorm.DB
+	cache *PermissionCache
+}
+
+func NewRoleService(db *gorm.DB) *RoleService {
+	return &RoleService{db: db}
+}
+
+// UsePermissionCache 修改角色状态和权限后使权限判定缓存失效
+func (s *RoleService) UsePermissionCache(cache *PermissionCache) {
+	s.cache = cache
+}
+
+// SyncPermissions 把权限目录写入权限表：新增的权限自动创建，已有权限更新显示名、资源和操作。
+// 超级管理员拥有全部权限，新权限同时授予超级管理员
+func (s *RoleService) SyncPermissions() error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, definition := range model.PermissionCatalog {
+			permission := model.Permission{
+				Name:        definition.Name,
+				DisplayName: definition.DisplayName,
+				Description: definition.Group,
+				Resource:    definition.Resource,
+				Action:      definition.Action,
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "name"}},
+				DoUpdates: clause.AssignmentColumns([]string{"display_name", "description", "resource", "action", "updated_at"}),
+			}).Create(&permission).Error; err != nil {
+				return fmt.Errorf("failed to sync permission %s: %w", definition.Name, err)
+			}
+		}
+
+		var superAdmin model.Role
+		err := tx.Where("name = ?", model.RoleSuperAdmin).First(&superAdmin).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO role_permissions (role_id, permission_id)
+			SELECT ?, id FROM permissions WHERE deleted_at IS NULL
+			ON CONFLICT DO NOTHING`, superAdmin.ID).Error
+	})
+}
+
+// Catalog 权限目录，附带权限表中的ID，便于为角色分配权限
+func (s *RoleService) Catalog() ([]model.Permission, error) {
+	names := make([]model.PermissionName, 0, len(model.PermissionCatalog))
+	for _, definition := range model.PermissionCatalog {
+		names = append(names, definition.Name)
+	}
+
+	var permissions []model.Permission
+	if err := s.db.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	// 按目录顺序返回
+	byName := make(map[model.PermissionName]model.Permission, len(permissions))
+	for _, permission := range permissions {
+		byName[permission.Name] = permission
+	}
+	ordered := make([]model.Permission, 0, len(permissions))
+	for _, name := range names {
+		if permission, ok := byName[name]; ok {
+			ordered = append(ordered, permission)
+		}
+	}
+	return ordered, nil
+}
+
+// CreateRole 创建自定义角色并分配权限
+func (s *RoleService) CreateRole(req *model.CreateRoleRequest) (*model.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, ErrRoleInvalidName
+	}
+	if model.RoleName(req.Name).IsBuiltin() {
+		return nil, ErrRoleExists
+	}
+
+	role := model.Role{
+		Name:        model.RoleName(req.Name),
+		DisplayName: req.DisplayName,
+		Description: req.Description,
+		Status:      1,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&model.Role{}).Where("name = ?", req.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrRoleExists
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return s.replacePermissions(tx, &role, req.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRole(role.ID)
+}
+
+// GetRole 获取角色及其权限
+func (s *RoleService) GetRole(id uint) (*model.Role, error) {
+	var role model.Role
+	if err := s.db.Preload("Permissions").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// UpdateRole 更新角色显示名、描述和状态，内置角色不能停用
+func (s *RoleService) UpdateRole(id uint, req *model.UpdateRoleRequest) (*model.Role, error) {
+	role, err := s.GetRole(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{}
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Status != nil {
+		if *req.Status != 0 && *req.Status != 1 {
+			return nil, ErrRoleInvalidStatus
+		}
+		if *req.Status == 0 && role.Name.IsBuiltin() {
+			return nil, ErrRoleBuiltin
+		}
+		updates["status"] = *req.Status
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(role).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		s.cache.Invalidate()
+	}
+	return s.GetRole(id)
+}
+
+// DeleteRole 删除自定义角色，仍有用户使用时拒绝删除
+func (s *RoleService) DeleteRole(id uint) (*model.Role, error) {
+	role, err := s.GetRole(id)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name.IsBuiltin() {
+		return nil, ErrRoleBuiltin
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.UserRole{}).Where("role_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrRoleInUse
+		}
+		if err := tx.Where("role_id = ?", id).Delete(&model.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Invalidate()
+	return role, nil
+}
+
+// SetRolePermissions 用给定的权限替换角色的全部权限。超级管理员始终拥有全部权限，不能修改
+func (s *RoleService) SetRolePermissions(id uint, names []model.PermissionName) (*model.Role, error) {
+	role, err := s.GetRole(id)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == model.RoleSuperAdmin {
+		return nil, ErrRoleBuiltin
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.replacePermissions(tx, role, names)
+	}); err != nil {
+		return nil, err
+	}
+	s.cache.Invalidate()
+	return s.GetRole(id)
+}
+
+func (s *RoleService) replacePermissions(tx *gorm.DB, role *model.Role, names []model.PermissionName) error {
+	var permissions []model.Permission
+	if len(names) > 0 {
+		if err := tx.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+			return err
+		}
+	}
+	found := make(map[model.PermissionName]bool, len(permissions))
+	for _, permission := range permissions {
+		found[permission.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return fmt.Errorf("%w: %s", ErrPermissionNotFound, name)
+		}
+	}
+
+	if err := tx.Where("role_id = ?", role.ID).Delete(&model.RolePermission{}).Error; err != nil {
+		return err
+	}
+	for _, permission := range permissions {
+		if err := tx.Create(&model.RolePermission{RoleID: role.ID, PermissionID: permission.ID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
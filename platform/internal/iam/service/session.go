@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSessionNotFound    = errors.New("会话不存在")
+	ErrSessionRevoked     = errors.New("会话已失效，请重新登录")
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用，会话已失效，请重新登录")
+)
+
+// SessionClient 发起登录的客户端信息
+type SessionClient struct {
+	IP        string
+	UserAgent string
+}
+
+// createSession 为一次登录创建会话
+func (s *AuthService) createSession(user *model.User, client SessionClient) (*model.UserSession, error) {
+	now := time.Now()
+	session := &model.UserSession{
+		ID:             uuid.NewString(),
+		UserID:         user.ID,
+		RefreshTokenID: uuid.NewString(),
+		IP:             client.IP,
+		UserAgent:      truncate(client.UserAgent, 255),
+		ExpiresAt:      now.Add(time.Duration(s.config.RefreshTokenExpireTime) * time.Hour),
+		LastUsedAt:     now,
+	}
+	if err := s.db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// rotateRefreshToken 校验刷新令牌并轮换。刷新令牌只能使用一次，
+// 已轮换的令牌再次出现说明令牌可能泄露，整个会话随之失效
+func (s *AuthService) rotateRefreshToken(sessionID, tokenID string) (*model.UserSession, error) {
+	var session model.UserSession
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, "id = ?", sessionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionRevoked
+			}
+			return err
+		}
+
+		now := time.Now()
+		if !session.Active(now) {
+			return ErrSessionRevoked
+		}
+		if session.RefreshTokenID != tokenID {
+			if err := revokeSessions(tx.Where("id = ?", session.ID), model.SessionRevokeTokenReused); err != nil {
+				return err
+			}
+			return ErrRefreshTokenReused
+		}
+
+		session.RefreshTokenID = uuid.NewString()
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(time.Duration(s.config.RefreshTokenExpireTime) * time.Hour)
+		return tx.Model(&session).Updates(map[string]any{
+			"refresh_token_id": session.RefreshTokenID,
+			"last_used_at":     session.LastUsedAt,
+			"expires_at":       session.ExpiresAt,
+		}).Error
+	})
+	// 令牌重用时会话已在事务内失效，这里仍返回会话以便记录审计日志
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return &session, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// loadActiveSession 获取未失效的会话
+func (s *AuthService) loadActiveSession(sessionID string) (*model.UserSession, error) {
+	var session model.UserSession
+	if err := s.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionRevoked
+		}
+		return nil, err
+	}
+	if !session.Active(time.Now()) {
+		return nil, ErrSessionRevoked
+	}
+	return &session, nil
+}
+
+// ListSessions 获取用户未失效的会话，currentSessionID 对应的会话标记为当前会话
+func (s *AuthService) ListSessions(userID uint, currentSessionID string) ([]model.UserSession, error) {
+	var sessions []model.UserSession
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession 使用户的单个会话失效
+func (s *AuthService) RevokeSession(userID uint, sessionID string, reason model.SessionRevokeReason) error {
+	var session model.UserSession
+	if err := s.db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return revokeSessions(s.db.Where("id = ?", session.ID), reason)
+}
+
+// RevokeUserSessions 使用户的全部会话失效，返回失效的会话数
+func (s *AuthService) RevokeUserSessions(userID uint, reason model.SessionRevokeReason) (int64, error) {
+	result := s.db.Model(&model.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]any{"revoked_at": time.Now(), "revoke_reason": reason})
+	return result.RowsAffected, result.Error
+}
+
+// revokeSessions 使满足条件且尚未失效的会话失效
+func revokeSessions(db *gorm.DB, reason model.SessionRevokeReason) error {
+	return db.Model(&model.UserSession{}).
+		Where("revoked_at IS NULL").
+		Updates(map[string]any{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+func truncate(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit])
+}
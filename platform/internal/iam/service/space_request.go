@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrSpaceNotFound 申请加入的空间不存在
+	ErrSpaceNotFound = errors.New("空间不存在")
+	// ErrAlreadySpaceMember 申请人已经以该角色在空间中
+	ErrAlreadySpaceMember = errors.New("已是该空间的成员")
+	// ErrSpaceRequestPending 已有审批中的同类申请
+	ErrSpaceRequestPending = errors.New("已有审批中的申请")
+	// ErrWorkflowEventNotReady 事件对应的申请尚未关联到该流程，稍后重试即可
+	ErrWorkflowEventNotReady = errors.New("申请尚未关联到该流程")
+)
+
+// spaceRequestEventStatus 流程结束事件对应的申请状态
+var spaceRequestEventStatus = map[model.WorkflowEventType]model.SpaceRequestStatus{
+	model.WorkflowEventCompleted: model.SpaceRequestStatusApproved,
+	model.WorkflowEventRejected:  model.SpaceRequestStatusRejected,
+	model.WorkflowEventCancelled: model.SpaceRequestStatusWithdrawn,
+	model.WorkflowEventWithdrawn: model.SpaceRequestStatusWithdrawn,
+}
+
+// SpaceRequestService 加入空间、创建空间申请。申请都通过工作流服务审批，
+// 审批结果由工作流服务以流程结束事件投递回来后生效
+type SpaceRequestService struct {
+	db             *gorm.DB
+	workflowClient *commonClient.WorkflowClient
+	cache          *PermissionCache
+}
+
+// NewSpaceRequestService 创建空间申请服务
+func NewSpaceRequestService(db *gorm.DB, workflowClient *commonClient.WorkflowClient) *SpaceRequestService {
+	return &SpaceRequestService{db: db, workflowClient: workflowClient}
+}
+
+// UsePermissionCache 设置权限判定缓存，审批通过加入空间后使其失效
+func (s *SpaceRequestService) UsePermissionCache(cache *PermissionCache) {
+	s.cache = cache
+}
+
+// RequestMembership 申请以指定角色加入空间
+func (s *SpaceRequestService) RequestMembership(ctx context.Context, spaceID uint, req *model.CreateSpaceMembershipRequest, userID uint) (*model.SpaceMembershipRequest, error) {
+	db := s.db.WithContext(ctx)
+	var space model.Space
+	if err := db.Select("id").First(&space, spaceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSpaceNotFound
+		}
+		return nil, fmt.Errorf("查询空间失败: %w", err)
+	}
+
+	var member model.SpaceMember
+	err := db.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&member).Error
+	if err == nil && slices.Contains(member.Roles, req.Role) {
+		return nil, ErrAlreadySpaceMember
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询空间成员失败: %w", err)
+	}
+
+	var pending int64
+	if err := db.Model(&model.SpaceMembershipRequest{}).
+		Where("space_id = ? AND user_id = ? AND status = ?", spaceID, userID, model.SpaceRequestStatusPending).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("查询加入空间申请失败: %w", err)
+	}
+	if pending > 0 {
+		return nil, ErrSpaceRequestPending
+	}
+
+	request := &model.SpaceMembershipRequest{
+		SpaceID: spaceID,
+		UserID:  userID,
+		Role:    req.Role,
+		Reason:  req.Reason,
+		Status:  model.SpaceRequestStatusPending,
+	}
+	if err := db.Create(request).Error; err != nil {
+		return nil, fmt.Errorf("创建加入空间申请失败: %w", err)
+	}
+
+	workflow := &model.Workflow{
+		Name:         "加入空间审批流程",
+		Description:  "用于加入空间申请的审批流程",
+		SpaceID:      spaceID,
+		ResourceType: model.ResourceTypeSpaceMembership,
+		ResourceID:   request.ID,
+	}
+	attributes := map[string]any{
+		"space_id": spaceID,
+		"user_id":  userID,
+		"role":     string(req.Role),
+		"reason":   req.Reason,
+	}
+	if err := s.submit(ctx, &model.SpaceMembershipRequest{}, request.ID, workflow, userID, attributes); err != nil {
+		return nil, err
+	}
+	return request, s.reload(ctx, request, request.ID)
+}
+
+// RequestSpaceCreation 申请创建空间。平台级申请不属于任何空间，由企业管理员审批
+func (s *SpaceRequestService) RequestSpaceCreation(ctx context.Context, req *model.CreateSpaceCreationRequest, userID uint) (*model.SpaceCreationRequest, error) {
+	db := s.db.WithContext(ctx)
+	var pending int64
+	if err := db.Model(&model.SpaceCreationRequest{}).
+		Where("created_by = ? AND name = ? AND status = ?", userID, req.Name, model.SpaceRequestStatusPending).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("查询创建空间申请失败: %w", err)
+	}
+	if pending > 0 {
+		return nil, ErrSpaceRequestPending
+	}
+
+	request := &model.SpaceCreationRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        req.Type,
+		Reason:      req.Reason,
+		Status:      model.SpaceRequestStatusPending,
+		CreatedBy:   userID,
+	}
+	if err := db.Create(request).Error; err != nil {
+		return nil, fmt.Errorf("创建空间申请失败: %w", err)
+	}
+
+	workflow := &model.Workflow{
+		Name:         "创建空间审批流程",
+		Description:  "用于创建空间申请的审批流程",
+		ResourceType: model.ResourceTypeSpaceCreation,
+		ResourceID:   request.ID,
+	}
+	attributes := map[string]any{
+		"name":   req.Name,
+		"type":   string(req.Type),
+		"reason": req.Reason,
+	}
+	if err := s.submit(ctx, &model.SpaceCreationRequest{}, request.ID, workflow, userID, attributes); err != nil {
+		return nil, err
+	}
+	return request, s.reload(ctx, request, request.ID)
+}
+
+// submit 创建并启动申请的审批流程。空间绑定了该类申请的审批模板时按模板实例化，
+// 否则使用默认的单步空间管理员审批；没有空间的申请由企业管理员审批。
+// 提交失败时删除申请，申请人可以重新提交
+func (s *SpaceRequestService) submit(ctx context.Context, record any, requestID uint, workflow *model.Workflow, userID uint, attributes map[string]any) error {
+	id, err := s.createWorkflow(ctx, workflow, userID)
+	if err == nil {
+		// 先关联流程再启动，流程结束事件到达时一定能找到申请
+		err = s.db.WithContext(ctx).Model(record).Where("id = ?", requestID).Update("workflow_id", id).Error
+	}
+	var started *model.Workflow
+	if err == nil {
+		started, err = s.workflowClient.StartWorkflow(ctx, id, userID, attributes)
+	}
+	if err != nil {
+		s.db.WithContext(ctx).Delete(record, requestID)
+		return fmt.Errorf("提交审批失败: %w", err)
+	}
+
+	if started.Status != model.WorkflowStatusCompleted {
+		return nil
+	}
+	// 没有满足条件的审批步骤时流程直接完成，按审批通过处理；完成事件可能已先处理
+	return s.applyEvent(ctx, &model.WorkflowStatusEvent{
+		EventType:    model.WorkflowEventCompleted,
+		WorkflowID:   id,
+		ResourceType: workflow.ResourceType,
+		ResourceID:   requestID,
+	})
+}
+
+// createWorkflow 按模板实例化审批流程，没有绑定模板时使用默认的单步审批
+func (s *SpaceRequestService) createWorkflow(ctx context.Context, workflow *model.Workflow, userID uint) (uint, error) {
+	if workflow.SpaceID != 0 {
+		id, err := s.workflowClient.CreateWorkflowFromTemplate(ctx, &model.CreateWorkflowFromTemplateRequest{
+			MatchWorkflowTemplateRequest: model.MatchWorkflowTemplateRequest{
+				ResourceType: workflow.ResourceType,
+				SpaceID:      workflow.SpaceID,
+			},
+			ResourceID: workflow.ResourceID,
+		}, userID)
+		if !errors.Is(err, commonClient.ErrWorkflowTemplateNotFound) {
+			return id, err
+		}
+	}
+
+	workflow.Status = model.WorkflowStatusProcessing
+	workflow.Steps = []model.Step{{
+		StepName:     workflow.Name,
+		StepOrder:    1,
+		StepRole:     string(model.SpaceMemberRoleAdmin),
+		IsRequired:   true,
+		TimeoutHours: 24 * 7,
+		Status:       model.StepStatusProcessing,
+	}}
+	return s.workflowClient.CreateWorkflow(ctx, workflow, userID)
+}
+
+func (s *SpaceRequestService) reload(ctx context.Context, request any, id uint) error {
+	if err := s.db.WithContext(ctx).First(request, id).Error; err != nil {
+		return fmt.Errorf("查询申请失败: %w", err)
+	}
+	return nil
+}
+
+// ApplyWorkflowEvent 处理工作流服务投递的流程结束事件，同一事件重复投递只处理一次。
+// 返回 false 表示事件此前已处理过
+func (s *SpaceRequestService) ApplyWorkflowEvent(ctx context.Context, event *model.WorkflowStatusEvent) (bool, error) {
+	if event.EventID == "" {
+		return false, fmt.Errorf("event id is required")
+	}
+
+	applied := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先登记事件，主键冲突说明已处理过
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ProcessedEvent{
+			EventID:     event.EventID,
+			EventType:   event.EventType,
+			ProcessedAt: time.Now(),
+		})
+		if result.Error != nil {
+			return fmt.Errorf("failed to record processed event: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		applied = true
+		return s.applyEventTx(tx, event)
+	})
+	if err != nil {
+		return false, err
+	}
+	if applied {
+		s.cache.Invalidate()
+	}
+	return applied, nil
+}
+
+// applyEvent 在单独的事务中应用流程结果，用于流程启动时直接完成的情况
+func (s *SpaceRequestService) applyEvent(ctx context.Context, event *model.WorkflowStatusEvent) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.applyEventTx(tx, event)
+	})
+	if err != nil {
+		return err
+	}
+	s.cache.Invalidate()
+	return nil
+}
+
+// applyEventTx 按资源类型更新申请状态，审批通过时加入或创建空间
+func (s *SpaceRequestService) applyEventTx(tx *gorm.DB, event *model.WorkflowStatusEvent) error {
+	status, ok := spaceRequestEventStatus[event.EventType]
+	if !ok {
+		logger.Warnf("ignore unsupported workflow event %s (%s)", event.EventID, event.EventType)
+		return nil
+	}
+
+	var record any
+	switch event.ResourceType {
+	case model.ResourceTypeSpaceMembership:
+		record = &model.SpaceMembershipRequest{}
+	case model.ResourceTypeSpaceCreation:
+		record = &model.SpaceCreationRequest{}
+	default:
+		logger.Warnf("ignore workflow event %s of unsupported resource type %s", event.EventID, event.ResourceType)
+		return nil
+	}
+
+	// 只更新仍在等待该流程的申请，重复或迟到的事件不会重复加入或创建空间
+	result := tx.Model(record).
+		Where("id = ? AND workflow_id = ? AND status = ?", event.ResourceID, event.WorkflowID, model.SpaceRequestStatusPending).
+		Update("status", status)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update request status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return checkRequestLinked(tx, record, event)
+	}
+	if status != model.SpaceRequestStatusApproved {
+		return nil
+	}
+
+	if event.ResourceType == model.ResourceTypeSpaceMembership {
+		return approveMembership(tx, event.ResourceID)
+	}
+	return approveSpaceCreation(tx, event.ResourceID)
+}
+
+// checkRequestLinked 事件没有更新申请时区分已处理和尚未关联：申请不存在或已处理则忽略，
+// 申请还没关联到该流程时返回 ErrWorkflowEventNotReady 由投递方重试
+func checkRequestLinked(tx *gorm.DB, record any, event *model.WorkflowStatusEvent) error {
+	var linked struct{ WorkflowID uint }
+	result := tx.Model(record).Select("workflow_id").Where("id = ?", event.ResourceID).Limit(1).Scan(&linked)
+	if result.Error != nil {
+		return fmt.Errorf("failed to get request: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		logger.Warnf("ignore workflow event %s of missing %s request %d", event.EventID, event.ResourceType, event.ResourceID)
+		return nil
+	}
+	if linked.WorkflowID >= event.WorkflowID {
+		logger.Warnf("ignore workflow event %s: %s request %d is no longer awaiting workflow %d",
+			event.EventID, event.ResourceType, event.ResourceID, event.WorkflowID)
+		return nil
+	}
+	return fmt.Errorf("%w: %s request %d is linked to workflow %d, event is for workflow %d",
+		ErrWorkflowEventNotReady, event.ResourceType, event.ResourceID, linked.WorkflowID, event.WorkflowID)
+}
+
+// approveMembership 以申请的角色加入空间，已是成员时追加角色
+func approveMembership(tx *gorm.DB, requestID uint) error {
+	var request model.SpaceMembershipRequest
+	if err := tx.First(&request, requestID).Error; err != nil {
+		return fmt.Errorf("failed to get membership request: %w", err)
+	}
+	return addSpaceMemberRole(tx, request.SpaceID, request.UserID, request.Role)
+}
+
+// approveSpaceCreation 创建空间，申请人成为空间管理员
+func approveSpaceCreation(tx *gorm.DB, requestID uint) error {
+	var request model.SpaceCreationRequest
+	if err := tx.First(&request, requestID).Error; err != nil {
+		return fmt.Errorf("failed to get space creation request: %w", err)
+	}
+
+	space := model.Space{
+		Name:        request.Name,
+		Description: request.Description,
+		Type:        request.Type,
+		CreatedBy:   request.CreatedBy,
+	}
+	if err := tx.Create(&space).Error; err != nil {
+		return fmt.Errorf("failed to create space: %w", err)
+	}
+	if err := tx.Model(&request).Update("space_id", space.ID).Error; err != nil {
+		return fmt.Errorf("failed to update space creation request: %w", err)
+	}
+	return addSpaceMemberRole(tx, space.ID, request.CreatedBy, model.SpaceMemberRoleAdmin)
+}
+
+func addSpaceMemberRole(tx *gorm.DB, spaceID uint, userID uint, role model.SpaceMemberRole) error {
+	var member model.SpaceMember
+	err := tx.Where("space_id = ? AND user_id = ?", spaceID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		member = model.SpaceMember{SpaceID: spaceID, UserID: userID, Roles: []model.SpaceMemberRole{role}}
+		if err := tx.Create(&member).Error; err != nil {
+			return fmt.Errorf("failed to add space member: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get space member: %w", err)
+	}
+	if slices.Contains(member.Roles, role) {
+		return nil
+	}
+	member.Roles = append(member.Roles, role)
+	if err := tx.Save(&member).Error; err != nil {
+		return fmt.Errorf("failed to update space member: %w", err)
+	}
+	return nil
+}
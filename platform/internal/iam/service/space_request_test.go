@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
+	"gorm.io/gorm"
+)
+
+func newSpaceRequestTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return dbtest.Open(t, &model.User{}, &model.Space{}, &model.SpaceMember{},
+		&model.SpaceMembershipRequest{}, &model.SpaceCreationRequest{}, &model.ProcessedEvent{})
+}
+
+func TestApplyMembershipWorkflowEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		workflowID uint
+		existing   []model.SpaceMemberRole
+		eventType  model.WorkflowEventType
+		wantErr    error
+		wantStatus model.SpaceRequestStatus
+		wantRoles  []model.SpaceMemberRole
+	}{
+		{name: "approved request adds member", workflowID: 10, eventType: model.WorkflowEventCompleted,
+			wantStatus: model.SpaceRequestStatusApproved, wantRoles: []model.SpaceMemberRole{model.SpaceMemberRoleEditor}},
+		{name: "approved request adds role to existing member", workflowID: 10, existing: []model.SpaceMemberRole{model.SpaceMemberRoleReader},
+			eventType: model.WorkflowEventCompleted, wantStatus: model.SpaceRequestStatusApproved,
+			wantRoles: []model.SpaceMemberRole{model.SpaceMemberRoleReader, model.SpaceMemberRoleEditor}},
+		{name: "rejected request adds nothing", workflowID: 10, eventType: model.WorkflowEventRejected,
+			wantStatus: model.SpaceRequestStatusRejected},
+		{name: "withdrawn request adds nothing", workflowID: 10, eventType: model.WorkflowEventWithdrawn,
+			wantStatus: model.SpaceRequestStatusWithdrawn},
+		{name: "event before the request is linked is deferred", workflowID: 0, eventType: model.WorkflowEventCompleted,
+			wantErr: ErrWorkflowEventNotReady, wantStatus: model.SpaceRequestStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newSpaceRequestTestDB(t)
+			if tt.existing != nil {
+				db.Create(&model.SpaceMember{SpaceID: 1, UserID: 2, Roles: tt.existing})
+			}
+			request := model.SpaceMembershipRequest{SpaceID: 1, UserID: 2, Role: model.SpaceMemberRoleEditor,
+				Status: model.SpaceRequestStatusPending, WorkflowID: tt.workflowID}
+			if err := db.Create(&request).Error; err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			s := NewSpaceRequestService(db, nil)
+
+			event := model.WorkflowStatusEvent{EventID: "event-1", EventType: tt.eventType, WorkflowID: 10,
+				ResourceType: model.ResourceTypeSpaceMembership, ResourceID: request.ID}
+			if _, err := s.ApplyWorkflowEvent(context.Background(), &event); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ApplyWorkflowEvent() error = %v, want %v", err, tt.wantErr)
+			}
+
+			db.First(&request, request.ID)
+			if request.Status != tt.wantStatus {
+				t.Fatalf("request status = %s, want %s", request.Status, tt.wantStatus)
+			}
+			var member model.SpaceMember
+			err := db.Where("space_id = ? AND user_id = ?", 1, 2).First(&member).Error
+			if tt.wantRoles == nil {
+				if err == nil && tt.existing == nil {
+					t.Fatalf("member = %+v, want none", member)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load member: %v", err)
+			}
+			if len(member.Roles) != len(tt.wantRoles) {
+				t.Fatalf("member roles = %v, want %v", member.Roles, tt.wantRoles)
+			}
+			for i := range tt.wantRoles {
+				if member.Roles[i] != tt.wantRoles[i] {
+					t.Fatalf("member roles = %v, want %v", member.Roles, tt.wantRoles)
+				}
+			}
+		})
+	}
+}
+
+func TestApplySpaceCreationWorkflowEvent(t *testing.T) {
+	db := newSpaceRequestTestDB(t)
+	request := model.SpaceCreationRequest{Name: "研发空间", Status: model.SpaceRequestStatusPending, WorkflowID: 10, CreatedBy: 2}
+	if err := db.Create(&request).Error; err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	s := NewSpaceRequestService(db, nil)
+	cache := NewPermissionCache(&config.PermissionCacheConfig{TTLSeconds: 60})
+	s.UsePermissionCache(cache)
+
+	event := model.WorkflowStatusEvent{EventID: "event-1", EventType: model.WorkflowEventCompleted, WorkflowID: 10,
+		ResourceType: model.ResourceTypeSpaceCreation, ResourceID: request.ID}
+	if applied, err := s.ApplyWorkflowEvent(context.Background(), &event); err != nil || !applied {
+		t.Fatalf("ApplyWorkflowEvent() = %v, %v, want applied", applied, err)
+	}
+	if cache.Version() != 1 {
+		t.Fatalf("cache version = %d, want invalidated once after commit", cache.Version())
+	}
+	// 重复投递不能重复创建空间，也不会再次使缓存失效
+	if applied, err := s.ApplyWorkflowEvent(context.Background(), &event); err != nil || applied {
+		t.Fatalf("duplicate ApplyWorkflowEvent() = %v, %v, want duplicate", applied, err)
+	}
+	if cache.Version() != 1 {
+		t.Fatalf("cache version = %d after duplicate, want 1", cache.Version())
+	}
+
+	db.First(&request, request.ID)
+	if request.Status != model.SpaceRequestStatusApproved || request.SpaceID == 0 {
+		t.Fatalf("request = %+v, want approved with space", request)
+	}
+	var spaces []model.Space
+	db.Find(&spaces)
+	if len(spaces) != 1 || spaces[0].Name != request.Name || spaces[0].CreatedBy != request.CreatedBy {
+		t.Fatalf("spaces = %+v, want one created by the applicant", spaces)
+	}
+	var member model.SpaceMember
+	if err := db.Where("space_id = ? AND user_id = ?", request.SpaceID, request.CreatedBy).First(&member).Error; err != nil {
+		t.Fatalf("load member: %v", err)
+	}
+	if len(member.Roles) != 1 || member.Roles[0] != model.SpaceMemberRoleAdmin {
+		t.Fatalf("member roles = %v, want admin", member.Roles)
+	}
+}
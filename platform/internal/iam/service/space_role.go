@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+// ErrSpaceRoleInvalid 空间角色不存在
+var ErrSpaceRoleInvalid = errors.New("空间角色不存在")
+
+// SyncSpaceRolePermissions 为尚未配置权限的空间角色写入需求文档中的默认权限表，
+// 已配置过的空间角色保持管理员的修改不变。需在 SyncPermissions 之后调用
+func (s *RoleService) SyncSpaceRolePermissions() error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for role, grants := range model.DefaultSpaceRolePermissions {
+			var count int64
+			if err := tx.Model(&model.SpaceRolePermission{}).Where("space_role = ?", role).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			if err := s.replaceSpaceRolePermissions(tx, role, grants); err != nil {
+				return fmt.Errorf("failed to seed space role %s: %w", role, err)
+			}
+		}
+		return nil
+	})
+}
+
+// SpaceRolePermissions 获取各空间角色的权限，未配置任何权限的角色返回空列表
+func (s *RoleService) SpaceRolePermissions() (map[model.SpaceMemberRole][]model.SpaceRolePermission, error) {
+	var rows []model.SpaceRolePermission
+	if err := s.db.Preload("Permission").Order("permission_id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	matrix := make(map[model.SpaceMemberRole][]model.SpaceRolePermission, len(model.SpaceMemberRoleMap))
+	for role := range model.SpaceMemberRoleMap {
+		matrix[role] = []model.SpaceRolePermission{}
+	}
+	for _, row := range rows {
+		matrix[row.SpaceRole] = append(matrix[row.SpaceRole], row)
+	}
+	return matrix, nil
+}
+
+// SetSpaceRolePermissions 用给定的授权替换空间角色的全部权限
+func (s *RoleService) SetSpaceRolePermissions(role model.SpaceMemberRole, grants []model.SpaceRolePermissionGrant) ([]model.SpaceRolePermission, error) {
+	if _, ok := model.SpaceMemberRoleMap[role]; !ok {
+		return nil, ErrSpaceRoleInvalid
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.replaceSpaceRolePermissions(tx, role, grants)
+	}); err != nil {
+		return nil, err
+	}
+	s.cache.Invalidate()
+
+	matrix, err := s.SpaceRolePermissions()
+	if err != nil {
+		return nil, err
+	}
+	return matrix[role], nil
+}
+
+func (s *RoleService) replaceSpaceRolePermissions(tx *gorm.DB, role model.SpaceMemberRole, grants []model.SpaceRolePermissionGrant) error {
+	names := make([]model.PermissionName, 0, len(grants))
+	for _, grant := range grants {
+		names = append(names, grant.Name)
+	}
+
+	var permissions []model.Permission
+	if len(names) > 0 {
+		if err := tx.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+			return err
+		}
+	}
+	ids := make(map[model.PermissionName]uint, len(permissions))
+	for _, permission := range permissions {
+		ids[permission.Name] = permission.ID
+	}
+
+	rows := make(map[uint]model.SpaceRolePermission, len(grants))
+	for _, grant := range grants {
+		id, ok := ids[grant.Name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrPermissionNotFound, grant.Name)
+		}
+		rows[id] = model.SpaceRolePermission{SpaceRole: role, PermissionID: id, OwnOnly: grant.OwnOnly}
+	}
+
+	if err := tx.Where("space_role = ?", role).Delete(&model.SpaceRolePermission{}).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spaceRoleGrant 空间角色的一项授权，按资源和操作展开
+type spaceRoleGrant struct {
+	SpaceRole model.SpaceMemberRole
+	Resource  string
+	Action    string
+	OwnOnly   bool
+}
+
+// loadSpaceRoleGrants 读取完整的空间角色权限表，表很小，批量判定时一次读入内存
+func loadSpaceRoleGrants(db *gorm.DB) ([]spaceRoleGrant, error) {
+	var grants []spaceRoleGrant
+	err := db.Model(&model.SpaceRolePermission{}).
+		Select("space_role_permissions.space_role, space_role_permissions.own_only, permissions.resource, permissions.action").
+		Joins("JOIN permissions ON permissions.id = space_role_permissions.permission_id AND permissions.deleted_at IS NULL").
+		Scan(&grants).Error
+	return grants, err
+}
+
+// spaceRoleAllows 判断空间角色是否拥有资源的操作权限，仅限本人的授权只在 owner 为真时生效
+func spaceRoleAllows(grants []spaceRoleGrant, roles []model.SpaceMemberRole, resource, action string, owner bool) bool {
+	for _, grant := range grants {
+		if grant.Resource != resource || grant.Action != action || (grant.OwnOnly && !owner) {
+			continue
+		}
+		for _, role := range roles {
+			if grant.SpaceRole == role {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/csvsafe"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+// userExportBatch 导出时每批读取的用户数
+const userExportBatch = 500
+
+// userSpaceGrant 用户在一个空间内的角色，用于导出 spaces 列
+type userSpaceGrant struct {
+	UserID    uint
+	SpaceName string
+	Roles     []model.SpaceMemberRole `gorm:"serializer:json"`
+}
+
+// Export 按导入文件的列导出用户列表（不含服务账号），format 为 csv 或 xlsx。导出的文件可修改后以 upsert 模式重新导入
+func (s *UserImportService) Export(w io.Writer, query *model.UserExportQuery) error {
+	if query.Format == "xlsx" {
+		return s.exportXLSX(w, query)
+	}
+
+	// 写入 UTF-8 BOM，避免 Excel 打开中文乱码
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	// 单元格以 = + - @ 开头时加单引号，防止用电子表格打开时被当作公式执行
+	writer := csvsafe.NewWriter(w)
+	if err := writer.Write(model.UserExportColumns); err != nil {
+		return err
+	}
+	if err := s.eachUserRecord(query, writer.Write); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s *UserImportService) exportXLSX(w io.Writer, query *model.UserExportQuery) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet = "users"
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+	stream, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	index := 0
+	writeRow := func(values []string) error {
+		index++
+		cells := make([]any, len(values))
+		for i, v := range values {
+			cells[i] = v
+		}
+		cell, err := excelize.CoordinatesToCellName(1, index)
+		if err != nil {
+			return err
+		}
+		return stream.SetRow(cell, cells)
+	}
+
+	if err := writeRow(model.UserExportColumns); err != nil {
+		return err
+	}
+	if err := s.eachUserRecord(query, writeRow); err != nil {
+		return err
+	}
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// eachUserRecord 分批读取用户及其角色、空间成员，按 UserExportColumns 的顺序输出每个用户
+func (s *UserImportService) eachUserRecord(query *model.UserExportQuery, write func([]string) error) error {
+	db := s.db.Model(&model.User{}).Preload("Roles").Where("source <> ?", model.UserSourceService)
+	if query.Status != nil {
+		db = db.Where("status = ?", *query.Status)
+	}
+
+	var users []model.User
+	result := db.FindInBatches(&users, userExportBatch, func(tx *gorm.DB, batch int) error {
+		ids := make([]uint, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		var grants []userSpaceGrant
+		if err := s.db.Table("space_members").
+			Select("space_members.user_id, space_members.roles, spaces.name AS space_name").
+			Joins("JOIN spaces ON spaces.id = space_members.space_id AND spaces.deleted_at IS NULL").
+			Where("space_members.user_id IN ?", ids).
+			Order("spaces.id").
+			Scan(&grants).Error; err != nil {
+			return err
+		}
+		spaces := make(map[uint][]string, len(users))
+		for _, grant := range grants {
+			roles := make([]string, len(grant.Roles))
+			for i, role := range grant.Roles {
+				roles[i] = string(role)
+			}
+			spaces[grant.UserID] = append(spaces[grant.UserID], grant.SpaceName+":"+strings.Join(roles, "|"))
+		}
+
+		for _, user := range users {
+			roles := make([]string, len(user.Roles))
+			for i, role := range user.Roles {
+				roles[i] = string(role.Name)
+			}
+			sort.Strings(roles)
+
+			var orgUnitID string
+			if user.OrgUnitID != nil {
+				orgUnitID = strconv.FormatUint(uint64(*user.OrgUnitID), 10)
+			}
+			if err := write([]string{
+				user.Username, user.Phone, user.Email, user.Nickname, user.Company, user.Department,
+				orgUnitID, strconv.Itoa(user.Status), strings.Join(roles, ";"), strings.Join(spaces[user.ID], ";"),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result.Error
+}
@@ -0,0 +1,805 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/mail"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/csvsafe"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
+)
+
+var (
+	// ErrUserImportFormat 导入文件不是 CSV 或 XLSX
+	ErrUserImportFormat = errors.New("仅支持 .csv 和 .xlsx 文件")
+	// ErrUserImportInvalidFile 导入文件无法解析或列名不正确，具体原因附在错误信息后
+	ErrUserImportInvalidFile = errors.New("导入文件格式错误")
+	// ErrUserImportTooManyRows 导入文件的数据行数超过上限
+	ErrUserImportTooManyRows = errors.New("导入文件行数超过上限")
+	// ErrUserImportTooLarge 导入文件大小超过上限
+	ErrUserImportTooLarge = errors.New("导入文件大小超过上限")
+)
+
+// 按 IN 条件批量查询时每批的数量
+const userImportQueryBatch = 1000
+
+// UserImportService 批量导入与导出用户
+type UserImportService struct {
+	db       *gorm.DB
+	auth     *AuthService
+	notifier PasswordNotifier
+	maxRows  int
+	maxSize  int64
+}
+
+// NewUserImportService 创建批量导入服务
+func NewUserImportService(db *gorm.DB, auth *AuthService, cfg *config.UserImportConfig) *UserImportService {
+	return &UserImportService{
+		db:      db,
+		auth:    auth,
+		maxRows: cfg.MaxRows,
+		maxSize: int64(cfg.MaxFileSizeMB) << 20,
+	}
+}
+
+// UseNotifier 启用初始密码通知，未启用时初始密码在导入结果中返回
+func (s *UserImportService) UseNotifier(notifier PasswordNotifier) {
+	s.notifier = notifier
+}
+
+// userImportRow 解析后的一行数据，可选列为空时对已存在的用户保持原值不变
+type userImportRow struct {
+	result     *model.UserImportRowResult
+	username   string
+	phone      string
+	email      string
+	nickname   string
+	company    string
+	department string
+	orgUnit    *model.OrgUnit
+	status     *int
+	roles      []model.Role // nil 表示未填写
+	spaces     []model.SpaceMember
+	password   string
+	generated  bool // 初始密码为随机生成
+	existing   *model.User
+
+	// 需要对照数据库解析的原始值
+	orgUnitValue string
+	rolesValue   string
+	spacesValue  string
+}
+
+// userImportLookup 校验时用到的角色、空间、组织单元和已存在的用户
+type userImportLookup struct {
+	roles    map[model.RoleName]model.Role
+	spaces   map[string][]uint
+	orgUnits map[uint]*model.OrgUnit
+	byName   map[string]*model.User
+	byPhone  map[string]*model.User
+	byEmail  map[string]*model.User
+}
+
+// Import 校验并导入用户。任一行校验失败或 dry-run 时不写入任何数据，只返回每行的校验结果。
+// 非超级管理员不能授予超级管理员角色，也不能修改超级管理员
+func (s *UserImportService) Import(ctx context.Context, filename string, file io.Reader, size int64, req *model.UserImportRequest, operator *model.User) (*model.UserImportReport, error) {
+	if s.maxSize > 0 && size > s.maxSize {
+		return nil, fmt.Errorf("%w: 上限 %d MB", ErrUserImportTooLarge, s.maxSize>>20)
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = model.UserImportModeCreate
+	}
+
+	records, err := readUserSheet(filename, file)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := userImportColumns(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*userImportRow
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+		rows = append(rows, parseUserImportRow(i+2, record, columns))
+	}
+	if s.maxRows > 0 && len(rows) > s.maxRows {
+		return nil, fmt.Errorf("%w: %d 行，上限 %d 行", ErrUserImportTooManyRows, len(rows), s.maxRows)
+	}
+
+	lookup, err := s.loadLookup(rows)
+	if err != nil {
+		return nil, err
+	}
+	s.validateRows(rows, lookup, mode, isSuperAdmin(operator))
+
+	report := &model.UserImportReport{Mode: mode, DryRun: req.DryRun, Total: len(rows)}
+	for _, row := range rows {
+		if len(row.result.Errors) > 0 {
+			row.result.Action = model.UserImportActionError
+			report.Failed++
+		}
+	}
+	if report.Failed == 0 && !req.DryRun {
+		if err := s.apply(rows); err != nil {
+			return nil, err
+		}
+		report.Applied = true
+		s.sendNotices(ctx, rows)
+	}
+
+	for _, row := range rows {
+		switch row.result.Action {
+		case model.UserImportActionCreate:
+			report.Created++
+		case model.UserImportActionUpdate:
+			report.Updated++
+		}
+		report.Rows = append(report.Rows, *row.result)
+	}
+	return report, nil
+}
+
+// readUserSheet 按扩展名读取 CSV 或 XLSX（第一个工作表）的全部行
+func readUserSheet(filename string, file io.Reader) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUserImportInvalidFile, err)
+		}
+		if len(records) > 0 && len(records[0]) > 0 {
+			// 去掉 Excel 另存为 CSV 时写入的 UTF-8 BOM
+			records[0][0] = strings.TrimPrefix(records[0][0], "\xEF\xBB\xBF")
+		}
+		// 导出时为防止公式执行加的单引号在导入时去掉，导出的文件可以原样导入
+		for _, record := range records {
+			for i := range record {
+				record[i] = csvsafe.Unescape(record[i])
+			}
+		}
+		return records, nil
+	case ".xlsx":
+		f, err := excelize.OpenReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUserImportInvalidFile, err)
+		}
+		defer f.Close()
+		records, err := f.GetRows(f.GetSheetName(0))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUserImportInvalidFile, err)
+		}
+		return records, nil
+	default:
+		return nil, ErrUserImportFormat
+	}
+}
+
+// userImportColumns 解析首行列名，返回列名到列序号的映射
+func userImportColumns(records [][]string) (map[string]int, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: 文件为空", ErrUserImportInvalidFile)
+	}
+
+	known := make(map[string]bool, len(model.UserExportColumns)+1)
+	for _, name := range model.UserExportColumns {
+		known[name] = true
+	}
+	known[model.UserColumnPassword] = true
+
+	columns := make(map[string]int, len(records[0]))
+	for i, header := range records[0] {
+		name := strings.ToLower(strings.TrimSpace(header))
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("%w: 未知的列 %s", ErrUserImportInvalidFile, header)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: 列 %s 重复", ErrUserImportInvalidFile, header)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{model.UserColumnUsername, model.UserColumnPhone} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: 缺少 %s 列", ErrUserImportInvalidFile, name)
+		}
+	}
+	return columns, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// cell 读取一行中指定列的值，缺少该列或该行较短时返回空字符串
+func cell(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// parseUserImportRow 读取不依赖数据库的字段并做格式校验
+func parseUserImportRow(line int, record []string, columns map[string]int) *userImportRow {
+	row := &userImportRow{
+		result:     &model.UserImportRowResult{Row: line},
+		username:   cell(record, columns, model.UserColumnUsername),
+		phone:      cell(record, columns, model.UserColumnPhone),
+		email:      cell(record, columns, model.UserColumnEmail),
+		nickname:   cell(record, columns, model.UserColumnNickname),
+		company:    cell(record, columns, model.UserColumnCompany),
+		department: cell(record, columns, model.UserColumnDepartment),
+		password:   cell(record, columns, model.UserColumnPassword),
+
+		orgUnitValue: cell(record, columns, model.UserColumnOrgUnitID),
+		rolesValue:   cell(record, columns, model.UserColumnRoles),
+		spacesValue:  cell(record, columns, model.UserColumnSpaces),
+	}
+	row.result.Username = row.username
+	fail := func(format string, args ...any) {
+		row.result.Errors = append(row.result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case row.username == "":
+		fail("username 不能为空")
+	case utf8.RuneCountInString(row.username) > 50:
+		fail("username 不能超过 50 个字符")
+	}
+	if len(row.phone) != 11 || strings.Trim(row.phone, "0123456789") != "" {
+		fail("phone 必须为 11 位数字")
+	}
+	if row.email != "" {
+		if addr, err := mail.ParseAddress(row.email); err != nil || addr.Address != row.email || len(row.email) > 100 {
+			fail("email 格式不正确")
+		}
+	}
+	if utf8.RuneCountInString(row.nickname) > 50 {
+		fail("nickname 不能超过 50 个字符")
+	}
+	if utf8.RuneCountInString(row.company) > 100 || utf8.RuneCountInString(row.department) > 100 {
+		fail("company、department 不能超过 100 个字符")
+	}
+	if value := cell(record, columns, model.UserColumnStatus); value != "" {
+		if value != "0" && value != "1" {
+			fail("status 只能为 0 或 1")
+		} else {
+			status, _ := strconv.Atoi(value)
+			row.status = &status
+		}
+	}
+	return row
+}
+
+// loadLookup 一次性读取校验需要的角色、空间、组织单元，并按用户名、手机号、邮箱批量查询已存在的用户。
+// 已删除的用户仍占用用户名和手机号，一并查出
+func (s *UserImportService) loadLookup(rows []*userImportRow) (*userImportLookup, error) {
+	lookup := &userImportLookup{
+		roles:    make(map[model.RoleName]model.Role),
+		spaces:   make(map[string][]uint),
+		orgUnits: make(map[uint]*model.OrgUnit),
+		byName:   make(map[string]*model.User),
+		byPhone:  make(map[string]*model.User),
+		byEmail:  make(map[string]*model.User),
+	}
+
+	var roles []model.Role
+	if err := s.db.Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		lookup.roles[role.Name] = role
+	}
+
+	var spaces []model.Space
+	if err := s.db.Select("id", "name").Order("id").Find(&spaces).Error; err != nil {
+		return nil, err
+	}
+	for _, space := range spaces {
+		lookup.spaces[space.Name] = append(lookup.spaces[space.Name], space.ID)
+	}
+
+	var units []model.OrgUnit
+	if err := s.db.Find(&units).Error; err != nil {
+		return nil, err
+	}
+	for i := range units {
+		lookup.orgUnits[units[i].ID] = &units[i]
+	}
+
+	var names, phones, emails []string
+	for _, row := range rows {
+		names = append(names, row.username)
+		phones = append(phones, row.phone)
+		if row.email != "" {
+			emails = append(emails, row.email)
+		}
+	}
+	for _, query := range []struct {
+		column string
+		values []string
+	}{{"username", names}, {"phone", phones}, {"email", emails}} {
+		for start := 0; start < len(query.values); start += userImportQueryBatch {
+			end := min(start+userImportQueryBatch, len(query.values))
+			var users []model.User
+			if err := s.db.Unscoped().Preload("Roles").
+				Where(query.column+" IN ?", query.values[start:end]).
+				Find(&users).Error; err != nil {
+				return nil, err
+			}
+			for i := range users {
+				user := &users[i]
+				// 同一用户可能被多次查出，保持各映射指向同一份数据
+				if existing, ok := lookup.byName[user.Username]; ok {
+					user = existing
+				}
+				lookup.byName[user.Username] = user
+				lookup.byPhone[user.Phone] = user
+				if user.Email != "" {
+					lookup.byEmail[user.Email] = user
+				}
+			}
+		}
+	}
+	return lookup, nil
+}
+
+// validateRows 校验各行与数据库及文件内其他行的冲突，并解析角色、空间和组织单元
+func (s *UserImportService) validateRows(rows []*userImportRow, lookup *userImportLookup, mode model.UserImportMode, superAdmin bool) {
+	seenName := make(map[string]int)
+	seenPhone := make(map[string]int)
+	seenEmail := make(map[string]int)
+
+	for _, row := range rows {
+		fail := func(format string, args ...any) {
+			row.result.Errors = append(row.result.Errors, fmt.Sprintf(format, args...))
+		}
+
+		// 文件内重复
+		if line, ok := seenName[row.username]; ok && row.username != "" {
+			fail("username 与第 %d 行重复", line)
+		}
+		if line, ok := seenPhone[row.phone]; ok && row.phone != "" {
+			fail("phone 与第 %d 行重复", line)
+		}
+		if line, ok := seenEmail[row.email]; ok && row.email != "" {
+			fail("email 与第 %d 行重复", line)
+		}
+		seenName[row.username] = row.result.Row
+		seenPhone[row.phone] = row.result.Row
+		if row.email != "" {
+			seenEmail[row.email] = row.result.Row
+		}
+
+		// 与已存在用户的冲突
+		if existing, ok := lookup.byName[row.username]; ok {
+			switch {
+			case existing.DeletedAt.Valid:
+				fail("username 已被已删除的用户占用")
+			case mode != model.UserImportModeUpsert:
+				fail("username 已存在")
+			case existing.Source == model.UserSourceLDAP:
+				fail("目录用户的资料由目录同步，不能通过导入更新")
+			case existing.Source == model.UserSourceService:
+				fail("服务账号不能通过导入更新")
+			case !superAdmin && hasRole(existing, model.RoleSuperAdmin):
+				fail("只有超级管理员可以修改超级管理员")
+			case row.password != "":
+				fail("已存在的用户不能通过导入修改密码")
+			default:
+				row.existing = existing
+			}
+		}
+		if owner, ok := lookup.byPhone[row.phone]; ok && owner.Username != row.username {
+			fail("phone 已被用户 %s 使用", owner.Username)
+		}
+		if owner, ok := lookup.byEmail[row.email]; ok && row.email != "" && owner.Username != row.username {
+			fail("email 已被用户 %s 使用", owner.Username)
+		}
+
+		// 组织单元
+		if value := row.orgUnitValue; value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if unit, ok := lookup.orgUnits[uint(id)]; err == nil && ok {
+				row.orgUnit = unit
+			} else {
+				fail("org_unit_id %s 对应的组织单元不存在", value)
+			}
+		}
+
+		// 全局角色
+		if value := row.rolesValue; value != "" {
+			row.roles = []model.Role{}
+			for _, name := range splitList(value, ";") {
+				role, ok := lookup.roles[model.RoleName(name)]
+				switch {
+				case !ok:
+					fail("角色 %s 不存在", name)
+				case role.Status != 1:
+					fail("角色 %s 已停用", name)
+				case role.Name == model.RoleSuperAdmin && !superAdmin:
+					fail("只有超级管理员可以授予超级管理员角色")
+				default:
+					row.roles = append(row.roles, role)
+				}
+			}
+		}
+
+		// 空间成员
+		if value := row.spacesValue; value != "" {
+			for _, entry := range splitList(value, ";") {
+				member, err := parseSpaceGrant(entry, lookup.spaces)
+				if err != nil {
+					fail("%s", err.Error())
+					continue
+				}
+				row.spaces = append(row.spaces, member)
+			}
+		}
+
+		// 初始密码
+		if row.existing == nil && row.password != "" {
+			if err := s.auth.validatePassword(row.password, row.username); err != nil {
+				fail("password %s", err.Error())
+			}
+		}
+
+		if row.existing != nil {
+			row.result.Action = model.UserImportActionUpdate
+			row.result.UserID = row.existing.ID
+		} else {
+			row.result.Action = model.UserImportActionCreate
+		}
+	}
+}
+
+// parseSpaceGrant 解析「空间名:空间角色|空间角色」，空间名中可以包含冒号
+func parseSpaceGrant(entry string, spaces map[string][]uint) (model.SpaceMember, error) {
+	i := strings.LastIndex(entry, ":")
+	if i <= 0 {
+		return model.SpaceMember{}, fmt.Errorf("空间 %s 格式错误，应为 空间名:角色|角色", entry)
+	}
+	name := strings.TrimSpace(entry[:i])
+	ids := spaces[name]
+	switch len(ids) {
+	case 0:
+		return model.SpaceMember{}, fmt.Errorf("空间 %s 不存在", name)
+	case 1:
+	default:
+		return model.SpaceMember{}, fmt.Errorf("存在多个名为 %s 的空间，无法确定", name)
+	}
+
+	member := model.SpaceMember{SpaceID: ids[0]}
+	for _, value := range splitList(entry[i+1:], "|") {
+		role := model.SpaceMemberRole(value)
+		if _, ok := model.SpaceMemberRoleMap[role]; !ok {
+			return model.SpaceMember{}, fmt.Errorf("空间 %s 的角色 %s 不存在", name, value)
+		}
+		member.Roles = append(member.Roles, role)
+	}
+	if len(member.Roles) == 0 {
+		return model.SpaceMember{}, fmt.Errorf("空间 %s 未填写角色", name)
+	}
+	return member, nil
+}
+
+func splitList(value string, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func hasRole(user *model.User, name model.RoleName) bool {
+	for _, role := range user.Roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isSuperAdmin(user *model.User) bool {
+	return user != nil && hasRole(user, model.RoleSuperAdmin)
+}
+
+// apply 在一个事务内写入全部行，新建用户的密码哈希提前并发计算
+func (s *UserImportService) apply(rows []*userImportRow) error {
+	var creates []*userImportRow
+	for _, row := range rows {
+		if row.existing != nil {
+			continue
+		}
+		if row.password == "" {
+			password, err := s.generatePassword(row.username)
+			if err != nil {
+				return err
+			}
+			row.password, row.generated = password, true
+		}
+		creates = append(creates, row)
+	}
+	hashes, err := hashPasswords(creates)
+	if err != nil {
+		return err
+	}
+
+	var disabled []uint
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i, row := range creates {
+			if err := s.createUser(tx, row, hashes[i]); err != nil {
+				return fmt.Errorf("第 %d 行: %w", row.result.Row, err)
+			}
+		}
+		for _, row := range rows {
+			if row.existing == nil {
+				continue
+			}
+			if err := s.updateUser(tx, row); err != nil {
+				return fmt.Errorf("第 %d 行: %w", row.result.Row, err)
+			}
+			if row.existing.Status == 1 && row.status != nil && *row.status != 1 {
+				disabled = append(disabled, row.existing.ID)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	// 导入会替换用户的全局角色和空间成员
+	s.auth.InvalidatePermissions()
+
+	// 禁用用户时结束其全部会话
+	for _, userID := range disabled {
+		if _, err := s.auth.RevokeUserSessions(userID, model.SessionRevokeUserDisabled); err != nil {
+			logger.Errorf("user import: failed to revoke sessions of user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *UserImportService) createUser(tx *gorm.DB, row *userImportRow, hash string) error {
+	now := time.Now()
+	user := &model.User{
+		Username:          row.username,
+		Phone:             row.phone,
+		Email:             row.email,
+		Password:          hash,
+		PasswordChangedAt: &now,
+		Nickname:          row.nickname,
+		Department:        row.department,
+		Company:           row.company,
+		Status:            1,
+		Source:            model.UserSourceLocal,
+	}
+	if row.status != nil {
+		user.Status = *row.status
+	}
+	if row.orgUnit != nil {
+		department, company, err := orgUnitNames(tx, row.orgUnit)
+		if err != nil {
+			return err
+		}
+		user.OrgUnitID, user.Department, user.Company = &row.orgUnit.ID, department, company
+	}
+
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+	// status 有默认值，零值不会写入，导入为禁用的用户需单独更新
+	if user.Status == 0 {
+		if err := tx.Model(user).Update("status", 0).Error; err != nil {
+			return err
+		}
+	}
+	if err := s.auth.recordPasswordHistory(tx, user); err != nil {
+		return err
+	}
+	row.result.UserID = user.ID
+	return writeUserGrants(tx, user.ID, row)
+}
+
+func (s *UserImportService) updateUser(tx *gorm.DB, row *userImportRow) error {
+	updates := map[string]any{"phone": row.phone}
+	for column, value := range map[string]string{
+		"email":      row.email,
+		"nickname":   row.nickname,
+		"department": row.department,
+		"company":    row.company,
+	} {
+		if value != "" {
+			updates[column] = value
+		}
+	}
+	if row.orgUnit != nil {
+		department, company, err := orgUnitNames(tx, row.orgUnit)
+		if err != nil {
+			return err
+		}
+		updates["org_unit_id"], updates["department"], updates["company"] = row.orgUnit.ID, department, company
+	}
+	if row.status != nil {
+		updates["status"] = *row.status
+	}
+
+	if err := tx.Model(&model.User{}).Where("id = ?", row.existing.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	return writeUserGrants(tx, row.existing.ID, row)
+}
+
+// writeUserGrants 填写了角色时替换用户的全局角色；填写的空间替换用户在该空间的角色，未填写的空间不变
+func writeUserGrants(tx *gorm.DB, userID uint, row *userImportRow) error {
+	if row.roles != nil {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserRole{}).Error; err != nil {
+			return err
+		}
+		for _, role := range row.roles {
+			if err := tx.Create(&model.UserRole{UserID: userID, RoleID: role.ID}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, member := range row.spaces {
+		if err := tx.Where("space_id = ? AND user_id = ?", member.SpaceID, userID).Delete(&model.SpaceMember{}).Error; err != nil {
+			return err
+		}
+		member.UserID = userID
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashPasswords 并发计算密码哈希，bcrypt 较慢，逐个计算时数千行的导入会超过网关超时
+func hashPasswords(rows []*userImportRow) ([]string, error) {
+	hashes := make([]string, len(rows))
+	errs := make([]error, len(rows))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hash, err := bcrypt.GenerateFromPassword([]byte(rows[i].password), bcrypt.DefaultCost)
+				hashes[i], errs[i] = string(hash), err
+			}
+		}()
+	}
+	for i := range rows {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// 随机初始密码使用的字符，去掉了容易混淆的 0/O、1/l/I
+const (
+	passwordUpper  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordLower  = "abcdefghijkmnopqrstuvwxyz"
+	passwordDigit  = "23456789"
+	passwordSymbol = "!@#$%^&*-_=+?"
+)
+
+// generatePassword 生成满足密码策略的随机初始密码，四类字符各至少一个
+func (s *UserImportService) generatePassword(username string) (string, error) {
+	length := max(s.auth.passwordPolicy.MinLength, 12)
+	sets := []string{passwordUpper, passwordLower, passwordDigit, passwordSymbol}
+	all := strings.Join(sets, "")
+
+	for {
+		password := make([]byte, 0, length)
+		for _, set := range sets {
+			c, err := randomChar(set)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, c)
+		}
+		for len(password) < length {
+			c, err := randomChar(all)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, c)
+		}
+		// 打乱顺序，避免固定位置出现固定类别的字符
+		for i := len(password) - 1; i > 0; i-- {
+			j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+			if err != nil {
+				return "", err
+			}
+			password[i], password[j.Int64()] = password[j.Int64()], password[i]
+		}
+		if s.auth.validatePassword(string(password), username) == nil {
+			return string(password), nil
+		}
+	}
+}
+
+func randomChar(set string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, err
+	}
+	return set[n.Int64()], nil
+}
+
+// sendNotices 向新建用户发送初始密码通知。未启用通知或发送失败时在结果中返回初始密码，
+// 管理员在文件中指定的密码不返回
+func (s *UserImportService) sendNotices(ctx context.Context, rows []*userImportRow) {
+	for _, row := range rows {
+		if row.existing != nil {
+			continue
+		}
+		if s.notifier == nil {
+			if row.generated {
+				row.result.InitialPassword = row.password
+			}
+			continue
+		}
+
+		err := s.notifier.NotifyInitialPassword(ctx, &model.InitialPasswordNotice{
+			UserID:   row.result.UserID,
+			Username: row.username,
+			Nickname: row.nickname,
+			Phone:    row.phone,
+			Email:    row.email,
+			Password: row.password,
+		})
+		if err != nil {
+			logger.Warnf("user import: failed to send initial password notice to %s: %v", row.username, err)
+			row.result.NoticeError = err.Error()
+			if row.generated {
+				row.result.InitialPassword = row.password
+			}
+			continue
+		}
+		row.result.NoticeSent = true
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/iam/config"
+)
+
+// NoticeEventInitialPassword 初始密码通知的事件类型，放在 X-KBase-Event 请求头中
+const NoticeEventInitialPassword = "user.initial_password"
+
+// PasswordNotifier 向新建用户发送初始密码通知
+type PasswordNotifier interface {
+	NotifyInitialPassword(ctx context.Context, notice *model.InitialPasswordNotice) error
+}
+
+// HTTPPasswordNotifier 将初始密码通知以签名的 POST 请求发送给通知服务，由其转发短信或邮件
+type HTTPPasswordNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewHTTPPasswordNotifier 创建初始密码通知发送器，未配置通知地址时返回 nil
+func NewHTTPPasswordNotifier(cfg *config.UserImportConfig) *HTTPPasswordNotifier {
+	if cfg.NoticeURL == "" {
+		return nil
+	}
+	return &HTTPPasswordNotifier{
+		url:    cfg.NoticeURL,
+		secret: cfg.NoticeSecret,
+		client: &http.Client{Timeout: time.Duration(cfg.NoticeTimeoutSeconds) * time.Second},
+	}
+}
+
+// NotifyInitialPassword 发送初始密码通知，签名方式与 Webhook 相同
+func (n *HTTPPasswordNotifier) NotifyInitialPassword(ctx context.Context, notice *model.InitialPasswordNotice) error {
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notice: %w", err)
+	}
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, NoticeEventInitialPassword)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if n.secret != "" {
+		req.Header.Set(webhook.HeaderSignature, "sha256="+webhook.Sign(n.secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 2048))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("unexpected status " + resp.Status)
+	}
+	return nil
+}
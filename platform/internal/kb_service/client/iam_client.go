@@ -1,3 +1,104 @@
 package client
 
-// TODO 存取流程打通后接入
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/config"
+)
+
+// IamClient 调用 IAM 服务校验用户在空间内的权限
+type IamClient struct {
+	config *config.IamConfig
+	client *http.Client
+}
+
+func NewIamClient(config *config.IamConfig) *IamClient {
+	return &IamClient{
+		config: config,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// CheckPermission 检查用户在空间内是否拥有资源的操作权限，ownerID 为资源创建者，新建资源时传 0
+func (c *IamClient) CheckPermission(ctx context.Context, userID, spaceID uint, resource, action string, ownerID uint) (bool, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/permissions/check", c.config.Url)
+	jsonData, err := json.Marshal(map[string]any{
+		"space_id": spaceID,
+		"resource": resource,
+		"action":   action,
+		"owner_id": ownerID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal permission check: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", targetURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-User-ID", fmt.Sprintf("%d", userID))
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("iam service returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Data struct {
+			HasPermission bool `json:"has_permission"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return response.Data.HasPermission, nil
+}
+
+// CheckPermissions 批量检查用户权限，结果按检查项顺序返回，单次最多 500 项
+func (c *IamClient) CheckPermissions(ctx context.Context, userID uint, checks []model.PermissionCheck) ([]model.PermissionCheckResult, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/permissions/check-batch", c.config.Url)
+	jsonData, err := json.Marshal(model.BatchPermissionCheckRequest{Checks: checks})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal permission checks: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", targetURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-User-ID", fmt.Sprintf("%d", userID))
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("iam service returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Data []model.PermissionCheckResult `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(response.Data) != len(checks) {
+		return nil, fmt.Errorf("iam service returned %d results for %d checks", len(response.Data), len(checks))
+	}
+	return response.Data, nil
+}
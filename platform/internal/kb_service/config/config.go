@@ -13,7 +13,7 @@ import (
 type Config struct {
 	Server   commonConfig.ServerConfig   `mapstructure:"server"`
 	Iam      IamConfig                   `mapstructure:"iam"`
-	Workflow WorkflowConfig              `mapstructure:"workflow"`
+	Workflow commonConfig.WorkflowConfig `mapstructure:"workflow"`
 	Database commonConfig.DatabaseConfig `mapstructure:"database"`
 	Minio    MinioConfig                 `mapstructure:"minio"`
 	Gin      commonConfig.GinConfig      `mapstructure:"gin"`
@@ -21,6 +21,11 @@ type Config struct {
 	OpenAI   OpenAIConfig                `mapstructure:"openai"`
 	OCR      PaddleOCRConfig             `mapstructure:"ocr"`
 	Vector   QdrantConfig                `mapstructure:"vector"`
+	Webhook  commonConfig.WebhookConfig  `mapstructure:"webhook"`
+	Outbox   commonConfig.OutboxConfig   `mapstructure:"outbox"`
+
+	// TrustedProxies 网关的地址或网段，只有来自这些地址的 X-Real-IP 才被采信；为空时直接使用连接来源IP
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // IamConfig IAM 服务配置（KB Service 特有）
@@ -28,11 +33,6 @@ type IamConfig struct {
 	Url string `mapstructure:"url"`
 }
 
-// WorkflowConfig Workflow 服务配置（KB Service 特有）
-type WorkflowConfig struct {
-	Url string `mapstructure:"url"`
-}
-
 // MinioConfig Minio 配置（KB Service 特有）
 type MinioConfig struct {
 	Endpoint  string `mapstructure:"endpoint"`
@@ -172,8 +172,20 @@ func bindEnvVars(v *viper.Viper) {
 	// Iam配置
 	v.BindEnv("iam.url", "KBASE_IAM_URL", "IAM_URL")
 
+	// Webhook配置
+	v.BindEnv("webhook.timeout_seconds", "KBASE_WEBHOOK_TIMEOUT_SECONDS", "WEBHOOK_TIMEOUT_SECONDS")
+	v.BindEnv("webhook.max_attempts", "KBASE_WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_MAX_ATTEMPTS")
+	v.BindEnv("webhook.retry_base_seconds", "KBASE_WEBHOOK_RETRY_BASE_SECONDS", "WEBHOOK_RETRY_BASE_SECONDS")
+	v.BindEnv("webhook.poll_interval_seconds", "KBASE_WEBHOOK_POLL_INTERVAL_SECONDS", "WEBHOOK_POLL_INTERVAL_SECONDS")
+
+	// 工作流事件验签配置
+	v.BindEnv("outbox.secret", "KBASE_OUTBOX_SECRET", "OUTBOX_SECRET")
+
 	// Gin配置
 	v.BindEnv("gin.mode", "KBASE_GIN_MODE", "GIN_MODE")
+
+	// 可信代理配置
+	v.BindEnv("trusted_proxies", "KBASE_TRUSTED_PROXIES", "TRUSTED_PROXIES")
 }
 
 func setDefaults(v *viper.Viper) {
@@ -215,4 +227,13 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("vector.vector_size", 7)
 	v.SetDefault("vector.distance", "Cosine")
 	v.SetDefault("vector.timeout_seconds", 30)
+
+	// 默认 Webhook 投递配置
+	v.SetDefault("webhook.timeout_seconds", 10)
+	v.SetDefault("webhook.max_attempts", 6)
+	v.SetDefault("webhook.retry_base_seconds", 30)
+	v.SetDefault("webhook.poll_interval_seconds", 15)
+
+	// 可信代理默认配置，本地部署时网关与服务在同一主机
+	v.SetDefault("trusted_proxies", []string{"127.0.0.1", "::1"})
 }
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exportService *service.ExportService
+	recorder      *audit.Recorder
+}
+
+func NewExportHandler(exportService *service.ExportService, recorder *audit.Recorder) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		recorder:      recorder,
+	}
+}
+
+// CreateExportJob 创建导出任务
+func (h *ExportHandler) CreateExportJob(c *gin.Context) {
+	var req model.CreateExportJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.exportService.CreateJob(c.Request.Context(), &req, user)
+	if err != nil {
+		respondExportError(c, "Failed to create export job", err)
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDataExport,
+		ResourceType: "export_job",
+		ResourceID:   job.ID,
+		SpaceID:      job.SpaceID,
+		After:        job,
+	})
+
+	message := "Export job created successfully"
+	if job.Status == model.ExportJobStatusPendingApproval {
+		message = "Export job is awaiting approval"
+	}
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    job,
+	})
+}
+
+// ListExportJobs 获取当前用户的导出任务
+func (h *ExportHandler) ListExportJobs(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	jobs, err := h.exportService.ListJobs(c.Request.Context(), user, page, pageSize)
+	if err != nil {
+		respondExportError(c, "Failed to list export jobs", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    jobs,
+	})
+}
+
+// GetExportJob 获取导出任务状态
+func (h *ExportHandler) GetExportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid export job ID",
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.exportService.GetJob(c.Request.Context(), uint(id), user)
+	if err != nil {
+		respondExportError(c, "Failed to get export job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    job,
+	})
+}
+
+// DownloadExportJob 下载导出的 ZIP 包
+func (h *ExportHandler) DownloadExportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid export job ID",
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	job, reader, err := h.exportService.DownloadJob(c.Request.Context(), uint(id), user)
+	if err != nil {
+		respondExportError(c, "Failed to download export job", err)
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"export_%d.zip\"", job.ID))
+	c.Header("Content-Length", strconv.FormatInt(job.FileSize, 10))
+	c.Status(http.StatusOK)
+
+	if _, err := io.Copy(c.Writer, reader); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// ExportSearchResults 导出检索结果为 CSV 或 Excel
+func (h *ExportHandler) ExportSearchResults(c *gin.Context) {
+	var req model.ExportSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.exportService.ExportSearchResults(c.Request.Context(), &req, user)
+	if err != nil {
+		respondExportError(c, "Failed to export search results", err)
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDataExport,
+		ResourceType: "knowledge",
+		SpaceID:      req.SpaceID,
+		Detail:       req.Query,
+	})
+
+	contentType, ext := "text/csv; charset=utf-8", "csv"
+	if req.Format == "xlsx" {
+		contentType, ext = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"
+	}
+	filename := fmt.Sprintf("search_results_%s.%s", time.Now().Format("20060102150405"), ext)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func respondExportError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrExportJobNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrExportForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrExportInvalidScope), errors.Is(err, service.ErrExportInvalidFormat),
+		errors.Is(err, service.ErrOrgUnitNotFound):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrExportJobNotReady):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, &model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
@@ -12,18 +12,23 @@ import (
 
 	openai "github.com/openai/openai-go/v2"
 
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	kbMiddleware "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/middleware"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/service"
 	"github.com/gin-gonic/gin"
 )
 
 type DocumentHandler struct {
 	documentService *service.DocumentService
+	recorder        *audit.Recorder
 }
 
-func NewDocumentHandler(documentService *service.DocumentService) *DocumentHandler {
+func NewDocumentHandler(documentService *service.DocumentService, recorder *audit.Recorder) *DocumentHandler {
 	return &DocumentHandler{
 		documentService: documentService,
+		recorder:        recorder,
 	}
 }
 
@@ -137,6 +142,11 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		return
 	}
 
+	// 未填写部门时取上传人所在部门
+	if department == "" {
+		department = user.(*model.User).Department
+	}
+
 	// 构建服务请求
 	req := &model.UploadDocumentRequest{
 		File:            file,
@@ -151,6 +161,7 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		CreatedBy:       user.(*model.User).ID,
 		CreatorNickName: user.(*model.User).Nickname,
 		Department:      department,
+		OrgUnitID:       user.(*model.User).OrgUnitID,
 		NeedApproval:    needApproval,
 		Version:         version,
 		UseType:         model.UseType(useType),
@@ -166,6 +177,14 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		return
 	}
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDocumentUpload,
+		ResourceType: "document",
+		ResourceID:   document.ID,
+		SpaceID:      document.SpaceID,
+		After:        document,
+	})
+
 	c.JSON(http.StatusOK, &model.APIResponse{
 		Code:    http.StatusOK,
 		Message: "Document uploaded successfully",
@@ -326,7 +345,37 @@ func (h *DocumentHandler) SearchKnowledge(c *gin.Context) {
 		return
 	}
 
-	results, err := h.documentService.SearchKnowledge(c.Request.Context(), &req)
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	// 限定空间的 API Key 只能检索授权空间
+	if _, restricted := kbMiddleware.APIKeySpaces(c); restricted {
+		if req.SpaceID == 0 {
+			c.JSON(http.StatusForbidden, &model.APIResponse{
+				Code:    http.StatusForbidden,
+				Message: "API key is restricted to specific spaces; space_id is required",
+			})
+			return
+		}
+		if !kbMiddleware.APIKeyAllowsSpace(c, req.SpaceID) {
+			c.JSON(http.StatusForbidden, &model.APIResponse{
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("API key is not granted for space %d", req.SpaceID),
+			})
+			return
+		}
+	}
+
+	results, err := h.documentService.SearchKnowledge(c.Request.Context(), &req, user.ID)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationKnowledgeSearch,
+		ResourceType: "knowledge",
+		SpaceID:      req.SpaceID,
+		Detail:       req.Query,
+		Err:          err,
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrVectorClientNotConfigured):
@@ -334,6 +383,11 @@ func (h *DocumentHandler) SearchKnowledge(c *gin.Context) {
 				Code:    http.StatusInternalServerError,
 				Message: "Vector search not configured",
 			})
+		case errors.Is(err, service.ErrOrgUnitNotFound):
+			c.JSON(http.StatusBadRequest, &model.APIResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Org unit not found",
+			})
 		default:
 			c.JSON(http.StatusInternalServerError, &model.APIResponse{
 				Code:    http.StatusInternalServerError,
@@ -439,6 +493,13 @@ func (h *DocumentHandler) PreviewDocument(c *gin.Context) {
 	}
 	defer fileReader.Close()
 
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDocumentPreview,
+		ResourceType: "document",
+		ResourceID:   document.ID,
+		SpaceID:      document.SpaceID,
+	})
+
 	// 使用CopyBuffer提高性能，添加错误处理
 	buffer := make([]byte, 64*1024) // 64KB buffer for better performance
 	_, err = io.CopyBuffer(c.Writer, fileReader, buffer)
@@ -538,6 +599,7 @@ func (h *DocumentHandler) PublishDocument(c *gin.Context) {
 		return
 	}
 
+	beforeStatus := document.Status
 	document.Status = model.DocumentStatusPublished
 	err = h.documentService.PublishDocument(c.Request.Context(), document)
 	if err != nil {
@@ -547,6 +609,15 @@ func (h *DocumentHandler) PublishDocument(c *gin.Context) {
 		})
 		return
 	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDocumentPublish,
+		ResourceType: "document",
+		ResourceID:   document.ID,
+		SpaceID:      document.SpaceID,
+		Before:       gin.H{"status": beforeStatus},
+		After:        gin.H{"status": document.Status},
+	})
 	c.JSON(http.StatusOK, &model.APIResponse{
 		Code:    http.StatusOK,
 		Message: "Document published successfully",
@@ -554,6 +625,180 @@ func (h *DocumentHandler) PublishDocument(c *gin.Context) {
 	})
 }
 
+// GetReviewHistory 获取文档的审批记录，包含每一轮的步骤、审批意见和时间
+func (h *DocumentHandler) GetReviewHistory(c *gin.Context) {
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid document ID",
+		})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, &model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "User not found",
+		})
+		return
+	}
+
+	history, err := h.documentService.GetReviewHistory(c.Request.Context(), uint(documentID), user.(*model.User).ID)
+	if err != nil {
+		if err.Error() == "document not found" {
+			c.JSON(http.StatusNotFound, &model.APIResponse{
+				Code:    http.StatusNotFound,
+				Message: "Document not found",
+			})
+			return
+		}
+		if errors.Is(err, commonClient.ErrWorkflowForbidden) {
+			c.JSON(http.StatusForbidden, &model.APIResponse{
+				Code:    http.StatusForbidden,
+				Message: "No permission to view review history",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get review history: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    history,
+	})
+}
+
+// ResubmitDocument 审批被拒绝或撤回后重新提交，可选替换文件（file）或修改元数据
+func (h *DocumentHandler) ResubmitDocument(c *gin.Context) {
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid document ID",
+		})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, &model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "User not found",
+		})
+		return
+	}
+
+	req := &model.ResubmitDocumentRequest{
+		DocumentID: uint(documentID),
+		UserID:     user.(*model.User).ID,
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	switch {
+	case err == nil:
+		defer file.Close()
+		req.File = file
+		req.FileName = c.PostForm("file_name")
+		if req.FileName == "" {
+			req.FileName = header.Filename
+		}
+		req.FileSize = header.Size
+		req.ContentType = header.Header.Get("Content-Type")
+	case !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart):
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to get uploaded file",
+		})
+		return
+	}
+
+	for field, target := range map[string]*uint{"sub_space_id": &req.SubSpaceID, "class_id": &req.ClassID} {
+		value := c.PostForm(field)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &model.APIResponse{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid " + field,
+			})
+			return
+		}
+		*target = uint(id)
+	}
+	if tags, ok := c.GetPostForm("tags"); ok {
+		req.Tags = &tags
+	}
+	if summary, ok := c.GetPostForm("summary"); ok {
+		req.Summary = &summary
+	}
+	if department, ok := c.GetPostForm("department"); ok {
+		req.Department = &department
+	}
+	if version, ok := c.GetPostForm("version"); ok {
+		req.Version = &version
+	}
+	if useType, ok := c.GetPostForm("use_type"); ok {
+		value := model.UseType(useType)
+		req.UseType = &value
+	}
+
+	before, err := h.documentService.GetDocument(c.Request.Context(), uint(documentID))
+	if err != nil {
+		if err.Error() == "document not found" {
+			c.JSON(http.StatusNotFound, &model.APIResponse{
+				Code:    http.StatusNotFound,
+				Message: "Document not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get document: " + err.Error(),
+		})
+		return
+	}
+
+	document, err := h.documentService.ResubmitDocument(c.Request.Context(), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, model.ErrDocumentResubmitForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, model.ErrDocumentNotResubmittable):
+			status = http.StatusConflict
+		}
+		c.JSON(status, &model.APIResponse{
+			Code:    status,
+			Message: "Failed to resubmit document: " + err.Error(),
+		})
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDocumentResubmit,
+		ResourceType: "document",
+		ResourceID:   document.ID,
+		SpaceID:      document.SpaceID,
+		Before:       gin.H{"status": before.Status, "workflow_id": before.WorkflowID, "file_name": before.FileName},
+		After:        gin.H{"status": document.Status, "workflow_id": document.WorkflowID, "file_name": document.FileName},
+	})
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Document resubmitted successfully",
+		Data:    document,
+	})
+}
+
 func (h *DocumentHandler) UnpublishDocument(c *gin.Context) {
 	documentIDStr := c.Param("id")
 	documentID, err := strconv.ParseUint(documentIDStr, 10, 32)
@@ -582,6 +827,7 @@ func (h *DocumentHandler) UnpublishDocument(c *gin.Context) {
 		return
 	}
 
+	beforeStatus := document.Status
 	document.Status = model.DocumentStatusPendingPublish
 	err = h.documentService.UnpublishDocument(c.Request.Context(), document)
 	if err != nil {
@@ -591,6 +837,15 @@ func (h *DocumentHandler) UnpublishDocument(c *gin.Context) {
 		})
 		return
 	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDocumentUnpublish,
+		ResourceType: "document",
+		ResourceID:   document.ID,
+		SpaceID:      document.SpaceID,
+		Before:       gin.H{"status": beforeStatus},
+		After:        gin.H{"status": document.Status},
+	})
 	c.JSON(http.StatusOK, &model.APIResponse{
 		Code:    http.StatusOK,
 		Message: "Document unpublished successfully",
@@ -619,6 +874,14 @@ func (h *DocumentHandler) ChatDocument(c *gin.Context) {
 	}
 
 	resp, err := h.documentService.ChatDocument(c.Request.Context(), uint(spaceID), &chatReq)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationKnowledgeChat,
+		ResourceType: "space",
+		ResourceID:   uint(spaceID),
+		SpaceID:      uint(spaceID),
+		Detail:       chatReq.Question,
+		Err:          err,
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrEmptyChatQuestion):
@@ -669,6 +932,14 @@ func (h *DocumentHandler) ChatDocumentStream(c *gin.Context) {
 	}
 
 	result, err := h.documentService.ChatDocumentStream(c.Request.Context(), uint(spaceID), &chatReq)
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationKnowledgeChat,
+		ResourceType: "space",
+		ResourceID:   uint(spaceID),
+		SpaceID:      uint(spaceID),
+		Detail:       chatReq.Question,
+		Err:          err,
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrEmptyChatQuestion):
@@ -812,7 +1083,35 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	err = h.documentService.DeleteDocument(c.Request.Context(), uint(documentID))
+	before, err := h.documentService.GetDocument(c.Request.Context(), uint(documentID))
+	if err != nil {
+		if err.Error() == "document not found" {
+			c.JSON(http.StatusNotFound, &model.APIResponse{
+				Code:    http.StatusNotFound,
+				Message: "Document not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, &model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get document: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	pending, err := h.documentService.RequestDocumentDeletion(c.Request.Context(), before, user.ID)
+	if errors.Is(err, service.ErrDocumentDeletionPending) {
+		c.JSON(http.StatusConflict, &model.APIResponse{
+			Code:    http.StatusConflict,
+			Message: "Document deletion is pending approval",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, &model.APIResponse{
 			Code:    http.StatusInternalServerError,
@@ -820,6 +1119,22 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 		})
 		return
 	}
+	if pending {
+		// 审批通过后由流程结束事件删除文档
+		c.JSON(http.StatusAccepted, &model.APIResponse{
+			Code:    http.StatusAccepted,
+			Message: "Document deletion is awaiting approval",
+		})
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDocumentDelete,
+		ResourceType: "document",
+		ResourceID:   before.ID,
+		SpaceID:      before.SpaceID,
+		Before:       before,
+	})
 
 	c.JSON(http.StatusOK, &model.APIResponse{
 		Code:    http.StatusNoContent,
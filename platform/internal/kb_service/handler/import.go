@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ImportHandler struct {
+	importService *service.ImportService
+	recorder      *audit.Recorder
+}
+
+func NewImportHandler(importService *service.ImportService, recorder *audit.Recorder) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+		recorder:      recorder,
+	}
+}
+
+// ImportArchive 从导出包导入数据，dry_run=true 时只返回导入计划与冲突
+func (h *ImportHandler) ImportArchive(c *gin.Context) {
+	var req model.ImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "No archive uploaded",
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to open archive: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.importService.Import(c.Request.Context(), file, fileHeader.Size, req.DryRun, user)
+	if report != nil && !req.DryRun {
+		// 中途失败时已导入的文档同样需要处理
+		h.importService.EnqueueProcessing(report.DocumentIDs)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrImportForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, service.ErrImportInvalidArchive):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, &model.APIResponse{
+			Code:    status,
+			Message: "Failed to import archive: " + err.Error(),
+			Data:    report,
+		})
+		return
+	}
+
+	if !req.DryRun {
+		h.recorder.Record(c, audit.Entry{
+			Action:       model.OperationDataImport,
+			ResourceType: "archive",
+			ResourceID:   fileHeader.Filename,
+			After:        gin.H{"created": report.Created, "reused": report.Reused, "skipped": report.Skipped},
+		})
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    report,
+	})
+}
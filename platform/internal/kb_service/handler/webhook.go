@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/service"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook 创建 Webhook 订阅
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req model.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.CreateSubscription(c.Request.Context(), &req, user)
+	if err != nil {
+		respondWebhookError(c, "Failed to create webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Webhook created successfully",
+		Data:    subscription,
+	})
+}
+
+// ListWebhooks 获取空间下的 Webhook 订阅
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	spaceID, err := strconv.ParseUint(c.Query("space_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid space_id",
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := h.webhookService.ListSubscriptions(c.Request.Context(), uint(spaceID), user)
+	if err != nil {
+		respondWebhookError(c, "Failed to list webhooks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    subscriptions,
+	})
+}
+
+// GetWebhook 获取 Webhook 订阅详情
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid webhook ID",
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.GetSubscription(c.Request.Context(), uint(id), user)
+	if err != nil {
+		respondWebhookError(c, "Failed to get webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    subscription,
+	})
+}
+
+// UpdateWebhook 更新 Webhook 订阅
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid webhook ID",
+		})
+		return
+	}
+
+	var req model.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.UpdateSubscription(c.Request.Context(), uint(id), &req, user)
+	if err != nil {
+		respondWebhookError(c, "Failed to update webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Webhook updated successfully",
+		Data:    subscription,
+	})
+}
+
+// DeleteWebhook 删除 Webhook 订阅
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid webhook ID",
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(c.Request.Context(), uint(id), user); err != nil {
+		respondWebhookError(c, "Failed to delete webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// ListDeliveries 获取 Webhook 投递记录
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid webhook ID",
+		})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), uint(id), c.Query("status"), page, pageSize, user)
+	if err != nil {
+		respondWebhookError(c, "Failed to list deliveries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    deliveries,
+	})
+}
+
+// RedeliverWebhook 手动重新投递
+func (h *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid delivery ID",
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.Request.Context(), uint(deliveryID), user)
+	if err != nil {
+		respondWebhookError(c, "Failed to redeliver webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Webhook redelivered",
+		Data:    delivery,
+	})
+}
+
+// currentUser 从上下文获取 FetchUserFromHeader 设置的用户，失败时直接写入响应
+func currentUser(c *gin.Context) (*model.User, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, &model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "User not found",
+		})
+		return nil, false
+	}
+
+	userModel, ok := user.(*model.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, &model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Invalid user info",
+		})
+		return nil, false
+	}
+	return userModel, true
+}
+
+func respondWebhookError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrWebhookDeliveryNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrWebhookForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrWebhookInvalidEvent), errors.Is(err, service.ErrWebhookInvalidURL):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, &model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
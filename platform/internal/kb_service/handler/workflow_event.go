@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/outbox"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxWorkflowEventBody 单个事件请求体的大小上限
+const maxWorkflowEventBody = 64 << 10
+
+// WorkflowEventHandler 接收工作流服务投递的流程结束事件
+type WorkflowEventHandler struct {
+	documentService *service.DocumentService
+	secret          string
+}
+
+// NewWorkflowEventHandler 创建工作流事件处理器，secret 用于校验事件签名
+func NewWorkflowEventHandler(documentService *service.DocumentService, secret string) *WorkflowEventHandler {
+	return &WorkflowEventHandler{
+		documentService: documentService,
+		secret:          secret,
+	}
+}
+
+// ReceiveWorkflowEvent 校验签名后更新文档状态；重复投递的事件直接返回成功
+func (h *WorkflowEventHandler) ReceiveWorkflowEvent(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWorkflowEventBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to read request body",
+		})
+		return
+	}
+
+	if err := outbox.Verify(h.secret, c.GetHeader(webhook.HeaderTimestamp), c.GetHeader(webhook.HeaderSignature), body); err != nil {
+		c.JSON(http.StatusUnauthorized, &model.APIResponse{
+			Code:    http.StatusUnauthorized,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var event model.WorkflowStatusEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		c.JSON(http.StatusBadRequest, &model.APIResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	applied, err := h.documentService.ApplyWorkflowEvent(c.Request.Context(), &event)
+	if errors.Is(err, service.ErrWorkflowEventNotReady) {
+		logger.Warnf("defer workflow event %s: %v", event.EventID, err)
+		c.JSON(http.StatusConflict, &model.APIResponse{
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		logger.Errorf("failed to apply workflow event %s: %v", event.EventID, err)
+		c.JSON(http.StatusInternalServerError, &model.APIResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to apply workflow event: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.APIResponse{
+		Code:    http.StatusOK,
+		Message: "Workflow event accepted",
+		Data:    gin.H{"event_id": event.EventID, "duplicate": !applied},
+	})
+}
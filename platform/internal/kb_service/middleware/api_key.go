@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeySpaces 返回网关写入的 API Key 空间范围，restricted 为 false 表示不是通过限定空间的 API Key 调用
+func APIKeySpaces(c *gin.Context) (spaces map[uint]bool, restricted bool) {
+	values, ok := c.Request.Header[http.CanonicalHeaderKey(model.HeaderAPIKeySpaces)]
+	if !ok {
+		return nil, false
+	}
+
+	spaces = make(map[uint]bool)
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if id, err := strconv.ParseUint(strings.TrimSpace(item), 10, 32); err == nil {
+				spaces[uint(id)] = true
+			}
+		}
+	}
+	return spaces, true
+}
+
+// APIKeyAllowsSpace 判断本次调用是否可以访问指定空间
+func APIKeyAllowsSpace(c *gin.Context, spaceID uint) bool {
+	spaces, restricted := APIKeySpaces(c)
+	return !restricted || spaces[spaceID]
+}
+
+// RestrictAPIKeySpace 通过限定空间的 API Key 访问带 :id 的文档接口时，校验文档所属空间在授权范围内
+func RestrictAPIKeySpace(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, restricted := APIKeySpaces(c); !restricted {
+			c.Next()
+			return
+		}
+
+		document, ok := loadDocument(c, db)
+		if !ok {
+			return
+		}
+		if !APIKeyAllowsSpace(c, document.SpaceID) {
+			abort(c, http.StatusForbidden, fmt.Sprintf("API key is not granted for space %d", document.SpaceID))
+			return
+		}
+		c.Next()
+	}
+}
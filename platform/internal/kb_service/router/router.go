@@ -1,10 +1,17 @@
 package router
 
 import (
+	"log"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/middleware"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/config"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/handler"
+	kbMiddleware "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/middleware"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/service"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -12,11 +19,16 @@ import (
 	"gorm.io/gorm"
 )
 
-func Setup(cfg *config.Config, db *gorm.DB, minioClient *client.S3Client, workflowClient *client.WorkflowClient, openaiClient *client.OpenAIClient, ocrClient *client.PaddleOCRClient, vectorClient *client.QdrantClient) *gin.Engine {
+func Setup(cfg *config.Config, db *gorm.DB, minioClient *client.S3Client, workflowClient *commonClient.WorkflowClient, iamClient *client.IamClient, openaiClient *client.OpenAIClient, ocrClient *client.PaddleOCRClient, vectorClient *client.QdrantClient, dispatcher *webhook.Dispatcher) *gin.Engine {
 	// 设置Gin模式
 	gin.SetMode(cfg.Gin.Mode)
 
 	r := gin.New()
+	// 客户端IP只取网关写入的 X-Real-IP，且只采信来自网关的请求头
+	r.RemoteIPHeaders = []string{"X-Real-IP"}
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted_proxies: %v", err)
+	}
 
 	// 添加中间件
 	r.Use(middleware.Logger())
@@ -24,10 +36,26 @@ func Setup(cfg *config.Config, db *gorm.DB, minioClient *client.S3Client, workfl
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/swagger/doc.json")))
 
 	// 创建服务层
-	documentService := service.NewDocumentService(db, minioClient, workflowClient, openaiClient, ocrClient, vectorClient)
+	documentService := service.NewDocumentService(db, minioClient, workflowClient, openaiClient, ocrClient, vectorClient, dispatcher)
+	documentService.UseIamClient(iamClient)
+	webhookService := service.NewWebhookService(db, dispatcher)
+	exportService := service.NewExportService(db, minioClient, documentService)
+	importService := service.NewImportService(db, minioClient, documentService)
 
 	// 创建处理器
-	documentHandler := handler.NewDocumentHandler(documentService)
+	recorder := audit.NewRecorder(db, audit.ServiceKb)
+	documentHandler := handler.NewDocumentHandler(documentService, recorder)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	exportHandler := handler.NewExportHandler(exportService, recorder)
+	importHandler := handler.NewImportHandler(importService, recorder)
+	workflowEventHandler := handler.NewWorkflowEventHandler(documentService, cfg.Outbox.Secret)
+
+	// 空间权限校验，按 IAM 中的空间角色权限表判断
+	canUpload := kbMiddleware.RequireDocumentPermission(db, iamClient, model.PermissionCreateDocument)
+	canDelete := kbMiddleware.RequireDocumentPermission(db, iamClient, model.PermissionDeleteDocument)
+	canPublish := kbMiddleware.RequireDocumentPermission(db, iamClient, model.PermissionPublishDocument)
+	// 通过限定空间的 API Key 调用时，校验文档所属空间
+	inKeySpace := kbMiddleware.RestrictAPIKeySpace(db)
 
 	// API路由组
 	api := r.Group("/api/v1")
@@ -39,26 +67,58 @@ func Setup(cfg *config.Config, db *gorm.DB, minioClient *client.S3Client, workfl
 		// 文档相关路由
 		documents := api.Group("/documents")
 		{
-			documents.POST("/upload", middleware.FetchUserFromHeader(db), documentHandler.UploadDocument)
+			documents.POST("/upload", middleware.FetchUserFromHeader(db), canUpload, documentHandler.UploadDocument)
 			documents.GET("/tag-cloud", documentHandler.GetTagCloud)
-			documents.POST("/search", documentHandler.SearchKnowledge)
+			documents.POST("/search", middleware.FetchUserFromHeader(db), documentHandler.SearchKnowledge) // 只返回有查看权限的空间中的结果
+			documents.POST("/search/export", middleware.FetchUserFromHeader(db), exportHandler.ExportSearchResults)
 
-			documents.GET("/:id/preview", documentHandler.PreviewDocument)
-			documents.GET("/:id/info", documentHandler.GetDocument)
+			documents.GET("/:id/preview", inKeySpace, documentHandler.PreviewDocument)
+			documents.GET("/:id/info", inKeySpace, documentHandler.GetDocument)
 			documents.GET("/:id/space", documentHandler.GetDocumentsBySpaceId)
 			documents.GET("/homepage", documentHandler.GetHomepageDocuments) // 展示5个知识库，3个二级知识库，每个二级知识库展示6个文档
 
-			documents.DELETE("/:id", middleware.FetchUserFromHeader(db), documentHandler.DeleteDocument)
+			documents.DELETE("/:id", middleware.FetchUserFromHeader(db), canDelete, documentHandler.DeleteDocument)
+
+			documents.POST("/:id/publish", middleware.FetchUserFromHeader(db), canPublish, documentHandler.PublishDocument)
+			documents.POST("/:id/unpublish", middleware.FetchUserFromHeader(db), canPublish, documentHandler.UnpublishDocument)
+			documents.GET("/:id/review-history", middleware.FetchUserFromHeader(db), documentHandler.GetReviewHistory)        // 审批记录
+			documents.POST("/:id/resubmit", middleware.FetchUserFromHeader(db), inKeySpace, documentHandler.ResubmitDocument) // 被拒绝或撤回后重新提交审批
 
-			documents.POST("/:id/publish", middleware.FetchUserFromHeader(db), documentHandler.PublishDocument)
-			documents.POST("/:id/unpublish", middleware.FetchUserFromHeader(db), documentHandler.UnpublishDocument)
+			// 工作流服务投递的流程结束事件，仅供服务间调用，通过签名校验来源
+			documents.POST("/workflow-events", workflowEventHandler.ReceiveWorkflowEvent)
 
 			// 重试处理
 			documents.POST("/retry-process", middleware.FetchUserFromHeader(db), documentHandler.RetryProcessDocument)
 
 			// 文档对话
-			documents.POST("/:id/chat", documentHandler.ChatDocument)
-			documents.POST("/:id/chat/stream", documentHandler.ChatDocumentStream)
+			documents.POST("/:id/chat", inKeySpace, documentHandler.ChatDocument)
+			documents.POST("/:id/chat/stream", inKeySpace, documentHandler.ChatDocumentStream)
+
+			// Webhook 订阅
+			webhooks := documents.Group("/webhooks")
+			webhooks.Use(middleware.FetchUserFromHeader(db))
+			{
+				webhooks.POST("", webhookHandler.CreateWebhook)
+				webhooks.GET("", webhookHandler.ListWebhooks) // ?space_id=
+				webhooks.GET("/:id", webhookHandler.GetWebhook)
+				webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+				webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+				webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+				webhooks.POST("/deliveries/:delivery_id/redeliver", webhookHandler.RedeliverWebhook)
+			}
+
+			// 数据导出
+			exports := documents.Group("/exports")
+			exports.Use(middleware.FetchUserFromHeader(db))
+			{
+				exports.POST("", exportHandler.CreateExportJob)
+				exports.GET("", exportHandler.ListExportJobs)
+				exports.GET("/:id", exportHandler.GetExportJob)
+				exports.GET("/:id/download", exportHandler.DownloadExportJob)
+			}
+
+			// 从导出包导入
+			documents.POST("/import", middleware.FetchUserFromHeader(db), importHandler.ImportArchive)
 		}
 	}
 
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+// ErrDocumentDeletionPending 文档已有审批中的删除申请
+var ErrDocumentDeletionPending = errors.New("document deletion is pending approval")
+
+// RequestDocumentDeletion 删除文档。空间为文档删除绑定了审批模板时先发起审批，审批通过后再删除，
+// 没有绑定模板时直接删除。返回 true 表示已提交审批，文档尚未删除
+func (s *DocumentService) RequestDocumentDeletion(ctx context.Context, document *model.Document, userID uint) (bool, error) {
+	if document.DeletionWorkflowID != 0 {
+		return false, ErrDocumentDeletionPending
+	}
+	if s.workflowClient == nil {
+		return false, s.DeleteDocument(ctx, document.ID)
+	}
+
+	workflowID, err := s.workflowClient.CreateWorkflowFromTemplate(ctx, &model.CreateWorkflowFromTemplateRequest{
+		MatchWorkflowTemplateRequest: model.MatchWorkflowTemplateRequest{
+			ResourceType: model.ResourceTypeDocumentDeletion,
+			SpaceID:      document.SpaceID,
+			SubSpaceID:   document.SubSpaceID,
+			ClassID:      document.ClassID,
+		},
+		ResourceID: document.ID,
+	}, userID)
+	if errors.Is(err, commonClient.ErrWorkflowTemplateNotFound) {
+		return false, s.DeleteDocument(ctx, document.ID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create deletion workflow: %w", err)
+	}
+
+	// 先关联流程再启动，流程结束事件到达时一定能找到文档；条件更新避免并发申请重复关联
+	result := s.db.WithContext(ctx).Model(&model.Document{}).
+		Where("id = ? AND deletion_workflow_id = 0", document.ID).
+		Update("deletion_workflow_id", workflowID)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to link deletion workflow: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, ErrDocumentDeletionPending
+	}
+
+	started, err := s.workflowClient.StartWorkflow(ctx, workflowID, userID, map[string]any{
+		"file_name":    document.FileName,
+		"status":       string(document.Status),
+		"space_id":     document.SpaceID,
+		"sub_space_id": document.SubSpaceID,
+		"class_id":     document.ClassID,
+		"created_by":   document.CreatedBy,
+	})
+	if err != nil {
+		// 流程没有启动，解除关联以便重新申请
+		s.db.WithContext(ctx).Model(&model.Document{}).
+			Where("id = ? AND deletion_workflow_id = ?", document.ID, workflowID).
+			Update("deletion_workflow_id", 0)
+		return false, fmt.Errorf("failed to start deletion workflow: %w", err)
+	}
+	if started.Status != model.WorkflowStatusCompleted {
+		return true, nil
+	}
+
+	// 没有满足条件的审批步骤时流程直接完成，完成事件可能已先删除文档
+	filePath, err := deleteLinkedDocument(s.db.WithContext(ctx), document.ID, workflowID)
+	if err != nil {
+		return false, err
+	}
+	s.removeDocumentFile(ctx, filePath)
+	return false, nil
+}
+
+// applyDocumentDeletionWorkflowEvent 删除审批通过后删除文档，其他结果解除文档与删除流程的关联
+func (s *DocumentService) applyDocumentDeletionWorkflowEvent(tx *gorm.DB, event *model.WorkflowStatusEvent) (func(), error) {
+	switch event.EventType {
+	case model.WorkflowEventCompleted:
+		filePath, err := deleteLinkedDocument(tx, event.ResourceID, event.WorkflowID)
+		if err != nil {
+			return nil, err
+		}
+		if filePath == "" {
+			logger.Warnf("ignore workflow event %s: document %d is not linked to deletion workflow %d", event.EventID, event.ResourceID, event.WorkflowID)
+			return nil, nil
+		}
+		// 文件无法随事务回滚，提交后再删除
+		return func() { s.removeDocumentFile(context.Background(), filePath) }, nil
+	case model.WorkflowEventRejected, model.WorkflowEventCancelled, model.WorkflowEventWithdrawn:
+		if err := tx.Model(&model.Document{}).
+			Where("id = ? AND deletion_workflow_id = ?", event.ResourceID, event.WorkflowID).
+			Update("deletion_workflow_id", 0).Error; err != nil {
+			return nil, fmt.Errorf("failed to clear deletion workflow: %w", err)
+		}
+		return nil, nil
+	default:
+		logger.Warnf("ignore unsupported workflow event %s (%s)", event.EventID, event.EventType)
+		return nil, nil
+	}
+}
+
+// deleteLinkedDocument 删除仍关联该删除流程的文档，返回文档的文件路径；文档已删除或不再关联时返回空串
+func deleteLinkedDocument(tx *gorm.DB, documentID uint, workflowID uint) (string, error) {
+	var document model.Document
+	if err := tx.Select("id", "file_path").
+		Where("deletion_workflow_id = ?", workflowID).
+		First(&document, documentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get document: %w", err)
+	}
+
+	result := tx.Where("deletion_workflow_id = ?", workflowID).Delete(&model.Document{}, documentID)
+	if result.Error != nil {
+		return "", fmt.Errorf("failed to delete document: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return "", nil
+	}
+	return document.FilePath, nil
+}
+
+// removeDocumentFile 删除已删除文档的文件，失败只记录日志，文档记录已删除不再回滚
+func (s *DocumentService) removeDocumentFile(ctx context.Context, filePath string) {
+	if filePath == "" {
+		return
+	}
+	if err := s.minioClient.DeleteFile(ctx, filePath); err != nil {
+		logger.Warnf("failed to remove file %s of deleted document: %v", filePath, err)
+	}
+}
@@ -0,0 +1,809 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/csvsafe"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/client"
+)
+
+const exportPathPrefix = "/exports/"
+
+var (
+	ErrExportForbidden     = errors.New("no permission to export data")
+	ErrExportInvalidScope  = errors.New("invalid export scope")
+	ErrExportJobNotFound   = errors.New("export job not found")
+	ErrExportJobNotReady   = errors.New("export job is not finished")
+	ErrExportInvalidFormat = errors.New("unsupported export format")
+)
+
+// ExportService 数据导出：空间/二级空间/分类打包导出与检索结果导出
+type ExportService struct {
+	db              *gorm.DB
+	minioClient     *client.S3Client
+	documentService *DocumentService
+}
+
+// exportRejectedErrors 审批未通过时写入导出任务的错误信息
+var exportRejectedErrors = map[model.WorkflowEventType]string{
+	model.WorkflowEventRejected:  "export approval was rejected",
+	model.WorkflowEventCancelled: "export approval was cancelled",
+	model.WorkflowEventWithdrawn: "export approval was withdrawn",
+}
+
+// NewExportService 创建导出服务，并向文档服务注册导出审批结果的处理
+func NewExportService(db *gorm.DB, minioClient *client.S3Client, documentService *DocumentService) *ExportService {
+	s := &ExportService{
+		db:              db,
+		minioClient:     minioClient,
+		documentService: documentService,
+	}
+	documentService.RegisterWorkflowEventApplier(model.ResourceTypeDataExport, s.applyWorkflowEvent)
+	return s
+}
+
+// CreateJob 创建导出任务并在后台执行。空间为数据导出绑定了审批模板时先发起审批，
+// 任务保持待审批状态，审批通过后再执行；全量导出不属于任何空间，不走审批
+func (s *ExportService) CreateJob(ctx context.Context, req *model.CreateExportJobRequest, user *model.User) (*model.ExportJob, error) {
+	job := &model.ExportJob{
+		Scope:       req.Scope,
+		SpaceID:     req.SpaceID,
+		SubSpaceID:  req.SubSpaceID,
+		ClassID:     req.ClassID,
+		IncludeText: req.IncludeText,
+		Status:      model.ExportJobStatusPendingApproval,
+		CreatedBy:   user.ID,
+	}
+	if err := s.resolveScope(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := s.checkExportPermission(ctx, user, job.Scope, job.SpaceID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	pending, err := s.submitForApproval(ctx, job)
+	if err != nil {
+		now := time.Now()
+		s.db.WithContext(ctx).Model(job).Updates(map[string]any{
+			"status":      model.ExportJobStatusFailed,
+			"error":       err.Error(),
+			"finished_at": &now,
+		})
+		return nil, err
+	}
+	if pending {
+		return job, nil
+	}
+
+	// 无需审批，或者没有满足条件的审批步骤时流程直接完成；完成事件可能已先启动任务
+	released, err := releaseExportJob(s.db.WithContext(ctx), job.ID, job.WorkflowID)
+	if err != nil {
+		return nil, err
+	}
+	if released {
+		s.startJob(job.ID)
+	}
+	job.Status = model.ExportJobStatusPending
+	return job, nil
+}
+
+// submitForApproval 按导出范围实例化数据导出审批模板并启动流程，返回 true 表示流程审批中。
+// 没有绑定模板、全量导出或未配置工作流服务时不发起审批
+func (s *ExportService) submitForApproval(ctx context.Context, job *model.ExportJob) (bool, error) {
+	workflowClient := s.documentService.workflowClient
+	if job.SpaceID == 0 || workflowClient == nil {
+		return false, nil
+	}
+
+	workflowID, err := workflowClient.CreateWorkflowFromTemplate(ctx, &model.CreateWorkflowFromTemplateRequest{
+		MatchWorkflowTemplateRequest: model.MatchWorkflowTemplateRequest{
+			ResourceType: model.ResourceTypeDataExport,
+			SpaceID:      job.SpaceID,
+			SubSpaceID:   job.SubSpaceID,
+			ClassID:      job.ClassID,
+		},
+		ResourceID: job.ID,
+	}, job.CreatedBy)
+	if errors.Is(err, commonClient.ErrWorkflowTemplateNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create export workflow: %w", err)
+	}
+
+	// 先关联流程再启动，流程结束事件到达时一定能找到任务
+	if err := s.db.WithContext(ctx).Model(job).Update("workflow_id", workflowID).Error; err != nil {
+		return false, fmt.Errorf("failed to link export workflow: %w", err)
+	}
+	started, err := workflowClient.StartWorkflow(ctx, workflowID, job.CreatedBy, map[string]any{
+		"scope":        string(job.Scope),
+		"space_id":     job.SpaceID,
+		"sub_space_id": job.SubSpaceID,
+		"class_id":     job.ClassID,
+		"include_text": job.IncludeText,
+		"created_by":   job.CreatedBy,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to start export workflow: %w", err)
+	}
+	return started.Status != model.WorkflowStatusCompleted, nil
+}
+
+// applyWorkflowEvent 导出审批通过后执行任务，其他结果把任务标记为已拒绝
+func (s *ExportService) applyWorkflowEvent(tx *gorm.DB, event *model.WorkflowStatusEvent) (func(), error) {
+	if event.EventType == model.WorkflowEventCompleted {
+		released, err := releaseExportJob(tx, event.ResourceID, event.WorkflowID)
+		if err != nil {
+			return nil, err
+		}
+		if !released {
+			return nil, checkExportJobLinked(tx, event)
+		}
+		// 任务在后台读取导出数据，提交后再启动
+		return func() { s.startJob(event.ResourceID) }, nil
+	}
+
+	message, ok := exportRejectedErrors[event.EventType]
+	if !ok {
+		logger.Warnf("ignore unsupported workflow event %s (%s)", event.EventID, event.EventType)
+		return nil, nil
+	}
+	now := time.Now()
+	result := tx.Model(&model.ExportJob{}).
+		Where("id = ? AND workflow_id = ? AND status = ?", event.ResourceID, event.WorkflowID, model.ExportJobStatusPendingApproval).
+		Updates(map[string]any{
+			"status":      model.ExportJobStatusRejected,
+			"error":       message,
+			"finished_at": &now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update export job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, checkExportJobLinked(tx, event)
+	}
+	return nil, nil
+}
+
+// releaseExportJob 把仍在等待该流程审批的任务改为待执行，返回 false 表示任务已被放行或不再关联该流程
+func releaseExportJob(tx *gorm.DB, jobID uint, workflowID uint) (bool, error) {
+	result := tx.Model(&model.ExportJob{}).
+		Where("id = ? AND workflow_id = ? AND status = ?", jobID, workflowID, model.ExportJobStatusPendingApproval).
+		Update("status", model.ExportJobStatusPending)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update export job status: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// checkExportJobLinked 事件没有更新任务时区分已处理和尚未关联：任务不存在或已处理则忽略，
+// 任务还没关联到该流程时返回 ErrWorkflowEventNotReady 由投递方重试
+func checkExportJobLinked(tx *gorm.DB, event *model.WorkflowStatusEvent) error {
+	var job model.ExportJob
+	if err := tx.Select("id", "workflow_id").First(&job, event.ResourceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warnf("ignore workflow event %s of missing export job %d", event.EventID, event.ResourceID)
+			return nil
+		}
+		return fmt.Errorf("failed to get export job: %w", err)
+	}
+	if job.WorkflowID >= event.WorkflowID {
+		logger.Warnf("ignore workflow event %s: export job %d is linked to workflow %d and no longer awaiting it",
+			event.EventID, job.ID, job.WorkflowID)
+		return nil
+	}
+	return fmt.Errorf("%w: export job %d is linked to workflow %d, event is for workflow %d",
+		ErrWorkflowEventNotReady, job.ID, job.WorkflowID, event.WorkflowID)
+}
+
+// startJob 在后台执行导出任务
+func (s *ExportService) startJob(jobID uint) {
+	go func() {
+		if err := s.runJob(context.Background(), jobID); err != nil {
+			logger.Errorf("export job %d failed: %v", jobID, err)
+		}
+	}()
+}
+
+// GetJob 获取导出任务，只有创建者和超级管理员可以查看
+func (s *ExportService) GetJob(ctx context.Context, id uint, user *model.User) (*model.ExportJob, error) {
+	var job model.ExportJob
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExportJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+	if job.CreatedBy != user.ID && !hasGlobalRole(user, model.RoleSuperAdmin) {
+		return nil, ErrExportJobNotFound
+	}
+	return &job, nil
+}
+
+// ListJobs 分页获取当前用户的导出任务
+func (s *ExportService) ListJobs(ctx context.Context, user *model.User, page int, pageSize int) (*model.PaginationResponse, error) {
+	query := s.db.WithContext(ctx).Model(&model.ExportJob{}).Where("created_by = ?", user.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count export jobs: %w", err)
+	}
+
+	var jobs []model.ExportJob
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list export jobs: %w", err)
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	return &model.PaginationResponse{
+		Items:      jobs,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// DownloadJob 下载导出结果
+func (s *ExportService) DownloadJob(ctx context.Context, id uint, user *model.User) (*model.ExportJob, io.ReadCloser, error) {
+	job, err := s.GetJob(ctx, id, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != model.ExportJobStatusSucceeded || job.FilePath == "" {
+		return nil, nil, ErrExportJobNotReady
+	}
+
+	reader, err := s.minioClient.DownloadFile(ctx, job.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, reader, nil
+}
+
+// ExportSearchResults 执行检索并将结果导出为 CSV 或 Excel 文件内容
+func (s *ExportService) ExportSearchResults(ctx context.Context, req *model.ExportSearchRequest, user *model.User) ([]byte, error) {
+	scope := model.ExportScopeSpace
+	if req.SpaceID == 0 {
+		scope = model.ExportScopeAll
+	}
+	if err := s.checkExportPermission(ctx, user, scope, req.SpaceID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.documentService.SearchKnowledge(ctx, &req.KnowledgeSearchRequest, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	header := []string{"文档ID", "片段ID", "标题", "文件名", "相关度", "摘要", "内容"}
+	rows := make([][]string, 0, len(result.Items))
+	for _, item := range result.Items {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(item.DocumentID), 10),
+			strconv.FormatUint(uint64(item.ChunkID), 10),
+			item.Title,
+			item.FileName,
+			strconv.FormatFloat(item.Score, 'f', 4, 64),
+			item.Snippet,
+			item.Content,
+		})
+	}
+
+	var buf bytes.Buffer
+	switch req.Format {
+	case "", "csv":
+		// 写入 UTF-8 BOM，避免 Excel 打开中文乱码
+		buf.WriteString("\xEF\xBB\xBF")
+		err = writeCSV(&buf, header, rows)
+	case "xlsx":
+		err = writeXLSX(&buf, "检索结果", header, rows)
+	default:
+		err = ErrExportInvalidFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resolveScope 校验导出范围参数，并补全所属空间
+func (s *ExportService) resolveScope(ctx context.Context, job *model.ExportJob) error {
+	db := s.db.WithContext(ctx)
+	switch job.Scope {
+	case model.ExportScopeAll:
+		job.SpaceID, job.SubSpaceID, job.ClassID = 0, 0, 0
+	case model.ExportScopeSpace:
+		if job.SpaceID == 0 {
+			return fmt.Errorf("%w: space_id is required", ErrExportInvalidScope)
+		}
+		job.SubSpaceID, job.ClassID = 0, 0
+	case model.ExportScopeSubSpace:
+		var subSpace model.SubSpace
+		if err := db.First(&subSpace, job.SubSpaceID).Error; err != nil {
+			return fmt.Errorf("%w: sub space not found", ErrExportInvalidScope)
+		}
+		job.SpaceID, job.ClassID = subSpace.SpaceID, 0
+	case model.ExportScopeClass:
+		var class model.Class
+		if err := db.First(&class, job.ClassID).Error; err != nil {
+			return fmt.Errorf("%w: class not found", ErrExportInvalidScope)
+		}
+		var subSpace model.SubSpace
+		if err := db.First(&subSpace, class.SubSpaceID).Error; err != nil {
+			return fmt.Errorf("%w: sub space not found", ErrExportInvalidScope)
+		}
+		job.SpaceID, job.SubSpaceID = subSpace.SpaceID, subSpace.ID
+	default:
+		return ErrExportInvalidScope
+	}
+	return nil
+}
+
+// checkExportPermission 全量导出需要 export_all_data；按范围导出需要 export_all_data，
+// 或者 export_data 且为该空间成员
+func (s *ExportService) checkExportPermission(ctx context.Context, user *model.User, scope model.ExportScope, spaceID uint) error {
+	var u model.User
+	if err := s.db.WithContext(ctx).Preload("Roles.Permissions").First(&u, user.ID).Error; err != nil {
+		return fmt.Errorf("failed to load user permissions: %w", err)
+	}
+
+	permissions := make(map[model.PermissionName]bool)
+	for _, role := range u.Roles {
+		if role.Name == model.RoleSuperAdmin {
+			return nil
+		}
+		for _, permission := range role.Permissions {
+			permissions[permission.Name] = true
+		}
+	}
+
+	if permissions[model.PermissionExportAllData] {
+		return nil
+	}
+	if scope == model.ExportScopeAll || !permissions[model.PermissionExportData] {
+		return ErrExportForbidden
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&model.SpaceMember{}).
+		Where("space_id = ? AND user_id = ?", spaceID, user.ID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check space membership: %w", err)
+	}
+	if count == 0 {
+		return ErrExportForbidden
+	}
+	return nil
+}
+
+// runJob 执行导出：打包原始文件、manifest 和可选的解析文本，上传到 MinIO
+func (s *ExportService) runJob(ctx context.Context, jobID uint) error {
+	var job model.ExportJob
+	if err := s.db.WithContext(ctx).First(&job, jobID).Error; err != nil {
+		return err
+	}
+	s.db.Model(&job).Updates(map[string]any{"status": model.ExportJobStatusRunning, "progress": 0})
+
+	fail := func(err error) error {
+		now := time.Now()
+		s.db.Model(&job).Updates(map[string]any{
+			"status":      model.ExportJobStatusFailed,
+			"error":       err.Error(),
+			"finished_at": &now,
+		})
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", fmt.Sprintf("kb_export_%d_*.zip", job.ID))
+	if err != nil {
+		return fail(fmt.Errorf("failed to create temp file: %w", err))
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	count, err := s.writeArchive(ctx, &job, tmp)
+	if err != nil {
+		return fail(err)
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fail(err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fail(err)
+	}
+
+	objectName := fmt.Sprintf("%sexport_%d_%s.zip", exportPathPrefix, job.ID, time.Now().Format("20060102150405"))
+	if _, err := s.minioClient.UploadFile(ctx, objectName, tmp, size, "application/zip"); err != nil {
+		return fail(fmt.Errorf("failed to upload export archive: %w", err))
+	}
+
+	now := time.Now()
+	return s.db.Model(&job).Updates(map[string]any{
+		"status":         model.ExportJobStatusSucceeded,
+		"progress":       100,
+		"document_count": count,
+		"file_path":      objectName,
+		"file_size":      size,
+		"finished_at":    &now,
+	}).Error
+}
+
+func (s *ExportService) writeArchive(ctx context.Context, job *model.ExportJob, w io.Writer) (int, error) {
+	db := s.db.WithContext(ctx)
+
+	manifest := model.ExportManifest{
+		ExportedAt: time.Now(),
+		ExportedBy: job.CreatedBy,
+		Scope:      job.Scope,
+	}
+	if err := s.loadStructure(ctx, job, &manifest); err != nil {
+		return 0, err
+	}
+
+	var documents []model.Document
+	if err := scopeDocuments(db, job).Order("id ASC").Find(&documents).Error; err != nil {
+		return 0, fmt.Errorf("failed to load documents: %w", err)
+	}
+
+	creators, err := s.loadCreators(ctx, documents)
+	if err != nil {
+		return 0, err
+	}
+
+	archive := zip.NewWriter(w)
+
+	for i, doc := range documents {
+		entry := model.ExportManifestDocument{
+			Document:        doc,
+			ApprovalHistory: []model.ExportApprovalRecord{},
+		}
+		if creator, ok := creators[doc.CreatedBy]; ok {
+			entry.CreatorUsername = creator.Username
+			entry.CreatorPhone = creator.Phone
+		}
+
+		archivePath := path.Join("files", fmt.Sprintf("%d_%s", doc.ID, sanitizeArchiveName(doc.FileName)))
+		if err := s.copyObject(ctx, archive, archivePath, doc.FilePath); err != nil {
+			// 单个文件缺失不影响整体导出，记录在日志中
+			logger.Warnf("export job %d: skip file of document %d: %v", job.ID, doc.ID, err)
+		} else {
+			entry.ArchivePath = archivePath
+		}
+
+		if job.IncludeText {
+			textPath := path.Join("text", fmt.Sprintf("%d.txt", doc.ID))
+			written, err := s.writeDocumentText(ctx, archive, textPath, doc.ID)
+			if err != nil {
+				return 0, err
+			}
+			if written {
+				entry.TextPath = textPath
+			}
+		}
+
+		history, err := s.loadApprovalHistory(ctx, doc.ID, job.CreatedBy)
+		if err != nil {
+			return 0, err
+		}
+		entry.ApprovalHistory = history
+
+		manifest.Documents = append(manifest.Documents, entry)
+
+		progress := (i + 1) * 90 / len(documents)
+		db.Model(job).Update("progress", progress)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := writeArchiveFile(archive, "manifest.json", manifestJSON); err != nil {
+		return 0, err
+	}
+
+	csvWriter, err := archive.Create("manifest.csv")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := csvWriter.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return 0, err
+	}
+	if err := writeCSV(csvWriter, manifestCSVHeader, manifestCSVRows(manifest.Documents)); err != nil {
+		return 0, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return 0, fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	return len(documents), nil
+}
+
+// loadStructure 加载导出范围内的空间、二级空间和分类
+func (s *ExportService) loadStructure(ctx context.Context, job *model.ExportJob, manifest *model.ExportManifest) error {
+	db := s.db.WithContext(ctx)
+
+	spaceQuery := db.Model(&model.Space{})
+	subSpaceQuery := db.Model(&model.SubSpace{})
+	classQuery := db.Model(&model.Class{})
+
+	switch job.Scope {
+	case model.ExportScopeSpace:
+		spaceQuery = spaceQuery.Where("id = ?", job.SpaceID)
+		subSpaceQuery = subSpaceQuery.Where("space_id = ?", job.SpaceID)
+		classQuery = classQuery.Where("sub_space_id IN (?)", db.Model(&model.SubSpace{}).Select("id").Where("space_id = ?", job.SpaceID))
+	case model.ExportScopeSubSpace:
+		spaceQuery = spaceQuery.Where("id = ?", job.SpaceID)
+		subSpaceQuery = subSpaceQuery.Where("id = ?", job.SubSpaceID)
+		classQuery = classQuery.Where("sub_space_id = ?", job.SubSpaceID)
+	case model.ExportScopeClass:
+		spaceQuery = spaceQuery.Where("id = ?", job.SpaceID)
+		subSpaceQuery = subSpaceQuery.Where("id = ?", job.SubSpaceID)
+		classQuery = classQuery.Where("id = ?", job.ClassID)
+	}
+
+	if err := spaceQuery.Order("id ASC").Find(&manifest.Spaces).Error; err != nil {
+		return fmt.Errorf("failed to load spaces: %w", err)
+	}
+	if err := subSpaceQuery.Order("id ASC").Find(&manifest.SubSpaces).Error; err != nil {
+		return fmt.Errorf("failed to load sub spaces: %w", err)
+	}
+	if err := classQuery.Order("id ASC").Find(&manifest.Classes).Error; err != nil {
+		return fmt.Errorf("failed to load classes: %w", err)
+	}
+	return nil
+}
+
+func (s *ExportService) loadCreators(ctx context.Context, documents []model.Document) (map[uint]model.User, error) {
+	ids := make([]uint, 0, len(documents))
+	for _, doc := range documents {
+		ids = append(ids, doc.CreatedBy)
+	}
+
+	creators := make(map[uint]model.User)
+	if len(ids) == 0 {
+		return creators, nil
+	}
+
+	var users []model.User
+	if err := s.db.WithContext(ctx).Unscoped().Select("id", "username", "phone", "nickname").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to load document creators: %w", err)
+	}
+	for _, u := range users {
+		creators[u.ID] = u
+	}
+	return creators, nil
+}
+
+// loadApprovalHistory 通过工作流服务获取文档所有审批轮次中的任务记录，只包含导出人有权查看的轮次
+func (s *ExportService) loadApprovalHistory(ctx context.Context, documentID uint, userID uint) ([]model.ExportApprovalRecord, error) {
+	history := []model.ExportApprovalRecord{}
+	workflowClient := s.documentService.workflowClient
+	if workflowClient == nil {
+		return history, nil
+	}
+
+	timeline, err := workflowClient.GetResourceTimeline(ctx, model.ResourceTypeDocument, documentID, userID)
+	if errors.Is(err, commonClient.ErrWorkflowNotFound) || errors.Is(err, commonClient.ErrWorkflowForbidden) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval history: %w", err)
+	}
+
+	for _, round := range timeline.Rounds {
+		if round.Workflow == nil {
+			continue
+		}
+		for _, step := range round.Workflow.Steps {
+			for _, task := range step.Tasks {
+				history = append(history, model.ExportApprovalRecord{
+					WorkflowID:       round.Workflow.ID,
+					StepName:         step.StepName,
+					StepOrder:        step.StepOrder,
+					ApproverID:       task.ApproverID,
+					ApproverNickName: task.ApproverNickName,
+					Status:           task.Status,
+					Comment:          task.Comment,
+				})
+			}
+		}
+	}
+	return history, nil
+}
+
+func (s *ExportService) copyObject(ctx context.Context, archive *zip.Writer, archivePath string, objectName string) error {
+	reader, err := s.minioClient.DownloadFile(ctx, objectName)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	w, err := archive.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+func (s *ExportService) writeDocumentText(ctx context.Context, archive *zip.Writer, archivePath string, documentID uint) (bool, error) {
+	var chunks []model.DocumentChunk
+	if err := s.db.WithContext(ctx).
+		Where("document_id = ?", documentID).
+		Order("index ASC").
+		Find(&chunks).Error; err != nil {
+		return false, fmt.Errorf("failed to load document chunks: %w", err)
+	}
+	if len(chunks) == 0 {
+		return false, nil
+	}
+
+	contents := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		contents = append(contents, chunk.Content)
+	}
+	return true, writeArchiveFile(archive, archivePath, []byte(strings.Join(contents, "\n\n")))
+}
+
+// scopeDocuments 导出范围内的文档，只包含已发布的文档和导出人自己上传的文档，
+// 其他人未发布、被驳回或已撤回的文档不应随空间一起导出
+func scopeDocuments(db *gorm.DB, job *model.ExportJob) *gorm.DB {
+	query := db.Model(&model.Document{}).
+		Where("status = ? OR created_by = ?", model.DocumentStatusPublished, job.CreatedBy)
+	switch job.Scope {
+	case model.ExportScopeSpace:
+		query = query.Where("space_id = ?", job.SpaceID)
+	case model.ExportScopeSubSpace:
+		query = query.Where("sub_space_id = ?", job.SubSpaceID)
+	case model.ExportScopeClass:
+		query = query.Where("class_id = ?", job.ClassID)
+	}
+	return query
+}
+
+var manifestCSVHeader = []string{
+	"文档ID", "标题", "文件名", "文件类型", "文件大小", "版本", "状态", "使用类型", "标签", "摘要",
+	"空间ID", "二级空间ID", "分类ID", "部门", "创建人ID", "创建人用户名", "创建人手机号", "创建人昵称",
+	"创建时间", "更新时间", "文件路径", "文本路径", "审批记录",
+}
+
+func manifestCSVRows(documents []model.ExportManifestDocument) [][]string {
+	rows := make([][]string, 0, len(documents))
+	for _, doc := range documents {
+		approvals := make([]string, 0, len(doc.ApprovalHistory))
+		for _, record := range doc.ApprovalHistory {
+			approvals = append(approvals, fmt.Sprintf("%s/%s/%s/%s", record.StepName, record.ApproverNickName, record.Status, record.Comment))
+		}
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(doc.ID), 10),
+			doc.Title,
+			doc.FileName,
+			doc.FileType,
+			strconv.FormatInt(doc.FileSize, 10),
+			doc.Version,
+			string(doc.Status),
+			string(doc.UseType),
+			doc.Tags,
+			doc.Summary,
+			strconv.FormatUint(uint64(doc.SpaceID), 10),
+			strconv.FormatUint(uint64(doc.SubSpaceID), 10),
+			strconv.FormatUint(uint64(doc.ClassID), 10),
+			doc.Department,
+			strconv.FormatUint(uint64(doc.CreatedBy), 10),
+			doc.CreatorUsername,
+			doc.CreatorPhone,
+			doc.CreatorNickName,
+			doc.CreatedAt.Format(time.RFC3339),
+			doc.UpdatedAt.Format(time.RFC3339),
+			doc.ArchivePath,
+			doc.TextPath,
+			strings.Join(approvals, "; "),
+		})
+	}
+	return rows
+}
+
+func writeArchiveFile(archive *zip.Writer, name string, data []byte) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// writeCSV 写入 CSV，单元格经过转义，避免以 = + - @ 开头的内容在 Excel 中被当作公式执行
+func writeCSV(w io.Writer, header []string, rows [][]string) error {
+	writer := csvsafe.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	return writer.WriteAll(rows)
+}
+
+func writeXLSX(w io.Writer, sheet string, header []string, rows [][]string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+
+	stream, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	writeRow := func(index int, values []string) error {
+		cells := make([]any, len(values))
+		for i, v := range values {
+			cells[i] = v
+		}
+		cell, err := excelize.CoordinatesToCellName(1, index)
+		if err != nil {
+			return err
+		}
+		return stream.SetRow(cell, cells)
+	}
+
+	if err := writeRow(1, header); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		if err := writeRow(i+2, row); err != nil {
+			return err
+		}
+	}
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// sanitizeArchiveName 去除文件名中的路径分隔符，避免 ZIP 路径穿越
+func sanitizeArchiveName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+func hasGlobalRole(user *model.User, roleName model.RoleName) bool {
+	for _, role := range user.Roles {
+		if role.Name == roleName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
+	commonConfig "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/config"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestScopeDocuments(t *testing.T) {
+	db := dbtest.Open(t, &model.Document{})
+
+	const exporter, other = 1, 2
+	documents := []model.Document{
+		{ID: 1, SpaceID: 1, SubSpaceID: 10, ClassID: 100, CreatedBy: other, Status: model.DocumentStatusPublished},
+		{ID: 2, SpaceID: 1, SubSpaceID: 10, ClassID: 100, CreatedBy: other, Status: model.DocumentStatusPendingApproval},
+		{ID: 3, SpaceID: 1, SubSpaceID: 10, ClassID: 101, CreatedBy: other, Status: model.DocumentStatusFailed},
+		{ID: 4, SpaceID: 1, SubSpaceID: 11, ClassID: 102, CreatedBy: other, Status: model.DocumentStatusWithdrawn},
+		{ID: 5, SpaceID: 1, SubSpaceID: 11, ClassID: 102, CreatedBy: exporter, Status: model.DocumentStatusPendingApproval},
+		{ID: 6, SpaceID: 1, SubSpaceID: 11, ClassID: 102, CreatedBy: other, Status: model.DocumentStatusPublished},
+		{ID: 7, SpaceID: 2, SubSpaceID: 20, ClassID: 200, CreatedBy: other, Status: model.DocumentStatusPublished},
+	}
+	for i := range documents {
+		documents[i].FileName = "f.pdf"
+		documents[i].FilePath = "/f.pdf"
+		documents[i].FileType = "pdf"
+		documents[i].Title = "doc"
+	}
+	if err := db.Create(&documents).Error; err != nil {
+		t.Fatalf("create documents: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		job  model.ExportJob
+		want []uint
+	}{
+		{name: "all", job: model.ExportJob{Scope: model.ExportScopeAll}, want: []uint{1, 5, 6, 7}},
+		{name: "space", job: model.ExportJob{Scope: model.ExportScopeSpace, SpaceID: 1}, want: []uint{1, 5, 6}},
+		{name: "sub space", job: model.ExportJob{Scope: model.ExportScopeSubSpace, SpaceID: 1, SubSpaceID: 11}, want: []uint{5, 6}},
+		{name: "class", job: model.ExportJob{Scope: model.ExportScopeClass, SpaceID: 1, SubSpaceID: 10, ClassID: 101}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.job.CreatedBy = exporter
+			var ids []uint
+			if err := scopeDocuments(db, &tt.job).Pluck("id", &ids).Error; err != nil {
+				t.Fatalf("scopeDocuments() error = %v", err)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			if len(ids) != len(tt.want) {
+				t.Fatalf("documents = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("documents = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	rows := [][]string{{"1", "=HYPERLINK(\"http://evil\")", "@SUM(A1)", "正常"}}
+	if err := writeCSV(&buf, []string{"ID", "标题", "文件名", "摘要"}, rows); err != nil {
+		t.Fatalf("writeCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	want := []string{"1", "'=HYPERLINK(\"http://evil\")", "'@SUM(A1)", "正常"}
+	for i, cell := range records[1] {
+		if cell != want[i] {
+			t.Errorf("cell %d = %q, want %q", i, cell, want[i])
+		}
+	}
+}
+
+func TestLoadApprovalHistory(t *testing.T) {
+	const exporter = 9
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-User-ID") != "9" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Query().Get("resource_id") {
+		case "1":
+			json.NewEncoder(w).Encode(map[string]any{"code": http.StatusOK, "data": model.ResourceTimeline{
+				Rounds: []model.WorkflowTimeline{
+					{Workflow: &model.Workflow{ID: 10, Steps: []model.Step{
+						{StepName: "初审", StepOrder: 1, Tasks: []model.Task{{ApproverID: 2, Status: model.TaskStatusRejected, Comment: "补充材料"}}},
+					}}},
+					{Workflow: &model.Workflow{ID: 11, Steps: []model.Step{
+						{StepName: "初审", StepOrder: 1, Tasks: []model.Task{{ApproverID: 2, Status: model.TaskStatusApproved}}},
+						{StepName: "复审", StepOrder: 2, Tasks: []model.Task{{ApproverID: 3, Status: model.TaskStatusApproved}}},
+					}}},
+				},
+			}})
+		case "2":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	s := &ExportService{documentService: &DocumentService{
+		workflowClient: commonClient.NewWorkflowClient(&commonConfig.WorkflowConfig{Url: server.URL}),
+	}}
+
+	history, err := s.loadApprovalHistory(context.Background(), 1, exporter)
+	if err != nil {
+		t.Fatalf("loadApprovalHistory() error = %v", err)
+	}
+	want := []model.ExportApprovalRecord{
+		{WorkflowID: 10, StepName: "初审", StepOrder: 1, ApproverID: 2, Status: model.TaskStatusRejected, Comment: "补充材料"},
+		{WorkflowID: 11, StepName: "初审", StepOrder: 1, ApproverID: 2, Status: model.TaskStatusApproved},
+		{WorkflowID: 11, StepName: "复审", StepOrder: 2, ApproverID: 3, Status: model.TaskStatusApproved},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("history = %+v, want %+v", history, want)
+	}
+	for i := range want {
+		if history[i] != want[i] {
+			t.Fatalf("history[%d] = %+v, want %+v", i, history[i], want[i])
+		}
+	}
+
+	// 没有审批记录或导出人无权查看时导出空记录
+	for _, documentID := range []uint{2, 3} {
+		history, err := s.loadApprovalHistory(context.Background(), documentID, exporter)
+		if err != nil || len(history) != 0 {
+			t.Fatalf("loadApprovalHistory(%d) = %+v, %v, want empty history", documentID, history, err)
+		}
+	}
+}
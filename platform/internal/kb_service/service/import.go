@@ -0,0 +1,428 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/client"
+)
+
+// maxImportFileSize 导入包中单个文件解压后的大小上限。zip 头中声明的大小不可信，
+// 上传时按实际读取的字节数限制
+const maxImportFileSize = 2 << 30
+
+var (
+	ErrImportForbidden      = errors.New("only super admin or corp admin can import data")
+	ErrImportInvalidArchive = errors.New("invalid export archive")
+)
+
+// ImportService 从导出包导入空间、二级空间、分类和文档
+type ImportService struct {
+	db              *gorm.DB
+	minioClient     *client.S3Client
+	documentService *DocumentService
+}
+
+// NewImportService 创建导入服务
+func NewImportService(db *gorm.DB, minioClient *client.S3Client, documentService *DocumentService) *ImportService {
+	return &ImportService{
+		db:              db,
+		minioClient:     minioClient,
+		documentService: documentService,
+	}
+}
+
+// importPlan 导出包中的ID与目标环境ID的映射，目标ID为 0 表示 dry-run 下待新建
+type importPlan struct {
+	spaces    map[uint]uint
+	subSpaces map[uint]uint
+	classes   map[uint]uint
+	users     map[uint]uint
+}
+
+// Import 导入导出包。空间按名称、二级空间和分类按所属上级与名称匹配已有对象，
+// 用户按手机号、用户名匹配，匹配不到时归属到操作人。dryRun 为 true 时只返回导入计划与冲突
+func (s *ImportService) Import(ctx context.Context, archive io.ReaderAt, size int64, dryRun bool, operator *model.User) (*model.ImportReport, error) {
+	if !hasGlobalRole(operator, model.RoleSuperAdmin) && !hasGlobalRole(operator, model.RoleEnterpriseAdmin) {
+		return nil, ErrImportForbidden
+	}
+
+	reader, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportInvalidArchive, err)
+	}
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		files[f.Name] = f
+	}
+
+	manifest, err := readManifest(files)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ImportReport{
+		DryRun:      dryRun,
+		Items:       []model.ImportItem{},
+		Users:       []model.ImportUserMapping{},
+		Conflicts:   []string{},
+		DocumentIDs: []uint{},
+	}
+	plan := &importPlan{
+		spaces:    make(map[uint]uint),
+		subSpaces: make(map[uint]uint),
+		classes:   make(map[uint]uint),
+		users:     make(map[uint]uint),
+	}
+
+	// 空间结构在同一个事务中创建，dry-run 时回滚
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.planStructure(tx, manifest, plan, report, dryRun, operator); err != nil {
+			return err
+		}
+		return s.planUsers(tx, manifest, plan, report, operator)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 文档逐个上传并入库，中途失败时已导入的文档保留，重新导入同一个包时会按同名同版本跳过，
+	// 从失败的文档继续
+	for _, doc := range manifest.Documents {
+		if err := s.importDocument(ctx, files, &doc, plan, report, dryRun); err != nil {
+			return report, err
+		}
+	}
+
+	for _, item := range report.Items {
+		switch item.Action {
+		case model.ImportActionCreate:
+			report.Created++
+		case model.ImportActionReuse:
+			report.Reused++
+		case model.ImportActionSkip:
+			report.Skipped++
+		}
+	}
+
+	return report, nil
+}
+
+// EnqueueProcessing 在后台依次处理导入的文档（解析、向量化）
+func (s *ImportService) EnqueueProcessing(documentIDs []uint) {
+	if len(documentIDs) == 0 {
+		return
+	}
+	go s.ProcessDocuments(context.Background(), documentIDs)
+}
+
+// ProcessDocuments 依次处理导入的文档，单个文档失败不影响其他文档
+func (s *ImportService) ProcessDocuments(ctx context.Context, documentIDs []uint) {
+	for _, id := range documentIDs {
+		if err := s.documentService.ProcessDocument(ctx, id); err != nil {
+			logger.Errorf("failed to process imported document %d: %v", id, err)
+		}
+	}
+}
+
+func (s *ImportService) planStructure(tx *gorm.DB, manifest *model.ExportManifest, plan *importPlan, report *model.ImportReport, dryRun bool, operator *model.User) error {
+	for _, space := range manifest.Spaces {
+		item := model.ImportItem{Type: "space", SourceID: space.ID, Name: space.Name}
+
+		var existing model.Space
+		err := tx.Where("name = ?", space.Name).First(&existing).Error
+		switch {
+		case err == nil:
+			item.Action, item.TargetID = model.ImportActionReuse, existing.ID
+			item.Reason = "space with the same name already exists"
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			item.Action = model.ImportActionCreate
+			if !dryRun {
+				created := model.Space{
+					Name:        space.Name,
+					Description: space.Description,
+					Type:        space.Type,
+					Status:      space.Status,
+					CreatedBy:   operator.ID,
+				}
+				if err := tx.Create(&created).Error; err != nil {
+					return fmt.Errorf("failed to create space %q: %w", space.Name, err)
+				}
+				item.TargetID = created.ID
+			}
+		default:
+			return fmt.Errorf("failed to look up space %q: %w", space.Name, err)
+		}
+
+		plan.spaces[space.ID] = item.TargetID
+		report.Items = append(report.Items, item)
+	}
+
+	for _, subSpace := range manifest.SubSpaces {
+		item := model.ImportItem{Type: "sub_space", SourceID: subSpace.ID, Name: subSpace.Name}
+
+		spaceID, ok := plan.spaces[subSpace.SpaceID]
+		if !ok {
+			item.Action, item.Reason = model.ImportActionSkip, "parent space is not in the archive"
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("sub space %q: %s", subSpace.Name, item.Reason))
+			report.Items = append(report.Items, item)
+			continue
+		}
+
+		var existing model.SubSpace
+		err := gorm.ErrRecordNotFound
+		if spaceID != 0 {
+			err = tx.Where("space_id = ? AND name = ?", spaceID, subSpace.Name).First(&existing).Error
+		}
+		switch {
+		case err == nil:
+			item.Action, item.TargetID = model.ImportActionReuse, existing.ID
+			item.Reason = "sub space with the same name already exists"
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			item.Action = model.ImportActionCreate
+			if !dryRun {
+				created := model.SubSpace{
+					Name:        subSpace.Name,
+					Description: subSpace.Description,
+					Status:      subSpace.Status,
+					CreatedBy:   operator.ID,
+					SpaceID:     spaceID,
+				}
+				if err := tx.Create(&created).Error; err != nil {
+					return fmt.Errorf("failed to create sub space %q: %w", subSpace.Name, err)
+				}
+				item.TargetID = created.ID
+			}
+		default:
+			return fmt.Errorf("failed to look up sub space %q: %w", subSpace.Name, err)
+		}
+
+		plan.subSpaces[subSpace.ID] = item.TargetID
+		report.Items = append(report.Items, item)
+	}
+
+	for _, class := range manifest.Classes {
+		item := model.ImportItem{Type: "class", SourceID: class.ID, Name: class.Name}
+
+		subSpaceID, ok := plan.subSpaces[class.SubSpaceID]
+		if !ok {
+			item.Action, item.Reason = model.ImportActionSkip, "parent sub space is not in the archive"
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("class %q: %s", class.Name, item.Reason))
+			report.Items = append(report.Items, item)
+			continue
+		}
+
+		var existing model.Class
+		err := gorm.ErrRecordNotFound
+		if subSpaceID != 0 {
+			err = tx.Where("sub_space_id = ? AND name = ?", subSpaceID, class.Name).First(&existing).Error
+		}
+		switch {
+		case err == nil:
+			item.Action, item.TargetID = model.ImportActionReuse, existing.ID
+			item.Reason = "class with the same name already exists"
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			item.Action = model.ImportActionCreate
+			if !dryRun {
+				created := model.Class{
+					Name:        class.Name,
+					Description: class.Description,
+					Status:      class.Status,
+					CreatedBy:   operator.ID,
+					SubSpaceID:  subSpaceID,
+				}
+				if err := tx.Create(&created).Error; err != nil {
+					return fmt.Errorf("failed to create class %q: %w", class.Name, err)
+				}
+				item.TargetID = created.ID
+			}
+		default:
+			return fmt.Errorf("failed to look up class %q: %w", class.Name, err)
+		}
+
+		plan.classes[class.ID] = item.TargetID
+		report.Items = append(report.Items, item)
+	}
+
+	return nil
+}
+
+// planUsers 按手机号、用户名匹配文档创建人
+func (s *ImportService) planUsers(tx *gorm.DB, manifest *model.ExportManifest, plan *importPlan, report *model.ImportReport, operator *model.User) error {
+	for _, doc := range manifest.Documents {
+		if _, ok := plan.users[doc.CreatedBy]; ok {
+			continue
+		}
+
+		mapping := model.ImportUserMapping{
+			SourceID: doc.CreatedBy,
+			Username: doc.CreatorUsername,
+			Phone:    doc.CreatorPhone,
+		}
+
+		var user model.User
+		var err error = gorm.ErrRecordNotFound
+		if doc.CreatorPhone != "" {
+			err = tx.Select("id").Where("phone = ?", doc.CreatorPhone).First(&user).Error
+			mapping.MatchedBy = "phone"
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) && doc.CreatorUsername != "" {
+			err = tx.Select("id").Where("username = ?", doc.CreatorUsername).First(&user).Error
+			mapping.MatchedBy = "username"
+		}
+		switch {
+		case err == nil:
+			mapping.TargetID = user.ID
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			mapping.TargetID, mapping.MatchedBy, mapping.FallbackUsed = operator.ID, "fallback", true
+			report.Conflicts = append(report.Conflicts, fmt.Sprintf("user %q (%s) not found, documents will be owned by %s", doc.CreatorUsername, doc.CreatorPhone, operator.Username))
+		default:
+			return fmt.Errorf("failed to look up user %q: %w", doc.CreatorUsername, err)
+		}
+
+		plan.users[doc.CreatedBy] = mapping.TargetID
+		report.Users = append(report.Users, mapping)
+	}
+	return nil
+}
+
+func (s *ImportService) importDocument(ctx context.Context, files map[string]*zip.File, doc *model.ExportManifestDocument, plan *importPlan, report *model.ImportReport, dryRun bool) error {
+	// 导出包中的文件名不可信，去掉路径部分，避免写到对象存储的其他目录下
+	fileName := sanitizeArchiveName(path.Base(doc.FileName))
+	item := model.ImportItem{Type: "document", SourceID: doc.ID, Name: fileName}
+	skip := func(reason string) {
+		item.Action, item.Reason = model.ImportActionSkip, reason
+		report.Conflicts = append(report.Conflicts, fmt.Sprintf("document %q: %s", fileName, reason))
+		report.Items = append(report.Items, item)
+	}
+
+	spaceID, ok := plan.spaces[doc.SpaceID]
+	if !ok {
+		skip("space is not in the archive")
+		return nil
+	}
+	subSpaceID, ok := plan.subSpaces[doc.SubSpaceID]
+	if !ok && doc.SubSpaceID != 0 {
+		skip("sub space is not in the archive or was skipped")
+		return nil
+	}
+	classID, ok := plan.classes[doc.ClassID]
+	if !ok && doc.ClassID != 0 {
+		skip("class is not in the archive or was skipped")
+		return nil
+	}
+
+	file, ok := files[doc.ArchivePath]
+	if doc.ArchivePath == "" || !ok {
+		skip("original file is missing from the archive")
+		return nil
+	}
+	if file.UncompressedSize64 > maxImportFileSize {
+		skip("original file exceeds the size limit")
+		return nil
+	}
+
+	// 目标位置已存在时，同名同版本文档视为冲突；dry-run 下待新建的位置不可能有冲突
+	locationExists := spaceID != 0 && (doc.SubSpaceID == 0 || subSpaceID != 0) && (doc.ClassID == 0 || classID != 0)
+	if locationExists {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&model.Document{}).
+			Where("space_id = ? AND sub_space_id = ? AND class_id = ? AND file_name = ? AND version = ?",
+				spaceID, subSpaceID, classID, fileName, doc.Version).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check existing documents: %w", err)
+		}
+		if count > 0 {
+			skip("document with the same file name and version already exists")
+			return nil
+		}
+	}
+
+	item.Action = model.ImportActionCreate
+	if dryRun {
+		report.Items = append(report.Items, item)
+		return nil
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", doc.ArchivePath, err)
+	}
+	defer reader.Close()
+
+	filePath := fmt.Sprintf("%s%d_%s", client.PathPrefixPermanent, time.Now().UnixNano(), fileName)
+	uploadedSize, err := s.minioClient.UploadFile(ctx, filePath, io.LimitReader(reader, maxImportFileSize+1), -1, doc.MimeType)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", fileName, err)
+	}
+	if uploadedSize > maxImportFileSize {
+		s.removeObject(ctx, filePath)
+		return fmt.Errorf("%w: %s exceeds the size limit", ErrImportInvalidArchive, fileName)
+	}
+
+	document := &model.Document{
+		Title:           doc.Title,
+		FileName:        fileName,
+		FilePath:        filePath,
+		FileSize:        uploadedSize,
+		FileType:        doc.FileType,
+		Status:          model.DocumentStatusUploading,
+		NeedApproval:    doc.NeedApproval,
+		MimeType:        doc.MimeType,
+		Version:         doc.Version,
+		UseType:         doc.UseType,
+		SpaceID:         spaceID,
+		SubSpaceID:      subSpaceID,
+		ClassID:         classID,
+		CreatedBy:       plan.users[doc.CreatedBy],
+		CreatorNickName: doc.CreatorNickName,
+		Department:      doc.Department,
+		Tags:            doc.Tags,
+		Summary:         doc.Summary,
+	}
+	if err := s.db.WithContext(ctx).Create(document).Error; err != nil {
+		s.removeObject(ctx, filePath)
+		return fmt.Errorf("failed to create document %s: %w", fileName, err)
+	}
+
+	item.TargetID = document.ID
+	report.Items = append(report.Items, item)
+	report.DocumentIDs = append(report.DocumentIDs, document.ID)
+	return nil
+}
+
+// removeObject 删除没有对应文档记录的对象，避免留下孤儿文件
+func (s *ImportService) removeObject(ctx context.Context, objectName string) {
+	if err := s.minioClient.DeleteFile(ctx, objectName); err != nil {
+		logger.Warnf("failed to remove orphaned import object %s: %v", objectName, err)
+	}
+}
+
+func readManifest(files map[string]*zip.File) (*model.ExportManifest, error) {
+	f, ok := files["manifest.json"]
+	if !ok {
+		return nil, fmt.Errorf("%w: manifest.json not found", ErrImportInvalidArchive)
+	}
+
+	reader, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportInvalidArchive, err)
+	}
+	defer reader.Close()
+
+	var manifest model.ExportManifest
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse manifest.json: %v", ErrImportInvalidArchive, err)
+	}
+	return &manifest, nil
+}
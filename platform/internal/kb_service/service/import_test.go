@@ -0,0 +1,99 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func buildArchive(t *testing.T, manifest model.ExportManifest, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("marshal manifest: %v", err)
+	}
+	if err := writeArchiveFile(w, "manifest.json", data); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	for name, content := range files {
+		if err := writeArchiveFile(w, name, []byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close archive: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestImportDryRunSanitizesFileNames(t *testing.T) {
+	db := dbtest.Open(t, &model.Space{}, &model.SubSpace{}, &model.Class{}, &model.User{}, &model.Document{})
+
+	space := model.Space{Name: "研发空间", CreatedBy: 1}
+	if err := db.Create(&space).Error; err != nil {
+		t.Fatalf("create space: %v", err)
+	}
+	existing := model.Document{Title: "已有", FileName: "evil.pdf", FilePath: "/p/evil.pdf", FileType: "pdf", Version: "v1", SpaceID: space.ID}
+	if err := db.Create(&existing).Error; err != nil {
+		t.Fatalf("create document: %v", err)
+	}
+
+	manifestDocument := func(id uint, fileName string) model.ExportManifestDocument {
+		return model.ExportManifestDocument{
+			Document:    model.Document{ID: id, FileName: fileName, Version: "v1", SpaceID: 7},
+			ArchivePath: "files/" + fileName,
+		}
+	}
+	manifest := model.ExportManifest{
+		Spaces: []model.Space{{ID: 7, Name: "研发空间"}},
+		Documents: []model.ExportManifestDocument{
+			manifestDocument(1, "../../etc/evil.pdf"),
+			manifestDocument(2, "nested/dir/report.pdf"),
+			manifestDocument(3, ".."),
+		},
+	}
+	archive := buildArchive(t, manifest, map[string]string{
+		"files/../../etc/evil.pdf":    "a",
+		"files/nested/dir/report.pdf": "b",
+		"files/..":                    "c",
+	})
+
+	s := NewImportService(db, nil, nil)
+	operator := &model.User{ID: 1, Username: "admin", Roles: []model.Role{{Name: model.RoleSuperAdmin}}}
+	report, err := s.Import(context.Background(), archive, archive.Size(), true, operator)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	want := map[uint]struct {
+		name   string
+		action model.ImportAction
+	}{
+		1: {name: "evil.pdf", action: model.ImportActionSkip},
+		2: {name: "report.pdf", action: model.ImportActionCreate},
+		3: {name: "file", action: model.ImportActionCreate},
+	}
+	for _, item := range report.Items {
+		if item.Type != "document" {
+			continue
+		}
+		w, ok := want[item.SourceID]
+		if !ok {
+			t.Fatalf("unexpected document item %+v", item)
+		}
+		if item.Name != w.name || item.Action != w.action {
+			t.Errorf("document %d = %q/%s, want %q/%s", item.SourceID, item.Name, item.Action, w.name, w.action)
+		}
+		delete(want, item.SourceID)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing document items: %v", want)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -19,8 +20,10 @@ import (
 	openai "github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/packages/ssestream"
 
+	commonClient "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/kb_service/client"
 	"gorm.io/gorm"
 )
@@ -29,14 +32,22 @@ import (
 type DocumentService struct {
 	db             *gorm.DB
 	minioClient    *client.S3Client
-	workflowClient *client.WorkflowClient
+	workflowClient *commonClient.WorkflowClient
 	openaiClient   *client.OpenAIClient
 	ocrClient      *client.PaddleOCRClient
 	vectorClient   *client.QdrantClient
+	dispatcher     *webhook.Dispatcher
+	iamClient      *client.IamClient // 检索结果按空间权限过滤，未设置时拒绝检索
+
+	appliersMu            sync.RWMutex
+	workflowEventAppliers map[string]WorkflowEventApplier // 其他组件注册的流程结束事件处理，见 RegisterWorkflowEventApplier
 }
 
 var errUnsupportedFileType = errors.New("unsupported file type for text extraction")
 
+// ErrOrgUnitNotFound 检索条件中的组织单元不存在
+var ErrOrgUnitNotFound = errors.New("org unit not found")
+
 type chunkSearchResult struct {
 	Document *model.Document
 	ChunkID  uint
@@ -54,10 +65,11 @@ type ChatDocumentStreamResult struct {
 func NewDocumentService(
 	db *gorm.DB,
 	minioClient *client.S3Client,
-	workflowClient *client.WorkflowClient,
+	workflowClient *commonClient.WorkflowClient,
 	openaiClient *client.OpenAIClient,
 	ocrClient *client.PaddleOCRClient,
 	vectorClient *client.QdrantClient,
+	dispatcher *webhook.Dispatcher,
 ) *DocumentService {
 	return &DocumentService{
 		db:             db,
@@ -66,9 +78,15 @@ func NewDocumentService(
 		openaiClient:   openaiClient,
 		ocrClient:      ocrClient,
 		vectorClient:   vectorClient,
+		dispatcher:     dispatcher,
 	}
 }
 
+// UseIamClient 设置 IAM 客户端，用于按用户的空间权限过滤检索结果
+func (s *DocumentService) UseIamClient(iamClient *client.IamClient) {
+	s.iamClient = iamClient
+}
+
 // UploadDocument 上传文档
 func (s *DocumentService) UploadDocument(ctx context.Context, req *model.UploadDocumentRequest) (*model.Document, error) {
 	// 设置默认值
@@ -96,6 +114,7 @@ func (s *DocumentService) UploadDocument(ctx context.Context, req *model.UploadD
 		CreatedBy:       req.CreatedBy,
 		CreatorNickName: req.CreatorNickName,
 		Department:      req.Department,
+		OrgUnitID:       req.OrgUnitID,
 		Tags:            req.Tags,
 		Summary:         req.Summary,
 		NeedApproval:    req.NeedApproval,
@@ -121,6 +140,8 @@ func (s *DocumentService) UploadDocument(ctx context.Context, req *model.UploadD
 		return nil, fmt.Errorf("failed to create document record: %w", err)
 	}
 
+	s.dispatcher.Publish(ctx, model.WebhookEventDocumentUploaded, document.SpaceID, document)
+
 	// 异步处理文档（OCR/向量化）
 	// 处理完成后会根据NeedApproval字段决定后续状态
 	go func(docID uint) {
@@ -367,50 +388,83 @@ func (s *DocumentService) prepareChatDocumentFromFiles(ctx context.Context, spac
 	return question, fileContents, sources, nil
 }
 
-// createAndStartWorkflow 创建并启动审批流程（内部方法）
-func (s *DocumentService) createAndStartWorkflow(ctx context.Context, document *model.Document) error {
+// createAndStartWorkflow 创建并启动审批流程（内部方法），返回流程是否已直接完成。
+// 文档在启动前就与新流程关联并进入待审批，流程结束事件到达时总能匹配到文档
+func (s *DocumentService) createAndStartWorkflow(ctx context.Context, document *model.Document) (bool, error) {
 	if s.workflowClient == nil {
-		return errors.New("workflow client is not configured")
+		return false, errors.New("workflow client is not configured")
 	}
 
-	step := model.Step{
-		StepName:     "文档发布审批",
-		StepOrder:    1,
-		StepRole:     string(model.SpaceMemberRoleApprover),
-		IsRequired:   true,
-		TimeoutHours: 24 * 7,
-		Status:       model.StepStatusProcessing,
+	workflowID, err := s.createDocumentWorkflow(ctx, document)
+	if err != nil {
+		return false, fmt.Errorf("failed to create workflow: %w", err)
 	}
-	workflow := model.Workflow{
-		Name:         "文档发布审批流程",
-		Description:  "用于文档发布的审批流程",
-		SpaceID:      document.SpaceID,
-		Status:       model.WorkflowStatusProcessing,
-		Steps:        []model.Step{step},
-		ResourceType: "document",
-		ResourceID:   document.ID,
+
+	previousStatus := document.Status
+	if err := s.db.Model(document).Updates(map[string]any{
+		"workflow_id": workflowID,
+		"status":      model.DocumentStatusPendingApproval,
+	}).Error; err != nil {
+		return false, fmt.Errorf("failed to update document workflow_id: %w", err)
 	}
 
-	workflowID, err := s.workflowClient.CreateWorkflow(ctx, &workflow, document.CreatedBy)
+	// 启动审批流程
+	started, err := s.workflowClient.StartWorkflow(ctx, workflowID, document.CreatedBy, s.workflowAttributes(document))
 	if err != nil {
-		return fmt.Errorf("failed to create workflow: %w", err)
+		// 流程没有启动，恢复原状态以便重新提交
+		s.db.Model(&model.Document{}).
+			Where("id = ? AND workflow_id = ? AND status = ?", document.ID, workflowID, model.DocumentStatusPendingApproval).
+			Update("status", previousStatus)
+		document.Status = previousStatus
+		return false, fmt.Errorf("failed to start workflow: %w", err)
 	}
 
-	// 更新文档的workflow_id
-	if err := s.db.Model(document).Update("workflow_id", workflowID).Error; err != nil {
-		return fmt.Errorf("failed to update document workflow_id: %w", err)
+	if started.Status != model.WorkflowStatusCompleted {
+		return false, nil
+	}
+	// 没有满足条件的审批步骤时流程直接完成，完成事件可能已先到达
+	if err := s.db.Model(&model.Document{}).
+		Where("id = ? AND workflow_id = ? AND status = ?", document.ID, workflowID, model.DocumentStatusPendingApproval).
+		Update("status", model.DocumentStatusPendingPublish).Error; err != nil {
+		return false, fmt.Errorf("failed to update document status: %w", err)
 	}
+	document.Status = model.DocumentStatusPendingPublish
+	return true, nil
+}
 
-	// 启动审批流程
-	_, err = s.workflowClient.StartWorkflow(ctx, workflowID, document.CreatedBy)
+func (s *DocumentService) CreateWorkflow(ctx context.Context, document *model.Document) (*model.Document, error) {
+	workflowID, err := s.createDocumentWorkflow(ctx, document)
 	if err != nil {
-		return fmt.Errorf("failed to start workflow: %w", err)
+		return nil, fmt.Errorf("failed to create workflow: %w", err)
 	}
 
-	return nil
+	s.db.Model(document).Updates(map[string]any{
+		"workflow_id": workflowID,
+	})
+
+	return document, nil
 }
 
-func (s *DocumentService) CreateWorkflow(ctx context.Context, document *model.Document) (*model.Document, error) {
+// createDocumentWorkflow 按文档所在的空间、二级空间和分类实例化审批模板，
+// 没有绑定模板时使用默认的单步发布审批；文档已有流程时新流程作为下一轮提交
+func (s *DocumentService) createDocumentWorkflow(ctx context.Context, document *model.Document) (uint, error) {
+	workflowID, err := s.workflowClient.CreateWorkflowFromTemplate(ctx, &model.CreateWorkflowFromTemplateRequest{
+		MatchWorkflowTemplateRequest: model.MatchWorkflowTemplateRequest{
+			ResourceType: model.ResourceTypeDocument,
+			SpaceID:      document.SpaceID,
+			SubSpaceID:   document.SubSpaceID,
+			ClassID:      document.ClassID,
+		},
+		ResourceID:         document.ID,
+		PreviousWorkflowID: document.WorkflowID,
+	}, document.CreatedBy)
+	if err == nil {
+		return workflowID, nil
+	}
+	if !errors.Is(err, commonClient.ErrWorkflowTemplateNotFound) {
+		return 0, err
+	}
+
 	step := model.Step{
 		StepName:     "文档发布审批",
 		StepOrder:    1,
@@ -425,37 +479,202 @@ func (s *DocumentService) CreateWorkflow(ctx context.Context, document *model.Do
 		SpaceID:      document.SpaceID,
 		Status:       model.WorkflowStatusProcessing,
 		Steps:        []model.Step{step},
-		ResourceType: "document",
+		ResourceType: model.ResourceTypeDocument,
 		ResourceID:   document.ID,
-	}
 
-	workflowID, err := s.workflowClient.CreateWorkflow(ctx, &workflow, document.CreatedBy)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create workflow: %w", err)
+		PreviousWorkflowID: document.WorkflowID,
 	}
-
-	s.db.Model(document).Updates(map[string]any{
-		"workflow_id": workflowID,
-	})
-
-	return document, nil
+	return s.workflowClient.CreateWorkflow(ctx, &workflow, document.CreatedBy)
 }
 
 func (s *DocumentService) StartWorkflow(ctx context.Context, document *model.Document) (*model.Document, error) {
-	_, err := s.workflowClient.StartWorkflow(ctx, document.WorkflowID, document.CreatedBy)
+	// 先进入待审批，启动后产生的流程结束事件不会被这里覆盖
+	if err := s.db.Model(document).Updates(map[string]any{
+		"status":     model.DocumentStatusPendingApproval,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update document status: %w", err)
+	}
+
+	workflow, err := s.workflowClient.StartWorkflow(ctx, document.WorkflowID, document.CreatedBy, s.workflowAttributes(document))
 	if err != nil {
 		return nil, fmt.Errorf("failed to start workflow: %w", err)
 	}
 
-	s.db.Model(document).Updates(map[string]any{
-		"status":     model.DocumentStatusPendingApproval,
-		"updated_at": time.Now(),
-	})
+	// 没有满足条件的审批步骤时流程直接完成，文档进入待发布
+	if workflow.Status == model.WorkflowStatusCompleted {
+		s.db.Model(&model.Document{}).
+			Where("id = ? AND workflow_id = ? AND status = ?", document.ID, document.WorkflowID, model.DocumentStatusPendingApproval).
+			Update("status", model.DocumentStatusPendingPublish)
+	}
 	return document, nil
 }
 
-func (s *DocumentService) CheckWorkflowStatus(ctx context.Context, workflowID uint) (string, error) {
-	status, err := s.workflowClient.CheckWorkflowStatus(ctx, workflowID)
+// ResubmitDocument 审批被拒绝或撤回后由上传人重新提交，可替换文件或修改元数据。
+// 替换文件时重新解析，解析完成后发起新一轮审批；否则直接发起新一轮审批
+func (s *DocumentService) ResubmitDocument(ctx context.Context, req *model.ResubmitDocumentRequest) (*model.Document, error) {
+	var document model.Document
+	if err := s.db.WithContext(ctx).First(&document, req.DocumentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("document not found")
+		}
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	if document.CreatedBy != req.UserID {
+		return nil, model.ErrDocumentResubmitForbidden
+	}
+	if document.WorkflowID == 0 ||
+		(document.Status != model.DocumentStatusFailed && document.Status != model.DocumentStatusWithdrawn) {
+		return nil, model.ErrDocumentNotResubmittable
+	}
+
+	if req.SubSpaceID != 0 {
+		document.SubSpaceID = req.SubSpaceID
+	}
+	if req.ClassID != 0 {
+		document.ClassID = req.ClassID
+	}
+	if req.Tags != nil {
+		document.Tags = *req.Tags
+	}
+	if req.Summary != nil {
+		document.Summary = *req.Summary
+	}
+	if req.Department != nil {
+		document.Department = *req.Department
+	}
+	if req.Version != nil {
+		document.Version = *req.Version
+	}
+	if req.UseType != nil {
+		document.UseType = *req.UseType
+	}
+
+	oldFilePath := ""
+	if req.File != nil {
+		fileExt := strings.ToLower(filepath.Ext(req.FileName))
+		filePath := fmt.Sprintf("%s%d_%s", client.PathPrefixPermanent, time.Now().Unix(), req.FileName)
+		uploadedSize, err := s.minioClient.UploadFile(ctx, filePath, req.File, req.FileSize, req.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload file: %w", err)
+		}
+		if uploadedSize > 0 {
+			req.FileSize = uploadedSize
+		}
+
+		oldFilePath = document.FilePath
+		document.Title = strings.TrimSuffix(req.FileName, fileExt)
+		document.FileName = req.FileName
+		document.FilePath = filePath
+		document.FileSize = req.FileSize
+		document.MimeType = req.ContentType
+		document.FileType = fileExt
+		document.Status = model.DocumentStatusProcessing
+		document.ProcessProgress = 0
+		document.ParseError = ""
+	}
+
+	if err := s.db.WithContext(ctx).Save(&document).Error; err != nil {
+		return nil, fmt.Errorf("failed to update document: %w", err)
+	}
+
+	if oldFilePath != "" {
+		if err := s.minioClient.DeleteFile(ctx, oldFilePath); err != nil {
+			log.Printf("failed to delete replaced file %s of document %d: %v", oldFilePath, document.ID, err)
+		}
+
+		// 解析完成后由 ProcessDocument 发起新一轮审批
+		go func(docID uint) {
+			if err := s.ProcessDocument(context.Background(), docID); err != nil {
+				log.Printf("failed to process resubmitted document %d: %v", docID, err)
+			}
+		}(document.ID)
+		return &document, nil
+	}
+
+	if _, err := s.createAndStartWorkflow(ctx, &document); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// workflowAttributes 构造审批流程分支条件使用的文档属性
+func (s *DocumentService) workflowAttributes(document *model.Document) map[string]any {
+	attrs := map[string]any{
+		"file_name":    document.FileName,
+		"file_type":    document.FileType,
+		"mime_type":    document.MimeType,
+		"file_size":    document.FileSize,
+		"version":      document.Version,
+		"use_type":     string(document.UseType),
+		"department":   document.Department,
+		"space_id":     document.SpaceID,
+		"sub_space_id": document.SubSpaceID,
+		"class_id":     document.ClassID,
+		"created_by":   document.CreatedBy,
+		"tags":         parseTags(document.Tags),
+	}
+
+	var space model.Space
+	if err := s.db.Select("name").First(&space, document.SpaceID).Error; err == nil {
+		attrs["space_name"] = space.Name
+	}
+	var subSpace model.SubSpace
+	if err := s.db.Select("name").First(&subSpace, document.SubSpaceID).Error; err == nil {
+		attrs["sub_space_name"] = subSpace.Name
+	}
+	var class model.Class
+	if err := s.db.Select("name").First(&class, document.ClassID).Error; err == nil {
+		attrs["class_name"] = class.Name
+	}
+	return attrs
+}
+
+// parseTags 解析文档标签，兼容 JSON 数组和逗号分隔两种格式
+func parseTags(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return []string{}
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(raw), &tags); err == nil {
+		return tags
+	}
+	tags = []string{}
+	for _, tag := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '，' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// GetReviewHistory 获取文档所有轮次的审批记录，未提交过审批时返回空列表
+func (s *DocumentService) GetReviewHistory(ctx context.Context, documentID uint, userID uint) (*model.ResourceTimeline, error) {
+	if s.workflowClient == nil {
+		return nil, errors.New("workflow client is not configured")
+	}
+
+	var document model.Document
+	if err := s.db.WithContext(ctx).Select("id").First(&document, documentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("document not found")
+		}
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	timeline, err := s.workflowClient.GetResourceTimeline(ctx, model.ResourceTypeDocument, documentID, userID)
+	if errors.Is(err, commonClient.ErrWorkflowNotFound) {
+		return &model.ResourceTimeline{ResourceType: "document", ResourceID: documentID, Rounds: []model.WorkflowTimeline{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review history: %w", err)
+	}
+	return timeline, nil
+}
+
+func (s *DocumentService) CheckWorkflowStatus(ctx context.Context, workflowID uint, userID uint) (string, error) {
+	status, err := s.workflowClient.CheckWorkflowStatus(ctx, workflowID, userID)
 	if err != nil {
 		return "", fmt.Errorf("failed to check workflow status: %w", err)
 	}
@@ -657,11 +876,14 @@ func (s *DocumentService) GetTagCloud(ctx context.Context, spaceID, subSpaceID u
 	return items, nil
 }
 
-// SearchKnowledge 基于向量的知识搜索
-func (s *DocumentService) SearchKnowledge(ctx context.Context, req *model.KnowledgeSearchRequest) (*model.KnowledgeSearchResponse, error) {
+// SearchKnowledge 基于向量的知识搜索，只返回用户有查看权限的空间中的结果
+func (s *DocumentService) SearchKnowledge(ctx context.Context, req *model.KnowledgeSearchRequest, userID uint) (*model.KnowledgeSearchResponse, error) {
 	if req == nil {
 		return nil, errors.New("search request is nil")
 	}
+	if s.iamClient == nil {
+		return nil, errors.New("iam client is not configured")
+	}
 
 	query := strings.TrimSpace(req.Query)
 	if query == "" {
@@ -675,8 +897,22 @@ func (s *DocumentService) SearchKnowledge(ctx context.Context, req *model.Knowle
 	if limit > 20 {
 		limit = 20
 	}
+	// 按组织单元过滤
+	var orgUnits map[uint]bool
+	if req.OrgUnitID != 0 {
+		var err error
+		if orgUnits, err = s.orgUnitSubtree(ctx, req.OrgUnitID); err != nil {
+			return nil, err
+		}
+	}
 
-	chunks, err := s.searchChunks(ctx, req.SpaceID, query, req.SubSpaceID, req.ClassID, limit)
+	// 跨空间或按组织单元检索时部分结果会被过滤，多取一些候选
+	candidates := limit
+	if req.SpaceID == 0 || orgUnits != nil {
+		candidates = 20
+	}
+
+	chunks, err := s.searchChunks(ctx, req.SpaceID, query, req.SubSpaceID, req.ClassID, candidates)
 	if err != nil {
 		// 向量搜索失败时降级到PostgreSQL全量搜索
 		log.Printf("Vector search failed (%v), falling back to database chunks", err)
@@ -700,6 +936,9 @@ func (s *DocumentService) SearchKnowledge(ctx context.Context, req *model.Knowle
 		if chunk.Document == nil {
 			continue
 		}
+		if orgUnits != nil && (chunk.Document.OrgUnitID == nil || !orgUnits[*chunk.Document.OrgUnitID]) {
+			continue
+		}
 		content := strings.TrimSpace(chunk.Content)
 		if content == "" {
 			continue
@@ -707,6 +946,7 @@ func (s *DocumentService) SearchKnowledge(ctx context.Context, req *model.Knowle
 
 		results = append(results, model.KnowledgeSearchResult{
 			DocumentID: chunk.Document.ID,
+			SpaceID:    chunk.Document.SpaceID,
 			ChunkID:    chunk.ChunkID,
 			Title:      chunk.Document.Title,
 			Content:    content,
@@ -716,11 +956,80 @@ func (s *DocumentService) SearchKnowledge(ctx context.Context, req *model.Knowle
 		})
 	}
 
+	results, err = s.filterViewableResults(ctx, userID, results)
+	if err != nil {
+		return nil, err
+	}
+	if limit < len(results) {
+		results = results[:limit]
+	}
+
 	return &model.KnowledgeSearchResponse{
 		Items: results,
 	}, nil
 }
 
+// orgUnitSubtree 组织单元及其全部下级单元的ID集合
+func (s *DocumentService) orgUnitSubtree(ctx context.Context, orgUnitID uint) (map[uint]bool, error) {
+	db := s.db.WithContext(ctx)
+	var unit model.OrgUnit
+	if err := db.Select("id", "path").First(&unit, orgUnitID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrgUnitNotFound
+		}
+		return nil, fmt.Errorf("failed to load org unit: %w", err)
+	}
+
+	var ids []uint
+	if err := db.Model(&model.OrgUnit{}).Where("path LIKE ?", unit.Path+"%").Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to load org unit subtree: %w", err)
+	}
+	subtree := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		subtree[id] = true
+	}
+	return subtree, nil
+}
+
+// filterViewableResults 通过 IAM 批量检查用户对结果所属空间的查看权限，过滤掉无权查看的结果
+func (s *DocumentService) filterViewableResults(ctx context.Context, userID uint, results []model.KnowledgeSearchResult) ([]model.KnowledgeSearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	view, _ := model.LookupPermission(model.PermissionViewAllContent)
+	checks := make([]model.PermissionCheck, 0)
+	seen := make(map[uint]bool)
+	for _, result := range results {
+		if seen[result.SpaceID] {
+			continue
+		}
+		seen[result.SpaceID] = true
+		checks = append(checks, model.PermissionCheck{
+			SpaceID:  result.SpaceID,
+			Resource: view.Resource,
+			Action:   view.Action,
+		})
+	}
+
+	decisions, err := s.iamClient.CheckPermissions(ctx, userID, checks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check space permissions: %w", err)
+	}
+	allowed := make(map[uint]bool, len(decisions))
+	for _, decision := range decisions {
+		allowed[decision.SpaceID] = decision.HasPermission
+	}
+
+	filtered := results[:0]
+	for _, result := range results {
+		if allowed[result.SpaceID] {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered, nil
+}
+
 // getAllSpaceChunks 获取space下所有chunks（降级策略）
 func (s *DocumentService) getAllSpaceChunks(ctx context.Context, spaceID uint, limit int) ([]chunkSearchResult, error) {
 	// 查询该space下已处理完成的文档（包括待审批、待发布、已发布）
@@ -994,17 +1303,24 @@ func (s *DocumentService) ProcessDocument(ctx context.Context, documentID uint)
 	// 处理完成后，根据是否需要审批决定状态
 	if document.NeedApproval {
 		// 需要审批：创建审批流程
-		if err := s.createAndStartWorkflow(ctx, &document); err != nil {
-			log.Printf("failed to create workflow for document %d: %v", documentID, err)
-			s.updateDocumentStatus(documentID, model.DocumentStatusPendingPublish, 100, "处理完成，但审批流程创建失败，已设为待发布")
+		if completed, err := s.createAndStartWorkflow(ctx, &document); err != nil {
+			// 没有审批人等原因无法提交审批时不能绕过审批发布，标记为失败，上传人处理后重新提交
+			s.markDocumentWorkflowError(documentID, err)
+		} else if completed {
+			s.updateDocumentProgress(documentID, 100, "处理完成，没有满足条件的审批步骤，已设为待发布")
 		} else {
-			s.updateDocumentStatus(documentID, model.DocumentStatusPendingApproval, 100, "处理完成，等待审批")
+			// 状态已在启动流程前设置，这里只更新进度，避免覆盖已到达的审批结果
+			s.updateDocumentProgress(documentID, 100, "处理完成，等待审批")
 		}
 	} else {
 		// 不需要审批：直接设为待发布
 		s.updateDocumentStatus(documentID, model.DocumentStatusPendingPublish, 100, "处理完成，待发布")
 	}
 
+	if err := s.db.WithContext(ctx).First(&document, documentID).Error; err == nil {
+		s.dispatcher.Publish(ctx, model.WebhookEventDocumentProcessed, document.SpaceID, &document)
+	}
+
 	return nil
 }
 
@@ -1129,6 +1445,18 @@ func (s *DocumentService) updateDocumentStatus(documentID uint, status model.Doc
 	}
 }
 
+// updateDocumentProgress 只更新处理进度，不改变文档状态
+func (s *DocumentService) updateDocumentProgress(documentID uint, progress int, message string) {
+	if err := s.db.Model(&model.Document{}).Where("id = ?", documentID).Updates(map[string]any{
+		"process_progress": progress,
+		"parse_error":      "",
+	}).Error; err != nil {
+		log.Printf("failed to update document %d progress: %v", documentID, err)
+	} else {
+		log.Printf("document %d progress updated: %d%% - %s", documentID, progress, message)
+	}
+}
+
 func (s *DocumentService) markDocumentProcessingError(documentID uint, procErr error) {
 	if procErr == nil {
 		return
@@ -1149,6 +1477,18 @@ func (s *DocumentService) markDocumentProcessingError(documentID uint, procErr e
 	}
 }
 
+// markDocumentWorkflowError 解析完成但审批流程提交失败，文档标记为失败并把原因展示给上传人
+func (s *DocumentService) markDocumentWorkflowError(documentID uint, workflowErr error) {
+	logger.Errorf("failed to submit document %d for approval: %v", documentID, workflowErr)
+	if err := s.db.Model(&model.Document{}).Where("id = ?", documentID).Updates(map[string]any{
+		"status":           model.DocumentStatusFailed,
+		"parse_error":      "提交审批失败: " + workflowErr.Error(),
+		"process_progress": 100,
+	}).Error; err != nil {
+		logger.Errorf("failed to mark document %d as failed: %v", documentID, err)
+	}
+}
+
 func extractPlainText(fileType string, data []byte) (string, error) {
 	switch strings.ToLower(fileType) {
 	case ".txt", ".md", ".csv", ".log":
@@ -1470,7 +1810,12 @@ func buildSnippet(content string, maxRunes int) string {
 }
 
 func (s *DocumentService) PublishDocument(ctx context.Context, document *model.Document) error {
-	return s.db.WithContext(ctx).Model(document).Update("status", document.Status).Error
+	if err := s.db.WithContext(ctx).Model(document).Update("status", document.Status).Error; err != nil {
+		return err
+	}
+
+	s.dispatcher.Publish(ctx, model.WebhookEventDocumentPublished, document.SpaceID, document)
+	return nil
 }
 
 func (s *DocumentService) UnpublishDocument(ctx context.Context, document *model.Document) error {
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook subscription not found")
+	ErrWebhookForbidden        = errors.New("no permission to manage webhooks of this space")
+	ErrWebhookInvalidEvent     = errors.New("unsupported webhook event")
+	ErrWebhookInvalidURL       = errors.New("invalid webhook url")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// WebhookService Webhook 订阅管理
+type WebhookService struct {
+	db         *gorm.DB
+	dispatcher *webhook.Dispatcher
+}
+
+// NewWebhookService 创建 Webhook 服务
+func NewWebhookService(db *gorm.DB, dispatcher *webhook.Dispatcher) *WebhookService {
+	return &WebhookService{db: db, dispatcher: dispatcher}
+}
+
+// CreateSubscription 创建订阅，未指定密钥时自动生成
+func (s *WebhookService) CreateSubscription(ctx context.Context, req *model.CreateWebhookRequest, user *model.User) (*model.CreateWebhookResponse, error) {
+	if err := s.checkSpaceAdmin(ctx, user, req.SpaceID); err != nil {
+		return nil, err
+	}
+	if err := validateWebhook(ctx, req.URL, req.Events); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := webhook.GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	subscription := &model.WebhookSubscription{
+		SpaceID:   req.SpaceID,
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		Status:    1,
+		CreatedBy: user.ID,
+	}
+	if err := s.db.WithContext(ctx).Create(subscription).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return &model.CreateWebhookResponse{WebhookSubscription: subscription, Secret: secret}, nil
+}
+
+// ListSubscriptions 获取空间下的订阅
+func (s *WebhookService) ListSubscriptions(ctx context.Context, spaceID uint, user *model.User) ([]model.WebhookSubscription, error) {
+	if err := s.checkSpaceAdmin(ctx, user, spaceID); err != nil {
+		return nil, err
+	}
+
+	var subscriptions []model.WebhookSubscription
+	if err := s.db.WithContext(ctx).Where("space_id = ?", spaceID).Order("id DESC").Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// GetSubscription 获取订阅详情
+func (s *WebhookService) GetSubscription(ctx context.Context, id uint, user *model.User) (*model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+	if err := s.db.WithContext(ctx).First(&subscription, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	if err := s.checkSpaceAdmin(ctx, user, subscription.SpaceID); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// UpdateSubscription 更新订阅
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id uint, req *model.UpdateWebhookRequest, user *model.User) (*model.WebhookSubscription, error) {
+	subscription, err := s.GetSubscription(ctx, id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		subscription.Name = *req.Name
+	}
+	if req.URL != nil {
+		subscription.URL = *req.URL
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		subscription.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		subscription.Events = req.Events
+	}
+	if req.Status != nil {
+		subscription.Status = *req.Status
+	}
+	if err := validateWebhook(ctx, subscription.URL, subscription.Events); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Save(subscription).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// DeleteSubscription 删除订阅，未完成的投递会在下次尝试时标记为失败
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id uint, user *model.User) error {
+	subscription, err := s.GetSubscription(ctx, id, user)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Delete(subscription).Error; err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries 分页获取订阅的投递记录
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID uint, status string, page int, pageSize int, user *model.User) (*model.PaginationResponse, error) {
+	if _, err := s.GetSubscription(ctx, subscriptionID, user); err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	var deliveries []model.WebhookDelivery
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	return &model.PaginationResponse{
+		Items:      deliveries,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// Redeliver 手动重新投递
+func (s *WebhookService) Redeliver(ctx context.Context, deliveryID uint, user *model.User) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := s.db.WithContext(ctx).First(&delivery, deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	if _, err := s.GetSubscription(ctx, delivery.SubscriptionID, user); err != nil {
+		return nil, err
+	}
+
+	return s.dispatcher.Redeliver(ctx, deliveryID)
+}
+
+// checkSpaceAdmin 只有超级管理员、企业管理员或空间管理员可以管理空间的 Webhook
+func (s *WebhookService) checkSpaceAdmin(ctx context.Context, user *model.User, spaceID uint) error {
+	for _, role := range user.Roles {
+		if role.Name == model.RoleSuperAdmin || role.Name == model.RoleEnterpriseAdmin {
+			return nil
+		}
+	}
+
+	var member model.SpaceMember
+	if err := s.db.WithContext(ctx).Where("space_id = ? AND user_id = ?", spaceID, user.ID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWebhookForbidden
+		}
+		return fmt.Errorf("failed to check space membership: %w", err)
+	}
+	for _, role := range member.Roles {
+		if role == model.SpaceMemberRoleAdmin {
+			return nil
+		}
+	}
+	return ErrWebhookForbidden
+}
+
+// validateWebhook 校验投递地址和订阅事件，投递地址不能指向本机或内网
+func validateWebhook(ctx context.Context, url string, events []model.WebhookEvent) error {
+	if err := webhook.ValidateURL(ctx, url); err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookInvalidURL, err)
+	}
+	if len(events) == 0 {
+		return ErrWebhookInvalidEvent
+	}
+	for _, event := range events {
+		if !model.IsValidWebhookEvent(event) {
+			return fmt.Errorf("%w: %s", ErrWebhookInvalidEvent, event)
+		}
+	}
+	return nil
+}
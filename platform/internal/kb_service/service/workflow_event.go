@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrWorkflowEventNotReady 事件对应的资源尚未关联到该流程，稍后重试即可
+var ErrWorkflowEventNotReady = errors.New("resource is not linked to the workflow yet")
+
+// workflowEventDocumentStatus 流程结束事件对应的文档状态
+var workflowEventDocumentStatus = map[model.WorkflowEventType]model.DocumentStatus{
+	model.WorkflowEventCompleted: model.DocumentStatusPendingPublish,
+	model.WorkflowEventRejected:  model.DocumentStatusFailed,
+	model.WorkflowEventCancelled: model.DocumentStatusFailed,
+	model.WorkflowEventWithdrawn: model.DocumentStatusWithdrawn,
+}
+
+// WorkflowEventApplier 按资源类型处理流程结束事件，与幂等记录在同一事务内执行。
+// 返回的 afterCommit 在事务提交后执行，用于删除文件、启动后台任务等无法随事务回滚的操作，可以为 nil
+type WorkflowEventApplier func(tx *gorm.DB, event *model.WorkflowStatusEvent) (afterCommit func(), err error)
+
+// RegisterWorkflowEventApplier 注册其他服务组件负责的资源类型的事件处理，同一类型重复注册时后者覆盖前者
+func (s *DocumentService) RegisterWorkflowEventApplier(resourceType string, apply WorkflowEventApplier) {
+	s.appliersMu.Lock()
+	defer s.appliersMu.Unlock()
+	if s.workflowEventAppliers == nil {
+		s.workflowEventAppliers = make(map[string]WorkflowEventApplier)
+	}
+	s.workflowEventAppliers[resourceType] = apply
+}
+
+// workflowEventApplier 获取资源类型的事件处理，文档发布和删除由文档服务自身处理
+func (s *DocumentService) workflowEventApplier(resourceType string) (WorkflowEventApplier, bool) {
+	switch resourceType {
+	case model.ResourceTypeDocument:
+		return applyDocumentWorkflowEvent, true
+	case model.ResourceTypeDocumentDeletion:
+		return s.applyDocumentDeletionWorkflowEvent, true
+	}
+	s.appliersMu.RLock()
+	defer s.appliersMu.RUnlock()
+	apply, ok := s.workflowEventAppliers[resourceType]
+	return apply, ok
+}
+
+// ApplyWorkflowEvent 处理工作流服务投递的流程结束事件，同一事件重复投递只处理一次。
+// 返回 false 表示事件此前已处理过
+func (s *DocumentService) ApplyWorkflowEvent(ctx context.Context, event *model.WorkflowStatusEvent) (bool, error) {
+	if event.EventID == "" {
+		return false, fmt.Errorf("event id is required")
+	}
+
+	applied := false
+	var afterCommit func()
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先登记事件，主键冲突说明已处理过
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ProcessedEvent{
+			EventID:     event.EventID,
+			EventType:   event.EventType,
+			ProcessedAt: time.Now(),
+		})
+		if result.Error != nil {
+			return fmt.Errorf("failed to record processed event: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		applied = true
+
+		apply, ok := s.workflowEventApplier(event.ResourceType)
+		if !ok {
+			logger.Warnf("ignore workflow event %s of unsupported resource type %s", event.EventID, event.ResourceType)
+			return nil
+		}
+		var err error
+		afterCommit, err = apply(tx, event)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if afterCommit != nil {
+		afterCommit()
+	}
+	return applied, nil
+}
+
+// applyDocumentWorkflowEvent 根据文档发布审批的结果更新文档状态
+func applyDocumentWorkflowEvent(tx *gorm.DB, event *model.WorkflowStatusEvent) (func(), error) {
+	status, ok := workflowEventDocumentStatus[event.EventType]
+	if !ok {
+		logger.Warnf("ignore unsupported workflow event %s (%s)", event.EventID, event.EventType)
+		return nil, nil
+	}
+
+	// 只更新仍关联该流程的文档，旧一轮审批的迟到事件不会覆盖新一轮的状态
+	result := tx.Model(&model.Document{}).
+		Where("id = ? AND workflow_id = ?", event.ResourceID, event.WorkflowID).
+		Update("status", status)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update document status: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var document model.Document
+	if err := tx.Select("id", "workflow_id").First(&document, event.ResourceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warnf("ignore workflow event %s of missing document %d", event.EventID, event.ResourceID)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	if document.WorkflowID > event.WorkflowID {
+		logger.Warnf("ignore stale workflow event %s: document %d is linked to workflow %d", event.EventID, document.ID, document.WorkflowID)
+		return nil, nil
+	}
+	// 文档还没关联到该流程，回滚后由投递方重试
+	return nil, fmt.Errorf("%w: document %d is linked to workflow %d, event is for workflow %d",
+		ErrWorkflowEventNotReady, document.ID, document.WorkflowID, event.WorkflowID)
+}
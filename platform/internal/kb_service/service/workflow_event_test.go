@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestApplyWorkflowEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		workflowID  uint
+		event       model.WorkflowStatusEvent
+		wantErr     error
+		wantStatus  model.DocumentStatus
+		wantApplied bool
+	}{
+		{
+			name:        "completed event publishes pending document",
+			workflowID:  10,
+			event:       model.WorkflowStatusEvent{EventType: model.WorkflowEventCompleted, WorkflowID: 10},
+			wantStatus:  model.DocumentStatusPendingPublish,
+			wantApplied: true,
+		},
+		{
+			name:        "rejected event fails document",
+			workflowID:  10,
+			event:       model.WorkflowStatusEvent{EventType: model.WorkflowEventRejected, WorkflowID: 10},
+			wantStatus:  model.DocumentStatusFailed,
+			wantApplied: true,
+		},
+		{
+			name:        "stale event of earlier round is ignored",
+			workflowID:  11,
+			event:       model.WorkflowStatusEvent{EventType: model.WorkflowEventRejected, WorkflowID: 10},
+			wantStatus:  model.DocumentStatusPendingApproval,
+			wantApplied: true,
+		},
+		{
+			name:       "event before the document is linked is deferred",
+			workflowID: 9,
+			event:      model.WorkflowStatusEvent{EventType: model.WorkflowEventCompleted, WorkflowID: 10},
+			wantErr:    ErrWorkflowEventNotReady,
+			wantStatus: model.DocumentStatusPendingApproval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, &model.Document{}, &model.ProcessedEvent{})
+			document := model.Document{
+				ID: 1, SpaceID: 1, CreatedBy: 1, WorkflowID: tt.workflowID,
+				Title: "doc", FileName: "f.pdf", FilePath: "/f.pdf", FileType: "pdf",
+				Status: model.DocumentStatusPendingApproval,
+			}
+			if err := db.Create(&document).Error; err != nil {
+				t.Fatalf("create document: %v", err)
+			}
+			s := &DocumentService{db: db}
+
+			event := tt.event
+			event.EventID = "event-1"
+			event.ResourceType = model.ResourceTypeDocument
+			event.ResourceID = document.ID
+			applied, err := s.ApplyWorkflowEvent(context.Background(), &event)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ApplyWorkflowEvent() error = %v, want %v", err, tt.wantErr)
+			}
+			if applied != tt.wantApplied {
+				t.Fatalf("ApplyWorkflowEvent() applied = %v, want %v", applied, tt.wantApplied)
+			}
+
+			var got model.Document
+			if err := db.First(&got, document.ID).Error; err != nil {
+				t.Fatalf("reload document: %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Fatalf("document status = %s, want %s", got.Status, tt.wantStatus)
+			}
+
+			// 推迟的事件不登记，重试时仍会处理
+			var processed int64
+			db.Model(&model.ProcessedEvent{}).Where("event_id = ?", event.EventID).Count(&processed)
+			if want := tt.wantErr == nil; (processed == 1) != want {
+				t.Fatalf("processed event recorded = %v, want %v", processed == 1, want)
+			}
+		})
+	}
+}
+
+func TestApplyWorkflowEventDuplicate(t *testing.T) {
+	db := dbtest.Open(t, &model.Document{}, &model.ProcessedEvent{})
+	document := model.Document{
+		ID: 1, SpaceID: 1, CreatedBy: 1, WorkflowID: 10,
+		Title: "doc", FileName: "f.pdf", FilePath: "/f.pdf", FileType: "pdf",
+		Status: model.DocumentStatusPendingApproval,
+	}
+	if err := db.Create(&document).Error; err != nil {
+		t.Fatalf("create document: %v", err)
+	}
+	s := &DocumentService{db: db}
+
+	event := model.WorkflowStatusEvent{
+		EventID: "event-1", EventType: model.WorkflowEventCompleted, WorkflowID: 10,
+		ResourceType: model.ResourceTypeDocument, ResourceID: document.ID,
+	}
+	if applied, err := s.ApplyWorkflowEvent(context.Background(), &event); err != nil || !applied {
+		t.Fatalf("first ApplyWorkflowEvent() = %v, %v, want applied", applied, err)
+	}
+	// 发布后重复投递不能把状态改回待发布
+	db.Model(&document).Update("status", model.DocumentStatusPublished)
+	if applied, err := s.ApplyWorkflowEvent(context.Background(), &event); err != nil || applied {
+		t.Fatalf("second ApplyWorkflowEvent() = %v, %v, want duplicate", applied, err)
+	}
+	var got model.Document
+	db.First(&got, document.ID)
+	if got.Status != model.DocumentStatusPublished {
+		t.Fatalf("document status = %s, want %s", got.Status, model.DocumentStatusPublished)
+	}
+}
+
+func TestApplyDocumentDeletionWorkflowEvent(t *testing.T) {
+	tests := []struct {
+		name               string
+		deletionWorkflowID uint
+		eventType          model.WorkflowEventType
+		wantDeleted        bool
+		wantWorkflowID     uint
+	}{
+		{name: "rejected deletion keeps document", deletionWorkflowID: 10, eventType: model.WorkflowEventRejected, wantWorkflowID: 0},
+		{name: "withdrawn deletion keeps document", deletionWorkflowID: 10, eventType: model.WorkflowEventWithdrawn, wantWorkflowID: 0},
+		{name: "event of another workflow is ignored", deletionWorkflowID: 11, eventType: model.WorkflowEventCompleted, wantWorkflowID: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, &model.Document{}, &model.ProcessedEvent{})
+			document := model.Document{
+				ID: 1, SpaceID: 1, CreatedBy: 1, DeletionWorkflowID: tt.deletionWorkflowID,
+				Title: "doc", FileName: "f.pdf", FilePath: "/f.pdf", FileType: "pdf",
+				Status: model.DocumentStatusPublished,
+			}
+			if err := db.Create(&document).Error; err != nil {
+				t.Fatalf("create document: %v", err)
+			}
+			s := &DocumentService{db: db}
+
+			event := model.WorkflowStatusEvent{
+				EventID: "event-1", EventType: tt.eventType, WorkflowID: 10,
+				ResourceType: model.ResourceTypeDocumentDeletion, ResourceID: document.ID,
+			}
+			if _, err := s.ApplyWorkflowEvent(context.Background(), &event); err != nil {
+				t.Fatalf("ApplyWorkflowEvent() error = %v", err)
+			}
+
+			var got model.Document
+			if err := db.First(&got, document.ID).Error; err != nil {
+				t.Fatalf("reload document: %v", err)
+			}
+			if got.DeletionWorkflowID != tt.wantWorkflowID {
+				t.Fatalf("deletion workflow = %d, want %d", got.DeletionWorkflowID, tt.wantWorkflowID)
+			}
+		})
+	}
+}
+
+func TestApplyExportWorkflowEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		workflowID uint
+		status     model.ExportJobStatus
+		eventType  model.WorkflowEventType
+		wantErr    error
+		wantStatus model.ExportJobStatus
+	}{
+		{name: "rejected export is not run", workflowID: 10, status: model.ExportJobStatusPendingApproval,
+			eventType: model.WorkflowEventRejected, wantStatus: model.ExportJobStatusRejected},
+		{name: "released export is not rejected again", workflowID: 10, status: model.ExportJobStatusRunning,
+			eventType: model.WorkflowEventCancelled, wantStatus: model.ExportJobStatusRunning},
+		{name: "event before the job is linked is deferred", workflowID: 0, status: model.ExportJobStatusPendingApproval,
+			eventType: model.WorkflowEventCompleted, wantErr: ErrWorkflowEventNotReady, wantStatus: model.ExportJobStatusPendingApproval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t, &model.ExportJob{}, &model.ProcessedEvent{})
+			job := model.ExportJob{ID: 1, Scope: model.ExportScopeSpace, SpaceID: 1, CreatedBy: 1, WorkflowID: tt.workflowID, Status: tt.status}
+			if err := db.Create(&job).Error; err != nil {
+				t.Fatalf("create export job: %v", err)
+			}
+			documentService := &DocumentService{db: db}
+			NewExportService(db, nil, documentService)
+
+			event := model.WorkflowStatusEvent{
+				EventID: "event-1", EventType: tt.eventType, WorkflowID: 10,
+				ResourceType: model.ResourceTypeDataExport, ResourceID: job.ID,
+			}
+			if _, err := documentService.ApplyWorkflowEvent(context.Background(), &event); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ApplyWorkflowEvent() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var got model.ExportJob
+			if err := db.First(&got, job.ID).Error; err != nil {
+				t.Fatalf("reload export job: %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Fatalf("export job status = %s, want %s", got.Status, tt.wantStatus)
+			}
+		})
+	}
+}
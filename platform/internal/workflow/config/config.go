@@ -11,11 +11,31 @@ import (
 
 // Config Workflow 服务配置
 type Config struct {
-	Server   commonConfig.ServerConfig   `mapstructure:"server"`
-	Gin      commonConfig.GinConfig      `mapstructure:"gin"`
-	Database commonConfig.DatabaseConfig `mapstructure:"database"`
-	Log      commonConfig.LogConfig      `mapstructure:"log"`
-	Iam      commonConfig.IamConfig      `mapstructure:"iam"`
+	Server    commonConfig.ServerConfig   `mapstructure:"server"`
+	Gin       commonConfig.GinConfig      `mapstructure:"gin"`
+	Database  commonConfig.DatabaseConfig `mapstructure:"database"`
+	Log       commonConfig.LogConfig      `mapstructure:"log"`
+	Iam       commonConfig.IamConfig      `mapstructure:"iam"`
+	Webhook   commonConfig.WebhookConfig  `mapstructure:"webhook"`
+	Outbox    commonConfig.OutboxConfig   `mapstructure:"outbox"`
+	Scheduler SchedulerConfig             `mapstructure:"scheduler"`
+	Approval  ApprovalConfig              `mapstructure:"approval"`
+
+	// TrustedProxies 网关的地址或网段，只有来自这些地址的 X-Real-IP 才被采信；为空时直接使用连接来源IP
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// ApprovalConfig 审批人配置
+type ApprovalConfig struct {
+	// 步骤角色、空间管理员、企业管理员都找不到时的兜底审批人
+	DefaultApproverIDs []uint `mapstructure:"default_approver_ids"`
+}
+
+// SchedulerConfig 审批超时调度配置
+type SchedulerConfig struct {
+	IntervalSeconds     int `mapstructure:"interval_seconds"`      // 扫描间隔，0 表示关闭超时处理
+	RemindIntervalHours int `mapstructure:"remind_interval_hours"` // 同一任务两次超时提醒的间隔
+	GraceHours          int `mapstructure:"grace_hours"`           // 超时后执行升级或自动审批前的宽限时间
 }
 
 func Load() (*Config, error) {
@@ -86,6 +106,31 @@ func bindEnvVars(v *viper.Viper) {
 
 	// IAM配置
 	v.BindEnv("iam.url", "KBASE_IAM_URL", "IAM_URL")
+
+	// Webhook配置
+	v.BindEnv("webhook.timeout_seconds", "KBASE_WEBHOOK_TIMEOUT_SECONDS", "WEBHOOK_TIMEOUT_SECONDS")
+	v.BindEnv("webhook.max_attempts", "KBASE_WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_MAX_ATTEMPTS")
+	v.BindEnv("webhook.retry_base_seconds", "KBASE_WEBHOOK_RETRY_BASE_SECONDS", "WEBHOOK_RETRY_BASE_SECONDS")
+
+	// 事件发件箱配置
+	v.BindEnv("outbox.targets.kb", "KBASE_OUTBOX_TARGETS_KB", "OUTBOX_TARGETS_KB")
+	v.BindEnv("outbox.targets.iam", "KBASE_OUTBOX_TARGETS_IAM", "OUTBOX_TARGETS_IAM")
+	v.BindEnv("outbox.secret", "KBASE_OUTBOX_SECRET", "OUTBOX_SECRET")
+	v.BindEnv("outbox.timeout_seconds", "KBASE_OUTBOX_TIMEOUT_SECONDS", "OUTBOX_TIMEOUT_SECONDS")
+	v.BindEnv("outbox.max_attempts", "KBASE_OUTBOX_MAX_ATTEMPTS", "OUTBOX_MAX_ATTEMPTS")
+	v.BindEnv("outbox.retry_base_seconds", "KBASE_OUTBOX_RETRY_BASE_SECONDS", "OUTBOX_RETRY_BASE_SECONDS")
+	v.BindEnv("outbox.poll_interval_seconds", "KBASE_OUTBOX_POLL_INTERVAL_SECONDS", "OUTBOX_POLL_INTERVAL_SECONDS")
+
+	// 超时调度配置
+	v.BindEnv("scheduler.interval_seconds", "KBASE_SCHEDULER_INTERVAL_SECONDS", "SCHEDULER_INTERVAL_SECONDS")
+	v.BindEnv("scheduler.remind_interval_hours", "KBASE_SCHEDULER_REMIND_INTERVAL_HOURS", "SCHEDULER_REMIND_INTERVAL_HOURS")
+	v.BindEnv("scheduler.grace_hours", "KBASE_SCHEDULER_GRACE_HOURS", "SCHEDULER_GRACE_HOURS")
+
+	// 审批人配置，多个ID用逗号分隔
+	v.BindEnv("approval.default_approver_ids", "KBASE_APPROVAL_DEFAULT_APPROVER_IDS", "APPROVAL_DEFAULT_APPROVER_IDS")
+
+	// 可信代理配置
+	v.BindEnv("trusted_proxies", "KBASE_TRUSTED_PROXIES", "TRUSTED_PROXIES")
 }
 
 func setDefaults(v *viper.Viper) {
@@ -101,4 +146,20 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.db_log_level", "warn")
 	v.SetDefault("iam.url", "http://localhost:8081")
+	v.SetDefault("webhook.timeout_seconds", 10)
+	v.SetDefault("webhook.max_attempts", 6)
+	v.SetDefault("webhook.retry_base_seconds", 30)
+	v.SetDefault("outbox.targets.kb", "http://localhost:8083/api/v1/documents/workflow-events")
+	v.SetDefault("outbox.targets.iam", "http://localhost:8081/api/v1/spaces/workflow-events")
+	v.SetDefault("outbox.timeout_seconds", 10)
+	v.SetDefault("outbox.max_attempts", 10)
+	v.SetDefault("outbox.retry_base_seconds", 10)
+	v.SetDefault("outbox.poll_interval_seconds", 5)
+	v.SetDefault("scheduler.interval_seconds", 300)
+	v.SetDefault("scheduler.remind_interval_hours", 24)
+	v.SetDefault("scheduler.grace_hours", 24)
+	v.SetDefault("approval.default_approver_ids", []uint{})
+
+	// 可信代理默认配置，本地部署时网关与服务在同一主机
+	v.SetDefault("trusted_proxies", []string{"127.0.0.1", "::1"})
 }
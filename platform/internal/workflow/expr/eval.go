@@ -0,0 +1,252 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type node interface {
+	eval(attrs map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n *literalNode) eval(map[string]any) (any, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	path []string
+}
+
+func (n *identNode) eval(attrs map[string]any) (any, error) {
+	var current any = attrs
+	for _, key := range n.path {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		current = m[key]
+	}
+	return normalize(current), nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(attrs map[string]any) (any, error) {
+	values := make([]any, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(attrs map[string]any) (any, error) {
+	value, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(attrs map[string]any) (any, error) {
+	leftValue, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	left, err := toBool(leftValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// 短路求值
+	if n.op == "&&" && !left {
+		return false, nil
+	}
+	if n.op == "||" && left {
+		return true, nil
+	}
+
+	rightValue, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return toBool(rightValue)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(attrs map[string]any) (any, error) {
+	left, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		for _, item := range right.([]any) {
+			if equal(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "contains":
+		return contains(left, right)
+	default:
+		return order(n.op, left, right)
+	}
+}
+
+// normalize 将属性值统一为 float64、string、bool、[]any、map[string]any 或 nil
+func normalize(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		return values
+	default:
+		return value
+	}
+}
+
+func toBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("%w: %v 不是布尔值", ErrTypeMismatch, value)
+	}
+}
+
+func equal(left, right any) bool {
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case float64:
+		r, ok := right.(float64)
+		return ok && l == r
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	default:
+		return false
+	}
+}
+
+func contains(left, right any) (bool, error) {
+	if right == nil {
+		return false, nil
+	}
+	switch l := left.(type) {
+	case nil:
+		return false, nil
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("%w: contains 右侧必须是字符串", ErrTypeMismatch)
+		}
+		return strings.Contains(l, r), nil
+	case []any:
+		for _, item := range l {
+			if equal(item, right) {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: contains 左侧必须是字符串或列表", ErrTypeMismatch)
+	}
+}
+
+func order(op string, left, right any) (bool, error) {
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, fmt.Errorf("%w: 无法比较 %v 和 %v", ErrTypeMismatch, left, right)
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("%w: 无法比较 %v 和 %v", ErrTypeMismatch, left, right)
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return false, fmt.Errorf("%w: %v 不支持大小比较", ErrTypeMismatch, left)
+	}
+
+	switch op {
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "<":
+		return cmp < 0, nil
+	default:
+		return cmp <= 0, nil
+	}
+}
@@ -0,0 +1,87 @@
+// Package expr 实现审批流程分支使用的条件表达式。
+//
+// 表达式针对资源属性求值，例如：
+//
+//	file_type == "pdf" && file_size > 10485760
+//	department == "财务" || class_name in ["合同", "协议"]
+//	tags contains "机密" and not (use_type == "viewable")
+//
+// 支持的运算：== != > >= < <=、in [列表]、contains、&& (and)、|| (or)、! (not) 以及括号。
+// 字面量支持单/双引号字符串、数字、true、false 和 null；标识符可以用 . 访问嵌套属性。
+// 属性不存在时取值为 null，null 参与大小比较、in、contains 时结果为 false。
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTypeMismatch 运算两侧的类型不兼容
+var ErrTypeMismatch = errors.New("类型不匹配")
+
+// Expression 编译后的表达式
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile 解析表达式
+func Compile(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("位置 %d 处存在多余的内容 %q", tok.pos, tok.text)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String 返回表达式原文
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval 针对属性求值，表达式结果必须为布尔值
+func (e *Expression) Eval(attrs map[string]any) (bool, error) {
+	value, err := e.root.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	if value == nil {
+		return false, nil
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("表达式 %q 的结果不是布尔值", e.source)
+	}
+	return result, nil
+}
+
+// Validate 检查表达式语法，空表达式合法
+func Validate(source string) error {
+	if strings.TrimSpace(source) == "" {
+		return nil
+	}
+	_, err := Compile(source)
+	return err
+}
+
+// Match 编译并求值，空表达式视为满足条件
+func Match(source string, attrs map[string]any) (bool, error) {
+	if strings.TrimSpace(source) == "" {
+		return true, nil
+	}
+	e, err := Compile(source)
+	if err != nil {
+		return false, err
+	}
+	return e.Eval(attrs)
+}
@@ -0,0 +1,195 @@
+package expr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testAttrs = map[string]any{
+	"file_type":  "pdf",
+	"file_size":  int64(20 << 20),
+	"department": "财务",
+	"class_name": "合同",
+	"version":    2,
+	"published":  false,
+	"tags":       []string{"机密", "年度"},
+	"owner": map[string]any{
+		"name":  "alice",
+		"level": 3,
+	},
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   bool
+	}{
+		// 运算符
+		{name: "equal string", source: `file_type == "pdf"`, want: true},
+		{name: "single equals", source: `file_type = 'pdf'`, want: true},
+		{name: "not equal", source: `file_type != "docx"`, want: true},
+		{name: "integer attribute compares as number", source: `version == 2`, want: true},
+		{name: "greater than", source: `file_size > 10485760`, want: true},
+		{name: "less or equal", source: `version <= 2`, want: true},
+		{name: "decimal literal", source: `version < 2.5`, want: true},
+		{name: "string ordering", source: `file_type >= "doc"`, want: true},
+		{name: "bool literal", source: `published == false`, want: true},
+		{name: "nested attribute", source: `owner.name == "alice" && owner.level > 2`, want: true},
+		{name: "contains list", source: `tags contains "机密"`, want: true},
+		{name: "contains substring", source: `department contains "财"`, want: true},
+		{name: "keyword operators", source: `tags contains "年度" and not (file_type == "docx")`, want: true},
+		{name: "keywords are case insensitive", source: `file_type == "pdf" AND version == 2`, want: true},
+
+		// 优先级：! 高于 &&，&& 高于 ||
+		{name: "and binds tighter than or", source: `file_type == "pdf" || false && false`, want: true},
+		{name: "parentheses override precedence", source: `(file_type == "pdf" || false) && false`, want: false},
+		{name: "not binds tighter than and", source: `!published && version == 2`, want: true},
+		{name: "not applies to the whole comparison", source: `!file_type == "docx"`, want: true},
+		{name: "double negation", source: `not not published`, want: false},
+		{name: "or is left associative", source: `false || false || version == 2`, want: true},
+
+		// in 列表
+		{name: "in string list", source: `class_name in ["合同", "协议"]`, want: true},
+		{name: "not in string list", source: `class_name in ["报告"]`, want: false},
+		{name: "in number list", source: `version in [1, 2, 3]`, want: true},
+		{name: "in empty list", source: `version in []`, want: false},
+		{name: "in list with attribute items", source: `"财务" in [class_name, department]`, want: true},
+		{name: "in does not convert types", source: `version in ["2"]`, want: false},
+
+		// 属性不存在时取值为 null
+		{name: "unknown field equals null", source: `missing == null`, want: true},
+		{name: "unknown field not equal to value", source: `missing != "x"`, want: true},
+		{name: "unknown field in order comparison", source: `missing > 1`, want: false},
+		{name: "unknown field in list", source: `missing in ["x"]`, want: false},
+		{name: "unknown field contains", source: `missing contains "x"`, want: false},
+		{name: "contains unknown field", source: `department contains missing`, want: false},
+		{name: "unknown field as condition", source: `missing`, want: false},
+		{name: "negated unknown field", source: `!missing`, want: true},
+		{name: "path through non-object", source: `file_type.name == null`, want: true},
+		{name: "unknown nested field", source: `owner.missing.deep == null`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.source, err)
+			}
+			got, err := e.Eval(testAttrs)
+			if err != nil {
+				t.Fatalf("Eval(%q) error = %v", tt.source, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Eval(%q) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalShortCircuit(t *testing.T) {
+	// 右侧类型不匹配，短路时不会求值
+	tests := []string{
+		`false && file_size > "1"`,
+		`true || file_size > "1"`,
+	}
+	for _, source := range tests {
+		if _, err := Match(source, testAttrs); err != nil {
+			t.Errorf("Match(%q) error = %v, want short circuit", source, err)
+		}
+	}
+}
+
+func TestEvalTypeMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "number compared with string", source: `file_size > "10"`},
+		{name: "string compared with number", source: `file_type < 3`},
+		{name: "bool in order comparison", source: `published > false`},
+		{name: "list in order comparison", source: `tags >= 1`},
+		{name: "string as logical operand", source: `file_type && true`},
+		{name: "number negated", source: `!version`},
+		{name: "contains on number", source: `version contains 1`},
+		{name: "contains number in string", source: `department contains 1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Match(tt.source, testAttrs)
+			if !errors.Is(err, ErrTypeMismatch) {
+				t.Fatalf("Match(%q) error = %v, want %v", tt.source, err, ErrTypeMismatch)
+			}
+		})
+	}
+}
+
+func TestEvalNonBoolResult(t *testing.T) {
+	for _, source := range []string{`file_type`, `version`, `"text"`, `[1, 2]`} {
+		_, err := Match(source, testAttrs)
+		if err == nil || !strings.Contains(err.Error(), "不是布尔值") {
+			t.Errorf("Match(%q) error = %v, want non-bool result error", source, err)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{name: "unterminated string", source: `file_type == "pdf`, wantErr: "没有结束引号"},
+		{name: "invalid number", source: `version == 1.2.3`, wantErr: "数字"},
+		{name: "unknown character", source: `version # 2`, wantErr: "无法识别的字符"},
+		{name: "missing right operand", source: `version ==`, wantErr: "不完整"},
+		{name: "dangling operator", source: `version == 2 &&`, wantErr: "不完整"},
+		{name: "missing closing paren", source: `(version == 2`, wantErr: "缺少"},
+		{name: "extra closing paren", source: `version == 2)`, wantErr: "多余的内容"},
+		{name: "missing closing bracket", source: `version in [1, 2`, wantErr: "缺少"},
+		{name: "in without list", source: `version in 2`, wantErr: "in 右侧必须是列表"},
+		{name: "chained comparison", source: `1 < version < 3`, wantErr: "多余的内容"},
+		{name: "adjacent operands", source: `file_type "pdf"`, wantErr: "多余的内容"},
+		{name: "operator without left operand", source: `== 2`, wantErr: "意外"},
+		{name: "empty parentheses", source: `()`, wantErr: "意外"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.source)
+			if err == nil {
+				t.Fatalf("Compile(%q) succeeded, want error containing %q", tt.source, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Compile(%q) error = %v, want error containing %q", tt.source, err, tt.wantErr)
+			}
+			if err := Validate(tt.source); err == nil {
+				t.Fatalf("Validate(%q) succeeded, want error", tt.source)
+			}
+		})
+	}
+}
+
+func TestEmptyExpression(t *testing.T) {
+	for _, source := range []string{"", "   "} {
+		if err := Validate(source); err != nil {
+			t.Errorf("Validate(%q) error = %v", source, err)
+		}
+		matched, err := Match(source, nil)
+		if err != nil || !matched {
+			t.Errorf("Match(%q) = %v, %v, want true", source, matched, err)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	matched, err := Match(`name == "say \"hi\"" && code == 'it\'s'`, map[string]any{
+		"name": `say "hi"`,
+		"code": "it's",
+	})
+	if err != nil || !matched {
+		t.Fatalf("Match() = %v, %v, want true", matched, err)
+	}
+}
@@ -0,0 +1,132 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	number float64
+	pos    int
+}
+
+// 关键字运算符统一转换为符号形式
+var keywordOperators = map[string]string{
+	"and":      "&&",
+	"or":       "||",
+	"not":      "!",
+	"in":       "in",
+	"contains": "contains",
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == '[':
+			tokens = append(tokens, token{kind: tokenLBracket, text: "[", pos: i})
+			i++
+		case r == ']':
+			tokens = append(tokens, token{kind: tokenRBracket, text: "]", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("位置 %d 处的字符串没有结束引号", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			number, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("位置 %d 处的数字 %q 无效", start, text)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, number: number, pos: start})
+
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || runes[i] == '.' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			text := string(runes[start:i])
+			if op, ok := keywordOperators[strings.ToLower(text)]; ok {
+				tokens = append(tokens, token{kind: tokenOperator, text: op, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, text: text, pos: start})
+			}
+
+		default:
+			start := i
+			op, width := "", 1
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", ">=", "<=", "&&", "||":
+					op, width = two, 2
+				}
+			}
+			if op == "" {
+				switch r {
+				case '>', '<', '!':
+					op = string(r)
+				case '=':
+					// 单个等号按相等处理
+					op = "=="
+				default:
+					return nil, fmt.Errorf("位置 %d 处存在无法识别的字符 %q", start, r)
+				}
+			}
+			i += width
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: start})
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(runes)})
+	return tokens, nil
+}
@@ -0,0 +1,173 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) acceptOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		if tok.kind == tokenEOF {
+			return fmt.Errorf("表达式不完整，缺少 %q", text)
+		}
+		return fmt.Errorf("位置 %d 处应为 %q，实际为 %q", tok.pos, text, tok.text)
+	}
+	return nil
+}
+
+// parseOr or := and ('||' and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+}
+
+// parseAnd and := not ('&&' not)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+}
+
+// parseNot not := '!' not | comparison
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.acceptOperator("!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison comparison := primary (op primary)?
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.acceptOperator("==", "!=", ">", ">=", "<", "<=", "in", "contains")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if op == "in" {
+		if _, isList := right.(*listNode); !isList {
+			return nil, fmt.Errorf("in 右侧必须是列表，例如 x in [\"a\", \"b\"]")
+		}
+	}
+	return &compareNode{op: op, left: left, right: right}, nil
+}
+
+// parsePrimary primary := literal | identifier | list | '(' or ')'
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+	case tokenNumber:
+		return &literalNode{value: tok.number}, nil
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null", "nil":
+			return &literalNode{value: nil}, nil
+		}
+		return &identNode{path: strings.Split(tok.text, ".")}, nil
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tokenLBracket:
+		list := &listNode{}
+		if p.peek().kind == tokenRBracket {
+			p.next()
+			return list, nil
+		}
+		for {
+			item, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			if p.peek().kind == tokenComma {
+				p.next()
+				continue
+			}
+			if err := p.expect(tokenRBracket, "]"); err != nil {
+				return nil, err
+			}
+			return list, nil
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("表达式不完整")
+	default:
+		return nil, fmt.Errorf("位置 %d 处存在意外的 %q", tok.pos, tok.text)
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+// DelegationHandler 外出委托处理器
+type DelegationHandler struct {
+	delegationService *service.DelegationService
+	recorder          *audit.Recorder
+}
+
+// NewDelegationHandler 创建外出委托处理器
+func NewDelegationHandler(delegationService *service.DelegationService, recorder *audit.Recorder) *DelegationHandler {
+	return &DelegationHandler{
+		delegationService: delegationService,
+		recorder:          recorder,
+	}
+}
+
+// CreateDelegation 创建外出委托，立即生效时会转交当前待审批的任务
+func (h *DelegationHandler) CreateDelegation(c *gin.Context) {
+	var req model.CreateDelegationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	rule, moved, err := h.delegationService.CreateRule(&req, user)
+	if err != nil {
+		respondDelegationError(c, "创建委托失败", err)
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDelegationCreate,
+		ResourceType: "delegation",
+		ResourceID:   rule.ID,
+		SpaceID:      rule.SpaceID,
+		After:        rule,
+		User:         user,
+	})
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "委托创建成功",
+		Data: gin.H{
+			"rule":        rule,
+			"moved_tasks": moved,
+		},
+	})
+}
+
+// ListDelegations 获取当前用户的委托规则
+func (h *DelegationHandler) ListDelegations(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	rules, err := h.delegationService.ListRules(user)
+	if err != nil {
+		respondDelegationError(c, "获取委托失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取委托成功",
+		Data:    rules,
+	})
+}
+
+// CancelDelegation 取消委托规则
+func (h *DelegationHandler) CancelDelegation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "委托ID格式错误",
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	rule, err := h.delegationService.CancelRule(uint(id), user)
+	if err != nil {
+		respondDelegationError(c, "取消委托失败", err)
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationDelegationCancel,
+		ResourceType: "delegation",
+		ResourceID:   rule.ID,
+		SpaceID:      rule.SpaceID,
+		After:        rule,
+		User:         user,
+	})
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "委托已取消",
+		Data:    rule,
+	})
+}
+
+func respondDelegationError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrDelegationNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrDelegationInvalid):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
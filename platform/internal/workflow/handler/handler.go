@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
 
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/service"
 	"github.com/gin-gonic/gin"
@@ -14,13 +16,15 @@ import (
 type Handler struct {
 	db              *gorm.DB
 	workflowService *service.WorkflowService
+	recorder        *audit.Recorder
 }
 
 // NewHandler 创建新的处理器
-func NewHandler(db *gorm.DB, workflowService *service.WorkflowService) *Handler {
+func NewHandler(db *gorm.DB, workflowService *service.WorkflowService, recorder *audit.Recorder) *Handler {
 	return &Handler{
 		db:              db,
 		workflowService: workflowService,
+		recorder:        recorder,
 	}
 }
 
@@ -66,6 +70,13 @@ func (h *Handler) CreateWorkflow(c *gin.Context) {
 	}
 
 	workflow, err := h.workflowService.CreateWorkflow(&req, userModel)
+	if errors.Is(err, service.ErrResourceTypeUnsupported) {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "创建工作流失败: " + err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, model.APIResponse{
 			Code:    500,
@@ -111,6 +122,13 @@ func (h *Handler) StartWorkflow(c *gin.Context) {
 	}
 
 	workflow, err := h.workflowService.StartWorkflow(&req, userModel)
+	if errors.Is(err, service.ErrNoApprover) {
+		c.JSON(http.StatusUnprocessableEntity, model.APIResponse{
+			Code:    422,
+			Message: "启动工作流失败: " + err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, model.APIResponse{
 			Code:    500,
@@ -126,6 +144,31 @@ func (h *Handler) StartWorkflow(c *gin.Context) {
 	})
 }
 
+// GetWorkflow 获取工作流详情，包含各步骤的审批规则和投票统计
+func (h *Handler) GetWorkflow(c *gin.Context) {
+	id, ok := workflowID(c)
+	if !ok {
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workflow, err := h.workflowService.GetWorkflow(id, user)
+	if err != nil {
+		respondWorkflowError(c, "获取工作流失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取工作流成功",
+		Data:    workflow,
+	})
+}
+
 func (h *Handler) GetTasks(c *gin.Context) {
 
 	// 从上下文获取用户信息（需要中间件设置）
@@ -147,20 +190,16 @@ func (h *Handler) GetTasks(c *gin.Context) {
 		return
 	}
 
-	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "10")
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		page = 1
-	}
-
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil || pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	var query model.TaskQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
 	}
 
-	tasks, err := h.workflowService.GetTasks(userModel, page, pageSize)
+	tasks, err := h.workflowService.GetTasks(userModel, &query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, model.APIResponse{
 			Code:    500,
@@ -206,17 +245,109 @@ func (h *Handler) ApproveTask(c *gin.Context) {
 	}
 
 	task, err := h.workflowService.ApproveTask(&req, userModel)
+
+	action := model.OperationTaskApprove
+	if req.Status == model.TaskStatusRejected {
+		action = model.OperationTaskReject
+	}
+	entry := audit.Entry{
+		Action:       action,
+		ResourceType: "task",
+		ResourceID:   req.TaskID,
+		Before:       gin.H{"status": model.TaskStatusProcessing},
+		After:        gin.H{"status": req.Status, "comment": req.Comment},
+		User:         userModel,
+		Err:          err,
+	}
+	if task != nil {
+		entry.SpaceID = task.Workflow.SpaceID
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		respondTaskError(c, "审批任务失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "审批任务成功",
+		Data:    task,
+	})
+}
+
+// GetPendingTaskCount 获取当前用户待审批的任务数量
+func (h *Handler) GetPendingTaskCount(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.workflowService.GetPendingTaskCount(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, model.APIResponse{
 			Code:    500,
-			Message: "审批任务失败: " + err.Error(),
+			Message: "获取待审批数量失败: " + err.Error(),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, model.APIResponse{
 		Code:    200,
-		Message: "审批任务成功",
-		Data:    task,
+		Message: "获取待审批数量成功",
+		Data:    count,
+	})
+}
+
+// BulkApproveTasks 批量同意或拒绝任务，返回每个任务的处理结果
+func (h *Handler) BulkApproveTasks(c *gin.Context) {
+	var req model.BulkApproveTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.workflowService.BulkApproveTasks(&req, user)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "批量审批失败: " + err.Error(),
+		})
+		return
+	}
+
+	action := model.OperationTaskApprove
+	if req.Status == model.TaskStatusRejected {
+		action = model.OperationTaskReject
+	}
+	succeeded := 0
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		succeeded++
+		h.recorder.Record(c, audit.Entry{
+			Action:       action,
+			ResourceType: "task",
+			ResourceID:   result.TaskID,
+			SpaceID:      result.Task.Workflow.SpaceID,
+			Before:       gin.H{"status": model.TaskStatusProcessing},
+			After:        gin.H{"status": req.Status, "comment": req.Comment, "bulk": true},
+			User:         user,
+		})
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: fmt.Sprintf("批量审批完成，成功 %d 个，失败 %d 个", succeeded, len(results)-succeeded),
+		Data:    results,
 	})
 }
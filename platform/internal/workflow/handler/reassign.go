@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TransferTask 转交任务给同一空间内具备步骤审批角色的用户
+func (h *Handler) TransferTask(c *gin.Context) {
+	id, ok := taskID(c)
+	if !ok {
+		return
+	}
+
+	var req model.TransferTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.workflowService.TransferTask(id, &req, user)
+
+	entry := audit.Entry{
+		Action:       model.OperationTaskTransfer,
+		ResourceType: "task",
+		ResourceID:   id,
+		After:        gin.H{"to_user_id": req.ToUserID, "comment": req.Comment},
+		User:         user,
+		Err:          err,
+	}
+	if task != nil {
+		entry.SpaceID = task.Workflow.SpaceID
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		respondTaskError(c, "转交任务失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "任务转交成功",
+		Data:    task,
+	})
+}
+
+// AddApprover 在当前任务前或后加签
+func (h *Handler) AddApprover(c *gin.Context) {
+	id, ok := taskID(c)
+	if !ok {
+		return
+	}
+
+	var req model.AddApproverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.workflowService.AddApprover(id, &req, user)
+
+	entry := audit.Entry{
+		Action:       model.OperationTaskAddApprover,
+		ResourceType: "task",
+		ResourceID:   id,
+		After:        gin.H{"user_id": req.UserID, "position": req.Position, "comment": req.Comment},
+		User:         user,
+		Err:          err,
+	}
+	if task != nil {
+		entry.SpaceID = task.Workflow.SpaceID
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		respondTaskError(c, "加签失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "加签成功",
+		Data:    task,
+	})
+}
+
+func taskID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "任务ID格式错误",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondTaskError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrTaskForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrTaskNotProcessing), errors.Is(err, service.ErrAssigneeAlreadyAdded):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrAssigneeNotEligible):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrNoApprover):
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net/http"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetStuckWorkflows 管理员查看无人可处理的审批中流程
+func (h *Handler) GetStuckWorkflows(c *gin.Context) {
+	var query model.StuckWorkflowQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.workflowService.GetStuckWorkflows(&query, user)
+	if err != nil {
+		respondWorkflowError(c, "获取卡住的流程失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取卡住的流程成功",
+		Data:    result,
+	})
+}
+
+// ListResourceTypes 获取支持审批的资源类型及其可用于分支条件的属性
+func (h *Handler) ListResourceTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取资源类型成功",
+		Data:    h.workflowService.ListResourceTypes(),
+	})
+}
@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TemplateHandler 审批模板处理器
+type TemplateHandler struct {
+	templateService *service.TemplateService
+	recorder        *audit.Recorder
+}
+
+// NewTemplateHandler 创建审批模板处理器
+func NewTemplateHandler(templateService *service.TemplateService, recorder *audit.Recorder) *TemplateHandler {
+	return &TemplateHandler{
+		templateService: templateService,
+		recorder:        recorder,
+	}
+}
+
+// CreateTemplate 创建审批模板，需要 configure_workflow 权限
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var req model.WorkflowTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.templateService.CreateTemplate(&req, user)
+	if err != nil {
+		respondTemplateError(c, "创建审批模板失败", err)
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationWorkflowTemplateCreate,
+		ResourceType: "workflow_template",
+		ResourceID:   template.ID,
+		SpaceID:      template.SpaceID,
+		After:        template,
+		User:         user,
+	})
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "审批模板创建成功",
+		Data:    template,
+	})
+}
+
+// ListTemplates 查询审批模板
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	var query model.WorkflowTemplateQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	templates, err := h.templateService.ListTemplates(&query)
+	if err != nil {
+		respondTemplateError(c, "获取审批模板失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取审批模板成功",
+		Data:    templates,
+	})
+}
+
+// MatchTemplate 按资源所在的空间、二级空间和分类查找生效的模板
+func (h *TemplateHandler) MatchTemplate(c *gin.Context) {
+	var req model.MatchWorkflowTemplateRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	template, err := h.templateService.MatchTemplate(&req)
+	if err != nil {
+		respondTemplateError(c, "匹配审批模板失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "匹配审批模板成功",
+		Data:    template,
+	})
+}
+
+// GetTemplate 获取审批模板
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.templateService.GetTemplate(id)
+	if err != nil {
+		respondTemplateError(c, "获取审批模板失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取审批模板成功",
+		Data:    template,
+	})
+}
+
+// UpdateTemplate 更新审批模板，需要 configure_workflow 权限
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	var req model.WorkflowTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	before, err := h.templateService.GetTemplate(id)
+	if err != nil {
+		respondTemplateError(c, "更新审批模板失败", err)
+		return
+	}
+	beforeSnapshot := *before
+
+	template, err := h.templateService.UpdateTemplate(id, &req, user)
+	if err != nil {
+		respondTemplateError(c, "更新审批模板失败", err)
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationWorkflowTemplateUpdate,
+		ResourceType: "workflow_template",
+		ResourceID:   template.ID,
+		SpaceID:      template.SpaceID,
+		Before:       beforeSnapshot,
+		After:        template,
+		User:         user,
+	})
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "审批模板更新成功",
+		Data:    template,
+	})
+}
+
+// DeleteTemplate 删除审批模板，需要 configure_workflow 权限
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.templateService.DeleteTemplate(id, user)
+	if err != nil {
+		respondTemplateError(c, "删除审批模板失败", err)
+		return
+	}
+
+	h.recorder.Record(c, audit.Entry{
+		Action:       model.OperationWorkflowTemplateDelete,
+		ResourceType: "workflow_template",
+		ResourceID:   template.ID,
+		SpaceID:      template.SpaceID,
+		Before:       template,
+		User:         user,
+	})
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "审批模板删除成功",
+	})
+}
+
+// CreateWorkflowFromTemplate 按模板创建审批流程
+func (h *TemplateHandler) CreateWorkflowFromTemplate(c *gin.Context) {
+	var req model.CreateWorkflowFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workflow, err := h.templateService.CreateWorkflowFromTemplate(&req, user)
+	if err != nil {
+		respondTemplateError(c, "按模板创建工作流失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "工作流创建成功",
+		Data:    workflow,
+	})
+}
+
+func templateID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "模板ID格式错误",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// currentUser 从上下文获取用户信息（需要中间件设置）
+func currentUser(c *gin.Context) (*model.User, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.APIResponse{
+			Code:    401,
+			Message: "用户未认证",
+		})
+		return nil, false
+	}
+
+	userModel, ok := user.(*model.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Code:    500,
+			Message: "用户信息格式错误",
+		})
+		return nil, false
+	}
+	return userModel, true
+}
+
+func respondTemplateError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrTemplateNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrTemplateForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrTemplateConflict):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrTemplateInvalid), errors.Is(err, service.ErrResourceTypeUnsupported):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetWorkflowTimeline 获取一轮审批的步骤、任务和时间线
+func (h *Handler) GetWorkflowTimeline(c *gin.Context) {
+	id, ok := workflowID(c)
+	if !ok {
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	timeline, err := h.workflowService.GetWorkflowTimeline(id, user)
+	if err != nil {
+		respondWorkflowError(c, "获取审批时间线失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取审批时间线成功",
+		Data:    timeline,
+	})
+}
+
+// GetResourceTimeline 按 resource_type 和 resource_id 获取资源所有轮次的审批时间线
+func (h *Handler) GetResourceTimeline(c *gin.Context) {
+	var query model.ResourceTimelineQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	timeline, err := h.workflowService.GetResourceTimeline(query.ResourceType, query.ResourceID, user)
+	if err != nil {
+		respondWorkflowError(c, "获取审批时间线失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取审批时间线成功",
+		Data:    timeline,
+	})
+}
+
+// GetWorkflowStatus 获取工作流状态
+func (h *Handler) GetWorkflowStatus(c *gin.Context) {
+	id, ok := workflowID(c)
+	if !ok {
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.workflowService.GetWorkflowStatus(id, user)
+	if err != nil {
+		respondWorkflowError(c, "获取工作流状态失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "获取工作流状态成功",
+		Data:    status,
+	})
+}
+
+func workflowID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Code:    400,
+			Message: "工作流ID格式错误",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
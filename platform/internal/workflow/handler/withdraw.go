@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/service"
+	"github.com/gin-gonic/gin"
+)
+
+// WithdrawWorkflow 发起人撤回流程，仅在尚无审批人同意时可用
+func (h *Handler) WithdrawWorkflow(c *gin.Context) {
+	h.terminateWorkflow(c, model.OperationWorkflowWithdraw, "撤回", h.workflowService.WithdrawWorkflow)
+}
+
+// CancelWorkflow 空间管理员取消流程
+func (h *Handler) CancelWorkflow(c *gin.Context) {
+	h.terminateWorkflow(c, model.OperationWorkflowCancel, "取消", h.workflowService.CancelWorkflow)
+}
+
+type terminateFunc func(id uint, req *model.WithdrawWorkflowRequest, user *model.User) (*model.Workflow, error)
+
+func (h *Handler) terminateWorkflow(c *gin.Context, action model.OperationAction, verb string, terminate terminateFunc) {
+	id, ok := workflowID(c)
+	if !ok {
+		return
+	}
+
+	// 原因可以不填
+	var req model.WithdrawWorkflowRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Code:    400,
+				Message: "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	workflow, err := terminate(id, &req, user)
+
+	entry := audit.Entry{
+		Action:       action,
+		ResourceType: "workflow",
+		ResourceID:   id,
+		Before:       gin.H{"status": model.WorkflowStatusProcessing},
+		User:         user,
+		Err:          err,
+	}
+	if workflow != nil {
+		entry.SpaceID = workflow.SpaceID
+		entry.After = gin.H{"status": workflow.Status, "reason": req.Reason}
+	}
+	h.recorder.Record(c, entry)
+
+	if err != nil {
+		respondWorkflowError(c, verb+"工作流失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Code:    200,
+		Message: "工作流已" + verb,
+		Data:    workflow,
+	})
+}
+
+func respondWorkflowError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrWorkflowNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrWorkflowForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrWorkflowNotRunning), errors.Is(err, service.ErrWorkflowApproved):
+		status = http.StatusConflict
+	}
+	c.JSON(status, model.APIResponse{
+		Code:    status,
+		Message: message + ": " + err.Error(),
+	})
+}
@@ -1,6 +1,9 @@
 package router
 
 import (
+	"log"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/audit"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/middleware"
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/config"
@@ -12,9 +15,14 @@ import (
 	"gorm.io/gorm"
 )
 
-func Setup(cfg *config.Config, db *gorm.DB, iamClient *client.IamClient) *gin.Engine {
+func Setup(cfg *config.Config, db *gorm.DB, iamClient *client.IamClient, workflowService *service.WorkflowService) *gin.Engine {
 	gin.SetMode(cfg.Gin.Mode)
 	r := gin.New()
+	// 客户端IP只取网关写入的 X-Real-IP，且只采信来自网关的请求头
+	r.RemoteIPHeaders = []string{"X-Real-IP"}
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted_proxies: %v", err)
+	}
 	r.Use(middleware.Logger())
 	r.Use(middleware.Recovery())
 
@@ -22,8 +30,11 @@ func Setup(cfg *config.Config, db *gorm.DB, iamClient *client.IamClient) *gin.En
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/swagger/doc.json")))
 
 	// 初始化服务
-	workflowService := service.NewWorkflowService(db, iamClient)
-	handler := handler.NewHandler(db, workflowService)
+	templateService := service.NewTemplateService(db, iamClient, workflowService)
+	recorder := audit.NewRecorder(db, audit.ServiceWorkflow)
+	templateHandler := handler.NewTemplateHandler(templateService, recorder)
+	delegationHandler := handler.NewDelegationHandler(service.NewDelegationService(db, workflowService), recorder)
+	handler := handler.NewHandler(db, workflowService, recorder)
 
 	// API路由组
 	api := r.Group("/api/v1")
@@ -42,16 +53,71 @@ func Setup(cfg *config.Config, db *gorm.DB, iamClient *client.IamClient) *gin.En
 				workflows.POST("", middleware.FetchUserFromHeader(db),
 					handler.CreateWorkflow) // 创建流程
 
+				workflows.POST("/from-template", middleware.FetchUserFromHeader(db),
+					templateHandler.CreateWorkflowFromTemplate) // 按模板创建流程
+
 				workflows.POST("/:id/start", middleware.FetchUserFromHeader(db),
 					handler.StartWorkflow) // 启动流程
+
+				workflows.GET("/:id", middleware.FetchUserFromHeader(db),
+					handler.GetWorkflow) // 获取流程详情及投票统计
+
+				workflows.GET("/resource", middleware.FetchUserFromHeader(db),
+					handler.GetResourceTimeline) // 按资源获取所有轮次的审批时间线
+
+				workflows.GET("/resource-types", handler.ListResourceTypes) // 支持审批的资源类型
+
+				workflows.GET("/stuck", middleware.FetchUserFromHeader(db),
+					handler.GetStuckWorkflows) // 管理员查看无人可处理的流程
+
+				workflows.GET("/:id/timeline", middleware.FetchUserFromHeader(db),
+					handler.GetWorkflowTimeline) // 获取审批时间线
+
+				workflows.GET("/:id/status", middleware.FetchUserFromHeader(db),
+					handler.GetWorkflowStatus) // 获取流程状态，资源所属服务调用时带上当前用户
+
+				workflows.POST("/:id/withdraw", middleware.FetchUserFromHeader(db),
+					handler.WithdrawWorkflow) // 发起人撤回
+
+				workflows.POST("/:id/cancel", middleware.FetchUserFromHeader(db),
+					handler.CancelWorkflow) // 管理员取消
+			}
+
+			// 审批模板管理，增删改需要 configure_workflow 权限
+			templates := workflow.Group("/templates")
+			templates.Use(middleware.FetchUserFromHeader(db))
+			{
+				templates.GET("", templateHandler.ListTemplates)
+				templates.GET("/match", templateHandler.MatchTemplate)
+				templates.GET("/:id", templateHandler.GetTemplate)
+				templates.POST("", templateHandler.CreateTemplate)
+				templates.PUT("/:id", templateHandler.UpdateTemplate)
+				templates.DELETE("/:id", templateHandler.DeleteTemplate)
 			}
 
 			tasks := workflow.Group("/tasks")
 			{
 				tasks.GET("", middleware.FetchUserFromHeader(db),
 					handler.GetTasks) // 获取任务
+				tasks.GET("/pending-count", middleware.FetchUserFromHeader(db),
+					handler.GetPendingTaskCount) // 待审批数量
 				tasks.POST("/approve", middleware.FetchUserFromHeader(db),
 					handler.ApproveTask) // 审批任务
+				tasks.POST("/bulk-approve", middleware.FetchUserFromHeader(db),
+					handler.BulkApproveTasks) // 批量审批
+				tasks.POST("/:id/transfer", middleware.FetchUserFromHeader(db),
+					handler.TransferTask) // 转交任务
+				tasks.POST("/:id/add-approver", middleware.FetchUserFromHeader(db),
+					handler.AddApprover) // 加签
+			}
+
+			// 外出委托
+			delegations := workflow.Group("/delegations")
+			delegations.Use(middleware.FetchUserFromHeader(db))
+			{
+				delegations.GET("", delegationHandler.ListDelegations)
+				delegations.POST("", delegationHandler.CreateDelegation)
+				delegations.DELETE("/:id", delegationHandler.CancelDelegation)
 			}
 		}
 	}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+// checkWorkflowAccess 只有系统管理员、流程发起人、流程中的审批人（含委托前的原审批人）
+// 以及流程所属空间的成员可以查看流程
+func (s *WorkflowService) checkWorkflowAccess(workflow *model.Workflow, user *model.User) error {
+	if isGlobalAdmin(user) || workflow.CreatedBy == user.ID {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.Model(&model.Task{}).
+		Where("workflow_id = ? AND (approver_id = ? OR original_approver_id = ?)", workflow.ID, user.ID, user.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if workflow.SpaceID != 0 {
+		if err := s.db.Model(&model.SpaceMember{}).
+			Where("space_id = ? AND user_id = ?", workflow.SpaceID, user.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+	}
+	return ErrWorkflowForbidden
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+// stepOutcome 一次审批后步骤的结果
+type stepOutcome int
+
+const (
+	stepPending  stepOutcome = iota // 尚未满足通过或驳回条件
+	stepApproved                    // 步骤通过
+	stepRejected                    // 步骤驳回
+)
+
+// validateApprovalRule 校验步骤的审批规则，未设置时默认为或签
+func validateApprovalRule(step *model.Step) error {
+	switch step.ApprovalMode {
+	case "":
+		step.ApprovalMode = model.ApprovalModeAny
+	case model.ApprovalModeAny, model.ApprovalModeAll:
+	case model.ApprovalModePercentage:
+		if step.ApprovalThreshold < 1 || step.ApprovalThreshold > 100 {
+			return fmt.Errorf("步骤 %s 的审批比例必须在 1-100 之间", step.StepName)
+		}
+	case model.ApprovalModeNOfM:
+		if step.ApprovalThreshold < 1 {
+			return fmt.Errorf("步骤 %s 的通过人数必须大于 0", step.StepName)
+		}
+	default:
+		return fmt.Errorf("步骤 %s 的审批模式 %s 不支持", step.StepName, step.ApprovalMode)
+	}
+	return nil
+}
+
+// requiredApprovals 根据审批模式和审批人总数计算通过所需的同意人数
+func requiredApprovals(step *model.Step, total int) int {
+	var required int
+	switch step.ApprovalMode {
+	case model.ApprovalModeAll:
+		required = total
+	case model.ApprovalModePercentage:
+		// 向上取整，例如 3 人 50% 需要 2 人同意
+		required = (total*step.ApprovalThreshold + 99) / 100
+	case model.ApprovalModeNOfM:
+		required = step.ApprovalThreshold
+	default:
+		required = 1
+	}
+
+	if required > total {
+		required = total
+	}
+	if required < 1 {
+		required = 1
+	}
+	return required
+}
+
+// evaluateStep 根据当前投票统计判断步骤结果
+func evaluateStep(step *model.Step) stepOutcome {
+	if step.ApprovalMode == model.ApprovalModeAny || step.ApprovalMode == "" {
+		// 或签：第一个审批人的决定即为步骤结果
+		switch {
+		case step.ApprovedCount > 0:
+			return stepApproved
+		case step.RejectedCount > 0:
+			return stepRejected
+		}
+		return stepPending
+	}
+
+	if step.ApprovedCount >= step.RequiredCount {
+		return stepApproved
+	}
+	// 剩余未投票的人全部同意也无法达到通过人数时，步骤驳回
+	if step.TotalCount-step.RejectedCount < step.RequiredCount {
+		return stepRejected
+	}
+	return stepPending
+}
+
+// validateDecision 审批结果只能是同意或拒绝
+func validateDecision(status model.TaskStatus) error {
+	if status != model.TaskStatusApproved && status != model.TaskStatusRejected {
+		return errors.New("审批结果只能是 approved 或 rejected")
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"testing"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestRequiredApprovals(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      model.ApprovalMode
+		threshold int
+		total     int
+		want      int
+	}{
+		{name: "any", mode: model.ApprovalModeAny, total: 4, want: 1},
+		{name: "empty mode is any", mode: "", total: 4, want: 1},
+		{name: "all", mode: model.ApprovalModeAll, total: 4, want: 4},
+		{name: "percentage rounds up", mode: model.ApprovalModePercentage, threshold: 50, total: 3, want: 2},
+		{name: "percentage exact", mode: model.ApprovalModePercentage, threshold: 75, total: 4, want: 3},
+		{name: "percentage at least one", mode: model.ApprovalModePercentage, threshold: 1, total: 1, want: 1},
+		{name: "n of m", mode: model.ApprovalModeNOfM, threshold: 2, total: 4, want: 2},
+		{name: "n of m capped by total", mode: model.ApprovalModeNOfM, threshold: 5, total: 3, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &model.Step{ApprovalMode: tt.mode, ApprovalThreshold: tt.threshold}
+			if got := requiredApprovals(step, tt.total); got != tt.want {
+				t.Fatalf("requiredApprovals() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateApprovalRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      model.ApprovalMode
+		threshold int
+		wantErr   bool
+	}{
+		{name: "empty defaults to any", mode: ""},
+		{name: "all", mode: model.ApprovalModeAll},
+		{name: "percentage in range", mode: model.ApprovalModePercentage, threshold: 60},
+		{name: "percentage zero", mode: model.ApprovalModePercentage, threshold: 0, wantErr: true},
+		{name: "percentage over 100", mode: model.ApprovalModePercentage, threshold: 101, wantErr: true},
+		{name: "n of m zero", mode: model.ApprovalModeNOfM, threshold: 0, wantErr: true},
+		{name: "unknown mode", mode: "majority", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := &model.Step{StepName: "审批", ApprovalMode: tt.mode, ApprovalThreshold: tt.threshold}
+			err := validateApprovalRule(step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateApprovalRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.mode == "" && step.ApprovalMode != model.ApprovalModeAny {
+				t.Fatalf("mode = %q, want %q", step.ApprovalMode, model.ApprovalModeAny)
+			}
+		})
+	}
+}
+
+func TestApprovalModes(t *testing.T) {
+	type vote struct {
+		user   model.User
+		status model.TaskStatus
+	}
+	approve := func(u model.User) vote { return vote{u, model.TaskStatusApproved} }
+	reject := func(u model.User) vote { return vote{u, model.TaskStatusRejected} }
+
+	tests := []struct {
+		name      string
+		mode      model.ApprovalMode
+		threshold int
+		votes     []vote
+		want      model.WorkflowStatus
+		approved  int
+		rejected  int
+	}{
+		{name: "any approves on first approval", mode: model.ApprovalModeAny, votes: []vote{approve(alice)}, want: model.WorkflowStatusCompleted, approved: 1},
+		{name: "any rejects on first rejection", mode: model.ApprovalModeAny, votes: []vote{reject(bob)}, want: model.WorkflowStatusRejected, rejected: 1},
+		{name: "all waits for everyone", mode: model.ApprovalModeAll, votes: []vote{approve(alice), approve(bob), approve(carol)}, want: model.WorkflowStatusProcessing, approved: 3},
+		{name: "all approves when everyone agrees", mode: model.ApprovalModeAll, votes: []vote{approve(alice), approve(bob), approve(carol), approve(dave)}, want: model.WorkflowStatusCompleted, approved: 4},
+		{name: "all rejects on any rejection", mode: model.ApprovalModeAll, votes: []vote{approve(alice), reject(bob)}, want: model.WorkflowStatusRejected, approved: 1, rejected: 1},
+		{name: "percentage reaches threshold", mode: model.ApprovalModePercentage, threshold: 50, votes: []vote{reject(alice), approve(bob), approve(carol)}, want: model.WorkflowStatusCompleted, approved: 2, rejected: 1},
+		{name: "percentage becomes unreachable", mode: model.ApprovalModePercentage, threshold: 75, votes: []vote{reject(alice), reject(bob)}, want: model.WorkflowStatusRejected, rejected: 2},
+		{name: "n of m pending", mode: model.ApprovalModeNOfM, threshold: 3, votes: []vote{approve(alice), reject(bob), approve(carol)}, want: model.WorkflowStatusProcessing, approved: 2, rejected: 1},
+		{name: "n of m reached", mode: model.ApprovalModeNOfM, threshold: 3, votes: []vote{approve(alice), reject(bob), approve(carol), approve(dave)}, want: model.WorkflowStatusCompleted, approved: 3, rejected: 1},
+		{name: "n of m unreachable", mode: model.ApprovalModeNOfM, threshold: 3, votes: []vote{reject(alice), reject(bob)}, want: model.WorkflowStatusRejected, rejected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, iam, db := newTestService(t)
+			iam.setRole(testSpaceID, "approver", alice, bob, carol, dave)
+
+			workflow := startWorkflow(t, s, model.Step{
+				StepName: "会审", StepOrder: 1, StepRole: "approver",
+				ApprovalMode: tt.mode, ApprovalThreshold: tt.threshold,
+			})
+			for _, v := range tt.votes {
+				decide(t, s, db, workflow.ID, v.user, v.status)
+			}
+
+			if got := reloadWorkflow(t, db, workflow.ID).Status; got != tt.want {
+				t.Fatalf("workflow status = %s, want %s", got, tt.want)
+			}
+			var step model.Step
+			if err := db.Where("workflow_id = ?", workflow.ID).First(&step).Error; err != nil {
+				t.Fatalf("load step: %v", err)
+			}
+			if step.TotalCount != 4 || step.ApprovedCount != tt.approved || step.RejectedCount != tt.rejected {
+				t.Fatalf("votes total/approved/rejected = %d/%d/%d, want 4/%d/%d",
+					step.TotalCount, step.ApprovedCount, step.RejectedCount, tt.approved, tt.rejected)
+			}
+			if tt.want != model.WorkflowStatusProcessing && len(openTasks(t, db, workflow.ID)) != 0 {
+				t.Fatalf("finished workflow still has open tasks")
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+var ErrNoApprover = errors.New("没有可用的审批人")
+
+// notStartedGrace 流程创建后超过该时间仍未启动，才视为启动失败
+const notStartedGrace = 10 * time.Minute
+
+// resolveApprovers 按步骤角色（或发起人的部门负责人）、空间管理员、企业管理员、配置的默认审批人依次查找审批人。
+// 使用兜底审批人时返回说明，用于记录流程历史；都找不到时返回 ErrNoApprover
+func (s *WorkflowService) resolveApprovers(workflow *model.Workflow, step *model.Step, user *model.User) ([]model.User, string, error) {
+	var missing string
+	if step.StepRole == model.StepRoleDepartmentHead {
+		head, err := s.iamClient.GetDepartmentHead(user, workflow.CreatedBy)
+		if err != nil {
+			return nil, "", err
+		}
+		if head != nil && head.Status == 1 {
+			return []model.User{*head}, "", nil
+		}
+		missing = "发起人没有可用的部门负责人"
+	} else {
+		members, err := s.iamClient.GetSpaceMemebersByRole(user, workflow.SpaceID, step.StepRole)
+		if err != nil {
+			return nil, "", err
+		}
+		if approvers := activeUsers(members); len(approvers) > 0 {
+			return approvers, "", nil
+		}
+		missing = fmt.Sprintf("空间内没有 %s 角色的成员", step.StepRole)
+	}
+
+	if step.StepRole != string(model.SpaceMemberRoleAdmin) {
+		admins, err := s.iamClient.GetSpaceMemebersByRole(user, workflow.SpaceID, string(model.SpaceMemberRoleAdmin))
+		if err != nil {
+			return nil, "", err
+		}
+		if approvers := activeUsers(admins); len(approvers) > 0 {
+			return approvers, missing + "，由空间管理员审批", nil
+		}
+	}
+
+	corpAdmins, err := s.iamClient.GetUsersByRole(user, string(model.RoleEnterpriseAdmin))
+	if err != nil {
+		return nil, "", err
+	}
+	if approvers := activeUsers(corpAdmins); len(approvers) > 0 {
+		return approvers, missing + "，也没有空间管理员，由企业管理员审批", nil
+	}
+
+	if len(s.defaultApproverIDs) > 0 {
+		var defaults []model.User
+		if err := s.db.Where("id IN ? AND status = 1", s.defaultApproverIDs).Order("id").Find(&defaults).Error; err != nil {
+			return nil, "", err
+		}
+		if len(defaults) > 0 {
+			return defaults, missing + "，也没有管理员，由默认审批人审批", nil
+		}
+	}
+
+	return nil, "", fmt.Errorf("%w: 步骤 %s：%s，且未找到空间管理员、企业管理员或默认审批人",
+		ErrNoApprover, step.StepName, missing)
+}
+
+// activeUsers 过滤掉已禁用的用户
+func activeUsers(users []model.User) []model.User {
+	active := make([]model.User, 0, len(users))
+	for _, user := range users {
+		if user.Status == 1 {
+			active = append(active, user)
+		}
+	}
+	return active
+}
+
+// GetStuckWorkflows 列出审批中但无人可处理的流程：启动失败的流程，以及当前步骤没有待处理任务的流程
+func (s *WorkflowService) GetStuckWorkflows(query *model.StuckWorkflowQuery, user *model.User) (model.PaginationResponse, error) {
+	if !isGlobalAdmin(user) {
+		return model.PaginationResponse{}, ErrWorkflowForbidden
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 || query.PageSize > 100 {
+		query.PageSize = 10
+	}
+
+	openTasks := s.db.Model(&model.Task{}).Select("1").
+		Where("tasks.step_id = workflows.current_step_id AND tasks.status IN ?", openTaskStatuses)
+	db := s.db.Table("workflows").
+		Joins("LEFT JOIN steps ON steps.id = workflows.current_step_id").
+		Where("workflows.status = ?", model.WorkflowStatusProcessing).
+		Where("(workflows.current_step_id = 0 AND workflows.created_at < ?) OR (workflows.current_step_id <> 0 AND NOT EXISTS (?))",
+			time.Now().Add(-notStartedGrace), openTasks)
+	if query.SpaceID != 0 {
+		db = db.Where("workflows.space_id = ?", query.SpaceID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return model.PaginationResponse{}, err
+	}
+
+	var items []model.StuckWorkflow
+	if err := db.Select(`workflows.id AS workflow_id, workflows.name, workflows.space_id, workflows.resource_type,
+		workflows.resource_id, workflows.created_by, workflows.creator_nick_name, workflows.current_step_id,
+		workflows.created_at, steps.step_name, steps.step_role, steps.started_at AS step_started_at`).
+		Order("workflows.id ASC").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Scan(&items).Error; err != nil {
+		return model.PaginationResponse{}, err
+	}
+	for i := range items {
+		items[i].Reason = model.StuckWorkflowNoOpenTasks
+		if items[i].CurrentStepID == 0 {
+			items[i].Reason = model.StuckWorkflowNotStarted
+		}
+	}
+
+	return model.PaginationResponse{
+		Items:      items,
+		Total:      total,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalPages: int((total + int64(query.PageSize) - 1) / int64(query.PageSize)),
+	}, nil
+}
+
+// isGlobalAdmin 超级管理员或企业管理员
+func isGlobalAdmin(user *model.User) bool {
+	for _, role := range user.Roles {
+		if role.Name == model.RoleSuperAdmin || role.Name == model.RoleEnterpriseAdmin {
+			return true
+		}
+	}
+	return false
+}
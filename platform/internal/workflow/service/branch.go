@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/workflow/expr"
+	"gorm.io/gorm"
+)
+
+// validateBranching 校验步骤序号、进入条件和流转规则
+func validateBranching(steps []model.Step) error {
+	orders := make(map[int]bool, len(steps))
+	for _, step := range steps {
+		if orders[step.StepOrder] {
+			return fmt.Errorf("步骤序号 %d 重复", step.StepOrder)
+		}
+		orders[step.StepOrder] = true
+	}
+
+	for _, step := range steps {
+		if err := expr.Validate(step.Condition); err != nil {
+			return fmt.Errorf("步骤 %s 的条件表达式错误: %w", step.StepName, err)
+		}
+		for _, transition := range step.Transitions {
+			if err := expr.Validate(transition.Condition); err != nil {
+				return fmt.Errorf("步骤 %s 的流转条件错误: %w", step.StepName, err)
+			}
+			if transition.NextStepOrder == 0 {
+				continue
+			}
+			// 只允许向后流转，避免流程出现循环
+			if transition.NextStepOrder <= step.StepOrder {
+				return fmt.Errorf("步骤 %s 只能流转到后续步骤", step.StepName)
+			}
+			if !orders[transition.NextStepOrder] {
+				return fmt.Errorf("步骤 %s 流转的目标步骤 %d 不存在", step.StepName, transition.NextStepOrder)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveNextStep 计算当前步骤之后应进入的步骤，被跳过的步骤标记为 skipped；
+// 返回 nil 表示流程结束
+func resolveNextStep(tx *gorm.DB, workflow *model.Workflow, current *model.Step) (*model.Step, error) {
+	fromOrder, targetOrder := 0, 0
+	if current != nil {
+		fromOrder = current.StepOrder
+		for _, transition := range current.Transitions {
+			matched, err := expr.Match(transition.Condition, workflow.Attributes)
+			if err != nil {
+				return nil, fmt.Errorf("步骤 %s 的流转条件求值失败: %w", current.StepName, err)
+			}
+			if !matched {
+				continue
+			}
+			if transition.NextStepOrder == 0 {
+				// 流转到结束，剩余步骤全部跳过
+				return nil, skipSteps(tx, workflow.ID, fromOrder, "流转到结束")
+			}
+			targetOrder = transition.NextStepOrder
+			break
+		}
+	}
+
+	var steps []model.Step
+	if err := tx.Where("workflow_id = ? AND step_order > ?", workflow.ID, fromOrder).
+		Order("step_order ASC").Find(&steps).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		entered := step.StepOrder >= targetOrder
+		if entered {
+			matched, err := expr.Match(step.Condition, workflow.Attributes)
+			if err != nil {
+				return nil, fmt.Errorf("步骤 %s 的条件求值失败: %w", step.StepName, err)
+			}
+			entered = matched
+		}
+		if entered {
+			return step, nil
+		}
+
+		reason := "条件不满足"
+		if step.StepOrder < targetOrder {
+			reason = "流转规则跳过"
+		}
+		if err := skipStep(tx, step, reason); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// skipSteps 将 fromOrder 之后的步骤全部标记为 skipped
+func skipSteps(tx *gorm.DB, workflowID uint, fromOrder int, reason string) error {
+	var steps []model.Step
+	if err := tx.Where("workflow_id = ? AND step_order > ?", workflowID, fromOrder).
+		Order("step_order ASC").Find(&steps).Error; err != nil {
+		return err
+	}
+	for i := range steps {
+		if err := skipStep(tx, &steps[i], reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipStep 标记步骤为 skipped 并写入流程历史
+func skipStep(tx *gorm.DB, step *model.Step, reason string) error {
+	now := time.Now()
+	step.Status = model.StepStatusSkipped
+	step.FinishedAt = &now
+	if err := tx.Model(step).Updates(map[string]any{
+		"status":      step.Status,
+		"finished_at": step.FinishedAt,
+	}).Error; err != nil {
+		return err
+	}
+	return recordHistory(tx, step.WorkflowID, step.ID, 0, model.WorkflowHistoryStepSkipped, nil, step.StepName+"："+reason)
+}
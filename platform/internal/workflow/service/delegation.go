@@ -0,0 +1,239 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDelegationNotFound = errors.New("委托规则不存在")
+	ErrDelegationInvalid  = errors.New("委托规则无效")
+)
+
+type DelegationService struct {
+	db              *gorm.DB
+	workflowService *WorkflowService
+}
+
+func NewDelegationService(db *gorm.DB, workflowService *WorkflowService) *DelegationService {
+	return &DelegationService{db: db, workflowService: workflowService}
+}
+
+// CreateRule 创建外出委托规则；规则立即生效时，委托人手中待审批的任务同时转给代理人
+func (s *DelegationService) CreateRule(req *model.CreateDelegationRequest, user *model.User) (*model.DelegationRule, int, error) {
+	if req.DelegateID == user.ID {
+		return nil, 0, fmt.Errorf("%w: 不能委托给自己", ErrDelegationInvalid)
+	}
+	if !req.EndAt.After(req.StartAt) || !req.EndAt.After(time.Now()) {
+		return nil, 0, fmt.Errorf("%w: 结束时间必须晚于开始时间和当前时间", ErrDelegationInvalid)
+	}
+
+	var delegate model.User
+	if err := s.db.Where("id = ? AND status = 1", req.DelegateID).First(&delegate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, fmt.Errorf("%w: 代理人不存在或已禁用", ErrDelegationInvalid)
+		}
+		return nil, 0, err
+	}
+
+	rule := &model.DelegationRule{
+		UserID:       user.ID,
+		DelegateID:   delegate.ID,
+		DelegateName: delegate.Nickname,
+		SpaceID:      req.SpaceID,
+		StartAt:      req.StartAt,
+		EndAt:        req.EndAt,
+		Reason:       req.Reason,
+		Status:       1,
+	}
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, 0, err
+	}
+
+	moved := 0
+	if !rule.StartAt.After(time.Now()) {
+		moved = s.workflowService.delegatePendingTasks(rule, user, &delegate)
+	}
+	return rule, moved, nil
+}
+
+// ListRules 查询用户自己的委托规则
+func (s *DelegationService) ListRules(user *model.User) ([]model.DelegationRule, error) {
+	var rules []model.DelegationRule
+	if err := s.db.Where("user_id = ?", user.ID).Order("id DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CancelRule 停用委托规则，已转给代理人的任务不会收回
+func (s *DelegationService) CancelRule(id uint, user *model.User) (*model.DelegationRule, error) {
+	rule := &model.DelegationRule{}
+	if err := s.db.Where("id = ? AND user_id = ?", id, user.ID).First(rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDelegationNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	rule.Status = 0
+	rule.CancelledAt = &now
+	if err := s.db.Save(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// delegatePendingTasks 将委托人手中待审批的任务转给代理人，代理人不具备步骤审批角色的任务保持不变
+func (s *WorkflowService) delegatePendingTasks(rule *model.DelegationRule, user *model.User, delegate *model.User) int {
+	query := s.db.Model(&model.Task{}).
+		Joins("JOIN workflows ON workflows.id = tasks.workflow_id").
+		Where("tasks.approver_id = ? AND tasks.status = ?", user.ID, model.TaskStatusProcessing)
+	if rule.SpaceID != 0 {
+		query = query.Where("workflows.space_id = ?", rule.SpaceID)
+	}
+
+	var taskIDs []uint
+	if err := query.Pluck("tasks.id", &taskIDs).Error; err != nil {
+		logger.Errorf("failed to load pending tasks for delegation rule %d: %v", rule.ID, err)
+		return 0
+	}
+
+	moved := 0
+	for _, taskID := range taskIDs {
+		newTask, err := s.delegateTask(taskID, user, delegate)
+		if err != nil {
+			logger.Warnf("task %d not delegated by rule %d: %v", taskID, rule.ID, err)
+			continue
+		}
+		s.publishTasksAssigned(&newTask.Workflow, []model.Task{*newTask})
+		moved++
+	}
+	return moved
+}
+
+func (s *WorkflowService) delegateTask(taskID uint, user *model.User, delegate *model.User) (*model.Task, error) {
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	task, err := s.loadOperableTask(tx, taskID, user)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	target, err := s.checkAssignee(tx, task, user, delegate.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	comment := fmt.Sprintf("%s 外出委托，由 %s 代为审批", task.ApproverNickName, target.Nickname)
+	newTask, err := reassignTask(tx, task, target, user.ID, model.WorkflowHistoryDelegated, user, comment)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return newTask, nil
+}
+
+// applyDelegations 新建任务时按生效的委托规则替换审批人。代理人必须是空间内具有步骤审批角色的正常成员，
+// 部门负责人步骤只要求代理人是正常用户；代理人已是该步骤的审批人时保留原审批人，避免同一人占用两个审批名额。
+// 返回被替换的任务下标
+func (s *WorkflowService) applyDelegations(tx *gorm.DB, workflow *model.Workflow, step *model.Step, tasks []model.Task, operator *model.User) ([]int, error) {
+	approverIDs := make([]uint, len(tasks))
+	assigned := make(map[uint]bool, len(tasks))
+	for i, task := range tasks {
+		approverIDs[i] = task.ApproverID
+		assigned[task.ApproverID] = true
+	}
+
+	now := time.Now()
+	var rules []model.DelegationRule
+	if err := tx.Where("user_id IN ? AND status = 1 AND start_at <= ? AND end_at > ? AND (space_id = 0 OR space_id = ?)",
+		approverIDs, now, now, workflow.SpaceID).
+		Order("space_id DESC, id DESC").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	// 指定空间的规则优先
+	ruleByUser := make(map[uint]model.DelegationRule)
+	delegateIDs := make([]uint, 0, len(rules))
+	for _, rule := range rules {
+		if _, ok := ruleByUser[rule.UserID]; !ok {
+			ruleByUser[rule.UserID] = rule
+			delegateIDs = append(delegateIDs, rule.DelegateID)
+		}
+	}
+
+	candidates, err := s.delegateCandidates(tx, workflow, step, delegateIDs, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	var delegated []int
+	for i := range tasks {
+		rule, ok := ruleByUser[tasks[i].ApproverID]
+		if !ok {
+			continue
+		}
+		delegate, eligible := candidates[rule.DelegateID]
+		if !eligible || assigned[delegate.ID] {
+			continue
+		}
+
+		assigned[delegate.ID] = true
+		tasks[i].OriginalApproverID = tasks[i].ApproverID
+		tasks[i].ApproverID = delegate.ID
+		tasks[i].ApproverNickName = delegate.Nickname
+		delegated = append(delegated, i)
+	}
+	return delegated, nil
+}
+
+// delegateCandidates 从代理人中筛出可以审批该步骤的用户：空间内具有步骤审批角色的正常成员，
+// 部门负责人步骤不对应空间角色，只要求是正常用户
+func (s *WorkflowService) delegateCandidates(tx *gorm.DB, workflow *model.Workflow, step *model.Step, delegateIDs []uint, operator *model.User) (map[uint]model.User, error) {
+	var users []model.User
+	if step.StepRole == model.StepRoleDepartmentHead {
+		if err := tx.Where("id IN ? AND status = 1", delegateIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		members, err := s.iamClient.GetSpaceMemebersByRole(operator, workflow.SpaceID, step.StepRole)
+		if err != nil {
+			return nil, err
+		}
+		users = activeUsers(members)
+	}
+
+	wanted := make(map[uint]bool, len(delegateIDs))
+	for _, id := range delegateIDs {
+		wanted[id] = true
+	}
+	candidates := make(map[uint]model.User, len(delegateIDs))
+	for _, user := range users {
+		if wanted[user.ID] {
+			candidates[user.ID] = user
+		}
+	}
+	return candidates, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestCreateDelegationRuleValidation(t *testing.T) {
+	s, _, db := newTestService(t)
+	disabled := testUser(6, "disabled")
+	disabled.Status = 0
+	saveUsers(t, db, alice, bob, disabled)
+	delegations := NewDelegationService(db, s)
+
+	now := time.Now()
+	tests := []struct {
+		name string
+		req  model.CreateDelegationRequest
+	}{
+		{name: "delegate to self", req: model.CreateDelegationRequest{DelegateID: alice.ID, StartAt: now, EndAt: now.Add(time.Hour)}},
+		{name: "end before start", req: model.CreateDelegationRequest{DelegateID: bob.ID, StartAt: now, EndAt: now.Add(-time.Minute)}},
+		{name: "already ended", req: model.CreateDelegationRequest{DelegateID: bob.ID, StartAt: now.Add(-2 * time.Hour), EndAt: now.Add(-time.Hour)}},
+		{name: "unknown delegate", req: model.CreateDelegationRequest{DelegateID: 99, StartAt: now, EndAt: now.Add(time.Hour)}},
+		{name: "disabled delegate", req: model.CreateDelegationRequest{DelegateID: disabled.ID, StartAt: now, EndAt: now.Add(time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := delegations.CreateRule(&tt.req, &alice); !errors.Is(err, ErrDelegationInvalid) {
+				t.Fatalf("CreateRule() error = %v, want %v", err, ErrDelegationInvalid)
+			}
+		})
+	}
+}
+
+func TestDelegation(t *testing.T) {
+	tests := []struct {
+		name      string
+		stepRole  string
+		members   []model.User // 规则生效时步骤角色的空间成员
+		ruleSpace uint
+		existing  bool // true 时先有待办任务再创建规则
+		wantTo    uint // 任务最终的审批人
+	}{
+		{name: "pending task moves to delegate with step role", stepRole: "approver", members: []model.User{alice, bob}, existing: true, wantTo: bob.ID},
+		{name: "delegate without step role keeps approver", stepRole: "approver", members: []model.User{alice}, existing: true, wantTo: alice.ID},
+		{name: "rule for another space is ignored", stepRole: "approver", members: []model.User{alice, bob}, ruleSpace: testSpaceID + 1, existing: true, wantTo: alice.ID},
+		{name: "pending department head task moves to active delegate", stepRole: model.StepRoleDepartmentHead, existing: true, wantTo: bob.ID},
+		{name: "new department head task goes to delegate", stepRole: model.StepRoleDepartmentHead, wantTo: bob.ID},
+		{name: "delegate already approving the step keeps approver", stepRole: "approver", members: []model.User{alice, bob}, wantTo: alice.ID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, iam, db := newTestService(t)
+			saveUsers(t, db, alice, bob)
+			iam.setHead(initiator.ID, alice)
+			iam.setRole(testSpaceID, "approver", alice)
+			delegations := NewDelegationService(db, s)
+			req := &model.CreateDelegationRequest{
+				DelegateID: bob.ID, SpaceID: tt.ruleSpace,
+				StartAt: time.Now().Add(-time.Minute), EndAt: time.Now().Add(time.Hour),
+			}
+			step := model.Step{StepName: "审批", StepOrder: 1, StepRole: tt.stepRole, ApprovalMode: model.ApprovalModeAll}
+
+			var workflow *model.Workflow
+			if tt.existing {
+				workflow = startWorkflow(t, s, step)
+			}
+			iam.setRole(testSpaceID, "approver", tt.members...)
+			if _, _, err := delegations.CreateRule(req, &alice); err != nil {
+				t.Fatalf("CreateRule() error = %v", err)
+			}
+			if !tt.existing {
+				workflow = startWorkflow(t, s, step)
+			}
+
+			task, ok := openTasks(t, db, workflow.ID)[tt.wantTo]
+			if !ok {
+				t.Fatalf("no open task for %d", tt.wantTo)
+			}
+			wantOriginal := uint(0)
+			if tt.wantTo != alice.ID {
+				wantOriginal = alice.ID
+			}
+			if task.OriginalApproverID != wantOriginal {
+				t.Fatalf("original approver = %d, want %d", task.OriginalApproverID, wantOriginal)
+			}
+			if _, ok := openTasks(t, db, workflow.ID)[alice.ID]; ok && tt.wantTo != alice.ID {
+				t.Fatalf("alice still has an open task after delegation")
+			}
+		})
+	}
+}
+
+func TestCancelledDelegationRuleStopsDelegating(t *testing.T) {
+	s, iam, db := newTestService(t)
+	saveUsers(t, db, alice, bob)
+	iam.setRole(testSpaceID, "approver", alice, bob)
+	delegations := NewDelegationService(db, s)
+
+	rule, _, err := delegations.CreateRule(&model.CreateDelegationRequest{
+		DelegateID: bob.ID, StartAt: time.Now().Add(-time.Minute), EndAt: time.Now().Add(time.Hour),
+	}, &alice)
+	if err != nil {
+		t.Fatalf("CreateRule() error = %v", err)
+	}
+	if _, err := delegations.CancelRule(rule.ID, &bob); !errors.Is(err, ErrDelegationNotFound) {
+		t.Fatalf("CancelRule() by other user error = %v, want %v", err, ErrDelegationNotFound)
+	}
+	if _, err := delegations.CancelRule(rule.ID, &alice); err != nil {
+		t.Fatalf("CancelRule() error = %v", err)
+	}
+
+	iam.setRole(testSpaceID, "approver", alice)
+	workflow := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver"})
+	if _, ok := openTasks(t, db, workflow.ID)[alice.ID]; !ok {
+		t.Fatalf("task was delegated after the rule was cancelled")
+	}
+}
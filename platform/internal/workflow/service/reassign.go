@@ -0,0 +1,295 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTaskNotFound         = errors.New("任务不存在")
+	ErrTaskForbidden        = errors.New("用户无权限操作该任务")
+	ErrTaskNotProcessing    = errors.New("任务不在待审批状态")
+	ErrAssigneeNotEligible  = errors.New("目标用户不具备该步骤的审批角色")
+	ErrAssigneeAlreadyAdded = errors.New("目标用户已是该步骤的审批人")
+)
+
+// TransferTask 将任务转交给同一空间内具备步骤审批角色的其他用户
+func (s *WorkflowService) TransferTask(taskID uint, req *model.TransferTaskRequest, user *model.User) (*model.Task, error) {
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	task, err := s.loadOperableTask(tx, taskID, user)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	target, err := s.checkAssignee(tx, task, user, req.ToUserID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	comment := fmt.Sprintf("%s 转交给 %s", task.ApproverNickName, target.Nickname)
+	if req.Comment != "" {
+		comment += "：" + req.Comment
+	}
+	newTask, err := reassignTask(tx, task, target, 0, model.WorkflowHistoryTransferred, user, comment)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	s.publishTasksAssigned(&task.Workflow, []model.Task{*newTask})
+	return newTask, nil
+}
+
+// AddApprover 加签：before 时加签人先审批，同意后交回当前审批人；
+// after 时当前审批人同意后还需要加签人同意，加签链的最终结果计为一票
+func (s *WorkflowService) AddApprover(taskID uint, req *model.AddApproverRequest, user *model.User) (*model.Task, error) {
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	task, err := s.loadOperableTask(tx, taskID, user)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	target, err := s.checkAssignee(tx, task, user, req.UserID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	added := model.Task{
+		WorkflowID:       task.WorkflowID,
+		StepID:           task.StepID,
+		ApproverID:       target.ID,
+		ApproverNickName: target.Nickname,
+		TaskName:         task.TaskName,
+		IsRequired:       task.IsRequired,
+		TimeoutHours:     task.TimeoutHours,
+		ParentTaskID:     task.ID,
+		AddPosition:      req.Position,
+	}
+
+	var positionName string
+	if req.Position == model.AddApproverBefore {
+		positionName = "前加签"
+		added.Status = model.TaskStatusProcessing
+		added.DueAt = taskDueAt(task.TimeoutHours)
+
+		// 当前任务等待加签人处理
+		task.Status = model.TaskStatusWaiting
+		if err := tx.Model(task).Update("status", task.Status).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	} else {
+		positionName = "后加签"
+
+		// 每个任务同时只能有一个后加签
+		var count int64
+		if err := tx.Model(&model.Task{}).
+			Where("parent_task_id = ? AND add_position = ? AND status = ?", task.ID, model.AddApproverAfter, model.TaskStatusWaiting).
+			Count(&count).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if count > 0 {
+			tx.Rollback()
+			return nil, errors.New("该任务已有待处理的后加签")
+		}
+		added.Status = model.TaskStatusWaiting
+	}
+
+	if err := tx.Create(&added).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	comment := fmt.Sprintf("%s %s %s", task.ApproverNickName, positionName, target.Nickname)
+	if req.Comment != "" {
+		comment += "：" + req.Comment
+	}
+	if err := recordHistory(tx, task.WorkflowID, task.StepID, added.ID, model.WorkflowHistoryAddApprover, user, comment); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	if added.Status == model.TaskStatusProcessing {
+		s.publishTasksAssigned(&task.Workflow, []model.Task{added})
+	}
+	return &added, nil
+}
+
+// loadOperableTask 锁定并加载待审批的任务，只有当前审批人可以操作；
+// 任务委托出去后由代理人处理，委托人不能再转交或加签
+func (s *WorkflowService) loadOperableTask(tx *gorm.DB, taskID uint, user *model.User) (*model.Task, error) {
+	task, err := lockTask(tx, taskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	if task.ApproverID != user.ID {
+		return nil, ErrTaskForbidden
+	}
+	if task.Status != model.TaskStatusProcessing {
+		return nil, ErrTaskNotProcessing
+	}
+	return task, nil
+}
+
+// checkAssignee 目标用户必须能审批该步骤（与委托代理人的要求相同），且尚未参与该步骤
+func (s *WorkflowService) checkAssignee(tx *gorm.DB, task *model.Task, operator *model.User, targetID uint) (*model.User, error) {
+	candidates, err := s.delegateCandidates(tx, &task.Workflow, &task.Step, []uint{targetID}, operator)
+	if err != nil {
+		return nil, err
+	}
+	target, ok := candidates[targetID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAssigneeNotEligible, task.Step.StepRole)
+	}
+
+	var count int64
+	if err := tx.Model(&model.Task{}).
+		Where("step_id = ? AND approver_id = ? AND status IN ?", task.StepID, targetID, openTaskStatuses).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAssigneeAlreadyAdded
+	}
+	return &target, nil
+}
+
+// reassignTask 关闭原任务并为目标用户创建接续任务，加签关系和升级来源随任务转移
+func reassignTask(tx *gorm.DB, task *model.Task, target *model.User, originalApproverID uint, action model.WorkflowHistoryAction, operator *model.User, comment string) (*model.Task, error) {
+	task.Status = model.TaskStatusTransferred
+	if err := tx.Model(task).Update("status", task.Status).Error; err != nil {
+		return nil, err
+	}
+
+	newTask := model.Task{
+		WorkflowID:         task.WorkflowID,
+		StepID:             task.StepID,
+		ApproverID:         target.ID,
+		ApproverNickName:   target.Nickname,
+		TaskName:           task.TaskName,
+		IsRequired:         task.IsRequired,
+		TimeoutHours:       task.TimeoutHours,
+		Status:             model.TaskStatusProcessing,
+		DueAt:              taskDueAt(task.TimeoutHours),
+		EscalatedFromID:    task.EscalatedFromID,
+		TransferredFromID:  task.ID,
+		OriginalApproverID: originalApproverID,
+		ParentTaskID:       task.ParentTaskID,
+		AddPosition:        task.AddPosition,
+	}
+	if err := tx.Create(&newTask).Error; err != nil {
+		return nil, err
+	}
+
+	// 原任务上的加签挂到新任务上
+	if err := tx.Model(&model.Task{}).Where("parent_task_id = ?", task.ID).
+		Update("parent_task_id", newTask.ID).Error; err != nil {
+		return nil, err
+	}
+
+	if err := recordHistory(tx, task.WorkflowID, task.StepID, newTask.ID, action, operator, comment); err != nil {
+		return nil, err
+	}
+	newTask.Workflow = task.Workflow
+	return &newTask, nil
+}
+
+// resolveAddedApprover 处理加签链：前加签人同意后交回被加签任务，被加签任务同意后交给后加签人，
+// 这两种情况不计票并返回被激活的任务；拒绝时取消链上其余任务并计为拒绝票
+func resolveAddedApprover(tx *gorm.DB, task *model.Task, decision model.TaskStatus) (*model.Task, bool, error) {
+	// 前加签人的决定
+	if task.ParentTaskID != 0 && task.AddPosition == model.AddApproverBefore {
+		parent := &model.Task{}
+		if err := tx.First(parent, task.ParentTaskID).Error; err != nil {
+			return nil, false, err
+		}
+		if parent.Status == model.TaskStatusWaiting {
+			if decision == model.TaskStatusApproved {
+				return activateTask(tx, parent)
+			}
+			if err := cancelAddChain(tx, parent.ID); err != nil {
+				return nil, false, err
+			}
+			if err := tx.Model(parent).Update("status", model.TaskStatusCancelled).Error; err != nil {
+				return nil, false, err
+			}
+		}
+		return nil, true, nil
+	}
+
+	// 当前任务（或其升级来源）上待处理的后加签
+	parentIDs := []uint{task.ID}
+	if task.EscalatedFromID != 0 {
+		parentIDs = append(parentIDs, task.EscalatedFromID)
+	}
+	child := &model.Task{}
+	err := tx.Where("parent_task_id IN ? AND add_position = ? AND status = ?", parentIDs, model.AddApproverAfter, model.TaskStatusWaiting).
+		First(child).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if decision == model.TaskStatusApproved {
+		return activateTask(tx, child)
+	}
+	if err := tx.Model(child).Update("status", model.TaskStatusCancelled).Error; err != nil {
+		return nil, false, err
+	}
+	return nil, true, nil
+}
+
+// activateTask 将等待中的任务交给审批人处理并重新计算截止时间
+func activateTask(tx *gorm.DB, task *model.Task) (*model.Task, bool, error) {
+	task.Status = model.TaskStatusProcessing
+	task.DueAt = taskDueAt(task.TimeoutHours)
+	task.RemindedAt = nil
+	if err := tx.Model(task).Updates(map[string]any{
+		"status":      task.Status,
+		"due_at":      task.DueAt,
+		"reminded_at": nil,
+	}).Error; err != nil {
+		return nil, false, err
+	}
+	return task, false, nil
+}
+
+// cancelAddChain 取消任务上所有未结束的加签
+func cancelAddChain(tx *gorm.DB, parentID uint) error {
+	return tx.Model(&model.Task{}).
+		Where("parent_task_id = ? AND status IN ?", parentID, openTaskStatuses).
+		Update("status", model.TaskStatusCancelled).Error
+}
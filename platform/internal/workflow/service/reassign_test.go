@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestTransferTask(t *testing.T) {
+	disabled := testUser(6, "disabled")
+	disabled.Status = 0
+
+	tests := []struct {
+		name     string
+		stepRole string
+		operator model.User
+		target   model.User
+		wantErr  error
+	}{
+		{name: "space role member", stepRole: "approver", operator: alice, target: bob},
+		{name: "target without step role", stepRole: "approver", operator: alice, target: carol, wantErr: ErrAssigneeNotEligible},
+		{name: "target already approving the step", stepRole: "approver", operator: alice, target: dave, wantErr: ErrAssigneeAlreadyAdded},
+		{name: "operator is not the approver", stepRole: "approver", operator: bob, target: carol, wantErr: ErrTaskForbidden},
+		{name: "department head step accepts active user", stepRole: model.StepRoleDepartmentHead, operator: alice, target: carol},
+		{name: "department head step rejects disabled user", stepRole: model.StepRoleDepartmentHead, operator: alice, target: disabled, wantErr: ErrAssigneeNotEligible},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, iam, db := newTestService(t)
+			saveUsers(t, db, alice, bob, carol, dave, disabled)
+			iam.setRole(testSpaceID, "approver", alice, dave)
+			iam.setHead(initiator.ID, alice)
+
+			workflow := startWorkflow(t, s, model.Step{
+				StepName: "审批", StepOrder: 1, StepRole: tt.stepRole, ApprovalMode: model.ApprovalModeAll,
+			})
+			// 启动后 bob 才加入审批角色，此时没有待办任务
+			iam.setRole(testSpaceID, "approver", alice, bob, dave)
+			task := openTasks(t, db, workflow.ID)[alice.ID]
+
+			newTask, err := s.TransferTask(task.ID, &model.TransferTaskRequest{ToUserID: tt.target.ID}, &tt.operator)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("TransferTask() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TransferTask() error = %v", err)
+			}
+			if newTask.ApproverID != tt.target.ID || newTask.TransferredFromID != task.ID {
+				t.Fatalf("new task approver/from = %d/%d, want %d/%d", newTask.ApproverID, newTask.TransferredFromID, tt.target.ID, task.ID)
+			}
+			var old model.Task
+			db.First(&old, task.ID)
+			if old.Status != model.TaskStatusTransferred {
+				t.Fatalf("old task status = %s, want %s", old.Status, model.TaskStatusTransferred)
+			}
+		})
+	}
+}
+
+func TestDelegatorCannotOperateDelegatedTask(t *testing.T) {
+	s, iam, db := newTestService(t)
+	saveUsers(t, db, alice, bob, carol)
+	iam.setRole(testSpaceID, "approver", alice)
+	workflow := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver"})
+	iam.setRole(testSpaceID, "approver", alice, bob, carol)
+
+	delegated, err := s.delegateTask(openTasks(t, db, workflow.ID)[alice.ID].ID, &alice, &bob)
+	if err != nil {
+		t.Fatalf("delegateTask() error = %v", err)
+	}
+	if _, err := s.TransferTask(delegated.ID, &model.TransferTaskRequest{ToUserID: carol.ID}, &alice); !errors.Is(err, ErrTaskForbidden) {
+		t.Fatalf("TransferTask() by delegator error = %v, want %v", err, ErrTaskForbidden)
+	}
+	if _, err := s.AddApprover(delegated.ID, &model.AddApproverRequest{UserID: carol.ID, Position: model.AddApproverBefore}, &alice); !errors.Is(err, ErrTaskForbidden) {
+		t.Fatalf("AddApprover() by delegator error = %v, want %v", err, ErrTaskForbidden)
+	}
+	if _, err := s.TransferTask(delegated.ID, &model.TransferTaskRequest{ToUserID: carol.ID}, &bob); err != nil {
+		t.Fatalf("TransferTask() by delegate error = %v", err)
+	}
+}
+
+func TestAddApprover(t *testing.T) {
+	type vote struct {
+		user   model.User
+		status model.TaskStatus
+	}
+	tests := []struct {
+		name     string
+		position model.AddApproverPosition
+		votes    []vote
+		want     model.WorkflowStatus
+	}{
+		{
+			name: "before: added approver first, then original", position: model.AddApproverBefore,
+			votes: []vote{{bob, model.TaskStatusApproved}, {alice, model.TaskStatusApproved}},
+			want:  model.WorkflowStatusCompleted,
+		},
+		{
+			name: "before: added approver rejects", position: model.AddApproverBefore,
+			votes: []vote{{bob, model.TaskStatusRejected}},
+			want:  model.WorkflowStatusRejected,
+		},
+		{
+			name: "after: original first, then added approver", position: model.AddApproverAfter,
+			votes: []vote{{alice, model.TaskStatusApproved}, {bob, model.TaskStatusApproved}},
+			want:  model.WorkflowStatusCompleted,
+		},
+		{
+			name: "after: waits for added approver", position: model.AddApproverAfter,
+			votes: []vote{{alice, model.TaskStatusApproved}},
+			want:  model.WorkflowStatusProcessing,
+		},
+		{
+			name: "after: added approver rejects", position: model.AddApproverAfter,
+			votes: []vote{{alice, model.TaskStatusApproved}, {bob, model.TaskStatusRejected}},
+			want:  model.WorkflowStatusRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, iam, db := newTestService(t)
+			iam.setRole(testSpaceID, "approver", alice)
+			workflow := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver"})
+			iam.setRole(testSpaceID, "approver", alice, bob)
+
+			task := openTasks(t, db, workflow.ID)[alice.ID]
+			added, err := s.AddApprover(task.ID, &model.AddApproverRequest{UserID: bob.ID, Position: tt.position}, &alice)
+			if err != nil {
+				t.Fatalf("AddApprover() error = %v", err)
+			}
+			if added.ParentTaskID != task.ID {
+				t.Fatalf("added task parent = %d, want %d", added.ParentTaskID, task.ID)
+			}
+
+			for _, v := range tt.votes {
+				decide(t, s, db, workflow.ID, v.user, v.status)
+			}
+			if got := reloadWorkflow(t, db, workflow.ID).Status; got != tt.want {
+				t.Fatalf("workflow status = %s, want %s", got, tt.want)
+			}
+
+			// 加签链的结果只计一票
+			var step model.Step
+			db.Where("workflow_id = ?", workflow.ID).First(&step)
+			if step.ApprovedCount+step.RejectedCount > 1 {
+				t.Fatalf("step votes = %d approved, %d rejected, want at most one vote", step.ApprovedCount, step.RejectedCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/outbox"
+	"gorm.io/gorm"
+)
+
+var ErrResourceTypeUnsupported = errors.New("不支持的审批资源类型")
+
+// ResourceHandler 资源类型在审批流程中的回调，流程引擎只通过它与资源交互
+type ResourceHandler interface {
+	// Info 资源类型的说明，用于配置审批模板
+	Info() model.ResourceTypeInfo
+	// Attributes 根据发起方提供的属性生成分支条件使用的资源属性
+	Attributes(workflow *model.Workflow, provided map[string]any) (map[string]any, error)
+	// OnApproved 流程审批通过，与流程状态在同一事务内执行
+	OnApproved(tx *gorm.DB, workflow *model.Workflow) error
+	// OnRejected 流程被拒绝、撤回或取消，event 区分具体原因
+	OnRejected(tx *gorm.DB, workflow *model.Workflow, event model.WorkflowEventType) error
+}
+
+// ResourceRegistry 资源类型到处理器的注册表
+type ResourceRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]ResourceHandler
+}
+
+// NewResourceRegistry 创建注册表并注册内置的资源类型
+func NewResourceRegistry() *ResourceRegistry {
+	registry := &ResourceRegistry{handlers: make(map[string]ResourceHandler)}
+	for _, handler := range builtinResourceHandlers() {
+		registry.Register(handler)
+	}
+	return registry
+}
+
+// Register 注册资源处理器，同一类型重复注册时后者覆盖前者
+func (r *ResourceRegistry) Register(handler ResourceHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[handler.Info().Type] = handler
+}
+
+// Get 获取资源类型的处理器
+func (r *ResourceRegistry) Get(resourceType string) (ResourceHandler, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, ok := r.handlers[resourceType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrResourceTypeUnsupported, resourceType)
+	}
+	return handler, nil
+}
+
+// List 按类型名排序返回已注册的资源类型
+func (r *ResourceRegistry) List() []model.ResourceTypeInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := make([]model.ResourceTypeInfo, 0, len(r.handlers))
+	for _, handler := range r.handlers {
+		infos = append(infos, handler.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Type < infos[j].Type })
+	return infos
+}
+
+// builtinResourceHandlers 内置资源类型，资源状态都由所属服务消费流程结束事件后自行更新
+func builtinResourceHandlers() []ResourceHandler {
+	return []ResourceHandler{
+		&eventResourceHandler{info: model.ResourceTypeInfo{
+			Type:       model.ResourceTypeDocument,
+			Name:       "文档发布",
+			Owner:      outbox.TargetKb,
+			Attributes: []string{"file_name", "file_type", "file_size", "version", "use_type", "department", "sub_space_id", "class_id", "tags"},
+		}},
+		&eventResourceHandler{info: model.ResourceTypeInfo{
+			Type:       model.ResourceTypeDocumentDeletion,
+			Name:       "文档删除",
+			Owner:      outbox.TargetKb,
+			Attributes: []string{"file_name", "status", "sub_space_id", "class_id"},
+		}},
+		&eventResourceHandler{info: model.ResourceTypeInfo{
+			Type:       model.ResourceTypeDataExport,
+			Name:       "数据导出",
+			Owner:      outbox.TargetKb,
+			Attributes: []string{"scope", "sub_space_id", "class_id", "include_text"},
+		}},
+		&eventResourceHandler{info: model.ResourceTypeInfo{
+			Type:       model.ResourceTypeSpaceMembership,
+			Name:       "加入空间申请",
+			Owner:      outbox.TargetIam,
+			Attributes: []string{"user_id", "role"},
+		}},
+		&eventResourceHandler{info: model.ResourceTypeInfo{
+			Type:       model.ResourceTypeSpaceCreation,
+			Name:       "创建空间申请",
+			Owner:      outbox.TargetIam,
+			Attributes: []string{"name"},
+		}},
+	}
+}
+
+// eventResourceHandler 通过发件箱把流程结果通知资源所属服务
+type eventResourceHandler struct {
+	info model.ResourceTypeInfo
+}
+
+func (h *eventResourceHandler) Info() model.ResourceTypeInfo {
+	return h.info
+}
+
+// Attributes 保留发起方提供的属性，并补充所有资源通用的流程属性
+func (h *eventResourceHandler) Attributes(workflow *model.Workflow, provided map[string]any) (map[string]any, error) {
+	attributes := make(map[string]any, len(provided)+2)
+	for key, value := range provided {
+		attributes[key] = value
+	}
+	attributes["resource_type"] = workflow.ResourceType
+	attributes["round"] = workflow.Round
+	return attributes, nil
+}
+
+func (h *eventResourceHandler) OnApproved(tx *gorm.DB, workflow *model.Workflow) error {
+	return h.enqueue(tx, workflow, model.WorkflowEventCompleted)
+}
+
+func (h *eventResourceHandler) OnRejected(tx *gorm.DB, workflow *model.Workflow, event model.WorkflowEventType) error {
+	return h.enqueue(tx, workflow, event)
+}
+
+// enqueue 在当前事务内写入流程结束事件。
+// 工作流服务不直接修改资源表，资源状态由资源所属服务消费事件后自行更新
+func (h *eventResourceHandler) enqueue(tx *gorm.DB, workflow *model.Workflow, eventType model.WorkflowEventType) error {
+	return outbox.Enqueue(tx, h.info.Owner, workflow.ID, &model.WorkflowStatusEvent{
+		EventType:    eventType,
+		WorkflowID:   workflow.ID,
+		ResourceType: workflow.ResourceType,
+		ResourceID:   workflow.ResourceID,
+		SpaceID:      workflow.SpaceID,
+		Status:       workflow.Status,
+		Round:        workflow.Round,
+	})
+}
+
+// RegisterResourceHandler 注册或替换资源类型的处理器
+func (s *WorkflowService) RegisterResourceHandler(handler ResourceHandler) {
+	s.resources.Register(handler)
+}
+
+// ListResourceTypes 获取支持审批的资源类型
+func (s *WorkflowService) ListResourceTypes() []model.ResourceTypeInfo {
+	return s.resources.List()
+}
+
+// notifyApproved 流程审批通过后回调资源处理器
+func (s *WorkflowService) notifyApproved(tx *gorm.DB, workflow *model.Workflow) error {
+	handler, ok := s.resourceHandler(workflow)
+	if !ok {
+		return nil
+	}
+	return handler.OnApproved(tx, workflow)
+}
+
+// notifyRejected 流程被拒绝、撤回或取消后回调资源处理器
+func (s *WorkflowService) notifyRejected(tx *gorm.DB, workflow *model.Workflow, event model.WorkflowEventType) error {
+	handler, ok := s.resourceHandler(workflow)
+	if !ok {
+		return nil
+	}
+	return handler.OnRejected(tx, workflow, event)
+}
+
+// resourceHandler 获取流程资源的处理器。注册表之前创建的流程可能使用未注册的类型，
+// 这类流程照常结束，只是不通知资源
+func (s *WorkflowService) resourceHandler(workflow *model.Workflow) (ResourceHandler, bool) {
+	handler, err := s.resources.Get(workflow.ResourceType)
+	if err != nil {
+		logger.Warnf("workflow %d: %v, resource will not be notified", workflow.ID, err)
+		return nil, false
+	}
+	return handler, true
+}
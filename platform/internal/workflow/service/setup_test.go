@@ -0,0 +1,190 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
+	commonConfig "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/config"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/database/dbtest"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
+	"gorm.io/gorm"
+)
+
+// testSpaceID 测试流程所在的空间
+const testSpaceID = 1
+
+// fakeIAM 只实现工作流服务调用的 IAM 接口：按角色列出空间成员、按全局角色列出用户、查询部门负责人
+type fakeIAM struct {
+	mu      sync.Mutex
+	members map[uint]map[string][]model.User // 空间 -> 空间角色 -> 成员
+	admins  []model.User                     // 企业管理员
+	heads   map[uint]model.User              // 用户 -> 部门负责人
+}
+
+func newFakeIAM(t *testing.T) (*fakeIAM, *client.IamClient) {
+	t.Helper()
+	iam := &fakeIAM{members: map[uint]map[string][]model.User{}, heads: map[uint]model.User{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/spaces/{space}/members/role/{role}", func(w http.ResponseWriter, r *http.Request) {
+		spaceID, _ := strconv.ParseUint(r.PathValue("space"), 10, 32)
+		iam.mu.Lock()
+		var members []model.SpaceMember
+		for _, user := range iam.members[uint(spaceID)][r.PathValue("role")] {
+			members = append(members, model.SpaceMember{SpaceID: uint(spaceID), UserID: user.ID, User: user})
+		}
+		iam.mu.Unlock()
+		writeJSON(w, http.StatusOK, members)
+	})
+	// /users/by-global-role/{role} 与 /users/{id}/department-head 的路由模式冲突，合并处理
+	mux.HandleFunc("GET /api/v1/users/{first}/{second}", func(w http.ResponseWriter, r *http.Request) {
+		iam.mu.Lock()
+		defer iam.mu.Unlock()
+		if r.PathValue("first") == "by-global-role" {
+			writeJSON(w, http.StatusOK, iam.admins)
+			return
+		}
+		userID, _ := strconv.ParseUint(r.PathValue("first"), 10, 32)
+		head, ok := iam.heads[uint(userID)]
+		if r.PathValue("second") != "department-head" || !ok {
+			writeJSON(w, http.StatusNotFound, nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, head)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return iam, client.NewIamClient(&commonConfig.IamConfig{Url: server.URL})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"code": status, "data": data})
+}
+
+// setRole 设置空间角色的成员
+func (f *fakeIAM) setRole(spaceID uint, role string, users ...model.User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.members[spaceID] == nil {
+		f.members[spaceID] = map[string][]model.User{}
+	}
+	f.members[spaceID][role] = users
+}
+
+// setHead 设置用户的部门负责人
+func (f *fakeIAM) setHead(userID uint, head model.User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.heads[userID] = head
+}
+
+// testUser 创建正常状态的测试用户
+func testUser(id uint, name string) model.User {
+	return model.User{ID: id, Username: name, Nickname: name, Status: 1}
+}
+
+var (
+	initiator = testUser(1, "initiator")
+	alice     = testUser(2, "alice")
+	bob       = testUser(3, "bob")
+	carol     = testUser(4, "carol")
+	dave      = testUser(5, "dave")
+	spaceAdm  = testUser(9, "space-admin")
+)
+
+// newTestService 创建使用临时数据库和模拟 IAM 的工作流服务
+func newTestService(t *testing.T) (*WorkflowService, *fakeIAM, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t,
+		&model.Workflow{}, &model.Step{}, &model.Task{}, &model.WorkflowHistory{},
+		&model.DelegationRule{}, &model.OutboxEvent{}, &model.User{}, &model.SpaceMember{},
+		&model.WebhookSubscription{}, &model.WebhookDelivery{},
+	)
+	iam, iamClient := newFakeIAM(t)
+	dispatcher := webhook.NewDispatcher(db, &commonConfig.WebhookConfig{})
+	return NewWorkflowService(db, iamClient, dispatcher, nil), iam, db
+}
+
+// startWorkflow 由 initiator 创建并启动文档审批流程
+func startWorkflow(t *testing.T, s *WorkflowService, steps ...model.Step) *model.Workflow {
+	t.Helper()
+	created, err := s.CreateWorkflow(&model.CreateWorkflowRequest{
+		Name:         "测试流程",
+		ResourceType: model.ResourceTypeDocument,
+		ResourceID:   100,
+		SpaceID:      testSpaceID,
+		Steps:        steps,
+	}, &initiator)
+	if err != nil {
+		t.Fatalf("CreateWorkflow() error = %v", err)
+	}
+	workflow, err := s.StartWorkflow(&model.StartWorkflowRequest{WorkflowID: created.ID}, &initiator)
+	if err != nil {
+		t.Fatalf("StartWorkflow() error = %v", err)
+	}
+	return workflow
+}
+
+// openTasks 按审批人返回流程中待处理的任务
+func openTasks(t *testing.T, db *gorm.DB, workflowID uint) map[uint]model.Task {
+	t.Helper()
+	var tasks []model.Task
+	if err := db.Where("workflow_id = ? AND status = ?", workflowID, model.TaskStatusProcessing).Find(&tasks).Error; err != nil {
+		t.Fatalf("load tasks: %v", err)
+	}
+	byApprover := make(map[uint]model.Task, len(tasks))
+	for _, task := range tasks {
+		byApprover[task.ApproverID] = task
+	}
+	return byApprover
+}
+
+// decide 审批人处理自己在流程中的待办任务
+func decide(t *testing.T, s *WorkflowService, db *gorm.DB, workflowID uint, approver model.User, status model.TaskStatus) {
+	t.Helper()
+	task, ok := openTasks(t, db, workflowID)[approver.ID]
+	if !ok {
+		t.Fatalf("%s has no open task in workflow %d", approver.Nickname, workflowID)
+	}
+	if _, err := s.ApproveTask(&model.ApproveTaskRequest{TaskID: task.ID, Status: status}, &approver); err != nil {
+		t.Fatalf("ApproveTask(%s, %s) error = %v", approver.Nickname, status, err)
+	}
+}
+
+// reloadWorkflow 重新读取流程状态
+func reloadWorkflow(t *testing.T, db *gorm.DB, id uint) *model.Workflow {
+	t.Helper()
+	workflow := &model.Workflow{}
+	if err := db.First(workflow, id).Error; err != nil {
+		t.Fatalf("load workflow %d: %v", id, err)
+	}
+	return workflow
+}
+
+// saveUsers 将测试用户写入用户表，委托和部门负责人步骤按用户表校验代理人
+func saveUsers(t *testing.T, db *gorm.DB, users ...model.User) {
+	t.Helper()
+	for _, user := range users {
+		disabled := user.Status == 0
+		user.Phone = user.Username
+		user.Password = "x"
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("create user %s: %v", user.Username, err)
+		}
+		if disabled {
+			// status 默认值为 1，禁用用户需要单独更新
+			if err := db.Model(&user).Update("status", 0).Error; err != nil {
+				t.Fatalf("disable user %s: %v", user.Username, err)
+			}
+		}
+	}
+}
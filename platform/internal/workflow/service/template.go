@@ -0,0 +1,297 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTemplateNotFound  = errors.New("审批模板不存在")
+	ErrTemplateForbidden = errors.New("没有配置审批流的权限")
+	ErrTemplateConflict  = errors.New("该位置已绑定启用的审批模板")
+	ErrTemplateInvalid   = errors.New("审批模板配置错误")
+)
+
+// 模板管理需要的权限（对应 PermissionConfigureWorkflow）
+const (
+	workflowPermissionResource = "workflow"
+	workflowPermissionAction   = "configure"
+)
+
+type TemplateService struct {
+	db              *gorm.DB
+	iamClient       *client.IamClient
+	workflowService *WorkflowService
+}
+
+func NewTemplateService(db *gorm.DB, iamClient *client.IamClient, workflowService *WorkflowService) *TemplateService {
+	return &TemplateService{db: db, iamClient: iamClient, workflowService: workflowService}
+}
+
+// CreateTemplate 创建审批模板
+func (s *TemplateService) CreateTemplate(req *model.WorkflowTemplateRequest, user *model.User) (*model.WorkflowTemplate, error) {
+	if err := s.checkConfigurePermission(user, req.SpaceID); err != nil {
+		return nil, err
+	}
+
+	template := &model.WorkflowTemplate{CreatedBy: user.ID}
+	if err := s.applyTemplateRequest(template, req, user); err != nil {
+		return nil, err
+	}
+	if err := s.checkBindingConflict(template); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(template).Error; err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// UpdateTemplate 更新审批模板，原空间和新空间都需要配置权限
+func (s *TemplateService) UpdateTemplate(id uint, req *model.WorkflowTemplateRequest, user *model.User) (*model.WorkflowTemplate, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkConfigurePermission(user, template.SpaceID); err != nil {
+		return nil, err
+	}
+	if req.SpaceID != template.SpaceID {
+		if err := s.checkConfigurePermission(user, req.SpaceID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.applyTemplateRequest(template, req, user); err != nil {
+		return nil, err
+	}
+	if err := s.checkBindingConflict(template); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(template).Error; err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// DeleteTemplate 删除审批模板，已创建的流程不受影响
+func (s *TemplateService) DeleteTemplate(id uint, user *model.User) (*model.WorkflowTemplate, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkConfigurePermission(user, template.SpaceID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Delete(template).Error; err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// GetTemplate 获取审批模板
+func (s *TemplateService) GetTemplate(id uint) (*model.WorkflowTemplate, error) {
+	template := &model.WorkflowTemplate{}
+	if err := s.db.First(template, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+// ListTemplates 分页查询审批模板
+func (s *TemplateService) ListTemplates(query *model.WorkflowTemplateQuery) (model.PaginationResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 || query.PageSize > 100 {
+		query.PageSize = 10
+	}
+
+	db := s.db.Model(&model.WorkflowTemplate{})
+	if query.SpaceID != 0 {
+		db = db.Where("space_id = ?", query.SpaceID)
+	}
+	if query.ResourceType != "" {
+		db = db.Where("resource_type = ?", query.ResourceType)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return model.PaginationResponse{}, err
+	}
+
+	var templates []model.WorkflowTemplate
+	if err := db.Order("space_id ASC, sub_space_id ASC, class_id ASC, id ASC").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Find(&templates).Error; err != nil {
+		return model.PaginationResponse{}, err
+	}
+
+	return model.PaginationResponse{
+		Items:      templates,
+		Total:      total,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalPages: int((total + int64(query.PageSize) - 1) / int64(query.PageSize)),
+	}, nil
+}
+
+// MatchTemplate 按分类、二级空间、空间的顺序查找启用的模板
+func (s *TemplateService) MatchTemplate(req *model.MatchWorkflowTemplateRequest) (*model.WorkflowTemplate, error) {
+	resourceType := req.ResourceType
+	if resourceType == "" {
+		resourceType = model.ResourceTypeDocument
+	}
+
+	// 候选绑定位置（二级空间ID，分类ID），越具体越优先
+	var bindings [][2]uint
+	if req.SubSpaceID != 0 && req.ClassID != 0 {
+		bindings = append(bindings, [2]uint{req.SubSpaceID, req.ClassID})
+	}
+	if req.SubSpaceID != 0 {
+		bindings = append(bindings, [2]uint{req.SubSpaceID, 0})
+	}
+	bindings = append(bindings, [2]uint{0, 0})
+
+	for _, binding := range bindings {
+		template := &model.WorkflowTemplate{}
+		err := s.db.Where("resource_type = ? AND space_id = ? AND sub_space_id = ? AND class_id = ? AND status = 1",
+			resourceType, req.SpaceID, binding[0], binding[1]).First(template).Error
+		if err == nil {
+			return template, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrTemplateNotFound
+}
+
+// CreateWorkflowFromTemplate 按模板实例化审批流程
+func (s *TemplateService) CreateWorkflowFromTemplate(req *model.CreateWorkflowFromTemplateRequest, user *model.User) (*model.Workflow, error) {
+	var template *model.WorkflowTemplate
+	var err error
+	if req.TemplateID != 0 {
+		template, err = s.GetTemplate(req.TemplateID)
+		if err == nil && template.Status != 1 {
+			err = ErrTemplateNotFound
+		}
+	} else {
+		template, err = s.MatchTemplate(&req.MatchWorkflowTemplateRequest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = template.Name
+	}
+	description := req.Description
+	if description == "" {
+		description = template.Description
+	}
+
+	steps := make([]model.Step, len(template.Steps))
+	for i, step := range template.Steps {
+		steps[i] = step.ToStep()
+	}
+
+	return s.workflowService.CreateWorkflow(&model.CreateWorkflowRequest{
+		Name:         name,
+		Description:  description,
+		ResourceType: template.ResourceType,
+		ResourceID:   req.ResourceID,
+		SpaceID:      req.SpaceID,
+		Steps:        steps,
+
+		PreviousWorkflowID: req.PreviousWorkflowID,
+	}, user)
+}
+
+// checkConfigurePermission 检查用户在空间内是否有配置审批流权限
+func (s *TemplateService) checkConfigurePermission(user *model.User, spaceID uint) error {
+	allowed, err := s.iamClient.CheckPermission(user, spaceID, workflowPermissionResource, workflowPermissionAction)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrTemplateForbidden
+	}
+	return nil
+}
+
+// checkBindingConflict 同一位置同一资源类型只能有一个启用的模板
+func (s *TemplateService) checkBindingConflict(template *model.WorkflowTemplate) error {
+	if template.Status != 1 {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.Model(&model.WorkflowTemplate{}).
+		Where("resource_type = ? AND space_id = ? AND sub_space_id = ? AND class_id = ? AND status = 1 AND id != ?",
+			template.ResourceType, template.SpaceID, template.SubSpaceID, template.ClassID, template.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTemplateConflict
+	}
+	return nil
+}
+
+// applyTemplateRequest 校验请求并写入模板字段
+func (s *TemplateService) applyTemplateRequest(template *model.WorkflowTemplate, req *model.WorkflowTemplateRequest, user *model.User) error {
+	if req.ClassID != 0 && req.SubSpaceID == 0 {
+		return fmt.Errorf("%w: 绑定分类时必须同时指定二级空间", ErrTemplateInvalid)
+	}
+
+	// 按流程步骤的规则校验，并写回默认值
+	steps := make([]model.Step, len(req.Steps))
+	for i := range req.Steps {
+		steps[i] = req.Steps[i].ToStep()
+		if err := validateApprovalRule(&steps[i]); err != nil {
+			return fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+		}
+		if err := validateTimeoutPolicy(&steps[i]); err != nil {
+			return fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+		}
+		req.Steps[i].ApprovalMode = steps[i].ApprovalMode
+		req.Steps[i].TimeoutAction = steps[i].TimeoutAction
+		req.Steps[i].EscalateRole = steps[i].EscalateRole
+	}
+	if err := validateBranching(steps); err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+
+	template.Name = req.Name
+	template.Description = req.Description
+	template.ResourceType = req.ResourceType
+	if template.ResourceType == "" {
+		template.ResourceType = model.ResourceTypeDocument
+	}
+	if _, err := s.workflowService.resources.Get(template.ResourceType); err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+	template.SpaceID = req.SpaceID
+	template.SubSpaceID = req.SubSpaceID
+	template.ClassID = req.ClassID
+	template.Steps = req.Steps
+	template.Status = 1
+	if req.Status != nil {
+		template.Status = *req.Status
+	}
+	template.UpdatedBy = user.ID
+	return nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+// GetWorkflowTimeline 获取一轮审批的步骤、任务以及带时间的事件，查看权限与 GetWorkflow 相同
+func (s *WorkflowService) GetWorkflowTimeline(id uint, user *model.User) (*model.WorkflowTimeline, error) {
+	workflow, err := s.GetWorkflow(id, user)
+	if err != nil {
+		return nil, err
+	}
+	return buildTimeline(workflow), nil
+}
+
+// GetWorkflowStatus 获取工作流状态，与 GetWorkflow 相同的查看权限
+func (s *WorkflowService) GetWorkflowStatus(id uint, user *model.User) (model.WorkflowStatus, error) {
+	workflow := &model.Workflow{}
+	if err := s.db.Select("id", "status", "space_id", "created_by").First(workflow, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrWorkflowNotFound
+		}
+		return "", err
+	}
+	if err := s.checkWorkflowAccess(workflow, user); err != nil {
+		return "", err
+	}
+	return workflow.Status, nil
+}
+
+// GetResourceTimeline 获取资源所有轮次的审批时间线，只返回用户有权查看的轮次
+func (s *WorkflowService) GetResourceTimeline(resourceType string, resourceID uint, user *model.User) (*model.ResourceTimeline, error) {
+	var workflows []model.Workflow
+	if err := s.db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Preload("Steps", func(db *gorm.DB) *gorm.DB {
+			return db.Order("step_order ASC")
+		}).
+		Preload("Steps.Tasks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		}).
+		Preload("Histories", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id ASC")
+		}).
+		Order("id ASC").
+		Find(&workflows).Error; err != nil {
+		return nil, err
+	}
+	if len(workflows) == 0 {
+		return nil, ErrWorkflowNotFound
+	}
+
+	result := &model.ResourceTimeline{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Rounds:       make([]model.WorkflowTimeline, 0, len(workflows)),
+	}
+	for i := range workflows {
+		err := s.checkWorkflowAccess(&workflows[i], user)
+		if errors.Is(err, ErrWorkflowForbidden) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Rounds = append(result.Rounds, *buildTimeline(&workflows[i]))
+	}
+	if len(result.Rounds) == 0 {
+		return nil, ErrWorkflowForbidden
+	}
+	return result, nil
+}
+
+// buildTimeline 将流程历史转换为时间线事件并补充步骤名称
+func buildTimeline(workflow *model.Workflow) *model.WorkflowTimeline {
+	stepNames := make(map[uint]string, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		stepNames[step.ID] = step.StepName
+	}
+
+	events := make([]model.TimelineEvent, len(workflow.Histories))
+	for i, history := range workflow.Histories {
+		events[i] = model.TimelineEvent{
+			Time:         history.CreatedAt,
+			Action:       history.Action,
+			StepID:       history.StepID,
+			StepName:     stepNames[history.StepID],
+			TaskID:       history.TaskID,
+			OperatorID:   history.OperatorID,
+			OperatorName: history.OperatorName,
+			Comment:      history.Comment,
+		}
+	}
+	return &model.WorkflowTimeline{Workflow: workflow, Events: events}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestWorkflowTimelineAccess(t *testing.T) {
+	s, iam, db := newTestService(t)
+	iam.setRole(testSpaceID, "approver", alice)
+	first := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver"})
+	decide(t, s, db, first.ID, alice, model.TaskStatusRejected)
+
+	// 第二轮由 bob 审批，alice 只能看到自己参与的第一轮
+	iam.setRole(testSpaceID, "approver", bob)
+	second := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver"})
+
+	if err := db.Create(&model.SpaceMember{SpaceID: testSpaceID, UserID: carol.ID}).Error; err != nil {
+		t.Fatalf("create space member: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		user       model.User
+		wantRounds []uint
+		wantErr    error
+	}{
+		{name: "initiator sees every round", user: initiator, wantRounds: []uint{first.ID, second.ID}},
+		{name: "space member sees every round", user: carol, wantRounds: []uint{first.ID, second.ID}},
+		{name: "approver sees own round", user: alice, wantRounds: []uint{first.ID}},
+		{name: "unrelated user", user: dave, wantErr: ErrWorkflowForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeline, err := s.GetResourceTimeline(model.ResourceTypeDocument, 100, &tt.user)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetResourceTimeline() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if _, err := s.GetWorkflowTimeline(first.ID, &tt.user); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetWorkflowTimeline() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			var rounds []uint
+			for _, round := range timeline.Rounds {
+				rounds = append(rounds, round.Workflow.ID)
+			}
+			if len(rounds) != len(tt.wantRounds) {
+				t.Fatalf("rounds = %v, want %v", rounds, tt.wantRounds)
+			}
+			for i := range rounds {
+				if rounds[i] != tt.wantRounds[i] {
+					t.Fatalf("rounds = %v, want %v", rounds, tt.wantRounds)
+				}
+				if _, err := s.GetWorkflowTimeline(rounds[i], &tt.user); err != nil {
+					t.Fatalf("GetWorkflowTimeline(%d) error = %v", rounds[i], err)
+				}
+			}
+		})
+	}
+
+	if _, err := s.GetResourceTimeline(model.ResourceTypeDocument, 999, &initiator); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Fatalf("GetResourceTimeline(missing) error = %v, want %v", err, ErrWorkflowNotFound)
+	}
+}
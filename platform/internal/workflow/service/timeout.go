@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/logger"
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+// timeoutBatchSize 每次扫描处理的超时任务数量
+const timeoutBatchSize = 100
+
+// TimeoutPolicy 超时处理参数
+type TimeoutPolicy struct {
+	RemindInterval time.Duration // 同一任务两次提醒的最小间隔
+	Grace          time.Duration // 超时后执行升级或自动审批前的宽限时间，期间只提醒
+}
+
+// validateTimeoutPolicy 校验步骤的超时策略，未设置时默认只提醒
+func validateTimeoutPolicy(step *model.Step) error {
+	switch step.TimeoutAction {
+	case "":
+		step.TimeoutAction = model.TimeoutActionRemind
+	case model.TimeoutActionRemind, model.TimeoutActionAutoApprove, model.TimeoutActionAutoReject:
+	case model.TimeoutActionEscalate:
+		if step.EscalateRole == "" {
+			step.EscalateRole = string(model.SpaceMemberRoleAdmin)
+		}
+		if _, ok := model.SpaceMemberRoleMap[model.SpaceMemberRole(step.EscalateRole)]; !ok {
+			return fmt.Errorf("步骤 %s 的升级角色 %s 不存在", step.StepName, step.EscalateRole)
+		}
+	default:
+		return fmt.Errorf("步骤 %s 的超时策略 %s 不支持", step.StepName, step.TimeoutAction)
+	}
+	return nil
+}
+
+// StartTimeoutScheduler 按 interval 定期检查超时任务，直到 ctx 结束
+func (s *WorkflowService) StartTimeoutScheduler(ctx context.Context, interval time.Duration, policy TimeoutPolicy) {
+	if interval <= 0 {
+		logger.Infof("workflow timeout scheduler disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CheckTimeouts(time.Now(), policy); err != nil {
+				logger.Errorf("failed to check workflow timeouts: %v", err)
+			}
+		}
+	}
+}
+
+// CheckTimeouts 处理截止时间早于 now 且仍未审批的任务
+func (s *WorkflowService) CheckTimeouts(now time.Time, policy TimeoutPolicy) error {
+	var lastID uint
+	for {
+		var taskIDs []uint
+		if err := s.db.Model(&model.Task{}).
+			Where("status = ? AND due_at IS NOT NULL AND due_at < ? AND id > ?", model.TaskStatusProcessing, now, lastID).
+			Order("id ASC").
+			Limit(timeoutBatchSize).
+			Pluck("id", &taskIDs).Error; err != nil {
+			return err
+		}
+
+		for _, id := range taskIDs {
+			if err := s.handleOverdueTask(id, now, policy); err != nil {
+				logger.Errorf("failed to handle overdue task %d: %v", id, err)
+			}
+		}
+
+		if len(taskIDs) < timeoutBatchSize {
+			return nil
+		}
+		lastID = taskIDs[len(taskIDs)-1]
+	}
+}
+
+// handleOverdueTask 按步骤的超时策略处理单个超时任务
+func (s *WorkflowService) handleOverdueTask(taskID uint, now time.Time, policy TimeoutPolicy) error {
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// 与人工审批相同，先锁定步骤再加载任务
+	task, err := lockTask(tx, taskID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	step := task.Step
+
+	if task.Status != model.TaskStatusProcessing || task.DueAt == nil {
+		tx.Rollback()
+		return nil
+	}
+
+	action := step.TimeoutAction
+	if action == model.TimeoutActionEscalate && task.EscalatedFromID != 0 {
+		// 升级后的任务不再继续升级，只提醒
+		action = model.TimeoutActionRemind
+	}
+	if action != model.TimeoutActionRemind && now.Before(task.DueAt.Add(policy.Grace)) {
+		// 宽限期内先提醒
+		action = model.TimeoutActionRemind
+	}
+
+	// 流程创建人作为系统操作的代理用户，用于查询 IAM
+	creator := &model.User{ID: task.Workflow.CreatedBy}
+
+	var (
+		event         model.WebhookEvent
+		payload       map[string]any
+		assignedTasks []model.Task
+		finished      bool
+	)
+
+	switch action {
+	case model.TimeoutActionAutoApprove, model.TimeoutActionAutoReject:
+		req := &model.ApproveTaskRequest{
+			TaskID:  task.ID,
+			Status:  model.TaskStatusApproved,
+			Comment: "审批超时，系统自动同意",
+		}
+		historyAction := model.WorkflowHistoryAutoApproved
+		if action == model.TimeoutActionAutoReject {
+			req.Status = model.TaskStatusRejected
+			req.Comment = "审批超时，系统自动拒绝"
+			historyAction = model.WorkflowHistoryAutoRejected
+		}
+		if err = recordHistory(tx, task.WorkflowID, task.StepID, task.ID, historyAction, nil, req.Comment); err == nil {
+			assignedTasks, finished, err = s.decideTask(tx, task, req, creator)
+		}
+
+	case model.TimeoutActionEscalate:
+		var escalatedTasks []model.Task
+		escalatedTasks, err = s.escalateTask(tx, task, creator, now)
+		if err == nil && len(escalatedTasks) == 0 {
+			// 没有可升级的对象时退回为提醒
+			event, payload, err = s.remindTask(tx, task, now, policy)
+			break
+		}
+		assignedTasks = escalatedTasks
+		event = model.WebhookEventTaskEscalated
+		payload = map[string]any{
+			"task":         task,
+			"workflow":     &task.Workflow,
+			"escalated_to": escalatedTasks,
+		}
+
+	default:
+		event, payload, err = s.remindTask(tx, task, now, policy)
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if event != "" {
+		s.dispatcher.Publish(context.Background(), event, task.Workflow.SpaceID, payload)
+	}
+	s.publishTasksAssigned(&task.Workflow, assignedTasks)
+	if finished {
+		s.publishWorkflowFinished(&task.Workflow)
+	}
+	return nil
+}
+
+// remindTask 距上次提醒超过间隔时记录提醒，返回需要发送的事件
+func (s *WorkflowService) remindTask(tx *gorm.DB, task *model.Task, now time.Time, policy TimeoutPolicy) (model.WebhookEvent, map[string]any, error) {
+	if task.RemindedAt != nil && now.Sub(*task.RemindedAt) < policy.RemindInterval {
+		return "", nil, nil
+	}
+
+	task.RemindedAt = &now
+	if err := tx.Model(task).Update("reminded_at", now).Error; err != nil {
+		return "", nil, err
+	}
+
+	comment := fmt.Sprintf("任务已于 %s 超时，提醒 %s 处理", task.DueAt.Format("2006-01-02 15:04"), task.ApproverNickName)
+	if err := recordHistory(tx, task.WorkflowID, task.StepID, task.ID, model.WorkflowHistoryReminded, nil, comment); err != nil {
+		return "", nil, err
+	}
+
+	return model.WebhookEventTaskReminded, map[string]any{
+		"task":     task,
+		"workflow": &task.Workflow,
+	}, nil
+}
+
+// escalateTask 将超时任务升级给审批人的上级，即其所在组织单元的部门负责人；没有可用的上级时，
+// 升级给步骤升级角色的空间成员。任一接收人处理即计为原任务的结果，已在该步骤有待办任务的用户不重复分配
+func (s *WorkflowService) escalateTask(tx *gorm.DB, task *model.Task, creator *model.User, now time.Time) ([]model.Task, error) {
+	members, err := s.escalationTargets(tx, task, creator)
+	if err != nil {
+		return nil, err
+	}
+
+	dueAt := taskDueAt(task.Step.TimeoutHours)
+	var tasks []model.Task
+	var names []string
+	for _, member := range members {
+		tasks = append(tasks, model.Task{
+			WorkflowID:       task.WorkflowID,
+			StepID:           task.StepID,
+			ApproverID:       member.ID,
+			ApproverNickName: member.Nickname,
+			TaskName:         task.TaskName,
+			IsRequired:       task.IsRequired,
+			TimeoutHours:     task.TimeoutHours,
+			Status:           model.TaskStatusProcessing,
+			DueAt:            dueAt,
+			EscalatedFromID:  task.ID,
+			ParentTaskID:     task.ParentTaskID,
+			AddPosition:      task.AddPosition,
+		})
+		names = append(names, member.Nickname)
+	}
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Create(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	task.Status = model.TaskStatusEscalated
+	task.EscalatedAt = &now
+	if err := tx.Model(task).Updates(map[string]any{
+		"status":       task.Status,
+		"escalated_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	comment := fmt.Sprintf("%s 审批超时，升级给 %s", task.ApproverNickName, strings.Join(names, "、"))
+	if err := recordHistory(tx, task.WorkflowID, task.StepID, task.ID, model.WorkflowHistoryEscalated, nil, comment); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// escalationTargets 查找升级接收人：优先审批人的部门负责人，其次步骤升级角色的空间成员
+func (s *WorkflowService) escalationTargets(tx *gorm.DB, task *model.Task, creator *model.User) ([]model.User, error) {
+	var busyIDs []uint
+	if err := tx.Model(&model.Task{}).
+		Where("step_id = ? AND status IN ?", task.StepID, openTaskStatuses).
+		Pluck("approver_id", &busyIDs).Error; err != nil {
+		return nil, err
+	}
+	excluded := map[uint]bool{task.ApproverID: true}
+	for _, id := range busyIDs {
+		excluded[id] = true
+	}
+
+	head, err := s.iamClient.GetDepartmentHead(creator, task.ApproverID)
+	if err != nil {
+		return nil, err
+	}
+	if head != nil && head.Status == 1 && !excluded[head.ID] {
+		return []model.User{*head}, nil
+	}
+
+	members, err := s.iamClient.GetSpaceMemebersByRole(creator, task.Workflow.SpaceID, task.Step.EscalateRole)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]model.User, 0, len(members))
+	for _, member := range activeUsers(members) {
+		if !excluded[member.ID] {
+			targets = append(targets, member)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestCheckTimeouts(t *testing.T) {
+	policy := TimeoutPolicy{RemindInterval: time.Hour, Grace: time.Hour}
+
+	tests := []struct {
+		name       string
+		action     model.TimeoutAction
+		overdue    time.Duration // now 相对截止时间的偏移
+		head       *model.User   // alice 的部门负责人
+		admins     []model.User  // 升级角色的空间成员
+		wantTask   model.TaskStatus
+		wantStatus model.WorkflowStatus
+		wantAssign []uint // 升级后接手的审批人
+		wantAction model.WorkflowHistoryAction
+	}{
+		{
+			name: "remind", action: model.TimeoutActionRemind, overdue: 2 * time.Hour,
+			wantTask: model.TaskStatusProcessing, wantStatus: model.WorkflowStatusProcessing,
+			wantAction: model.WorkflowHistoryReminded,
+		},
+		{
+			name: "auto approve", action: model.TimeoutActionAutoApprove, overdue: 2 * time.Hour,
+			wantTask: model.TaskStatusApproved, wantStatus: model.WorkflowStatusCompleted,
+			wantAction: model.WorkflowHistoryAutoApproved,
+		},
+		{
+			name: "auto reject", action: model.TimeoutActionAutoReject, overdue: 2 * time.Hour,
+			wantTask: model.TaskStatusRejected, wantStatus: model.WorkflowStatusRejected,
+			wantAction: model.WorkflowHistoryAutoRejected,
+		},
+		{
+			name: "auto approve waits for grace period", action: model.TimeoutActionAutoApprove, overdue: 30 * time.Minute,
+			wantTask: model.TaskStatusProcessing, wantStatus: model.WorkflowStatusProcessing,
+			wantAction: model.WorkflowHistoryReminded,
+		},
+		{
+			name: "escalate to department head", action: model.TimeoutActionEscalate, overdue: 2 * time.Hour,
+			head: &carol, admins: []model.User{spaceAdm},
+			wantTask: model.TaskStatusEscalated, wantStatus: model.WorkflowStatusProcessing,
+			wantAssign: []uint{carol.ID}, wantAction: model.WorkflowHistoryEscalated,
+		},
+		{
+			name: "escalate to escalation role without head", action: model.TimeoutActionEscalate, overdue: 2 * time.Hour,
+			admins:   []model.User{spaceAdm},
+			wantTask: model.TaskStatusEscalated, wantStatus: model.WorkflowStatusProcessing,
+			wantAssign: []uint{spaceAdm.ID}, wantAction: model.WorkflowHistoryEscalated,
+		},
+		{
+			name: "escalate skips head who already has a task", action: model.TimeoutActionEscalate, overdue: 2 * time.Hour,
+			head: &bob, admins: []model.User{spaceAdm},
+			wantTask: model.TaskStatusEscalated, wantStatus: model.WorkflowStatusProcessing,
+			wantAssign: []uint{spaceAdm.ID}, wantAction: model.WorkflowHistoryEscalated,
+		},
+		{
+			name: "escalate falls back to remind without targets", action: model.TimeoutActionEscalate, overdue: 2 * time.Hour,
+			wantTask: model.TaskStatusProcessing, wantStatus: model.WorkflowStatusProcessing,
+			wantAction: model.WorkflowHistoryReminded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, iam, db := newTestService(t)
+			iam.setRole(testSpaceID, "approver", alice, bob)
+			iam.setRole(testSpaceID, "admin", tt.admins...)
+			if tt.head != nil {
+				iam.setHead(alice.ID, *tt.head)
+			}
+
+			workflow := startWorkflow(t, s, model.Step{
+				StepName: "审批", StepOrder: 1, StepRole: "approver", TimeoutHours: 1,
+				ApprovalMode: model.ApprovalModeAll, TimeoutAction: tt.action,
+			})
+			task := openTasks(t, db, workflow.ID)[alice.ID]
+			// bob 的任务不超时，只检查 alice 的任务
+			if err := db.Model(&model.Task{}).Where("id != ?", task.ID).Update("due_at", nil).Error; err != nil {
+				t.Fatalf("clear due_at: %v", err)
+			}
+
+			if err := s.CheckTimeouts(task.DueAt.Add(tt.overdue), policy); err != nil {
+				t.Fatalf("CheckTimeouts() error = %v", err)
+			}
+
+			var got model.Task
+			if err := db.First(&got, task.ID).Error; err != nil {
+				t.Fatalf("load task: %v", err)
+			}
+			if got.Status != tt.wantTask {
+				t.Fatalf("task status = %s, want %s", got.Status, tt.wantTask)
+			}
+			if got.Status == model.TaskStatusApproved {
+				// 会签下自动同意只计为 alice 的一票，bob 同意后流程才通过
+				decide(t, s, db, workflow.ID, bob, model.TaskStatusApproved)
+			}
+			if status := reloadWorkflow(t, db, workflow.ID).Status; status != tt.wantStatus {
+				t.Fatalf("workflow status = %s, want %s", status, tt.wantStatus)
+			}
+
+			var escalated []model.Task
+			if err := db.Where("escalated_from_id = ?", task.ID).Find(&escalated).Error; err != nil {
+				t.Fatalf("load escalated tasks: %v", err)
+			}
+			if len(escalated) != len(tt.wantAssign) {
+				t.Fatalf("escalated tasks = %d, want %d", len(escalated), len(tt.wantAssign))
+			}
+			for i, e := range escalated {
+				if e.ApproverID != tt.wantAssign[i] || e.Status != model.TaskStatusProcessing {
+					t.Fatalf("escalated task %d = approver %d/%s, want %d/processing", i, e.ApproverID, e.Status, tt.wantAssign[i])
+				}
+			}
+
+			var actions []model.WorkflowHistoryAction
+			if err := db.Model(&model.WorkflowHistory{}).Where("task_id = ?", task.ID).Pluck("action", &actions).Error; err != nil {
+				t.Fatalf("load histories: %v", err)
+			}
+			if len(actions) != 1 || actions[0] != tt.wantAction {
+				t.Fatalf("task histories = %v, want [%s]", actions, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestCheckTimeoutsRemindInterval(t *testing.T) {
+	s, iam, db := newTestService(t)
+	iam.setRole(testSpaceID, "approver", alice)
+	workflow := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver", TimeoutHours: 1})
+	task := openTasks(t, db, workflow.ID)[alice.ID]
+
+	policy := TimeoutPolicy{RemindInterval: time.Hour}
+	start := task.DueAt.Add(time.Minute)
+	for _, now := range []time.Time{start, start.Add(30 * time.Minute), start.Add(90 * time.Minute)} {
+		if err := s.CheckTimeouts(now, policy); err != nil {
+			t.Fatalf("CheckTimeouts() error = %v", err)
+		}
+	}
+
+	var reminders int64
+	if err := db.Model(&model.WorkflowHistory{}).
+		Where("task_id = ? AND action = ?", task.ID, model.WorkflowHistoryReminded).
+		Count(&reminders).Error; err != nil {
+		t.Fatalf("count reminders: %v", err)
+	}
+	if reminders != 2 {
+		t.Fatalf("reminders = %d, want 2", reminders)
+	}
+}
+
+func TestCheckTimeoutsIgnoresTasksNotDue(t *testing.T) {
+	s, iam, db := newTestService(t)
+	iam.setRole(testSpaceID, "approver", alice)
+	workflow := startWorkflow(t, s, model.Step{
+		StepName: "审批", StepOrder: 1, StepRole: "approver", TimeoutHours: 1, TimeoutAction: model.TimeoutActionAutoApprove,
+	})
+	task := openTasks(t, db, workflow.ID)[alice.ID]
+
+	if err := s.CheckTimeouts(task.DueAt.Add(-time.Minute), TimeoutPolicy{}); err != nil {
+		t.Fatalf("CheckTimeouts() error = %v", err)
+	}
+	if status := reloadWorkflow(t, db, workflow.ID).Status; status != model.WorkflowStatusProcessing {
+		t.Fatalf("workflow status = %s, want processing", status)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWorkflowNotFound   = errors.New("工作流不存在")
+	ErrWorkflowForbidden  = errors.New("用户无权限操作该工作流")
+	ErrWorkflowNotRunning = errors.New("工作流不在审批中")
+	ErrWorkflowApproved   = errors.New("工作流已有审批人同意，不能撤回")
+)
+
+// WithdrawWorkflow 发起人在任何审批人同意之前撤回流程，资源回到可重新提交的状态
+func (s *WorkflowService) WithdrawWorkflow(id uint, req *model.WithdrawWorkflowRequest, user *model.User) (*model.Workflow, error) {
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	workflow, err := lockRunningWorkflow(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if workflow.CreatedBy != user.ID {
+		tx.Rollback()
+		return nil, ErrWorkflowForbidden
+	}
+
+	var approved int64
+	if err := tx.Model(&model.Task{}).
+		Where("workflow_id = ? AND status = ?", workflow.ID, model.TaskStatusApproved).
+		Count(&approved).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if approved > 0 {
+		tx.Rollback()
+		return nil, ErrWorkflowApproved
+	}
+
+	if err := s.terminateWorkflow(tx, workflow, model.WorkflowStatusWithdrawn, model.WorkflowHistoryWithdrawn,
+		model.WorkflowEventWithdrawn, user, req.Reason); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	s.dispatcher.Publish(context.Background(), model.WebhookEventWorkflowWithdrawn, workflow.SpaceID, workflow)
+	return workflow, nil
+}
+
+// CancelWorkflow 空间管理员取消审批中的流程，资源按审批未通过处理
+func (s *WorkflowService) CancelWorkflow(id uint, req *model.WithdrawWorkflowRequest, user *model.User) (*model.Workflow, error) {
+	workflow := &model.Workflow{}
+	if err := s.db.First(workflow, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkflowNotFound
+		}
+		return nil, err
+	}
+
+	admins, err := s.iamClient.GetSpaceMemebersByRole(user, workflow.SpaceID, string(model.SpaceMemberRoleAdmin))
+	if err != nil {
+		return nil, err
+	}
+	isAdmin := false
+	for _, admin := range admins {
+		if admin.ID == user.ID {
+			isAdmin = true
+			break
+		}
+	}
+	if !isAdmin {
+		return nil, ErrWorkflowForbidden
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	workflow, err = lockRunningWorkflow(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := s.terminateWorkflow(tx, workflow, model.WorkflowStatusCancelled, model.WorkflowHistoryCancelled,
+		model.WorkflowEventCancelled, user, req.Reason); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	s.dispatcher.Publish(context.Background(), model.WebhookEventWorkflowCancelled, workflow.SpaceID, workflow)
+	return workflow, nil
+}
+
+// lockRunningWorkflow 锁定流程当前步骤后加载流程，与审批使用相同的加锁顺序
+func lockRunningWorkflow(tx *gorm.DB, id uint) (*model.Workflow, error) {
+	workflow := &model.Workflow{}
+	if err := tx.First(workflow, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkflowNotFound
+		}
+		return nil, err
+	}
+	if workflow.CurrentStepID != 0 {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model.Step{}, workflow.CurrentStepID).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.First(workflow, id).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if workflow.Status != model.WorkflowStatusProcessing || workflow.CurrentStepID == 0 {
+		return nil, ErrWorkflowNotRunning
+	}
+	return workflow, nil
+}
+
+// terminateWorkflow 关闭未结束的任务和步骤，结束流程并通知资源
+func (s *WorkflowService) terminateWorkflow(tx *gorm.DB, workflow *model.Workflow, status model.WorkflowStatus, action model.WorkflowHistoryAction,
+	event model.WorkflowEventType, operator *model.User, reason string) error {
+	if err := tx.Model(&model.Task{}).
+		Where("workflow_id = ? AND status IN ?", workflow.ID, openTaskStatuses).
+		Update("status", model.TaskStatusCancelled).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := tx.Model(&model.Step{}).
+		Where("id = ? AND status = ?", workflow.CurrentStepID, model.StepStatusProcessing).
+		Updates(map[string]any{"status": model.StepStatusCancelled, "finished_at": now}).Error; err != nil {
+		return err
+	}
+
+	workflow.Status = status
+	workflow.FinishedAt = &now
+	if err := tx.Model(workflow).Updates(map[string]any{"status": status, "finished_at": now}).Error; err != nil {
+		return err
+	}
+
+	if err := recordHistory(tx, workflow.ID, workflow.CurrentStepID, 0, action, operator, reason); err != nil {
+		return err
+	}
+
+	return s.notifyRejected(tx, workflow, event)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gorm.io/gorm"
+)
+
+func TestWithdrawWorkflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		votes    []model.User // 撤回前同意的审批人
+		operator model.User
+		missing  bool
+		wantErr  error
+	}{
+		{name: "initiator before any approval", operator: initiator},
+		{name: "not the initiator", operator: alice, wantErr: ErrWorkflowForbidden},
+		{name: "after an approval", votes: []model.User{alice}, operator: initiator, wantErr: ErrWorkflowApproved},
+		{name: "finished workflow", votes: []model.User{alice, bob}, operator: initiator, wantErr: ErrWorkflowNotRunning},
+		{name: "missing workflow", operator: initiator, missing: true, wantErr: ErrWorkflowNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, iam, db := newTestService(t)
+			iam.setRole(testSpaceID, "approver", alice, bob)
+			workflow := startWorkflow(t, s, model.Step{
+				StepName: "会签", StepOrder: 1, StepRole: "approver", ApprovalMode: model.ApprovalModeAll,
+			})
+			for _, approver := range tt.votes {
+				decide(t, s, db, workflow.ID, approver, model.TaskStatusApproved)
+			}
+
+			id := workflow.ID
+			if tt.missing {
+				id += 100
+			}
+			_, err := s.WithdrawWorkflow(id, &model.WithdrawWorkflowRequest{Reason: "材料有误"}, &tt.operator)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WithdrawWorkflow() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			assertTerminated(t, db, workflow.ID, model.WorkflowStatusWithdrawn, model.WorkflowEventWithdrawn)
+		})
+	}
+}
+
+func TestCancelWorkflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator model.User
+		finished bool
+		wantErr  error
+	}{
+		{name: "space admin", operator: spaceAdm},
+		{name: "initiator is not space admin", operator: initiator, wantErr: ErrWorkflowForbidden},
+		{name: "approver is not space admin", operator: alice, wantErr: ErrWorkflowForbidden},
+		{name: "finished workflow", operator: spaceAdm, finished: true, wantErr: ErrWorkflowNotRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, iam, db := newTestService(t)
+			iam.setRole(testSpaceID, "approver", alice)
+			iam.setRole(testSpaceID, string(model.SpaceMemberRoleAdmin), spaceAdm)
+			workflow := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver"})
+			if tt.finished {
+				decide(t, s, db, workflow.ID, alice, model.TaskStatusApproved)
+			}
+
+			_, err := s.CancelWorkflow(workflow.ID, &model.WithdrawWorkflowRequest{}, &tt.operator)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CancelWorkflow() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			assertTerminated(t, db, workflow.ID, model.WorkflowStatusCancelled, model.WorkflowEventCancelled)
+		})
+	}
+}
+
+// assertTerminated 检查流程已结束、任务和当前步骤已关闭，并向资源所属服务写入了对应事件
+func assertTerminated(t *testing.T, db *gorm.DB, workflowID uint, status model.WorkflowStatus, event model.WorkflowEventType) {
+	t.Helper()
+	workflow := reloadWorkflow(t, db, workflowID)
+	if workflow.Status != status || workflow.FinishedAt == nil {
+		t.Fatalf("workflow status = %s (finished %v), want %s", workflow.Status, workflow.FinishedAt, status)
+	}
+	if tasks := openTasks(t, db, workflowID); len(tasks) != 0 {
+		t.Fatalf("open tasks after termination = %d, want 0", len(tasks))
+	}
+	var step model.Step
+	if err := db.First(&step, workflow.CurrentStepID).Error; err != nil {
+		t.Fatalf("load step: %v", err)
+	}
+	if step.Status != model.StepStatusCancelled {
+		t.Fatalf("step status = %s, want %s", step.Status, model.StepStatusCancelled)
+	}
+	var events []model.WorkflowEventType
+	if err := db.Model(&model.OutboxEvent{}).Where("aggregate_id = ?", workflowID).Pluck("event_type", &events).Error; err != nil {
+		t.Fatalf("load outbox events: %v", err)
+	}
+	if len(events) != 1 || events[0] != event {
+		t.Fatalf("outbox events = %v, want [%s]", events, event)
+	}
+}
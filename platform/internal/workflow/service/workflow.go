@@ -1,11 +1,17 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/client"
 	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+	"gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/webhook"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // User 用户模型（简化版，用于workflow服务）
@@ -15,16 +21,61 @@ type User struct {
 }
 
 type WorkflowService struct {
-	db        *gorm.DB
-	iamClient *client.IamClient
+	db         *gorm.DB
+	iamClient  *client.IamClient
+	dispatcher *webhook.Dispatcher
+	resources  *ResourceRegistry
+
+	defaultApproverIDs []uint // 找不到其他审批人时的兜底审批人
 }
 
-func NewWorkflowService(db *gorm.DB, iamClient *client.IamClient) *WorkflowService {
-	return &WorkflowService{db: db, iamClient: iamClient}
+func NewWorkflowService(db *gorm.DB, iamClient *client.IamClient, dispatcher *webhook.Dispatcher, defaultApproverIDs []uint) *WorkflowService {
+	return &WorkflowService{
+		db:                 db,
+		iamClient:          iamClient,
+		dispatcher:         dispatcher,
+		resources:          NewResourceRegistry(),
+		defaultApproverIDs: defaultApproverIDs,
+	}
 }
 
 // CreateWorkflow 创建审批流程
 func (s *WorkflowService) CreateWorkflow(req *model.CreateWorkflowRequest, user *model.User) (*model.Workflow, error) {
+	// 校验各步骤的审批规则
+	for i := range req.Steps {
+		if err := validateApprovalRule(&req.Steps[i]); err != nil {
+			return nil, err
+		}
+		if err := validateTimeoutPolicy(&req.Steps[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateBranching(req.Steps); err != nil {
+		return nil, err
+	}
+	if _, err := s.resources.Get(req.ResourceType); err != nil {
+		return nil, err
+	}
+
+	// 重新提交时接续上一轮流程
+	round := 1
+	if req.PreviousWorkflowID != 0 {
+		previous := &model.Workflow{}
+		if err := s.db.First(previous, req.PreviousWorkflowID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("上一轮流程不存在")
+			}
+			return nil, err
+		}
+		if previous.ResourceType != req.ResourceType || previous.ResourceID != req.ResourceID {
+			return nil, errors.New("上一轮流程不属于同一资源")
+		}
+		if previous.Status == model.WorkflowStatusProcessing {
+			return nil, errors.New("上一轮流程仍在审批中")
+		}
+		round = max(previous.Round, 1) + 1
+	}
+
 	// 开始事务
 	tx := s.db.Begin()
 	defer func() {
@@ -35,14 +86,16 @@ func (s *WorkflowService) CreateWorkflow(req *model.CreateWorkflowRequest, user
 
 	// 创建主工作流记录
 	result := model.Workflow{
-		Name:            req.Name,
-		Description:     req.Description,
-		SpaceID:         req.SpaceID,
-		Status:          model.WorkflowStatusProcessing,
-		CreatedBy:       user.ID,
-		CreatorNickName: user.Nickname,
-		ResourceType:    req.ResourceType,
-		ResourceID:      req.ResourceID,
+		Name:               req.Name,
+		Description:        req.Description,
+		SpaceID:            req.SpaceID,
+		Status:             model.WorkflowStatusProcessing,
+		CreatedBy:          user.ID,
+		CreatorNickName:    user.Nickname,
+		ResourceType:       req.ResourceType,
+		ResourceID:         req.ResourceID,
+		PreviousWorkflowID: req.PreviousWorkflowID,
+		Round:              round,
 	}
 	if err := tx.Create(&result).Error; err != nil {
 		tx.Rollback()
@@ -92,30 +145,8 @@ func (s *WorkflowService) StartWorkflow(req *model.StartWorkflowRequest, user *m
 		return nil, errors.New("用户无权限启动工作流")
 	}
 
-	currentStep := &model.Step{}
-	err = s.db.Where("workflow_id = ?", req.WorkflowID).Where("step_order = ?", 1).First(currentStep).Error
-	if err != nil {
-		return nil, err
-	}
-
-	// 创建任务 先通过iam获取有权限的user列表
-	userList, err := s.iamClient.GetSpaceMemebersByRole(user, workflow.SpaceID, currentStep.StepRole)
-	if err != nil {
-		return nil, err
-	}
-
-	tasks := make([]model.Task, len(userList))
-	for i, approver := range userList {
-		tasks[i] = model.Task{
-			WorkflowID:       req.WorkflowID,
-			StepID:           currentStep.ID,
-			ApproverID:       approver.ID,
-			ApproverNickName: approver.Nickname,
-			TaskName:         currentStep.StepName,
-			IsRequired:       currentStep.IsRequired,
-			TimeoutHours:     currentStep.TimeoutHours,
-			Status:           model.TaskStatusProcessing,
-		}
+	if workflow.CurrentStepID != 0 {
+		return nil, errors.New("工作流已启动")
 	}
 
 	tx := s.db.Begin()
@@ -125,15 +156,33 @@ func (s *WorkflowService) StartWorkflow(req *model.StartWorkflowRequest, user *m
 		}
 	}()
 
-	// 创建任务
-	if err := tx.Create(&tasks).Error; err != nil {
+	// 保存资源属性，分支条件据此求值
+	workflow.Attributes = req.Attributes
+	if handler, ok := s.resourceHandler(workflow); ok {
+		attributes, err := handler.Attributes(workflow, req.Attributes)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		workflow.Attributes = attributes
+	}
+	if err := tx.Save(workflow).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	// 更新工作流
-	workflow.CurrentStepID = currentStep.ID
-	if err := tx.Save(workflow).Error; err != nil {
+	comment := ""
+	if workflow.PreviousWorkflowID != 0 {
+		comment = fmt.Sprintf("第 %d 轮提交，上一轮流程 #%d", workflow.Round, workflow.PreviousWorkflowID)
+	}
+	if err := recordHistory(tx, workflow.ID, 0, 0, model.WorkflowHistoryStarted, user, comment); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 进入第一个满足条件的步骤并创建任务，没有满足条件的步骤时直接完成
+	tasks, finished, err := s.advanceWorkflow(tx, workflow, nil, user)
+	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
@@ -143,44 +192,194 @@ func (s *WorkflowService) StartWorkflow(req *model.StartWorkflowRequest, user *m
 		return nil, err
 	}
 
+	if finished {
+		s.publishWorkflowFinished(workflow)
+	}
+	s.publishTasksAssigned(workflow, tasks)
+
 	return workflow, nil
 }
 
-func (s *WorkflowService) GetTasks(user *model.User, page int, pageSize int) (model.PaginationResponse, error) {
+// GetTasks 按条件分页查询审批人的任务
+func (s *WorkflowService) GetTasks(user *model.User, query *model.TaskQuery) (model.PaginationResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize < 1 || query.PageSize > 100 {
+		query.PageSize = 10
+	}
+
 	var tasks []model.Task
 	var total int64
 
-	// 构建查询条件
-	query := s.db.Where("approver_id = ?", user.ID)
+	// 构建查询条件，流程相关的条件需要关联 workflows
+	db := s.db.Model(&model.Task{}).
+		Joins("JOIN workflows ON workflows.id = tasks.workflow_id").
+		Where("tasks.approver_id = ?", user.ID)
+	if query.Status != "" {
+		var statuses []model.TaskStatus
+		for _, status := range strings.Split(query.Status, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				statuses = append(statuses, model.TaskStatus(status))
+			}
+		}
+		db = db.Where("tasks.status IN ?", statuses)
+	}
+	if query.SpaceID != 0 {
+		db = db.Where("workflows.space_id = ?", query.SpaceID)
+	}
+	if query.ResourceType != "" {
+		db = db.Where("workflows.resource_type = ?", query.ResourceType)
+	}
+	if query.InitiatorID != 0 {
+		db = db.Where("workflows.created_by = ?", query.InitiatorID)
+	}
+	if !query.StartDate.IsZero() {
+		db = db.Where("tasks.created_at >= ?", query.StartDate)
+	}
+	if !query.EndDate.IsZero() {
+		db = db.Where("tasks.created_at < ?", query.EndDate.AddDate(0, 0, 1))
+	}
 
 	// 获取总数
-	if err := query.Model(&model.Task{}).Count(&total).Error; err != nil {
+	if err := db.Count(&total).Error; err != nil {
 		return model.PaginationResponse{}, err
 	}
 
 	// 分页查询，预加载关联的 Workflow
-	offset := (page - 1) * pageSize
-	if err := query.Preload("Workflow").
-		Order("id DESC").
-		Offset(offset).
-		Limit(pageSize).
+	if err := db.Preload("Workflow").Preload("Step").
+		Order(taskOrder(query.SortBy, query.SortOrder)).
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
 		Find(&tasks).Error; err != nil {
 		return model.PaginationResponse{}, err
 	}
 
 	// 计算总页数
-	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	totalPages := int((total + int64(query.PageSize) - 1) / int64(query.PageSize))
 
 	return model.PaginationResponse{
 		Items:      tasks,
 		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
 		TotalPages: totalPages,
 	}, nil
 }
 
+// taskSortColumns 任务列表允许的排序字段
+var taskSortColumns = map[string]string{
+	"id":         "tasks.id",
+	"created_at": "tasks.created_at",
+	"updated_at": "tasks.updated_at",
+	"due_at":     "tasks.due_at",
+}
+
+// taskOrder 生成任务列表的排序子句，未知字段按 id 排序，相同值再按 id 排序保证分页稳定
+func taskOrder(sortBy, sortOrder string) string {
+	column, ok := taskSortColumns[sortBy]
+	if !ok {
+		column = "tasks.id"
+	}
+	direction := "DESC"
+	if strings.EqualFold(sortOrder, "asc") {
+		direction = "ASC"
+	}
+	if column == "tasks.id" {
+		return column + " " + direction
+	}
+	return fmt.Sprintf("%s %s, tasks.id %s", column, direction, direction)
+}
+
+// GetPendingTaskCount 统计审批人待处理的任务数量
+func (s *WorkflowService) GetPendingTaskCount(user *model.User) (*model.PendingTaskCount, error) {
+	result := &model.PendingTaskCount{Spaces: []model.PendingTaskSpaceCount{}}
+
+	pending := func() *gorm.DB {
+		return s.db.Model(&model.Task{}).
+			Joins("JOIN workflows ON workflows.id = tasks.workflow_id").
+			Where("tasks.approver_id = ? AND tasks.status = ?", user.ID, model.TaskStatusProcessing)
+	}
+	if err := pending().Select("workflows.space_id AS space_id, COUNT(*) AS count").
+		Group("workflows.space_id").
+		Order("workflows.space_id ASC").
+		Scan(&result.Spaces).Error; err != nil {
+		return nil, err
+	}
+	for _, space := range result.Spaces {
+		result.Total += space.Count
+	}
+
+	if err := pending().Where("tasks.due_at < ?", time.Now()).Count(&result.Overdue).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BulkApproveTasks 批量审批，每个任务单独加锁和提交，单个任务失败不影响其他任务
+func (s *WorkflowService) BulkApproveTasks(req *model.BulkApproveTaskRequest, user *model.User) ([]model.BulkApproveTaskResult, error) {
+	if err := validateDecision(req.Status); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool, len(req.TaskIDs))
+	results := make([]model.BulkApproveTaskResult, 0, len(req.TaskIDs))
+	for _, taskID := range req.TaskIDs {
+		if seen[taskID] {
+			continue
+		}
+		seen[taskID] = true
+
+		task, err := s.ApproveTask(&model.ApproveTaskRequest{
+			TaskID:  taskID,
+			Comment: req.Comment,
+			Status:  req.Status,
+		}, user)
+		result := model.BulkApproveTaskResult{TaskID: taskID, Success: err == nil, Task: task}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetWorkflow 获取工作流及各步骤的审批规则和投票统计，只有发起人、审批人、所属空间成员和系统管理员可以查看
+func (s *WorkflowService) GetWorkflow(id uint, user *model.User) (*model.Workflow, error) {
+	workflow, err := s.loadWorkflow(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkflowNotFound
+		}
+		return nil, err
+	}
+	if err := s.checkWorkflowAccess(workflow, user); err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// loadWorkflow 加载工作流及步骤、任务和历史
+func (s *WorkflowService) loadWorkflow(id uint) (*model.Workflow, error) {
+	workflow := &model.Workflow{}
+	err := s.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("step_order ASC")
+	}).Preload("Steps.Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Histories", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(workflow, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
 func (s *WorkflowService) ApproveTask(req *model.ApproveTaskRequest, user *model.User) (*model.Task, error) {
+	if err := validateDecision(req.Status); err != nil {
+		return nil, err
+	}
+
 	// 开始事务
 	tx := s.db.Begin()
 	defer func() {
@@ -190,160 +389,327 @@ func (s *WorkflowService) ApproveTask(req *model.ApproveTaskRequest, user *model
 	}()
 
 	// 加载任务及其关联数据
-	task := &model.Task{}
-	err := tx.Preload("Workflow").Preload("Step").Where("id = ?", req.TaskID).First(task).Error
+	task, err := lockTask(tx, req.TaskID)
 	if err != nil {
 		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
 		return nil, err
 	}
 
 	// 检查权限
 	if task.ApproverID != user.ID {
 		tx.Rollback()
-		return nil, errors.New("用户无权限审批任务")
+		return nil, ErrTaskForbidden
 	}
 
 	// 检查任务状态
 	if task.Status != model.TaskStatusProcessing {
 		tx.Rollback()
-		return nil, errors.New("任务状态不正确，无法审批")
+		return nil, ErrTaskNotProcessing
+	}
+
+	action := model.WorkflowHistoryApproved
+	if req.Status == model.TaskStatusRejected {
+		action = model.WorkflowHistoryRejected
+	}
+	if err := recordHistory(tx, task.WorkflowID, task.StepID, task.ID, action, user, req.Comment); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	assignedTasks, finished, err := s.decideTask(tx, task, req, user)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
 	}
 
+	s.publishTasksAssigned(&task.Workflow, assignedTasks)
+	if finished {
+		s.publishWorkflowFinished(&task.Workflow)
+	}
+
+	return task, nil
+}
+
+// decideTask 记录一次审批并按步骤的审批规则推进流程，
+// 返回新分配的任务以及工作流是否已结束（通过或驳回）
+func (s *WorkflowService) decideTask(tx *gorm.DB, task *model.Task, req *model.ApproveTaskRequest, user *model.User) ([]model.Task, bool, error) {
 	// 更新当前任务状态和备注
+	now := time.Now()
 	task.Status = req.Status
 	task.Comment = req.Comment
+	task.DecidedAt = &now
 	if err := tx.Save(task).Error; err != nil {
-		tx.Rollback()
-		return nil, err
+		return nil, false, err
+	}
+
+	// 升级任务由任一接手人处理即可，关闭同一来源的其他升级任务
+	if task.EscalatedFromID != 0 {
+		closedStatus := model.TaskStatusApprovedByOther
+		if req.Status == model.TaskStatusRejected {
+			closedStatus = model.TaskStatusRejectedByOther
+		}
+		if err := tx.Model(&model.Task{}).
+			Where("escalated_from_id = ? AND id != ? AND status = ?", task.EscalatedFromID, task.ID, model.TaskStatusProcessing).
+			Update("status", closedStatus).Error; err != nil {
+			return nil, false, err
+		}
+	}
+
+	// 加签链中间环节不计票，只把任务交给链上的下一个人
+	next, counted, err := resolveAddedApprover(tx, task, req.Status)
+	if err != nil {
+		return nil, false, err
+	}
+	if !counted {
+		return []model.Task{*next}, false, nil
 	}
 
+	// 更新投票统计
+	step := &task.Step
 	if req.Status == model.TaskStatusApproved {
+		step.ApprovedCount++
+	} else {
+		step.RejectedCount++
+	}
+
+	switch evaluateStep(step) {
+	case stepApproved:
 		// 批准：更新 Step 状态
-		task.Step.Status = model.StepStatusApproved
-		if err := tx.Save(&task.Step).Error; err != nil {
-			tx.Rollback()
-			return nil, err
+		step.Status = model.StepStatusApproved
+		step.FinishedAt = &now
+		if err := tx.Save(step).Error; err != nil {
+			return nil, false, err
 		}
 
-		// 更新该 Step 的其他任务为 ApprovedByOther（排除当前任务）
+		// 更新该 Step 的其他未处理任务为 ApprovedByOther
 		if err := tx.Model(&model.Task{}).
-			Where("step_id = ? AND id != ? AND status = ?", task.StepID, task.ID, model.TaskStatusProcessing).
+			Where("step_id = ? AND id != ? AND status IN ?", task.StepID, task.ID, openTaskStatuses).
 			Update("status", model.TaskStatusApprovedByOther).Error; err != nil {
-			tx.Rollback()
-			return nil, err
+			return nil, false, err
 		}
 
-		// 查找下一个步骤
-		nextStep := &model.Step{}
-		err := tx.Where("workflow_id = ? AND step_order = ?", task.WorkflowID, task.Step.StepOrder+1).First(nextStep).Error
+		return s.advanceWorkflow(tx, &task.Workflow, step, user)
 
-		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-			// 数据库错误
-			tx.Rollback()
-			return nil, err
+	case stepRejected:
+		// 拒绝：更新 Step 状态
+		step.Status = model.StepStatusRejected
+		step.FinishedAt = &now
+		if err := tx.Save(step).Error; err != nil {
+			return nil, false, err
 		}
 
-		if err == nil && nextStep.ID != 0 {
-			// 有下一步：更新 Workflow 的当前步骤
-			task.Workflow.CurrentStepID = nextStep.ID
-			if err := tx.Save(&task.Workflow).Error; err != nil {
-				tx.Rollback()
-				return nil, err
-			}
+		// 更新该 Step 的其他未处理任务为 RejectedByOther
+		if err := tx.Model(&model.Task{}).
+			Where("step_id = ? AND id != ? AND status IN ?", task.StepID, task.ID, openTaskStatuses).
+			Update("status", model.TaskStatusRejectedByOther).Error; err != nil {
+			return nil, false, err
+		}
 
-			// 创建下一步的任务
-			userList, err := s.iamClient.GetSpaceMemebersByRole(user, task.Workflow.SpaceID, nextStep.StepRole)
-			if err != nil {
-				tx.Rollback()
-				return nil, err
-			}
+		// 更新 Workflow 状态为已拒绝
+		task.Workflow.Status = model.WorkflowStatusRejected
+		task.Workflow.FinishedAt = &now
+		if err := tx.Save(&task.Workflow).Error; err != nil {
+			return nil, false, err
+		}
 
-			if len(userList) == 0 {
-				tx.Rollback()
-				return nil, errors.New("未找到下一步骤的审批人")
-			}
+		// 通知资源审批被拒绝
+		if err := s.notifyRejected(tx, &task.Workflow, model.WorkflowEventRejected); err != nil {
+			return nil, false, err
+		}
+		return nil, true, nil
 
-			tasks := make([]model.Task, len(userList))
-			for i, approver := range userList {
-				tasks[i] = model.Task{
-					WorkflowID:       task.WorkflowID,
-					StepID:           nextStep.ID,
-					ApproverID:       approver.ID,
-					ApproverNickName: approver.Nickname,
-					TaskName:         nextStep.StepName,
-					IsRequired:       nextStep.IsRequired,
-					TimeoutHours:     nextStep.TimeoutHours,
-					Status:           model.TaskStatusProcessing,
-				}
-			}
-			if err := tx.Create(&tasks).Error; err != nil {
-				tx.Rollback()
-				return nil, err
-			}
-		} else {
-			// 没有下一步：工作流完成
-			task.Workflow.Status = model.WorkflowStatusCompleted
-			if err := tx.Save(&task.Workflow).Error; err != nil {
-				tx.Rollback()
-				return nil, err
-			}
+	default:
+		// 尚未满足规则，等待其他审批人
+		if err := tx.Save(step).Error; err != nil {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+}
 
-			// 更新资源状态
-			if task.Workflow.ResourceType == "document" {
-				doc := &model.Document{}
-				if err := tx.Where("id = ?", task.Workflow.ResourceID).First(doc).Error; err != nil {
-					tx.Rollback()
-					return nil, err
-				}
-				doc.Status = model.DocumentStatusPendingPublish
-				if err := tx.Save(doc).Error; err != nil {
-					tx.Rollback()
-					return nil, err
-				}
-			}
+// advanceWorkflow 根据当前步骤的流转规则进入下一步骤，没有下一步骤时完成工作流；
+// current 为 nil 时从第一个满足条件的步骤开始
+func (s *WorkflowService) advanceWorkflow(tx *gorm.DB, workflow *model.Workflow, current *model.Step, user *model.User) ([]model.Task, bool, error) {
+	// 查找下一个步骤
+	nextStep, err := resolveNextStep(tx, workflow, current)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if nextStep != nil {
+		// 有下一步：更新 Workflow 的当前步骤
+		workflow.CurrentStepID = nextStep.ID
+		if err := tx.Save(workflow).Error; err != nil {
+			return nil, false, err
 		}
-	} else {
-		// 拒绝：更新 Step 状态
-		task.Step.Status = model.StepStatusRejected
-		if err := tx.Save(&task.Step).Error; err != nil {
-			tx.Rollback()
-			return nil, err
+		if err := recordHistory(tx, workflow.ID, nextStep.ID, 0, model.WorkflowHistoryStepStarted, nil, nextStep.StepName); err != nil {
+			return nil, false, err
 		}
 
-		// 更新该 Step 的其他任务为 RejectedByOther（排除当前任务）
-		if err := tx.Model(&model.Task{}).
-			Where("step_id = ? AND id != ? AND status = ?", task.StepID, task.ID, model.TaskStatusProcessing).
-			Update("status", model.TaskStatusRejectedByOther).Error; err != nil {
-			tx.Rollback()
+		// 创建下一步的任务
+		tasks, err := s.createStepTasks(tx, workflow, nextStep, user)
+		if err != nil {
+			return nil, false, err
+		}
+		return tasks, false, nil
+	}
+
+	// 没有下一步：工作流完成
+	now := time.Now()
+	workflow.Status = model.WorkflowStatusCompleted
+	workflow.FinishedAt = &now
+	if err := tx.Save(workflow).Error; err != nil {
+		return nil, false, err
+	}
+	if err := recordHistory(tx, workflow.ID, 0, 0, model.WorkflowHistoryCompleted, nil, ""); err != nil {
+		return nil, false, err
+	}
+
+	// 通知资源审批通过
+	if err := s.notifyApproved(tx, workflow); err != nil {
+		return nil, false, err
+	}
+	return nil, true, nil
+}
+
+// createStepTasks 为步骤的每个审批人创建任务，并根据审批人数计算通过所需的同意人数
+func (s *WorkflowService) createStepTasks(tx *gorm.DB, workflow *model.Workflow, step *model.Step, user *model.User) ([]model.Task, error) {
+	// 先通过iam获取有权限的user列表，没有时回退到兜底审批人
+	userList, fallback, err := s.resolveApprovers(workflow, step, user)
+	if err != nil {
+		return nil, err
+	}
+	if fallback != "" {
+		if err := recordHistory(tx, workflow.ID, step.ID, 0, model.WorkflowHistoryApproverFallback, nil, fallback); err != nil {
 			return nil, err
 		}
+	}
 
-		// 更新 Workflow 状态为已取消
-		task.Workflow.Status = model.WorkflowStatusCancelled
-		if err := tx.Save(&task.Workflow).Error; err != nil {
-			tx.Rollback()
+	startedAt := time.Now()
+	dueAt := taskDueAt(step.TimeoutHours)
+	tasks := make([]model.Task, len(userList))
+	for i, approver := range userList {
+		tasks[i] = model.Task{
+			WorkflowID:       workflow.ID,
+			StepID:           step.ID,
+			ApproverID:       approver.ID,
+			ApproverNickName: approver.Nickname,
+			TaskName:         step.StepName,
+			IsRequired:       step.IsRequired,
+			TimeoutHours:     step.TimeoutHours,
+			Status:           model.TaskStatusProcessing,
+			DueAt:            dueAt,
+		}
+	}
+	// 外出委托的审批人由代理人审批
+	delegated, err := s.applyDelegations(tx, workflow, step, tasks, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Create(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	nicknames := make(map[uint]string, len(userList))
+	for _, approver := range userList {
+		nicknames[approver.ID] = approver.Nickname
+	}
+	for _, i := range delegated {
+		comment := fmt.Sprintf("%s 外出委托，由 %s 代为审批", nicknames[tasks[i].OriginalApproverID], tasks[i].ApproverNickName)
+		if err := recordHistory(tx, workflow.ID, step.ID, tasks[i].ID, model.WorkflowHistoryDelegated, nil, comment); err != nil {
 			return nil, err
 		}
+	}
 
-		// 更新资源状态为失败
-		if task.Workflow.ResourceType == "document" {
-			doc := &model.Document{}
-			if err := tx.Where("id = ?", task.Workflow.ResourceID).First(doc).Error; err != nil {
-				tx.Rollback()
-				return nil, err
-			}
-			doc.Status = model.DocumentStatusFailed
-			if err := tx.Save(doc).Error; err != nil {
-				tx.Rollback()
-				return nil, err
-			}
+	step.StartedAt = &startedAt
+	step.TotalCount = len(tasks)
+	step.RequiredCount = requiredApprovals(step, len(tasks))
+	step.ApprovedCount = 0
+	step.RejectedCount = 0
+	if err := tx.Save(step).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// publishWorkflowFinished 流程通过时发送 workflow.completed 事件，被驳回时发送 workflow.rejected 事件
+func (s *WorkflowService) publishWorkflowFinished(workflow *model.Workflow) {
+	switch workflow.Status {
+	case model.WorkflowStatusCompleted:
+		s.dispatcher.Publish(context.Background(), model.WebhookEventWorkflowCompleted, workflow.SpaceID, workflow)
+	case model.WorkflowStatusRejected:
+		s.dispatcher.Publish(context.Background(), model.WebhookEventWorkflowRejected, workflow.SpaceID, workflow)
+	}
+}
+
+// publishTasksAssigned 为每个新分配的任务发送 task.assigned 事件
+func (s *WorkflowService) publishTasksAssigned(workflow *model.Workflow, tasks []model.Task) {
+	for i := range tasks {
+		s.dispatcher.Publish(context.Background(), model.WebhookEventTaskAssigned, workflow.SpaceID, map[string]any{
+			"task":     &tasks[i],
+			"workflow": workflow,
+		})
+	}
+}
+
+// taskDueAt 根据超时小时数计算任务截止时间，0 表示不限时
+func taskDueAt(timeoutHours int) *time.Time {
+	if timeoutHours <= 0 {
+		return nil
+	}
+	dueAt := time.Now().Add(time.Duration(timeoutHours) * time.Hour)
+	return &dueAt
+}
+
+// recordHistory 写入工作流历史，operator 为 nil 表示系统自动处理
+func recordHistory(tx *gorm.DB, workflowID, stepID, taskID uint, action model.WorkflowHistoryAction, operator *model.User, comment string) error {
+	history := model.WorkflowHistory{
+		WorkflowID:   workflowID,
+		StepID:       stepID,
+		TaskID:       taskID,
+		Action:       action,
+		OperatorName: "系统",
+		Comment:      comment,
+	}
+	if operator != nil {
+		history.OperatorID = operator.ID
+		history.OperatorName = operator.Nickname
+		if history.OperatorName == "" {
+			history.OperatorName = operator.Username
 		}
 	}
+	return tx.Create(&history).Error
+}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
+// openTaskStatuses 尚未结束的任务状态，步骤结束时一并关闭
+var openTaskStatuses = []model.TaskStatus{model.TaskStatusProcessing, model.TaskStatusWaiting}
+
+// lockTask 锁定任务所在步骤后加载任务，避免并行审批、转交和加签时相互覆盖
+func lockTask(tx *gorm.DB, taskID uint) (*model.Task, error) {
+	task := &model.Task{}
+	if err := tx.Select("step_id").First(task, taskID).Error; err != nil {
 		return nil, err
 	}
 
+	step := model.Step{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&step, task.StepID).Error; err != nil {
+		return nil, err
+	}
+
+	// 加锁后重新加载任务，其他审批人可能已经决定了该步骤
+	if err := tx.Preload("Workflow").First(task, taskID).Error; err != nil {
+		return nil, err
+	}
+	task.Step = step
 	return task, nil
 }
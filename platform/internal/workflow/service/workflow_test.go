@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	model "gitee.com/sichuan-shutong-zhihui-data/k-base/internal/common/models"
+)
+
+func TestWorkflowFinishedWebhookEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision model.TaskStatus
+		status   model.WorkflowStatus
+		event    model.WebhookEvent
+	}{
+		{name: "approved workflow publishes completed", decision: model.TaskStatusApproved, status: model.WorkflowStatusCompleted, event: model.WebhookEventWorkflowCompleted},
+		{name: "rejected workflow publishes rejected", decision: model.TaskStatusRejected, status: model.WorkflowStatusRejected, event: model.WebhookEventWorkflowRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, iam, db := newTestService(t)
+			iam.setRole(testSpaceID, "approver", alice)
+			// 订阅地址指向本机，投递会在连接前被拒绝，这里只检查写入的投递记录
+			if err := db.Create(&model.WebhookSubscription{
+				SpaceID: testSpaceID,
+				URL:     "http://127.0.0.1:9/hook",
+				Secret:  "secret",
+				Events:  []model.WebhookEvent{model.WebhookEventWorkflowCompleted, model.WebhookEventWorkflowRejected},
+				Status:  1,
+			}).Error; err != nil {
+				t.Fatalf("create subscription: %v", err)
+			}
+
+			workflow := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver"})
+			decide(t, s, db, workflow.ID, alice, tt.decision)
+
+			if got := reloadWorkflow(t, db, workflow.ID).Status; got != tt.status {
+				t.Fatalf("workflow status = %s, want %s", got, tt.status)
+			}
+			var events []model.WebhookEvent
+			if err := db.Model(&model.WebhookDelivery{}).Order("id").Pluck("event", &events).Error; err != nil {
+				t.Fatalf("load deliveries: %v", err)
+			}
+			if len(events) != 1 || events[0] != tt.event {
+				t.Fatalf("published events = %v, want [%s]", events, tt.event)
+			}
+		})
+	}
+}
+
+func TestGetWorkflowAccess(t *testing.T) {
+	s, iam, db := newTestService(t)
+	iam.setRole(testSpaceID, "approver", alice)
+	workflow := startWorkflow(t, s, model.Step{StepName: "审批", StepOrder: 1, StepRole: "approver"})
+
+	// carol 是空间成员，dave 与流程无关
+	if err := db.Create(&model.SpaceMember{SpaceID: testSpaceID, UserID: carol.ID, Roles: []model.SpaceMemberRole{model.SpaceMemberRoleReader}}).Error; err != nil {
+		t.Fatalf("create space member: %v", err)
+	}
+	superAdmin := testUser(10, "super-admin")
+	superAdmin.Roles = []model.Role{{Name: model.RoleSuperAdmin}}
+
+	tests := []struct {
+		name    string
+		user    model.User
+		wantErr error
+	}{
+		{name: "initiator", user: initiator},
+		{name: "approver", user: alice},
+		{name: "space member", user: carol},
+		{name: "global admin", user: superAdmin},
+		{name: "unrelated user", user: dave, wantErr: ErrWorkflowForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.GetWorkflow(workflow.ID, &tt.user); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetWorkflow() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := s.GetWorkflowStatus(workflow.ID, &tt.user); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetWorkflowStatus() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := s.GetWorkflow(workflow.ID+100, &initiator); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Fatalf("GetWorkflow(missing) error = %v, want %v", err, ErrWorkflowNotFound)
+	}
+}